@@ -0,0 +1,104 @@
+// Command migrate applies pending database migrations, the same ones the server runs at startup.
+//
+// Usage:
+//
+//	MONGO_URI=mongodb://localhost:27017 go run ./cmd/migrate [-dry-run] [-status]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/db"
+	"github.com/riverajo/fitness-app/backend/internal/migrations"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+func main() {
+	mongoURI := flag.String("mongo-uri", os.Getenv("MONGO_URI"), "MongoDB connection string (defaults to $MONGO_URI)")
+	database := flag.String("db", "fitness_db", "database name")
+	dryRun := flag.Bool("dry-run", false, "list pending migrations and the documents they would change, without applying them")
+	status := flag.Bool("status", false, "list applied migrations and exit")
+	timeout := flag.Duration("timeout", 10*time.Minute, "give up after this long, including time spent waiting for another node's migrations")
+	flag.Parse()
+
+	if err := run(*mongoURI, *database, *dryRun, *status, *timeout); err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		os.Exit(1)
+	}
+}
+
+func run(mongoURI, database string, dryRun, status bool, timeout time.Duration) error {
+	client, err := db.Connect(mongoURI)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	mdb := client.Database(database)
+	runner := migrations.NewRunner(mdb, migrations.All,
+		repository.NewMongoUserRepository(mdb),
+		repository.NewMongoWorkoutRepository(mdb),
+		repository.NewMongoExerciseRepository(mdb),
+		repository.NewMongoRefreshTokenRepository(mdb),
+		repository.NewMongoBodyMetricRepository(mdb),
+		repository.NewMongoGoalRepository(mdb),
+		repository.NewMongoRateLimitRepository(mdb),
+		repository.NewMongoActionTokenRepository(mdb),
+		repository.NewMongoPasskeyRepository(mdb),
+		repository.NewMongoExternalIdentityRepository(mdb),
+		repository.NewMongoLeaseRepository(mdb),
+	)
+
+	if status {
+		applied, err := runner.Applied(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("No migrations applied.")
+		}
+		for _, m := range applied {
+			fmt.Printf("%4d  %-50s  %s\n", m.Version, m.Name, m.AppliedAt.Format(time.RFC3339))
+		}
+		return nil
+	}
+
+	if dryRun {
+		missing, err := runner.MissingIndexes(ctx)
+		if err != nil {
+			return err
+		}
+		for _, name := range missing {
+			fmt.Printf("index %s will be created\n", name)
+		}
+	}
+
+	runner.DryRun = dryRun
+	results, err := runner.Run(ctx)
+	for _, r := range results {
+		switch {
+		case !r.Applied && r.Pending >= 0:
+			fmt.Printf("%4d  %-50s  pending, %d documents to change\n", r.Version, r.Name, r.Pending)
+		case !r.Applied:
+			fmt.Printf("%4d  %-50s  pending\n", r.Version, r.Name)
+		default:
+			fmt.Printf("%4d  %-50s  applied in %s\n", r.Version, r.Name, r.Duration.Round(time.Millisecond))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		fmt.Println("Database is up to date.")
+	}
+	return nil
+}
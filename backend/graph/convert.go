@@ -0,0 +1,39 @@
+package graph
+
+import (
+	model1 "github.com/riverajo/fitness-app/backend/graph/model"
+	internalModel "github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+// Helpers for mapping between GraphQL inputs and domain models.
+// They live outside schema.resolvers.go so gqlgen does not relocate them on regeneration.
+
+// unitOrDefault returns the requested output unit, falling back to the storage unit (kilograms).
+func unitOrDefault(unit *internalModel.WeightUnit) internalModel.WeightUnit {
+	if unit == nil || *unit == "" {
+		return internalModel.WeightUnitKilograms
+	}
+	return *unit
+}
+
+func toInternalMeasurements(input []*model1.BodyMeasurementInput) []*internalModel.BodyMeasurement {
+	if input == nil {
+		return nil
+	}
+	measurements := make([]*internalModel.BodyMeasurement, 0, len(input))
+	for _, m := range input {
+		measurements = append(measurements, &internalModel.BodyMeasurement{
+			Site:  m.Site,
+			Value: m.Value,
+		})
+	}
+	return measurements
+}
+
+// goalValueInUnit converts weight-based goal values from kilograms; FREQUENCY values are returned unchanged.
+func goalValueInUnit(goalType internalModel.GoalType, value float64, unit *internalModel.WeightUnit) float64 {
+	if !goalType.IsWeightBased() {
+		return value
+	}
+	return unitOrDefault(unit).FromKilograms(value)
+}
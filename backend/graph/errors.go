@@ -0,0 +1,69 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/riverajo/fitness-app/backend/internal/service"
+)
+
+// Error codes returned in the "code" extension of structured GraphQL errors.
+const (
+	ErrCodeDuplicateExercise = "DUPLICATE_EXERCISE"
+	ErrCodeSimilarExercise   = "SIMILAR_EXERCISE"
+	ErrCodeRateLimited       = "RATE_LIMITED"
+	ErrCodeInvalidPassword   = "INVALID_PASSWORD"
+)
+
+// ErrorPresenter adds machine-readable extensions to known service errors so clients
+// can react to them (e.g. offer the existing exercise) instead of parsing messages.
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+
+	var similar *service.SimilarExerciseError
+	if errors.As(err, &similar) {
+		code := ErrCodeSimilarExercise
+		if similar.Duplicate {
+			code = ErrCodeDuplicateExercise
+		}
+		setExtensions(gqlErr, map[string]any{
+			"code":                 code,
+			"existingExerciseId":   similar.ExistingID,
+			"existingExerciseName": similar.ExistingName,
+		})
+	}
+
+	var limited *service.RateLimitedError
+	if errors.As(err, &limited) {
+		setExtensions(gqlErr, map[string]any{
+			"code": ErrCodeRateLimited,
+			// Whole seconds, rounded up so clients retrying on time are not rejected again
+			"retryAfter": int(math.Ceil(limited.RetryAfter.Seconds())),
+		})
+	}
+
+	var invalidPassword *service.InvalidPasswordError
+	if errors.As(err, &invalidPassword) {
+		setExtensions(gqlErr, map[string]any{
+			"code":       ErrCodeInvalidPassword,
+			"violations": invalidPassword.Violations,
+			"minLength":  invalidPassword.MinLength,
+			"maxBytes":   invalidPassword.MaxBytes,
+		})
+	}
+
+	return gqlErr
+}
+
+func setExtensions(gqlErr *gqlerror.Error, extensions map[string]any) {
+	if gqlErr.Extensions == nil {
+		gqlErr.Extensions = make(map[string]any, len(extensions))
+	}
+	for k, v := range extensions {
+		gqlErr.Extensions[k] = v
+	}
+}
@@ -9,15 +9,41 @@ import (
 )
 
 type AuthPayload struct {
-	Success bool        `json:"success"`
-	Message string      `json:"message"`
-	User    *model.User `json:"user,omitempty"`
-	Token   string      `json:"token"`
+	Success           bool        `json:"success"`
+	Message           string      `json:"message"`
+	User              *model.User `json:"user,omitempty"`
+	Token             string      `json:"token"`
+	TwoFactorRequired bool        `json:"twoFactorRequired"`
+	ChallengeToken    *string     `json:"challengeToken,omitempty"`
+}
+
+type BodyMeasurementInput struct {
+	Site  model.MeasurementSite `json:"site"`
+	Value float64               `json:"value"`
+}
+
+type CreateGoalInput struct {
+	Type        model.GoalType   `json:"type"`
+	Name        string           `json:"name"`
+	ExerciseID  *string          `json:"exerciseId,omitempty"`
+	TargetValue float64          `json:"targetValue"`
+	TargetReps  *int32           `json:"targetReps,omitempty"`
+	Unit        model.WeightUnit `json:"unit"`
+	StartDate   *time.Time       `json:"startDate,omitempty"`
+	Deadline    *time.Time       `json:"deadline,omitempty"`
 }
 
 type CreateUniqueExerciseInput struct {
-	Name        string  `json:"name"`
-	Description *string `json:"description,omitempty"`
+	Name             string                  `json:"name"`
+	Description      *string                 `json:"description,omitempty"`
+	Aliases          []string                `json:"aliases,omitempty"`
+	Category         *model.ExerciseCategory `json:"category,omitempty"`
+	PrimaryMuscles   []model.MuscleGroup     `json:"primaryMuscles,omitempty"`
+	SecondaryMuscles []model.MuscleGroup     `json:"secondaryMuscles,omitempty"`
+	Equipment        *model.Equipment        `json:"equipment,omitempty"`
+	MovementPattern  *model.MovementPattern  `json:"movementPattern,omitempty"`
+	Unilateral       *bool                   `json:"unilateral,omitempty"`
+	AllowSimilar     *bool                   `json:"allowSimilar,omitempty"`
 }
 
 type CreateWorkoutLogInput struct {
@@ -35,11 +61,25 @@ type ExerciseLogInput struct {
 	Notes            *string     `json:"notes,omitempty"`
 }
 
+type LogBodyMetricInput struct {
+	RecordedAt        *time.Time              `json:"recordedAt,omitempty"`
+	Unit              model.WeightUnit        `json:"unit"`
+	Weight            *float64                `json:"weight,omitempty"`
+	BodyFatPercentage *float64                `json:"bodyFatPercentage,omitempty"`
+	Measurements      []*BodyMeasurementInput `json:"measurements,omitempty"`
+	Notes             *string                 `json:"notes,omitempty"`
+}
+
 type LoginInput struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
+type LoginProvider struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
 type Mutation struct {
 }
 
@@ -60,11 +100,51 @@ type SetInput struct {
 	Order     int32            `json:"order"`
 }
 
+type TotpEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauthUri"`
+}
+
+type UpdateBodyMetricInput struct {
+	ID                string                  `json:"id"`
+	RecordedAt        *time.Time              `json:"recordedAt,omitempty"`
+	Unit              model.WeightUnit        `json:"unit"`
+	Weight            *float64                `json:"weight,omitempty"`
+	BodyFatPercentage *float64                `json:"bodyFatPercentage,omitempty"`
+	Measurements      []*BodyMeasurementInput `json:"measurements,omitempty"`
+	Notes             *string                 `json:"notes,omitempty"`
+}
+
+type UpdateGoalInput struct {
+	ID          string           `json:"id"`
+	Name        *string          `json:"name,omitempty"`
+	TargetValue *float64         `json:"targetValue,omitempty"`
+	TargetReps  *int32           `json:"targetReps,omitempty"`
+	Unit        model.WeightUnit `json:"unit"`
+	Deadline    *time.Time       `json:"deadline,omitempty"`
+}
+
+type UpdateUniqueExerciseInput struct {
+	ID               string                  `json:"id"`
+	Name             *string                 `json:"name,omitempty"`
+	Description      *string                 `json:"description,omitempty"`
+	Aliases          []string                `json:"aliases,omitempty"`
+	Category         *model.ExerciseCategory `json:"category,omitempty"`
+	PrimaryMuscles   []model.MuscleGroup     `json:"primaryMuscles,omitempty"`
+	SecondaryMuscles []model.MuscleGroup     `json:"secondaryMuscles,omitempty"`
+	Equipment        *model.Equipment        `json:"equipment,omitempty"`
+	MovementPattern  *model.MovementPattern  `json:"movementPattern,omitempty"`
+	Unilateral       *bool                   `json:"unilateral,omitempty"`
+}
+
 type UpdateUserInput struct {
 	Email           *string           `json:"email,omitempty"`
 	CurrentPassword string            `json:"currentPassword"`
 	NewPassword     *string           `json:"newPassword,omitempty"`
 	PreferredUnit   *model.WeightUnit `json:"preferredUnit,omitempty"`
+	Timezone        *string           `json:"timezone,omitempty"`
+	TrainingDays    []model.Weekday   `json:"trainingDays,omitempty"`
+	Locale          *string           `json:"locale,omitempty"`
 }
 
 type UpdateWorkoutLogInput struct {
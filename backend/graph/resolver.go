@@ -3,9 +3,24 @@ package graph
 //go:generate go run github.com/99designs/gqlgen generate
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
 	"github.com/riverajo/fitness-app/backend/internal/config"
+	"github.com/riverajo/fitness-app/backend/internal/loaders"
+	"github.com/riverajo/fitness-app/backend/internal/mailer"
+	"github.com/riverajo/fitness-app/backend/internal/middleware"
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/oidc"
 	"github.com/riverajo/fitness-app/backend/internal/repository"
 	"github.com/riverajo/fitness-app/backend/internal/service"
+	"github.com/riverajo/fitness-app/backend/internal/webauthn"
 )
 
 // This file will not be regenerated automatically.
@@ -14,28 +29,290 @@ import (
 
 type Resolver struct {
 	// 💡 Inject the service dependencies here
-	WorkoutService  *service.WorkoutService
-	UserService     *service.UserService
-	ExerciseService *service.ExerciseService
-	TokenService    *service.TokenService
-	JWTSecret       string
-	Config          *config.Config
+	WorkoutService    *service.WorkoutService
+	UserService       *service.UserService
+	ExerciseService   *service.ExerciseService
+	TokenService      *service.TokenService
+	BodyMetricService *service.BodyMetricService
+	GoalService       *service.GoalService
+	CalendarService   *service.CalendarService
+	RateLimiter       *service.RateLimiter
+	AccountService    *service.AccountService
+	AccountDeletion   *service.AccountDeletionService
+	TwoFactorService  *service.TwoFactorService
+	PasskeyService    *service.PasskeyService
+	OIDCService       *service.OIDCService
+	Mailer            mailer.Mailer
+	JWTSecret         string
+	Config            *config.Config
+}
+
+// Repositories groups the data access dependencies the services are built from.
+type Repositories struct {
+	Users         repository.UserRepository
+	Workouts      repository.WorkoutRepository
+	Exercises     repository.ExerciseRepository
+	RefreshTokens repository.RefreshTokenRepository
+	BodyMetrics   repository.BodyMetricRepository
+	Goals         repository.GoalRepository
+	Transactor    repository.Transactor
+	ActionTokens  repository.ActionTokenRepository
+	Tombstones    repository.AccountTombstoneRepository
+	Passkeys      repository.PasskeyRepository
+	Identities    repository.ExternalIdentityRepository
+	// RateLimits defaults to an in-memory repository, which does not share limits between replicas.
+	RateLimits repository.RateLimitRepository
+	// Leases defaults to an in-memory repository, which does not stop other replicas from
+	// running the same background jobs.
+	Leases repository.LeaseRepository
 }
 
 func NewResolver(
-	userRepo repository.UserRepository,
-	workoutRepo repository.WorkoutRepository,
-	exerciseRepo repository.ExerciseRepository,
-	refreshTokenRepo repository.RefreshTokenRepository,
+	repos Repositories,
 	jwtSecret string,
 	config *config.Config,
 ) *Resolver {
+	if repos.RateLimits == nil {
+		repos.RateLimits = repository.NewMemoryRateLimitRepository()
+	}
+	if repos.Leases == nil {
+		repos.Leases = repository.NewMemoryLeaseRepository()
+	}
+	userService := service.NewUserService(repos.Users, repos.RefreshTokens)
+	mail := newMailer(config)
+	rateLimiter := service.NewRateLimiter(repos.RateLimits)
+	accountDeletion := service.NewAccountDeletionService(service.AccountDeletionRepositories{
+		Users:         repos.Users,
+		Workouts:      repos.Workouts,
+		Exercises:     repos.Exercises,
+		BodyMetrics:   repos.BodyMetrics,
+		Goals:         repos.Goals,
+		RefreshTokens: repos.RefreshTokens,
+		ActionTokens:  repos.ActionTokens,
+		Tombstones:    repos.Tombstones,
+		Passkeys:      repos.Passkeys,
+		Identities:    repos.Identities,
+		Leases:        repos.Leases,
+	}, mail, deletionGracePeriod(config))
 	return &Resolver{
-		UserService:     service.NewUserService(userRepo),
-		WorkoutService:  service.NewWorkoutService(workoutRepo),
-		ExerciseService: service.NewExerciseService(exerciseRepo),
-		TokenService:    service.NewTokenService(refreshTokenRepo),
-		JWTSecret:       jwtSecret,
-		Config:          config,
+		UserService:       userService,
+		WorkoutService:    service.NewWorkoutService(repos.Workouts),
+		ExerciseService:   service.NewExerciseService(repos.Exercises, repos.Workouts, repos.Goals, repos.Transactor),
+		TokenService:      service.NewTokenService(repos.RefreshTokens),
+		BodyMetricService: service.NewBodyMetricService(repos.BodyMetrics),
+		GoalService:       service.NewGoalService(repos.Goals, repos.Workouts, repos.BodyMetrics, repos.Exercises),
+		CalendarService:   service.NewCalendarService(repos.Workouts, repos.Users),
+		RateLimiter:       rateLimiter,
+		AccountService:    service.NewAccountService(userService, repos.Users, repos.ActionTokens, repos.RefreshTokens, mail, appURL(config)),
+		AccountDeletion:   accountDeletion,
+		TwoFactorService:  service.NewTwoFactorService(repos.Users, repos.ActionTokens, rateLimiter, totpIssuer(config)),
+		PasskeyService:    service.NewPasskeyService(repos.Passkeys, repos.Users, repos.ActionTokens, relyingParty(config)),
+		OIDCService:       service.NewOIDCService(loginProviders(config), repos.Identities, repos.Users),
+		Mailer:            mail,
+		JWTSecret:         jwtSecret,
+		Config:            config,
+	}
+}
+
+// newMailer sends email through SMTP when a server is configured, and otherwise only logs it
+// (and writes it to MAIL_DIR, if set). config.Load requires SMTP in production.
+func newMailer(cfg *config.Config) mailer.Mailer {
+	if cfg == nil {
+		return &mailer.FileMailer{}
+	}
+	if cfg.SMTPHost == "" {
+		return &mailer.FileMailer{Dir: cfg.MailDir, From: cfg.MailFrom}
+	}
+	return &mailer.SMTPMailer{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.MailFrom,
+	}
+}
+
+func appURL(cfg *config.Config) string {
+	if cfg == nil {
+		return ""
+	}
+	return cfg.AppURL
+}
+
+func deletionGracePeriod(cfg *config.Config) time.Duration {
+	if cfg == nil {
+		return 0
+	}
+	return cfg.AccountDeletionGracePeriod
+}
+
+func totpIssuer(cfg *config.Config) string {
+	if cfg == nil || cfg.TOTPIssuer == "" {
+		return "Fitness App"
+	}
+	return cfg.TOTPIssuer
+}
+
+// relyingParty describes the site passkeys are registered with, defaulting to APP_URL.
+func relyingParty(cfg *config.Config) *webauthn.RelyingParty {
+	rp := &webauthn.RelyingParty{Name: "Fitness App", Timeout: 5 * time.Minute}
+	if cfg == nil {
+		return rp
+	}
+	if cfg.WebAuthnRPName != "" {
+		rp.Name = cfg.WebAuthnRPName
+	}
+	rp.ID, rp.Origins = cfg.WebAuthnRPID, cfg.WebAuthnOrigins
+	if app, err := url.Parse(cfg.AppURL); err == nil && app.Host != "" {
+		if rp.ID == "" {
+			rp.ID = app.Hostname()
+		}
+		if len(rp.Origins) == 0 {
+			rp.Origins = []string{app.Scheme + "://" + app.Host}
+		}
+	}
+	return rp
+}
+
+// loginProviders returns the configured OpenID Connect providers, which redirect back to
+// APP_URL/auth/oidc/{id}/callback.
+func loginProviders(cfg *config.Config) []service.LoginProvider {
+	if cfg == nil {
+		return nil
+	}
+	providers := make([]service.LoginProvider, 0, len(cfg.OIDCProviders))
+	for _, p := range cfg.OIDCProviders {
+		name := p.Name
+		if name == "" {
+			name = p.ID
+		}
+		providers = append(providers, service.LoginProvider{
+			ID:   p.ID,
+			Name: name,
+			Provider: &oidc.Provider{
+				Issuer:       p.Issuer,
+				ClientID:     p.ClientID,
+				ClientSecret: p.ClientSecret,
+				RedirectURL:  strings.TrimSuffix(cfg.AppURL, "/") + "/auth/oidc/" + p.ID + "/callback",
+				Scopes:       p.Scopes,
+			},
+		})
+	}
+	return providers
+}
+
+// loaders returns the request's loaders, or unshared ones when the resolver is called
+// outside the loaders middleware (e.g. in tests), in which case lookups are not batched across fields.
+func (r *Resolver) loaders(ctx context.Context) *loaders.Loaders {
+	if l := loaders.For(ctx); l != nil {
+		return l
+	}
+	return loaders.New(r.WorkoutService, r.ExerciseService, r.BodyMetricService)
+}
+
+// locales returns the languages to present exercises in, most preferred first: the user's
+// profile setting, then the request's Accept-Language.
+func (r *Resolver) locales(ctx context.Context) []string {
+	return middleware.GetRequestLocales(ctx).Resolve(func() *string {
+		userID, ok := ctx.Value(middleware.UserIDKey).(string)
+		if !ok {
+			return nil
+		}
+		user, err := r.UserService.GetUserByID(ctx, userID)
+		if err != nil {
+			slog.Warn("Failed to load locale setting", "user_id", userID, "error", err)
+			return nil
+		}
+		return user.Locale
+	})
+}
+
+// exerciseLocales is locales for an exercise's fields; it skips looking up the user's setting
+// for exercises without translations, such as custom ones.
+func (r *Resolver) exerciseLocales(ctx context.Context, exercise *model.UniqueExercise) []string {
+	if len(exercise.Translations) == 0 {
+		return nil
+	}
+	return r.locales(ctx)
+}
+
+// clientIP returns the address of the client making the request, or "" outside an HTTP request.
+func (r *Resolver) clientIP(ctx context.Context) string {
+	req := middleware.GetRequest(ctx)
+	if req == nil {
+		return ""
+	}
+	trustedProxies := 0
+	if r.Config != nil {
+		trustedProxies = r.Config.TrustedProxies
+	}
+	return middleware.ClientIP(req, trustedProxies)
+}
+
+// sessionClient describes the client making the request, for the sessions it signs in to.
+func (r *Resolver) sessionClient(ctx context.Context) model.SessionClient {
+	client := model.SessionClient{IP: r.clientIP(ctx)}
+	if req := middleware.GetRequest(ctx); req != nil {
+		client.UserAgent = req.UserAgent()
+	}
+	return client
+}
+
+// startSession signs the user in: it starts a session, sets its refresh cookie, and returns the
+// JWT. A session that fails to start is logged; the JWT still works, without refresh.
+func (r *Resolver) startSession(ctx context.Context, user *model.User) (string, error) {
+	sessionID := ""
+	refreshToken, session, err := r.TokenService.CreateCompositeRefreshToken(ctx, user.ID, r.sessionClient(ctx))
+	if err != nil {
+		slog.Error("Failed to generate refresh token", "error", err)
+	} else {
+		sessionID = session.FamilyID
+		http.SetCookie(middleware.GetResponseWriter(ctx), &http.Cookie{
+			Name:     "refresh_token",
+			Value:    refreshToken,
+			Path:     "/auth/refresh",
+			HttpOnly: true,
+			Secure:   r.Config.AppEnv == "production" && !r.Config.CI,
+			SameSite: http.SameSiteStrictMode,
+			MaxAge:   7 * 24 * 3600,
+		})
+	}
+
+	token, err := middleware.GenerateSessionJWT(user, sessionID, r.JWTSecret)
+	if err != nil {
+		slog.Error("CRITICAL: Failed to generate JWT for user", "user_id", user.ID, "error", err)
+		return "", fmt.Errorf("internal server error during session creation")
+	}
+	return token, nil
+}
+
+// checkRateLimit returns the *service.RateLimitedError for keys that must wait, and a generic
+// error if the limits cannot be read, so attempts are never let through unchecked.
+func (r *Resolver) checkRateLimit(ctx context.Context, keys ...service.RateLimitKey) error {
+	err := r.RateLimiter.Check(ctx, keys...)
+	var limited *service.RateLimitedError
+	if err != nil && !errors.As(err, &limited) {
+		slog.Error("Failed to check rate limit", "error", err)
+		return fmt.Errorf("internal server error")
+	}
+	return err
+}
+
+// attemptRateLimit counts an attempt against the keys before it is made, returning errors as
+// checkRateLimit does.
+func (r *Resolver) attemptRateLimit(ctx context.Context, keys ...service.RateLimitKey) error {
+	err := r.RateLimiter.Attempt(ctx, keys...)
+	var limited *service.RateLimitedError
+	if err != nil && !errors.As(err, &limited) {
+		slog.Error("Failed to record rate limited attempt", "error", err)
+		return fmt.Errorf("internal server error")
+	}
+	return err
+}
+
+// forgiveRateLimit takes back an attempt that was not a failure; errors are logged, as the attempt has already been handled.
+func (r *Resolver) forgiveRateLimit(ctx context.Context, keys ...service.RateLimitKey) {
+	if err := r.RateLimiter.Forgive(ctx, keys...); err != nil {
+		slog.Error("Failed to forgive rate limited attempt", "error", err)
 	}
 }
@@ -7,19 +7,102 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"time"
 
 	model1 "github.com/riverajo/fitness-app/backend/graph/model"
 	"github.com/riverajo/fitness-app/backend/internal/middleware"
 	internalModel "github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+	"github.com/riverajo/fitness-app/backend/internal/service"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Value is the resolver for the value field.
+func (r *bodyMeasurementResolver) Value(ctx context.Context, obj *internalModel.BodyMeasurement, unit *internalModel.WeightUnit) (float64, error) {
+	return unitOrDefault(unit).FromCentimeters(obj.Value), nil
+}
+
+// Weight is the resolver for the weight field.
+func (r *bodyMetricResolver) Weight(ctx context.Context, obj *internalModel.BodyMetric, unit *internalModel.WeightUnit) (*float64, error) {
+	if obj.Weight == nil {
+		return nil, nil
+	}
+	w := unitOrDefault(unit).FromKilograms(*obj.Weight)
+	return &w, nil
+}
+
+// Weight is the resolver for the weight field.
+func (r *bodyweightTrendPointResolver) Weight(ctx context.Context, obj *internalModel.BodyweightTrendPoint, unit *internalModel.WeightUnit) (float64, error) {
+	return unitOrDefault(unit).FromKilograms(obj.Weight), nil
+}
+
+// MovingAverage is the resolver for the movingAverage field.
+func (r *bodyweightTrendPointResolver) MovingAverage(ctx context.Context, obj *internalModel.BodyweightTrendPoint, unit *internalModel.WeightUnit) (float64, error) {
+	return unitOrDefault(unit).FromKilograms(obj.MovingAverage), nil
+}
+
 // UniqueExercise is the resolver for the uniqueExercise field.
 func (r *exerciseLogResolver) UniqueExercise(ctx context.Context, obj *internalModel.ExerciseLog) (*internalModel.UniqueExercise, error) {
-	return r.ExerciseService.GetExercise(ctx, obj.UniqueExerciseID)
+	return r.loaders(ctx).Exercise(ctx, obj.UniqueExerciseID)
+}
+
+// Previous is the resolver for the previous field.
+func (r *exerciseLogResolver) Previous(ctx context.Context, obj *internalModel.ExerciseLog) (*internalModel.ExerciseSession, error) {
+	if obj.Workout.WorkoutID == "" {
+		// Not loaded from a workout
+		return nil, nil
+	}
+
+	return r.loaders(ctx).PreviousSession(ctx, internalModel.PreviousSessionKey{
+		UserID:     obj.UserID,
+		ExerciseID: obj.UniqueExerciseID,
+		Before:     obj.Workout,
+	})
+}
+
+// Exercise is the resolver for the exercise field.
+func (r *goalResolver) Exercise(ctx context.Context, obj *internalModel.Goal) (*internalModel.UniqueExercise, error) {
+	if obj.UniqueExerciseID == nil {
+		return nil, nil
+	}
+	return r.loaders(ctx).Exercise(ctx, *obj.UniqueExerciseID)
+}
+
+// TargetValue is the resolver for the targetValue field.
+func (r *goalResolver) TargetValue(ctx context.Context, obj *internalModel.Goal, unit *internalModel.WeightUnit) (float64, error) {
+	return goalValueInUnit(obj.Type, obj.TargetValue, unit), nil
+}
+
+// Progress is the resolver for the progress field.
+func (r *goalResolver) Progress(ctx context.Context, obj *internalModel.Goal) (*internalModel.GoalProgress, error) {
+	return r.GoalService.EvaluateGoal(ctx, obj)
+}
+
+// CurrentValue is the resolver for the currentValue field.
+func (r *goalProgressResolver) CurrentValue(ctx context.Context, obj *internalModel.GoalProgress, unit *internalModel.WeightUnit) (*float64, error) {
+	if obj.CurrentValue == nil {
+		return nil, nil
+	}
+	v := goalValueInUnit(obj.GoalType, *obj.CurrentValue, unit)
+	return &v, nil
+}
+
+// StartingValue is the resolver for the startingValue field.
+func (r *goalProgressResolver) StartingValue(ctx context.Context, obj *internalModel.GoalProgress, unit *internalModel.WeightUnit) (*float64, error) {
+	if obj.StartingValue == nil {
+		return nil, nil
+	}
+	v := goalValueInUnit(obj.GoalType, *obj.StartingValue, unit)
+	return &v, nil
+}
+
+// TargetValue is the resolver for the targetValue field.
+func (r *goalProgressResolver) TargetValue(ctx context.Context, obj *internalModel.GoalProgress, unit *internalModel.WeightUnit) (float64, error) {
+	return goalValueInUnit(obj.GoalType, obj.TargetValue, unit), nil
 }
 
 // CreateWorkoutLog is the resolver for the createWorkoutLog field.
@@ -150,6 +233,18 @@ func (r *mutationResolver) UpdateWorkoutLog(ctx context.Context, input model1.Up
 
 // Register is the resolver for the register field.
 func (r *mutationResolver) Register(ctx context.Context, input model1.RegisterInput) (*model1.AuthPayload, error) {
+	// Every registration counts towards the per-address limit, to slow down mass account
+	// creation and probing for registered emails
+	if ip := r.clientIP(ctx); ip != "" {
+		if err := r.attemptRateLimit(ctx, service.RegisterIPKey(ip)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.UserService.ValidatePassword(input.Password, input.Email); err != nil {
+		return nil, err
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, fmt.Errorf("failed to process password: %w", err)
@@ -163,39 +258,22 @@ func (r *mutationResolver) Register(ctx context.Context, input model1.RegisterIn
 		fmt.Printf("Registration failed for email %s: %v\n", input.Email, err)
 
 		// 💡 Check for the "user already exists" error (if we want a specific front-end message)
-		if err.Error() == fmt.Sprintf("user with email %s already exists", input.Email) {
+		if errors.Is(err, repository.ErrDuplicateEmail) {
 			return nil, fmt.Errorf("this email is already registered")
 		}
 
 		return nil, fmt.Errorf("failed to register user")
 	}
 
-	// 2. Registration successful.
-	//    Generate the JWT token to return to the client.
-
-	// 2a. Generate the JWT token
-	token, err := middleware.GenerateJWT(internalUser, r.JWTSecret)
+	// 2. Registration successful; start the session and generate the JWT token
+	token, err := r.startSession(ctx, internalUser)
 	if err != nil {
-		slog.Error("CRITICAL: Failed to generate JWT for new user", "user_id", internalUser.ID, "error", err)
-		return nil, fmt.Errorf("registration successful, but failed to generate token")
+		return nil, err
 	}
 
-	// 2b. Generate and Set Refresh Token
-	refreshToken, err := r.TokenService.CreateCompositeRefreshToken(ctx, internalUser.ID)
-	if err != nil {
-		slog.Error("Failed to generate refresh token", "error", err)
-		// We can still return success, but client won't have refresh capability.
-		// Ideally we should fail or warn. For now, let's log error.
-	} else {
-		http.SetCookie(middleware.GetResponseWriter(ctx), &http.Cookie{
-			Name:     "refresh_token",
-			Value:    refreshToken,
-			Path:     "/auth/refresh",
-			HttpOnly: true,
-			Secure:   r.Config.AppEnv == "production" && !r.Config.CI,
-			SameSite: http.SameSiteStrictMode,
-			MaxAge:   7 * 24 * 3600, // 7 days
-		})
+	// 2a. Ask the user to verify their email; the account works without it
+	if err := r.AccountService.SendVerificationEmail(ctx, internalUser); err != nil {
+		slog.Warn("Failed to send verification email to new user", "user_id", internalUser.ID, "error", err)
 	}
 
 	// 3. Return success payload with token
@@ -209,36 +287,51 @@ func (r *mutationResolver) Register(ctx context.Context, input model1.RegisterIn
 
 // Login is the resolver for the login field.
 func (r *mutationResolver) Login(ctx context.Context, input model1.LoginInput) (*model1.AuthPayload, error) {
-	// ... (User verification logic remains the same) ...
+	// Count the attempt per account and per address before checking the password, so parallel
+	// guesses cannot all get past the limit
+	accountKey := service.LoginAccountKey(input.Email)
+	var ipKeys []service.RateLimitKey
+	if ip := r.clientIP(ctx); ip != "" {
+		ipKeys = append(ipKeys, service.LoginIPKey(ip))
+	}
+	if err := r.attemptRateLimit(ctx, append([]service.RateLimitKey{accountKey}, ipKeys...)...); err != nil {
+		return nil, err
+	}
+
 	user, err := r.UserService.VerifyPassword(ctx, input.Email, input.Password)
 	if err != nil {
 		return nil, fmt.Errorf("invalid login credentials")
 	}
 
-	// 1. Generate the JWT token
-	token, err := middleware.GenerateJWT(user, r.JWTSecret)
-	if err != nil {
-		slog.Error("CRITICAL: Failed to generate JWT for user", "user_id", user.ID, "error", err)
-		return nil, fmt.Errorf("internal server error during session creation")
+	// The address only has this attempt taken back and keeps earlier failures, so guessing
+	// across many accounts stays limited
+	if err := r.RateLimiter.Reset(ctx, accountKey); err != nil {
+		slog.Warn("Failed to reset login rate limit", "user_id", user.ID, "error", err)
 	}
+	r.forgiveRateLimit(ctx, ipKeys...)
 
-	// 2. Generate Refresh Token
-	refreshToken, err := r.TokenService.CreateCompositeRefreshToken(ctx, user.ID)
+	// 1. With two-factor authentication, the session only starts once verifyTwoFactor checks a code
+	if user.TOTPEnabled {
+		challengeToken, err := r.TwoFactorService.StartChallenge(ctx, user)
+		if err != nil {
+			slog.Error("Failed to start two-factor challenge", "user_id", user.ID, "error", err)
+			return nil, fmt.Errorf("internal server error during session creation")
+		}
+		return &model1.AuthPayload{
+			Success:           true,
+			Message:           "Enter the code from your authenticator app.",
+			TwoFactorRequired: true,
+			ChallengeToken:    &challengeToken,
+		}, nil
+	}
+
+	// 2. Start the session and generate the JWT token
+	token, err := r.startSession(ctx, user)
 	if err != nil {
-		slog.Error("Failed to generate refresh token", "error", err)
-	} else {
-		http.SetCookie(middleware.GetResponseWriter(ctx), &http.Cookie{
-			Name:     "refresh_token",
-			Value:    refreshToken,
-			Path:     "/auth/refresh",
-			HttpOnly: true,
-			Secure:   r.Config.AppEnv == "production" && !r.Config.CI,
-			SameSite: http.SameSiteStrictMode,
-			MaxAge:   7 * 24 * 3600,
-		})
+		return nil, err
 	}
 
-	// 2. Return successful payload with token
+	// 3. Return successful payload with token
 	return &model1.AuthPayload{
 		Success: true,
 		Message: "Login successful.",
@@ -257,21 +350,53 @@ func (r *mutationResolver) UpdateUser(ctx context.Context, input model1.UpdateUs
 	userID := userIDVal.(string)
 
 	// 💡 STEP 2 (ADAPTER): Map the GraphQL-generated input to the internal, decoupled input model
+	changingEmail := input.Email != nil && *input.Email != ""
+	if changingEmail {
+		if err := r.attemptRateLimit(ctx, service.EmailChangeKey(userID)); err != nil {
+			return nil, err
+		}
+	}
+
 	internalInput := internalModel.UserUpdateInput{
 		CurrentPassword: &input.CurrentPassword,
+		Email:           input.Email,
 		NewPassword:     input.NewPassword,
 		PreferredUnit:   input.PreferredUnit,
+		Timezone:        input.Timezone,
+		TrainingDays:    input.TrainingDays,
+		Locale:          input.Locale,
+
+		CurrentSessionID: middleware.GetSessionID(ctx),
 	}
 
 	// 3. Call the UserService with the internal model
 	updatedUser, err := r.UserService.UpdateUser(ctx, userID, internalInput)
 	if err != nil {
+		// Only confirmation emails sent count towards the limit
+		if changingEmail {
+			r.forgiveRateLimit(ctx, service.EmailChangeKey(userID))
+		}
+		if errors.Is(err, repository.ErrDuplicateEmail) {
+			return nil, fmt.Errorf("this email is already registered")
+		}
 		// This handles "invalid current password" and other service errors
 		return nil, err
 	}
 
+	// 3a. A new email only takes effect once confirmed through the link sent to it
+	message := "User profile updated successfully."
+	if changingEmail && updatedUser.PendingEmail == nil {
+		r.forgiveRateLimit(ctx, service.EmailChangeKey(userID))
+	} else if changingEmail {
+		if err := r.AccountService.SendEmailChangeConfirmation(ctx, updatedUser); err != nil {
+			slog.Error("Failed to send email change confirmation", "user_id", userID, "error", err)
+			return nil, fmt.Errorf("profile updated, but failed to send the confirmation email; please try again")
+		}
+		message = "User profile updated. Open the link sent to your new email address to confirm the change."
+	}
+
 	// 4. Generate new token for the updated user
-	token, err := middleware.GenerateJWT(updatedUser, r.JWTSecret)
+	token, err := middleware.GenerateSessionJWT(updatedUser, middleware.GetSessionID(ctx), r.JWTSecret)
 	if err != nil {
 		slog.Error("CRITICAL: Failed to generate JWT for updated user", "user_id", updatedUser.ID, "error", err)
 		return nil, fmt.Errorf("profile updated, but failed to refresh token")
@@ -280,7 +405,7 @@ func (r *mutationResolver) UpdateUser(ctx context.Context, input model1.UpdateUs
 	// 5. Return successful payload
 	return &model1.AuthPayload{
 		Success: true,
-		Message: "User profile updated successfully.",
+		Message: message,
 		User:    updatedUser,
 		Token:   token,
 	}, nil
@@ -301,6 +426,15 @@ func (r *mutationResolver) Logout(ctx context.Context) (*model1.AuthPayload, err
 		}
 	}
 
+	// 1a. The cookie is only sent to /auth/refresh, so also revoke the access token's session
+	if userID, ok := ctx.Value(middleware.UserIDKey).(string); ok {
+		if sessionID := middleware.GetSessionID(ctx); sessionID != "" {
+			if err := r.TokenService.RevokeSession(ctx, userID, sessionID); err != nil && !errors.Is(err, service.ErrSessionNotFound) {
+				slog.Warn("Failed to revoke session during logout", "user_id", userID, "error", err)
+			}
+		}
+	}
+
 	// 2. Clear the refresh cookie
 	http.SetCookie(middleware.GetResponseWriter(ctx), &http.Cookie{
 		Name:     "refresh_token",
@@ -319,6 +453,336 @@ func (r *mutationResolver) Logout(ctx context.Context) (*model1.AuthPayload, err
 	}, nil
 }
 
+// RequestPasswordReset is the resolver for the requestPasswordReset field.
+func (r *mutationResolver) RequestPasswordReset(ctx context.Context, email string) (bool, error) {
+	// Every request counts, whether or not the email is registered
+	keys := []service.RateLimitKey{service.PasswordResetAccountKey(email)}
+	if ip := r.clientIP(ctx); ip != "" {
+		keys = append(keys, service.PasswordResetIPKey(ip))
+	}
+	if err := r.attemptRateLimit(ctx, keys...); err != nil {
+		return false, err
+	}
+
+	// Failures are not reported, as they could reveal that the email is registered
+	if err := r.AccountService.RequestPasswordReset(ctx, email); err != nil {
+		slog.Error("Failed to send password reset email", "error", err)
+	}
+	return true, nil
+}
+
+// ResetPassword is the resolver for the resetPassword field.
+func (r *mutationResolver) ResetPassword(ctx context.Context, token string, newPassword string) (bool, error) {
+	user, err := r.AccountService.ResetPassword(ctx, token, newPassword)
+	if err != nil {
+		var invalidPassword *service.InvalidPasswordError
+		if errors.Is(err, service.ErrInvalidActionToken) || errors.As(err, &invalidPassword) {
+			return false, err
+		}
+		slog.Error("Failed to reset password", "error", err)
+		return false, fmt.Errorf("failed to reset password")
+	}
+
+	// A reset stops the failed logins that may have led to it from delaying the real owner
+	if err := r.RateLimiter.Reset(ctx, service.LoginAccountKey(user.Email)); err != nil {
+		slog.Warn("Failed to reset login rate limit", "user_id", user.ID, "error", err)
+	}
+	return true, nil
+}
+
+// SendVerificationEmail is the resolver for the sendVerificationEmail field.
+func (r *mutationResolver) SendVerificationEmail(ctx context.Context) (bool, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return false, fmt.Errorf("unauthorized: must be logged in to verify your email")
+	}
+	userID := userIDVal.(string)
+
+	if err := r.attemptRateLimit(ctx, service.EmailVerificationKey(userID)); err != nil {
+		return false, err
+	}
+
+	user, err := r.UserService.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch user: %w", err)
+	}
+	if err := r.AccountService.SendVerificationEmail(ctx, user); err != nil {
+		if errors.Is(err, service.ErrEmailAlreadyVerified) {
+			return false, err
+		}
+		slog.Error("Failed to send verification email", "user_id", userID, "error", err)
+		return false, fmt.Errorf("failed to send verification email")
+	}
+	return true, nil
+}
+
+// VerifyEmail is the resolver for the verifyEmail field.
+func (r *mutationResolver) VerifyEmail(ctx context.Context, token string) (bool, error) {
+	if _, err := r.AccountService.VerifyEmail(ctx, token); err != nil {
+		if errors.Is(err, service.ErrInvalidActionToken) {
+			return false, err
+		}
+		slog.Error("Failed to verify email", "error", err)
+		return false, fmt.Errorf("failed to verify email")
+	}
+	return true, nil
+}
+
+// ConfirmEmailChange is the resolver for the confirmEmailChange field.
+func (r *mutationResolver) ConfirmEmailChange(ctx context.Context, token string) (bool, error) {
+	if _, err := r.AccountService.ConfirmEmailChange(ctx, token); err != nil {
+		if errors.Is(err, service.ErrInvalidActionToken) {
+			return false, err
+		}
+		if errors.Is(err, repository.ErrDuplicateEmail) {
+			return false, fmt.Errorf("this email is already registered")
+		}
+		slog.Error("Failed to confirm email change", "error", err)
+		return false, fmt.Errorf("failed to change email")
+	}
+	return true, nil
+}
+
+// DeleteAccount is the resolver for the deleteAccount field.
+func (r *mutationResolver) DeleteAccount(ctx context.Context, currentPassword string) (*internalModel.User, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to delete your account")
+	}
+	userID := userIDVal.(string)
+
+	user, err := r.AccountDeletion.RequestDeletion(ctx, userID, currentPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every session was signed out; clear this one's refresh cookie too
+	http.SetCookie(middleware.GetResponseWriter(ctx), &http.Cookie{
+		Name:     "refresh_token",
+		Value:    "",
+		Path:     "/auth/refresh",
+		HttpOnly: true,
+		Secure:   r.Config.AppEnv == "production" && !r.Config.CI,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   -1,
+	})
+	return user, nil
+}
+
+// CancelAccountDeletion is the resolver for the cancelAccountDeletion field.
+func (r *mutationResolver) CancelAccountDeletion(ctx context.Context) (*internalModel.User, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to cancel account deletion")
+	}
+	return r.AccountDeletion.CancelDeletion(ctx, userIDVal.(string))
+}
+
+// RevokeSession is the resolver for the revokeSession field.
+func (r *mutationResolver) RevokeSession(ctx context.Context, id string) (bool, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return false, fmt.Errorf("unauthorized: must be logged in to revoke a session")
+	}
+	if err := r.TokenService.RevokeSession(ctx, userIDVal.(string), id); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			return false, err
+		}
+		slog.Error("Failed to revoke session", "user_id", userIDVal, "error", err)
+		return false, fmt.Errorf("failed to revoke session")
+	}
+	return true, nil
+}
+
+// RevokeOtherSessions is the resolver for the revokeOtherSessions field.
+func (r *mutationResolver) RevokeOtherSessions(ctx context.Context) (bool, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return false, fmt.Errorf("unauthorized: must be logged in to revoke sessions")
+	}
+	if err := r.TokenService.RevokeOtherSessions(ctx, userIDVal.(string), middleware.GetSessionID(ctx)); err != nil {
+		slog.Error("Failed to revoke other sessions", "user_id", userIDVal, "error", err)
+		return false, fmt.Errorf("failed to revoke sessions")
+	}
+	return true, nil
+}
+
+// EnrollTotp is the resolver for the enrollTotp field.
+func (r *mutationResolver) EnrollTotp(ctx context.Context, currentPassword string) (*model1.TotpEnrollment, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to set up two-factor authentication")
+	}
+
+	enrollment, err := r.TwoFactorService.Enroll(ctx, userIDVal.(string), currentPassword)
+	if err != nil {
+		return nil, err
+	}
+	return &model1.TotpEnrollment{Secret: enrollment.Secret, OtpauthURI: enrollment.URI}, nil
+}
+
+// ConfirmTotp is the resolver for the confirmTotp field.
+func (r *mutationResolver) ConfirmTotp(ctx context.Context, code string) ([]string, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to set up two-factor authentication")
+	}
+	return r.TwoFactorService.Confirm(ctx, userIDVal.(string), code)
+}
+
+// RegenerateRecoveryCodes is the resolver for the regenerateRecoveryCodes field.
+func (r *mutationResolver) RegenerateRecoveryCodes(ctx context.Context, code string) ([]string, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to regenerate recovery codes")
+	}
+	return r.TwoFactorService.RegenerateRecoveryCodes(ctx, userIDVal.(string), code)
+}
+
+// DisableTotp is the resolver for the disableTotp field.
+func (r *mutationResolver) DisableTotp(ctx context.Context, currentPassword string, code string) (*internalModel.User, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to turn off two-factor authentication")
+	}
+	return r.TwoFactorService.Disable(ctx, userIDVal.(string), currentPassword, code)
+}
+
+// VerifyTwoFactor is the resolver for the verifyTwoFactor field.
+func (r *mutationResolver) VerifyTwoFactor(ctx context.Context, challengeToken string, code string) (*model1.AuthPayload, error) {
+	user, err := r.TwoFactorService.VerifyChallenge(ctx, challengeToken, code)
+	if err != nil {
+		var limited *service.RateLimitedError
+		if errors.As(err, &limited) || errors.Is(err, service.ErrInvalidTwoFactorCode) || errors.Is(err, service.ErrInvalidTwoFactorChallenge) {
+			return nil, err
+		}
+		slog.Error("Failed to verify two-factor code", "error", err)
+		return nil, fmt.Errorf("internal server error")
+	}
+
+	token, err := r.startSession(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &model1.AuthPayload{
+		Success: true,
+		Message: "Login successful.",
+		User:    user,
+		Token:   token,
+	}, nil
+}
+
+// BeginPasskeyRegistration is the resolver for the beginPasskeyRegistration field.
+func (r *mutationResolver) BeginPasskeyRegistration(ctx context.Context) (string, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return "", fmt.Errorf("unauthorized: must be logged in to add a passkey")
+	}
+	options, err := r.PasskeyService.BeginRegistration(ctx, userIDVal.(string))
+	if err != nil {
+		slog.Error("Failed to start passkey registration", "user_id", userIDVal, "error", err)
+		return "", fmt.Errorf("failed to start passkey registration")
+	}
+	return options, nil
+}
+
+// FinishPasskeyRegistration is the resolver for the finishPasskeyRegistration field.
+func (r *mutationResolver) FinishPasskeyRegistration(ctx context.Context, credential string, name *string) (*internalModel.Passkey, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to add a passkey")
+	}
+	passkeyName := ""
+	if name != nil {
+		passkeyName = *name
+	}
+
+	passkey, err := r.PasskeyService.FinishRegistration(ctx, userIDVal.(string), passkeyName, credential)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPasskey) || errors.Is(err, service.ErrInvalidPasskeyChallenge) || errors.Is(err, repository.ErrDuplicatePasskey) {
+			return nil, err
+		}
+		slog.Error("Failed to register passkey", "user_id", userIDVal, "error", err)
+		return nil, fmt.Errorf("failed to add passkey")
+	}
+	return passkey, nil
+}
+
+// DeletePasskey is the resolver for the deletePasskey field.
+func (r *mutationResolver) DeletePasskey(ctx context.Context, id string) (bool, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return false, fmt.Errorf("unauthorized: must be logged in to remove a passkey")
+	}
+	if err := r.PasskeyService.DeletePasskey(ctx, userIDVal.(string), id); err != nil {
+		if errors.Is(err, service.ErrPasskeyNotFound) {
+			return false, err
+		}
+		slog.Error("Failed to delete passkey", "user_id", userIDVal, "error", err)
+		return false, fmt.Errorf("failed to remove passkey")
+	}
+	return true, nil
+}
+
+// BeginPasskeyLogin is the resolver for the beginPasskeyLogin field.
+func (r *mutationResolver) BeginPasskeyLogin(ctx context.Context) (string, error) {
+	// Anyone can start a sign-in, and each stores a challenge: addresses locked out of logging in
+	// cannot start one, and every one started counts towards the address's limit
+	if ip := r.clientIP(ctx); ip != "" {
+		if err := r.checkRateLimit(ctx, service.LoginIPKey(ip)); err != nil {
+			return "", err
+		}
+		if err := r.attemptRateLimit(ctx, service.PasskeyChallengeIPKey(ip)); err != nil {
+			return "", err
+		}
+	}
+
+	options, err := r.PasskeyService.BeginLogin(ctx)
+	if err != nil {
+		slog.Error("Failed to start passkey sign-in", "error", err)
+		return "", fmt.Errorf("internal server error")
+	}
+	return options, nil
+}
+
+// FinishPasskeyLogin is the resolver for the finishPasskeyLogin field.
+func (r *mutationResolver) FinishPasskeyLogin(ctx context.Context, credential string) (*model1.AuthPayload, error) {
+	// Limit failed attempts per address, as for password logins; only invalid passkeys keep
+	// the attempt counted
+	var keys []service.RateLimitKey
+	if ip := r.clientIP(ctx); ip != "" {
+		keys = append(keys, service.LoginIPKey(ip))
+	}
+	if err := r.attemptRateLimit(ctx, keys...); err != nil {
+		return nil, err
+	}
+
+	user, err := r.PasskeyService.FinishLogin(ctx, credential)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPasskey) {
+			return nil, err
+		}
+		r.forgiveRateLimit(ctx, keys...)
+		if errors.Is(err, service.ErrInvalidPasskeyChallenge) {
+			return nil, err
+		}
+		slog.Error("Failed to sign in with passkey", "error", err)
+		return nil, fmt.Errorf("internal server error")
+	}
+	r.forgiveRateLimit(ctx, keys...)
+
+	token, err := r.startSession(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &model1.AuthPayload{
+		Success: true,
+		Message: "Login successful.",
+		User:    user,
+		Token:   token,
+	}, nil
+}
+
 // CreateUniqueExercise is the resolver for the createUniqueExercise field.
 func (r *mutationResolver) CreateUniqueExercise(ctx context.Context, input model1.CreateUniqueExerciseInput) (*internalModel.UniqueExercise, error) {
 	// 1. Get UserID from context
@@ -329,7 +793,179 @@ func (r *mutationResolver) CreateUniqueExercise(ctx context.Context, input model
 	userID := userIDVal.(string)
 
 	// 2. Call Service
-	return r.ExerciseService.CreateExercise(ctx, input.Name, input.Description, &userID)
+	return r.ExerciseService.CreateExercise(ctx, internalModel.ExerciseInput{
+		Name:             &input.Name,
+		Description:      input.Description,
+		Aliases:          input.Aliases,
+		Category:         input.Category,
+		PrimaryMuscles:   input.PrimaryMuscles,
+		SecondaryMuscles: input.SecondaryMuscles,
+		Equipment:        input.Equipment,
+		MovementPattern:  input.MovementPattern,
+		Unilateral:       input.Unilateral,
+	}, &userID, input.AllowSimilar != nil && *input.AllowSimilar)
+}
+
+// UpdateUniqueExercise is the resolver for the updateUniqueExercise field.
+func (r *mutationResolver) UpdateUniqueExercise(ctx context.Context, input model1.UpdateUniqueExerciseInput) (*internalModel.UniqueExercise, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to update a custom exercise")
+	}
+	userID := userIDVal.(string)
+
+	return r.ExerciseService.UpdateExercise(ctx, userID, input.ID, internalModel.ExerciseInput{
+		Name:             input.Name,
+		Description:      input.Description,
+		Aliases:          input.Aliases,
+		Category:         input.Category,
+		PrimaryMuscles:   input.PrimaryMuscles,
+		SecondaryMuscles: input.SecondaryMuscles,
+		Equipment:        input.Equipment,
+		MovementPattern:  input.MovementPattern,
+		Unilateral:       input.Unilateral,
+	})
+}
+
+// ArchiveUniqueExercise is the resolver for the archiveUniqueExercise field.
+func (r *mutationResolver) ArchiveUniqueExercise(ctx context.Context, id string, archived *bool) (*internalModel.UniqueExercise, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to archive a custom exercise")
+	}
+	userID := userIDVal.(string)
+
+	archive := true
+	if archived != nil {
+		archive = *archived
+	}
+	return r.ExerciseService.ArchiveExercise(ctx, userID, id, archive)
+}
+
+// DeleteUniqueExercise is the resolver for the deleteUniqueExercise field.
+func (r *mutationResolver) DeleteUniqueExercise(ctx context.Context, id string, force *bool) (bool, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return false, fmt.Errorf("unauthorized: must be logged in to delete a custom exercise")
+	}
+	userID := userIDVal.(string)
+
+	if err := r.ExerciseService.DeleteExercise(ctx, userID, id, force != nil && *force); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// MergeUniqueExercises is the resolver for the mergeUniqueExercises field.
+func (r *mutationResolver) MergeUniqueExercises(ctx context.Context, sourceID string, targetID string) (*internalModel.UniqueExercise, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to merge exercises")
+	}
+	userID := userIDVal.(string)
+
+	return r.ExerciseService.MergeExercises(ctx, userID, sourceID, targetID)
+}
+
+// LogBodyMetric is the resolver for the logBodyMetric field.
+func (r *mutationResolver) LogBodyMetric(ctx context.Context, input model1.LogBodyMetricInput) (*internalModel.BodyMetric, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to log body metrics")
+	}
+	userID := userIDVal.(string)
+
+	return r.BodyMetricService.LogMetric(ctx, userID, internalModel.BodyMetricInput{
+		RecordedAt:        input.RecordedAt,
+		Unit:              input.Unit,
+		Weight:            input.Weight,
+		BodyFatPercentage: input.BodyFatPercentage,
+		Measurements:      toInternalMeasurements(input.Measurements),
+		Notes:             input.Notes,
+	})
+}
+
+// UpdateBodyMetric is the resolver for the updateBodyMetric field.
+func (r *mutationResolver) UpdateBodyMetric(ctx context.Context, input model1.UpdateBodyMetricInput) (*internalModel.BodyMetric, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to update body metrics")
+	}
+	userID := userIDVal.(string)
+
+	return r.BodyMetricService.UpdateMetric(ctx, userID, input.ID, internalModel.BodyMetricInput{
+		RecordedAt:        input.RecordedAt,
+		Unit:              input.Unit,
+		Weight:            input.Weight,
+		BodyFatPercentage: input.BodyFatPercentage,
+		Measurements:      toInternalMeasurements(input.Measurements),
+		Notes:             input.Notes,
+	})
+}
+
+// DeleteBodyMetric is the resolver for the deleteBodyMetric field.
+func (r *mutationResolver) DeleteBodyMetric(ctx context.Context, id string) (bool, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return false, fmt.Errorf("unauthorized: must be logged in to delete body metrics")
+	}
+	userID := userIDVal.(string)
+
+	if err := r.BodyMetricService.DeleteMetric(ctx, userID, id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CreateGoal is the resolver for the createGoal field.
+func (r *mutationResolver) CreateGoal(ctx context.Context, input model1.CreateGoalInput) (*internalModel.Goal, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to create a goal")
+	}
+	userID := userIDVal.(string)
+
+	return r.GoalService.CreateGoal(ctx, userID, internalModel.GoalInput{
+		Type:             input.Type,
+		Name:             &input.Name,
+		UniqueExerciseID: input.ExerciseID,
+		TargetValue:      &input.TargetValue,
+		TargetReps:       input.TargetReps,
+		Unit:             input.Unit,
+		StartDate:        input.StartDate,
+		Deadline:         input.Deadline,
+	})
+}
+
+// UpdateGoal is the resolver for the updateGoal field.
+func (r *mutationResolver) UpdateGoal(ctx context.Context, input model1.UpdateGoalInput) (*internalModel.Goal, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to update a goal")
+	}
+	userID := userIDVal.(string)
+
+	return r.GoalService.UpdateGoal(ctx, userID, input.ID, internalModel.GoalInput{
+		Name:        input.Name,
+		TargetValue: input.TargetValue,
+		TargetReps:  input.TargetReps,
+		Unit:        input.Unit,
+		Deadline:    input.Deadline,
+	})
+}
+
+// DeleteGoal is the resolver for the deleteGoal field.
+func (r *mutationResolver) DeleteGoal(ctx context.Context, id string) (bool, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return false, fmt.Errorf("unauthorized: must be logged in to delete a goal")
+	}
+	userID := userIDVal.(string)
+
+	if err := r.GoalService.DeleteGoal(ctx, userID, id); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // GetWorkoutLog is the resolver for the getWorkoutLog field.
@@ -398,8 +1034,35 @@ func (r *queryResolver) Me(ctx context.Context) (*internalModel.User, error) {
 	return internalUser, nil
 }
 
+// MySessions is the resolver for the mySessions field.
+func (r *queryResolver) MySessions(ctx context.Context) ([]*internalModel.Session, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to list sessions")
+	}
+	return r.TokenService.ListSessions(ctx, userIDVal.(string), middleware.GetSessionID(ctx))
+}
+
+// MyPasskeys is the resolver for the myPasskeys field.
+func (r *queryResolver) MyPasskeys(ctx context.Context) ([]*internalModel.Passkey, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to list passkeys")
+	}
+	return r.PasskeyService.ListPasskeys(ctx, userIDVal.(string))
+}
+
+// LoginProviders is the resolver for the loginProviders field.
+func (r *queryResolver) LoginProviders(ctx context.Context) ([]*model1.LoginProvider, error) {
+	providers := []*model1.LoginProvider{}
+	for _, p := range r.OIDCService.Providers() {
+		providers = append(providers, &model1.LoginProvider{ID: p.ID, Name: p.Name})
+	}
+	return providers, nil
+}
+
 // UniqueExercises is the resolver for the uniqueExercises field.
-func (r *queryResolver) UniqueExercises(ctx context.Context, query *string, limit *int32, offset *int32) ([]*internalModel.UniqueExercise, error) {
+func (r *queryResolver) UniqueExercises(ctx context.Context, query *string, filter *internalModel.ExerciseFilter, limit *int32, offset *int32) ([]*internalModel.UniqueExercise, error) {
 	// 1. Get UserID from context (optional, but needed to see custom exercises)
 	var userID *string
 	userIDVal := ctx.Value(middleware.UserIDKey)
@@ -421,14 +1084,107 @@ func (r *queryResolver) UniqueExercises(ctx context.Context, query *string, limi
 	if offset != nil {
 		o = int(*offset)
 	}
+	f := internalModel.ExerciseFilter{}
+	if filter != nil {
+		f = *filter
+	}
 
 	// 3. Call Service
-	return r.ExerciseService.SearchExercises(ctx, userID, q, l, o)
+	return r.ExerciseService.SearchExercises(ctx, userID, q, f, r.locales(ctx), l, o)
 }
 
 // GetUniqueExercise is the resolver for the getUniqueExercise field.
 func (r *queryResolver) GetUniqueExercise(ctx context.Context, id string) (*internalModel.UniqueExercise, error) {
-	return r.ExerciseService.GetExercise(ctx, id)
+	return r.loaders(ctx).Exercise(ctx, id)
+}
+
+// BodyMetrics is the resolver for the bodyMetrics field.
+func (r *queryResolver) BodyMetrics(ctx context.Context, from *time.Time, to *time.Time, limit *int32, offset *int32) ([]*internalModel.BodyMetric, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to list body metrics")
+	}
+	userID := userIDVal.(string)
+
+	l := 50
+	if limit != nil {
+		l = int(*limit)
+	}
+	o := 0
+	if offset != nil {
+		o = int(*offset)
+	}
+
+	return r.BodyMetricService.ListMetrics(ctx, userID, from, to, l, o)
+}
+
+// BodyweightTrend is the resolver for the bodyweightTrend field.
+func (r *queryResolver) BodyweightTrend(ctx context.Context, from time.Time, to time.Time, windowDays *int32) ([]*internalModel.BodyweightTrendPoint, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to view bodyweight trends")
+	}
+	userID := userIDVal.(string)
+
+	window := 0
+	if windowDays != nil {
+		window = int(*windowDays)
+	}
+
+	return r.BodyMetricService.BodyweightTrend(ctx, userID, from, to, window)
+}
+
+// Goals is the resolver for the goals field.
+func (r *queryResolver) Goals(ctx context.Context) ([]*internalModel.Goal, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to list goals")
+	}
+	userID := userIDVal.(string)
+
+	return r.GoalService.ListGoals(ctx, userID)
+}
+
+// TrainingCalendar is the resolver for the trainingCalendar field.
+func (r *queryResolver) TrainingCalendar(ctx context.Context, year int32) (*internalModel.TrainingCalendar, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to view training calendar")
+	}
+	userID := userIDVal.(string)
+
+	return r.CalendarService.TrainingCalendar(ctx, userID, int(year))
+}
+
+// ExerciseHistory is the resolver for the exerciseHistory field.
+func (r *queryResolver) ExerciseHistory(ctx context.Context, exerciseID string, first *int32, after *string) (*internalModel.ExerciseHistory, error) {
+	userIDVal := ctx.Value(middleware.UserIDKey)
+	if userIDVal == nil {
+		return nil, fmt.Errorf("unauthorized: must be logged in to view exercise history")
+	}
+	userID := userIDVal.(string)
+
+	f := service.DefaultHistoryPageSize
+	if first != nil {
+		f = int(*first)
+	}
+
+	return r.WorkoutService.ExerciseHistory(ctx, userID, exerciseID, f, after)
+}
+
+// Volume is the resolver for the volume field.
+func (r *trainingCalendarDayResolver) Volume(ctx context.Context, obj *internalModel.TrainingCalendarDay, unit *internalModel.WeightUnit) (float64, error) {
+	return unitOrDefault(unit).FromKilograms(obj.Volume), nil
+}
+
+// Name is the resolver for the name field.
+func (r *uniqueExerciseResolver) Name(ctx context.Context, obj *internalModel.UniqueExercise) (string, error) {
+	return obj.LocalizedName(r.exerciseLocales(ctx, obj)), nil
+}
+
+// Description is the resolver for the description field.
+func (r *uniqueExerciseResolver) Description(ctx context.Context, obj *internalModel.UniqueExercise) (*string, error) {
+	return obj.LocalizedDescription(r.exerciseLocales(ctx, obj)), nil
 }
 
 // IsCustom is the resolver for the isCustom field.
@@ -436,21 +1192,93 @@ func (r *uniqueExerciseResolver) IsCustom(ctx context.Context, obj *internalMode
 	return obj.UserID != nil, nil
 }
 
+// ReplacedBy is the resolver for the replacedBy field.
+func (r *uniqueExerciseResolver) ReplacedBy(ctx context.Context, obj *internalModel.UniqueExercise) (*internalModel.UniqueExercise, error) {
+	if obj.ReplacedByID == nil {
+		return nil, nil
+	}
+	return r.loaders(ctx).Exercise(ctx, *obj.ReplacedByID)
+}
+
+// Locale is the resolver for the locale field.
+func (r *uniqueExerciseResolver) Locale(ctx context.Context, obj *internalModel.UniqueExercise) (string, error) {
+	if t := obj.Translation(r.exerciseLocales(ctx, obj)); t != nil {
+		return t.Locale, nil
+	}
+	return internalModel.DefaultLocale, nil
+}
+
+// Translations is the resolver for the translations field.
+func (r *uniqueExerciseResolver) Translations(ctx context.Context, obj *internalModel.UniqueExercise) ([]*internalModel.ExerciseTranslation, error) {
+	return obj.TranslationList(), nil
+}
+
+// RecoveryCodesRemaining is the resolver for the recoveryCodesRemaining field.
+func (r *userResolver) RecoveryCodesRemaining(ctx context.Context, obj *internalModel.User) (int32, error) {
+	return int32(len(obj.RecoveryCodeHashes)), nil
+}
+
+// Bodyweight is the resolver for the bodyweight field.
+func (r *workoutLogResolver) Bodyweight(ctx context.Context, obj *internalModel.WorkoutLog, unit *internalModel.WeightUnit) (*float64, error) {
+	kg, err := r.loaders(ctx).Bodyweight(ctx, obj.UserID, obj.StartTime)
+	if err != nil || kg == nil {
+		return nil, err
+	}
+	w := unitOrDefault(unit).FromKilograms(*kg)
+	return &w, nil
+}
+
+// BodyMeasurement returns BodyMeasurementResolver implementation.
+func (r *Resolver) BodyMeasurement() BodyMeasurementResolver { return &bodyMeasurementResolver{r} }
+
+// BodyMetric returns BodyMetricResolver implementation.
+func (r *Resolver) BodyMetric() BodyMetricResolver { return &bodyMetricResolver{r} }
+
+// BodyweightTrendPoint returns BodyweightTrendPointResolver implementation.
+func (r *Resolver) BodyweightTrendPoint() BodyweightTrendPointResolver {
+	return &bodyweightTrendPointResolver{r}
+}
+
 // ExerciseLog returns ExerciseLogResolver implementation.
 func (r *Resolver) ExerciseLog() ExerciseLogResolver { return &exerciseLogResolver{r} }
 
+// Goal returns GoalResolver implementation.
+func (r *Resolver) Goal() GoalResolver { return &goalResolver{r} }
+
+// GoalProgress returns GoalProgressResolver implementation.
+func (r *Resolver) GoalProgress() GoalProgressResolver { return &goalProgressResolver{r} }
+
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// TrainingCalendarDay returns TrainingCalendarDayResolver implementation.
+func (r *Resolver) TrainingCalendarDay() TrainingCalendarDayResolver {
+	return &trainingCalendarDayResolver{r}
+}
+
 // UniqueExercise returns UniqueExerciseResolver implementation.
 func (r *Resolver) UniqueExercise() UniqueExerciseResolver { return &uniqueExerciseResolver{r} }
 
+// User returns UserResolver implementation.
+func (r *Resolver) User() UserResolver { return &userResolver{r} }
+
+// WorkoutLog returns WorkoutLogResolver implementation.
+func (r *Resolver) WorkoutLog() WorkoutLogResolver { return &workoutLogResolver{r} }
+
 type (
-	exerciseLogResolver    struct{ *Resolver }
-	mutationResolver       struct{ *Resolver }
-	queryResolver          struct{ *Resolver }
-	uniqueExerciseResolver struct{ *Resolver }
+	bodyMeasurementResolver      struct{ *Resolver }
+	bodyMetricResolver           struct{ *Resolver }
+	bodyweightTrendPointResolver struct{ *Resolver }
+	exerciseLogResolver          struct{ *Resolver }
+	goalResolver                 struct{ *Resolver }
+	goalProgressResolver         struct{ *Resolver }
+	mutationResolver             struct{ *Resolver }
+	queryResolver                struct{ *Resolver }
+	trainingCalendarDayResolver  struct{ *Resolver }
+	uniqueExerciseResolver       struct{ *Resolver }
+	userResolver                 struct{ *Resolver }
+	workoutLogResolver           struct{ *Resolver }
 )
@@ -2,15 +2,27 @@ package graph
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/riverajo/fitness-app/backend/graph/model"
 	"github.com/riverajo/fitness-app/backend/internal/config"
+	"github.com/riverajo/fitness-app/backend/internal/loaders"
+	"github.com/riverajo/fitness-app/backend/internal/mailer"
 	"github.com/riverajo/fitness-app/backend/internal/middleware"
 	internalModel "github.com/riverajo/fitness-app/backend/internal/model"
 	"github.com/riverajo/fitness-app/backend/internal/repository"
+	"github.com/riverajo/fitness-app/backend/internal/service"
+	"github.com/riverajo/fitness-app/backend/internal/webauthn/webauthntest"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
@@ -38,6 +50,13 @@ func TestRegister(t *testing.T) {
 	// Expect RefreshToken creation
 	mockRefreshTokenRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
 
+	// Expect a verification email
+	actionTokenRepo := new(repository.MockActionTokenRepository)
+	actionTokenRepo.On("DeleteForUser", mock.Anything, mock.Anything, internalModel.ActionTokenEmailVerification).Return(nil)
+	actionTokenRepo.On("Create", mock.Anything, mock.MatchedBy(func(token *internalModel.ActionToken) bool {
+		return token.Purpose == internalModel.ActionTokenEmailVerification && token.Email == input.Email
+	})).Return(nil)
+
 	// Inject mocks into resolver
 	// Use a simplified config for testing
 	cfg := &config.Config{
@@ -45,7 +64,7 @@ func TestRegister(t *testing.T) {
 		AppEnv:    "production",
 	}
 
-	resolver := NewResolver(userRepo, workoutRepo, exerciseRepo, mockRefreshTokenRepo, "testsecret", cfg)
+	resolver := NewResolver(Repositories{Users: userRepo, Workouts: workoutRepo, Exercises: exerciseRepo, RefreshTokens: mockRefreshTokenRepo, ActionTokens: actionTokenRepo}, "testsecret", cfg)
 	w := httptest.NewRecorder()
 	ctx := context.WithValue(context.Background(), middleware.ResponseWriterKey, w)
 
@@ -68,11 +87,43 @@ func TestRegister(t *testing.T) {
 	require.True(t, cookies[0].Secure)
 	require.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
 
+	sent := resolver.Mailer.(*mailer.FileMailer).Sent()
+	require.Len(t, sent, 1)
+	require.Equal(t, input.Email, sent[0].To)
+	require.Contains(t, sent[0].Body, "/verify-email?token=")
+
 	userRepo.AssertExpectations(t)
+	actionTokenRepo.AssertExpectations(t)
 
 	userRepo.AssertExpectations(t)
 }
 
+func TestRegisterDuplicateEmail(t *testing.T) {
+	userRepo := new(repository.MockUserRepository)
+	userRepo.On("Create", mock.Anything, mock.Anything).Return(repository.ErrDuplicateEmail)
+
+	resolver := NewResolver(Repositories{Users: userRepo}, "testsecret", &config.Config{JWTSecret: "testsecret"})
+	ctx := context.WithValue(context.Background(), middleware.ResponseWriterKey, httptest.NewRecorder())
+
+	_, err := resolver.Mutation().Register(ctx, model.RegisterInput{Email: "taken@example.com", Password: "password123"})
+	require.EqualError(t, err, "this email is already registered")
+}
+
+func TestRegisterInvalidPassword(t *testing.T) {
+	userRepo := new(repository.MockUserRepository)
+	resolver := NewResolver(Repositories{Users: userRepo}, "testsecret", &config.Config{JWTSecret: "testsecret"})
+	ctx := context.WithValue(context.Background(), middleware.ResponseWriterKey, httptest.NewRecorder())
+
+	_, err := resolver.Mutation().Register(ctx, model.RegisterInput{Email: "jane@example.com", Password: ""})
+	require.Error(t, err)
+
+	gqlErr := ErrorPresenter(ctx, err)
+	require.Equal(t, ErrCodeInvalidPassword, gqlErr.Extensions["code"])
+	require.Equal(t, []service.PasswordViolation{service.PasswordTooShort}, gqlErr.Extensions["violations"])
+	require.Equal(t, service.DefaultPasswordMinLength, gqlErr.Extensions["minLength"])
+	userRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
 func TestLogin(t *testing.T) {
 	t.Setenv("JWT_SECRET", "testsecret") // Set environment variable for this test
 	userRepo := new(repository.MockUserRepository)
@@ -80,7 +131,7 @@ func TestLogin(t *testing.T) {
 	exerciseRepo := new(repository.MockExerciseRepository)
 	mockRefreshTokenRepo := new(repository.MockRefreshTokenRepository)
 	mockRefreshTokenRepo.On("Create", mock.Anything, mock.Anything).Return(nil) // Allow Login to create token
-	resolver := NewResolver(userRepo, workoutRepo, exerciseRepo, mockRefreshTokenRepo, "testsecret", &config.Config{AppEnv: "production"})
+	resolver := NewResolver(Repositories{Users: userRepo, Workouts: workoutRepo, Exercises: exerciseRepo, RefreshTokens: mockRefreshTokenRepo}, "testsecret", &config.Config{AppEnv: "production"})
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
 	user := &internalModel.User{
@@ -123,13 +174,129 @@ func TestLogin(t *testing.T) {
 	userRepo.AssertExpectations(t)
 }
 
+func TestLoginRateLimited(t *testing.T) {
+	userRepo := new(repository.MockUserRepository)
+	mockRefreshTokenRepo := new(repository.MockRefreshTokenRepository)
+	mockRefreshTokenRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	rateLimits := repository.NewMemoryRateLimitRepository()
+	resolver := NewResolver(Repositories{Users: userRepo, RefreshTokens: mockRefreshTokenRepo, RateLimits: rateLimits}, "testsecret", &config.Config{})
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &internalModel.User{ID: "user123", Email: "test@example.com", PasswordHash: string(hashedPassword)}
+	userRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(user, nil)
+
+	req := httptest.NewRequest("POST", "/query", nil)
+	req.RemoteAddr = "203.0.113.7:5123"
+	ctx := context.WithValue(context.Background(), middleware.ResponseWriterKey, httptest.NewRecorder())
+	ctx = context.WithValue(ctx, middleware.RequestKey, req)
+
+	for i := 0; i < service.LoginAccountPolicy.FreeAttempts; i++ {
+		_, err := resolver.Mutation().Login(ctx, model.LoginInput{Email: "test@example.com", Password: "wrong"})
+		require.EqualError(t, err, "invalid login credentials")
+	}
+
+	// Even the right password is rejected until the delay has passed, and the email's case does not matter
+	_, err := resolver.Mutation().Login(ctx, model.LoginInput{Email: "Test@Example.com", Password: "password123"})
+	require.Error(t, err)
+	gqlErr := ErrorPresenter(ctx, err)
+	require.Equal(t, ErrCodeRateLimited, gqlErr.Extensions["code"])
+	require.Equal(t, 1, gqlErr.Extensions["retryAfter"])
+
+	// The address was counted too
+	state, err := rateLimits.Get(ctx, service.LoginIPKey("203.0.113.7").Key)
+	require.NoError(t, err)
+	require.Equal(t, service.LoginAccountPolicy.FreeAttempts, state.Failures)
+
+	// Once below the limit, a successful login clears the account's failures
+	require.NoError(t, rateLimits.Reset(ctx, service.LoginAccountKey("test@example.com").Key))
+	_, err = resolver.Mutation().Login(ctx, model.LoginInput{Email: "test@example.com", Password: "wrong"})
+	require.EqualError(t, err, "invalid login credentials")
+	_, err = resolver.Mutation().Login(ctx, model.LoginInput{Email: "test@example.com", Password: "password123"})
+	require.NoError(t, err)
+	state, err = rateLimits.Get(ctx, service.LoginAccountKey("test@example.com").Key)
+	require.NoError(t, err)
+	require.Nil(t, state)
+}
+
+func TestRegisterRateLimited(t *testing.T) {
+	userRepo := new(repository.MockUserRepository)
+	userRepo.On("Create", mock.Anything, mock.Anything).Return(repository.ErrDuplicateEmail)
+	resolver := NewResolver(Repositories{Users: userRepo}, "testsecret", &config.Config{JWTSecret: "testsecret"})
+
+	req := httptest.NewRequest("POST", "/query", nil)
+	req.RemoteAddr = "203.0.113.7:5123"
+	ctx := context.WithValue(context.Background(), middleware.ResponseWriterKey, httptest.NewRecorder())
+	ctx = context.WithValue(ctx, middleware.RequestKey, req)
+
+	for i := 0; i < service.RegisterIPPolicy.FreeAttempts; i++ {
+		_, err := resolver.Mutation().Register(ctx, model.RegisterInput{Email: "taken@example.com", Password: "password123"})
+		require.EqualError(t, err, "this email is already registered")
+	}
+
+	_, err := resolver.Mutation().Register(ctx, model.RegisterInput{Email: "new@example.com", Password: "password123"})
+	gqlErr := ErrorPresenter(ctx, err)
+	require.Equal(t, ErrCodeRateLimited, gqlErr.Extensions["code"])
+	require.Equal(t, int(service.RegisterIPPolicy.BaseDelay.Seconds()), gqlErr.Extensions["retryAfter"])
+	userRepo.AssertNumberOfCalls(t, "Create", service.RegisterIPPolicy.FreeAttempts)
+}
+
+func TestBeginPasskeyLoginRateLimited(t *testing.T) {
+	actionTokenRepo := new(repository.MockActionTokenRepository)
+	actionTokenRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	rateLimits := repository.NewMemoryRateLimitRepository()
+	resolver := NewResolver(Repositories{ActionTokens: actionTokenRepo, RateLimits: rateLimits}, "testsecret", &config.Config{AppURL: "https://fitness.example.com"})
+
+	req := httptest.NewRequest("POST", "/query", nil)
+	req.RemoteAddr = "203.0.113.7:5123"
+	ctx := context.WithValue(context.Background(), middleware.RequestKey, req)
+
+	for i := 0; i < service.PasskeyChallengeIPPolicy.FreeAttempts; i++ {
+		_, err := resolver.Mutation().BeginPasskeyLogin(ctx)
+		require.NoError(t, err)
+	}
+	_, err := resolver.Mutation().BeginPasskeyLogin(ctx)
+	gqlErr := ErrorPresenter(ctx, err)
+	require.Equal(t, ErrCodeRateLimited, gqlErr.Extensions["code"])
+	actionTokenRepo.AssertNumberOfCalls(t, "Create", service.PasskeyChallengeIPPolicy.FreeAttempts)
+
+	// An address locked out of logging in cannot start one either
+	req.RemoteAddr = "203.0.113.8:5123"
+	for i := 0; i < service.LoginIPPolicy.FreeAttempts; i++ {
+		require.NoError(t, resolver.RateLimiter.Fail(ctx, service.LoginIPKey("203.0.113.8")))
+	}
+	_, err = resolver.Mutation().BeginPasskeyLogin(ctx)
+	gqlErr = ErrorPresenter(ctx, err)
+	require.Equal(t, ErrCodeRateLimited, gqlErr.Extensions["code"])
+}
+
+func TestRequestPasswordResetUnknownEmail(t *testing.T) {
+	userRepo := new(repository.MockUserRepository)
+	userRepo.On("FindByEmail", mock.Anything, "nobody@example.com").Return(nil, nil)
+	resolver := NewResolver(Repositories{Users: userRepo}, "testsecret", &config.Config{})
+
+	// The answer is the same as for a registered email
+	ok, err := resolver.Mutation().RequestPasswordReset(context.Background(), "nobody@example.com")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Empty(t, resolver.Mailer.(*mailer.FileMailer).Sent())
+}
+
+func TestResetPasswordInvalidToken(t *testing.T) {
+	actionTokenRepo := new(repository.MockActionTokenRepository)
+	actionTokenRepo.On("FindByID", mock.Anything, "missing").Return(nil, nil)
+	resolver := NewResolver(Repositories{ActionTokens: actionTokenRepo}, "testsecret", &config.Config{})
+
+	_, err := resolver.Mutation().ResetPassword(context.Background(), "missing.secret", "new-passphrase")
+	require.ErrorIs(t, err, service.ErrInvalidActionToken)
+}
+
 func TestUpdateUser(t *testing.T) {
 	userRepo := new(repository.MockUserRepository)
 	workoutRepo := new(repository.MockWorkoutRepository)
 	exerciseRepo := new(repository.MockExerciseRepository)
 	mockRefreshTokenRepo := new(repository.MockRefreshTokenRepository)
 	mockRefreshTokenRepo.On("Create", mock.Anything, mock.Anything).Return(nil) // Allow Login to create token
-	resolver := NewResolver(userRepo, workoutRepo, exerciseRepo, mockRefreshTokenRepo, "testsecret", &config.Config{})
+	resolver := NewResolver(Repositories{Users: userRepo, Workouts: workoutRepo, Exercises: exerciseRepo, RefreshTokens: mockRefreshTokenRepo}, "testsecret", &config.Config{})
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
 	user := &internalModel.User{
@@ -164,6 +331,48 @@ func TestUpdateUser(t *testing.T) {
 	userRepo.AssertExpectations(t)
 }
 
+func TestUpdateUserEmail(t *testing.T) {
+	userRepo := new(repository.MockUserRepository)
+	actionTokenRepo := new(repository.MockActionTokenRepository)
+	resolver := NewResolver(Repositories{Users: userRepo, ActionTokens: actionTokenRepo}, "testsecret", &config.Config{JWTSecret: "testsecret"})
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user123")
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	userRepo.On("FindByID", mock.Anything, "user123").Return(&internalModel.User{ID: "user123", Email: "test@example.com", PasswordHash: string(hashedPassword)}, nil)
+
+	t.Run("Sends a confirmation link to the new address", func(t *testing.T) {
+		userRepo.On("FindByEmail", mock.Anything, "new@example.com").Return(nil, nil).Once()
+		userRepo.On("Update", mock.Anything, mock.MatchedBy(func(u *internalModel.User) bool {
+			return u.Email == "test@example.com" && u.PendingEmail != nil && *u.PendingEmail == "new@example.com"
+		})).Return(nil).Once()
+		actionTokenRepo.On("DeleteForUser", mock.Anything, "user123", internalModel.ActionTokenEmailChange).Return(nil).Once()
+		actionTokenRepo.On("Create", mock.Anything, mock.MatchedBy(func(token *internalModel.ActionToken) bool {
+			return token.Purpose == internalModel.ActionTokenEmailChange && token.Email == "new@example.com"
+		})).Return(nil).Once()
+
+		newEmail := "new@example.com"
+		payload, err := resolver.Mutation().UpdateUser(ctx, model.UpdateUserInput{CurrentPassword: "password123", Email: &newEmail})
+		require.NoError(t, err)
+		require.Equal(t, "test@example.com", payload.User.Email)
+		require.Equal(t, "new@example.com", *payload.User.PendingEmail)
+		require.Contains(t, payload.Message, "confirm")
+
+		sent := resolver.Mailer.(*mailer.FileMailer).Sent()
+		require.Len(t, sent, 2)
+		require.Equal(t, "new@example.com", sent[0].To)
+		require.Equal(t, "test@example.com", sent[1].To)
+		actionTokenRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejects a registered email", func(t *testing.T) {
+		userRepo.On("FindByEmail", mock.Anything, "taken@example.com").Return(&internalModel.User{ID: "other"}, nil).Once()
+
+		taken := "taken@example.com"
+		_, err := resolver.Mutation().UpdateUser(ctx, model.UpdateUserInput{CurrentPassword: "password123", Email: &taken})
+		require.EqualError(t, err, "this email is already registered")
+	})
+}
+
 func TestMe(t *testing.T) {
 	userRepo := new(repository.MockUserRepository)
 	workoutRepo := new(repository.MockWorkoutRepository)
@@ -178,7 +387,7 @@ func TestMe(t *testing.T) {
 
 	mockRefreshTokenRepo := new(repository.MockRefreshTokenRepository)
 	mockRefreshTokenRepo.On("Create", mock.Anything, mock.Anything).Return(nil) // Allow Login to create token
-	resolver := NewResolver(userRepo, workoutRepo, exerciseRepo, mockRefreshTokenRepo, "testsecret", &config.Config{})
+	resolver := NewResolver(Repositories{Users: userRepo, Workouts: workoutRepo, Exercises: exerciseRepo, RefreshTokens: mockRefreshTokenRepo}, "testsecret", &config.Config{})
 	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user123")
 
 	me, err := resolver.Query().Me(ctx)
@@ -195,7 +404,7 @@ func TestCreateWorkoutLog(t *testing.T) {
 	exerciseRepo := new(repository.MockExerciseRepository)
 	mockRefreshTokenRepo := new(repository.MockRefreshTokenRepository)
 	mockRefreshTokenRepo.On("Create", mock.Anything, mock.Anything).Return(nil) // Allow Login to create token
-	resolver := NewResolver(userRepo, workoutRepo, exerciseRepo, mockRefreshTokenRepo, "testsecret", &config.Config{})
+	resolver := NewResolver(Repositories{Users: userRepo, Workouts: workoutRepo, Exercises: exerciseRepo, RefreshTokens: mockRefreshTokenRepo}, "testsecret", &config.Config{})
 
 	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user123")
 
@@ -234,7 +443,7 @@ func TestGetWorkoutLog(t *testing.T) {
 	exerciseRepo := new(repository.MockExerciseRepository)
 	mockRefreshTokenRepo := new(repository.MockRefreshTokenRepository)
 	mockRefreshTokenRepo.On("Create", mock.Anything, mock.Anything).Return(nil) // Allow Login to create token
-	resolver := NewResolver(userRepo, workoutRepo, exerciseRepo, mockRefreshTokenRepo, "testsecret", &config.Config{})
+	resolver := NewResolver(Repositories{Users: userRepo, Workouts: workoutRepo, Exercises: exerciseRepo, RefreshTokens: mockRefreshTokenRepo}, "testsecret", &config.Config{})
 
 	expectedLog := &internalModel.WorkoutLog{
 		ID:   "log123",
@@ -256,7 +465,7 @@ func TestListWorkoutLogs(t *testing.T) {
 	exerciseRepo := new(repository.MockExerciseRepository)
 	mockRefreshTokenRepo := new(repository.MockRefreshTokenRepository)
 	mockRefreshTokenRepo.On("Create", mock.Anything, mock.Anything).Return(nil) // Allow Login to create token
-	resolver := NewResolver(userRepo, workoutRepo, exerciseRepo, mockRefreshTokenRepo, "testsecret", &config.Config{})
+	resolver := NewResolver(Repositories{Users: userRepo, Workouts: workoutRepo, Exercises: exerciseRepo, RefreshTokens: mockRefreshTokenRepo}, "testsecret", &config.Config{})
 
 	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user123")
 
@@ -324,7 +533,7 @@ func TestCreateUniqueExercise(t *testing.T) {
 	exerciseRepo := new(repository.MockExerciseRepository)
 	mockRefreshTokenRepo := new(repository.MockRefreshTokenRepository)
 	mockRefreshTokenRepo.On("Create", mock.Anything, mock.Anything).Return(nil) // Allow Login to create token
-	resolver := NewResolver(userRepo, workoutRepo, exerciseRepo, mockRefreshTokenRepo, "testsecret", &config.Config{})
+	resolver := NewResolver(Repositories{Users: userRepo, Workouts: workoutRepo, Exercises: exerciseRepo, RefreshTokens: mockRefreshTokenRepo}, "testsecret", &config.Config{})
 
 	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user123")
 
@@ -332,6 +541,10 @@ func TestCreateUniqueExercise(t *testing.T) {
 		Name: "My Custom Bench",
 	}
 
+	exerciseRepo.On("FindByName", mock.Anything, stringPtr("user123"), "My Custom Bench").Return(nil, nil)
+	exerciseRepo.On("ListVisible", mock.Anything, (*string)(nil), internalModel.ExerciseFilter{}).Return([]*internalModel.UniqueExercise{
+		{ID: "sys-bench", Name: "Bench Press"},
+	}, nil)
 	exerciseRepo.On("Create", mock.Anything, mock.MatchedBy(func(e *internalModel.UniqueExercise) bool {
 		return e.Name == "My Custom Bench" && *e.UserID == "user123"
 	})).Return(nil).Run(func(args mock.Arguments) {
@@ -347,13 +560,33 @@ func TestCreateUniqueExercise(t *testing.T) {
 	exerciseRepo.AssertExpectations(t)
 }
 
+func TestCreateUniqueExerciseSimilar(t *testing.T) {
+	exerciseRepo := new(repository.MockExerciseRepository)
+	resolver := NewResolver(Repositories{Exercises: exerciseRepo}, "testsecret", &config.Config{})
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user123")
+
+	exerciseRepo.On("FindByName", mock.Anything, stringPtr("user123"), "Bench Pres").Return(nil, nil)
+	exerciseRepo.On("ListVisible", mock.Anything, (*string)(nil), internalModel.ExerciseFilter{}).Return([]*internalModel.UniqueExercise{
+		{ID: "sys-bench", Name: "Bench Press"},
+	}, nil)
+
+	_, err := resolver.Mutation().CreateUniqueExercise(ctx, model.CreateUniqueExerciseInput{Name: "Bench Pres"})
+	require.Error(t, err)
+
+	gqlErr := ErrorPresenter(ctx, err)
+	require.Equal(t, ErrCodeSimilarExercise, gqlErr.Extensions["code"])
+	require.Equal(t, "sys-bench", gqlErr.Extensions["existingExerciseId"])
+	require.Equal(t, "Bench Press", gqlErr.Extensions["existingExerciseName"])
+	exerciseRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
 func TestUniqueExercises(t *testing.T) {
 	userRepo := new(repository.MockUserRepository)
 	workoutRepo := new(repository.MockWorkoutRepository)
 	exerciseRepo := new(repository.MockExerciseRepository)
 	mockRefreshTokenRepo := new(repository.MockRefreshTokenRepository)
 	mockRefreshTokenRepo.On("Create", mock.Anything, mock.Anything).Return(nil) // Allow Login to create token
-	resolver := NewResolver(userRepo, workoutRepo, exerciseRepo, mockRefreshTokenRepo, "testsecret", &config.Config{})
+	resolver := NewResolver(Repositories{Users: userRepo, Workouts: workoutRepo, Exercises: exerciseRepo, RefreshTokens: mockRefreshTokenRepo}, "testsecret", &config.Config{})
 
 	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user123")
 
@@ -362,16 +595,28 @@ func TestUniqueExercises(t *testing.T) {
 		{ID: "ex2", Name: "My Bench", UserID: stringPtr("user123")},
 	}
 
-	// Expect Search with default limit 50 and offset 0
-	exerciseRepo.On("Search", mock.Anything, stringPtr("user123"), "Bench", 50, 0).Return(expectedExercises, nil)
+	// Queries are ranked from every visible exercise
+	exerciseRepo.On("ListVisible", mock.Anything, stringPtr("user123"), internalModel.ExerciseFilter{}).Return(expectedExercises, nil).Once()
+	workoutRepo.On("CountExerciseUsage", mock.Anything, "user123", mock.Anything).Return(map[string]int{}, nil)
+	// Search runs in the user's language
+	userRepo.On("FindByID", mock.Anything, "user123").Return(&internalModel.User{ID: "user123"}, nil)
 
 	query := "Bench"
 	limit := int32(50)
 	offset := int32(0)
-	exercises, err := resolver.Query().UniqueExercises(ctx, &query, &limit, &offset)
+	exercises, err := resolver.Query().UniqueExercises(ctx, &query, nil, &limit, &offset)
 
 	require.NoError(t, err)
 	require.Len(t, exercises, 2)
+
+	// Taxonomy filters are passed through to the repository
+	barbell := internalModel.EquipmentBarbell
+	filter := &internalModel.ExerciseFilter{Equipment: []internalModel.Equipment{barbell}}
+	exerciseRepo.On("ListVisible", mock.Anything, stringPtr("user123"), *filter).Return(expectedExercises[:1], nil).Once()
+
+	exercises, err = resolver.Query().UniqueExercises(ctx, &query, filter, &limit, &offset)
+	require.NoError(t, err)
+	require.Len(t, exercises, 1)
 	exerciseRepo.AssertExpectations(t)
 }
 
@@ -388,14 +633,14 @@ func TestGetUniqueExercise(t *testing.T) {
 	exerciseRepo := new(repository.MockExerciseRepository)
 	mockRefreshTokenRepo := new(repository.MockRefreshTokenRepository)
 	mockRefreshTokenRepo.On("Create", mock.Anything, mock.Anything).Return(nil) // Allow Login to create token
-	resolver := NewResolver(userRepo, workoutRepo, exerciseRepo, mockRefreshTokenRepo, "testsecret", &config.Config{})
+	resolver := NewResolver(Repositories{Users: userRepo, Workouts: workoutRepo, Exercises: exerciseRepo, RefreshTokens: mockRefreshTokenRepo}, "testsecret", &config.Config{})
 
 	expectedExercise := &internalModel.UniqueExercise{
 		ID:   "ex123",
 		Name: "Test Exercise",
 	}
 
-	exerciseRepo.On("FindByID", mock.Anything, "ex123").Return(expectedExercise, nil)
+	exerciseRepo.On("FindByIDs", mock.Anything, []string{"ex123"}).Return([]*internalModel.UniqueExercise{expectedExercise}, nil)
 
 	ex, err := resolver.Query().GetUniqueExercise(context.Background(), "ex123")
 
@@ -411,7 +656,7 @@ func TestExerciseLogUniqueExercise(t *testing.T) {
 	exerciseRepo := new(repository.MockExerciseRepository)
 	mockRefreshTokenRepo := new(repository.MockRefreshTokenRepository)
 	mockRefreshTokenRepo.On("Create", mock.Anything, mock.Anything).Return(nil) // Allow Login to create token
-	resolver := NewResolver(userRepo, workoutRepo, exerciseRepo, mockRefreshTokenRepo, "testsecret", &config.Config{})
+	resolver := NewResolver(Repositories{Users: userRepo, Workouts: workoutRepo, Exercises: exerciseRepo, RefreshTokens: mockRefreshTokenRepo}, "testsecret", &config.Config{})
 
 	exerciseLog := &internalModel.ExerciseLog{
 		UniqueExerciseID: "ex123",
@@ -422,7 +667,7 @@ func TestExerciseLogUniqueExercise(t *testing.T) {
 		Name: "Test Exercise",
 	}
 
-	exerciseRepo.On("FindByID", mock.Anything, "ex123").Return(expectedExercise, nil)
+	exerciseRepo.On("FindByIDs", mock.Anything, []string{"ex123"}).Return([]*internalModel.UniqueExercise{expectedExercise}, nil)
 
 	ex, err := resolver.ExerciseLog().UniqueExercise(context.Background(), exerciseLog)
 
@@ -431,3 +676,548 @@ func TestExerciseLogUniqueExercise(t *testing.T) {
 	require.Equal(t, "Test Exercise", ex.Name)
 	exerciseRepo.AssertExpectations(t)
 }
+
+func TestExerciseLookupsAreBatched(t *testing.T) {
+	workoutRepo := new(repository.MockWorkoutRepository)
+	exerciseRepo := new(repository.MockExerciseRepository)
+	resolver := NewResolver(Repositories{Workouts: workoutRepo, Exercises: exerciseRepo}, "testsecret", &config.Config{})
+
+	start := time.Date(2026, 3, 3, 18, 0, 0, 0, time.UTC)
+	workouts := []*internalModel.WorkoutLog{
+		{ID: "w2", UserID: "user123", Name: "Push", StartTime: start, EndTime: start, ExerciseLogs: []*internalModel.ExerciseLog{
+			{UniqueExerciseID: "bench"}, {UniqueExerciseID: "ohp"},
+		}},
+		{ID: "w1", UserID: "user123", Name: "Push", StartTime: start, EndTime: start, ExerciseLogs: []*internalModel.ExerciseLog{
+			{UniqueExerciseID: "bench"}, {UniqueExerciseID: "dips"},
+		}},
+	}
+	workoutRepo.On("ListByUser", mock.Anything, "user123", 10, 0).Return(workouts, nil)
+	exerciseRepo.On("FindByIDs", mock.Anything, mock.MatchedBy(func(ids []string) bool {
+		return len(ids) == 3
+	})).Return([]*internalModel.UniqueExercise{
+		{ID: "bench", Name: "Bench Press"}, {ID: "ohp", Name: "Overhead Press"}, {ID: "dips", Name: "Dips"},
+	}, nil).Once()
+
+	srv := handler.New(NewExecutableSchema(Config{Resolvers: resolver}))
+	srv.AddTransport(transport.POST{})
+	authenticated := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.UserIDKey, "user123")
+		loaders.Middleware(srv, resolver.WorkoutService, resolver.ExerciseService, resolver.BodyMetricService).ServeHTTP(w, r.WithContext(ctx))
+	})
+
+	body := `{"query": "{ listWorkoutLogs { id exerciseLogs { uniqueExercise { name } } } }"}`
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	authenticated.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotContains(t, rec.Body.String(), "errors")
+	require.Contains(t, rec.Body.String(), "Overhead Press")
+	exerciseRepo.AssertExpectations(t)
+	exerciseRepo.AssertNumberOfCalls(t, "FindByIDs", 1)
+	exerciseRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+}
+
+func TestExerciseLogPrevious(t *testing.T) {
+	workoutRepo := new(repository.MockWorkoutRepository)
+	resolver := NewResolver(Repositories{Workouts: workoutRepo}, "testsecret", &config.Config{})
+	ctx := context.Background()
+
+	start := time.Date(2026, 3, 3, 18, 0, 0, 0, time.UTC)
+	workout := &internalModel.WorkoutLog{
+		ID:           "w2",
+		UserID:       "user123",
+		StartTime:    start,
+		ExerciseLogs: []*internalModel.ExerciseLog{{UniqueExerciseID: "bench"}},
+	}
+	workout.LinkExerciseLogs()
+
+	previous := &internalModel.ExerciseSession{WorkoutID: "w1", Date: start.Add(-48 * time.Hour)}
+	workoutRepo.On("FindPreviousSessions", mock.Anything, []internalModel.PreviousSessionKey{{
+		UserID:     "user123",
+		ExerciseID: "bench",
+		Before:     internalModel.SessionCursor{StartTime: start, WorkoutID: "w2"},
+	}}).Return([]*internalModel.ExerciseSession{previous}, nil).Once()
+
+	session, err := resolver.ExerciseLog().Previous(ctx, workout.ExerciseLogs[0])
+	require.NoError(t, err)
+	require.Equal(t, "w1", session.WorkoutID)
+
+	// Logs that were not loaded from a workout have no previous session
+	session, err = resolver.ExerciseLog().Previous(ctx, &internalModel.ExerciseLog{UniqueExerciseID: "bench"})
+	require.NoError(t, err)
+	require.Nil(t, session)
+	workoutRepo.AssertExpectations(t)
+}
+
+func TestLogBodyMetric(t *testing.T) {
+	bodyMetricRepo := new(repository.MockBodyMetricRepository)
+	resolver := NewResolver(Repositories{BodyMetrics: bodyMetricRepo}, "testsecret", &config.Config{})
+
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user123")
+
+	weight := 176.0
+	input := model.LogBodyMetricInput{
+		Unit:   internalModel.WeightUnitPounds,
+		Weight: &weight,
+		Measurements: []*model.BodyMeasurementInput{
+			{Site: internalModel.MeasurementSiteArm, Value: 15},
+		},
+	}
+
+	bodyMetricRepo.On("Create", mock.Anything, mock.MatchedBy(func(m *internalModel.BodyMetric) bool {
+		return m.UserID == "user123" && len(m.Measurements) == 1
+	})).Return(nil)
+
+	metric, err := resolver.Mutation().LogBodyMetric(ctx, input)
+	require.NoError(t, err)
+
+	// Output is converted back to the requested unit.
+	pounds := internalModel.WeightUnitPounds
+	w, err := resolver.BodyMetric().Weight(ctx, metric, &pounds)
+	require.NoError(t, err)
+	require.InDelta(t, 176.0, *w, 0.0001)
+
+	kg, err := resolver.BodyMetric().Weight(ctx, metric, nil)
+	require.NoError(t, err)
+	require.InDelta(t, 79.83, *kg, 0.01)
+
+	inches, err := resolver.BodyMeasurement().Value(ctx, metric.Measurements[0], &pounds)
+	require.NoError(t, err)
+	require.InDelta(t, 15.0, inches, 0.0001)
+	bodyMetricRepo.AssertExpectations(t)
+}
+
+func TestLogBodyMetricUnauthenticated(t *testing.T) {
+	resolver := NewResolver(Repositories{}, "testsecret", &config.Config{})
+
+	_, err := resolver.Mutation().LogBodyMetric(context.Background(), model.LogBodyMetricInput{})
+	require.Error(t, err)
+}
+
+func TestWorkoutLogBodyweight(t *testing.T) {
+	bodyMetricRepo := new(repository.MockBodyMetricRepository)
+	resolver := NewResolver(Repositories{BodyMetrics: bodyMetricRepo}, "testsecret", &config.Config{})
+
+	start := time.Date(2025, 2, 1, 18, 0, 0, 0, time.UTC)
+	log := &internalModel.WorkoutLog{ID: "log1", UserID: "user123", StartTime: start}
+	weight := 90.0
+	bodyMetricRepo.On("FindLatestWeightsBefore", mock.Anything, []internalModel.BodyweightKey{{UserID: "user123", At: start}}).
+		Return([]*internalModel.BodyMetric{{Weight: &weight}}, nil)
+
+	bw, err := resolver.WorkoutLog().Bodyweight(context.Background(), log, nil)
+
+	require.NoError(t, err)
+	require.Equal(t, 90.0, *bw)
+	bodyMetricRepo.AssertExpectations(t)
+}
+
+func TestCreateGoal(t *testing.T) {
+	goalRepo := new(repository.MockGoalRepository)
+	resolver := NewResolver(Repositories{Goals: goalRepo}, "testsecret", &config.Config{})
+
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user123")
+
+	deadline := time.Now().AddDate(0, 2, 0)
+	input := model.CreateGoalInput{
+		Type:        internalModel.GoalTypeFrequency,
+		Name:        "Train 4x/week",
+		TargetValue: 4,
+		Unit:        internalModel.WeightUnitKilograms,
+		Deadline:    &deadline,
+	}
+
+	goalRepo.On("Create", mock.Anything, mock.MatchedBy(func(g *internalModel.Goal) bool {
+		return g.UserID == "user123" && g.Type == internalModel.GoalTypeFrequency && g.TargetValue == 4
+	})).Return(nil)
+
+	goal, err := resolver.Mutation().CreateGoal(ctx, input)
+	require.NoError(t, err)
+	require.Equal(t, "Train 4x/week", goal.Name)
+
+	// Frequency targets are not weights and must not be converted.
+	pounds := internalModel.WeightUnitPounds
+	target, err := resolver.Goal().TargetValue(ctx, goal, &pounds)
+	require.NoError(t, err)
+	require.Equal(t, 4.0, target)
+	goalRepo.AssertExpectations(t)
+}
+
+func TestGoalProgressUnits(t *testing.T) {
+	resolver := NewResolver(Repositories{}, "testsecret", &config.Config{})
+	current := 100.0
+	progress := &internalModel.GoalProgress{GoalType: internalModel.GoalTypeLift, CurrentValue: &current, TargetValue: 140}
+
+	pounds := internalModel.WeightUnitPounds
+	v, err := resolver.GoalProgress().CurrentValue(context.Background(), progress, &pounds)
+	require.NoError(t, err)
+	require.InDelta(t, 220.46, *v, 0.01)
+
+	start, err := resolver.GoalProgress().StartingValue(context.Background(), progress, &pounds)
+	require.NoError(t, err)
+	require.Nil(t, start)
+}
+
+func TestTrainingCalendar(t *testing.T) {
+	userRepo := new(repository.MockUserRepository)
+	workoutRepo := new(repository.MockWorkoutRepository)
+	resolver := NewResolver(Repositories{Users: userRepo, Workouts: workoutRepo}, "testsecret", &config.Config{})
+
+	_, err := resolver.Query().TrainingCalendar(context.Background(), 2024)
+	require.ErrorContains(t, err, "unauthorized")
+
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user123")
+	userRepo.On("FindByID", mock.Anything, "user123").Return(&internalModel.User{ID: "user123", Timezone: "UTC"}, nil)
+	start := time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC)
+	workoutRepo.On("SummarizeDays", mock.Anything, "user123", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), "UTC").
+		Return([]*internalModel.TrainingCalendarDay{{Date: "2024-05-01", WorkoutCount: 1, DurationMinutes: 60, Volume: 500}}, nil)
+	workoutRepo.On("ListStartTimes", mock.Anything, "user123", mock.Anything).Return([]time.Time{start}, nil)
+
+	calendar, err := resolver.Query().TrainingCalendar(ctx, 2024)
+	require.NoError(t, err)
+	require.Len(t, calendar.Days, 1)
+	require.Equal(t, "2024-05-01", calendar.Days[0].Date)
+	require.Equal(t, int32(1), calendar.Streaks.LongestDaily)
+
+	pounds := internalModel.WeightUnitPounds
+	volume, err := resolver.TrainingCalendarDay().Volume(ctx, calendar.Days[0], &pounds)
+	require.NoError(t, err)
+	require.InDelta(t, 1102.31, volume, 0.01)
+}
+
+func TestExerciseHistory(t *testing.T) {
+	workoutRepo := new(repository.MockWorkoutRepository)
+	resolver := NewResolver(Repositories{Workouts: workoutRepo}, "testsecret", &config.Config{})
+
+	_, err := resolver.Query().ExerciseHistory(context.Background(), "bench", nil, nil)
+	require.ErrorContains(t, err, "unauthorized")
+
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user123")
+	workoutRepo.On("ListExerciseSessions", mock.Anything, "user123", "bench", (*internalModel.SessionCursor)(nil), 21).Return([]*internalModel.ExerciseSession{
+		{WorkoutID: "w1", WorkoutName: "Push", Date: time.Now(), Sets: []*internalModel.Set{{Reps: 5, Weight: 100}}},
+	}, nil)
+	workoutRepo.On("SummarizeExercise", mock.Anything, "user123", "bench").Return(&internalModel.ExerciseHistorySummary{TotalSessions: 1}, nil)
+
+	history, err := resolver.Query().ExerciseHistory(ctx, "bench", nil, nil)
+	require.NoError(t, err)
+	require.Len(t, history.Sessions, 1)
+	require.False(t, history.HasNextPage)
+	require.NotNil(t, history.EndCursor)
+	require.Equal(t, int32(1), history.Summary.TotalSessions)
+}
+
+func TestUniqueExerciseReplacedBy(t *testing.T) {
+	exerciseRepo := new(repository.MockExerciseRepository)
+	resolver := NewResolver(Repositories{Exercises: exerciseRepo}, "testsecret", &config.Config{})
+	ctx := context.Background()
+
+	active, err := resolver.UniqueExercise().ReplacedBy(ctx, &internalModel.UniqueExercise{ID: "dips"})
+	require.NoError(t, err)
+	require.Nil(t, active)
+
+	exerciseRepo.On("FindByIDs", mock.Anything, []string{"tricep-dips"}).Return([]*internalModel.UniqueExercise{
+		{ID: "tricep-dips", Name: "Tricep Dips"},
+	}, nil).Once()
+	replacement, err := resolver.UniqueExercise().ReplacedBy(ctx, &internalModel.UniqueExercise{ID: "dips", ReplacedByID: stringPtr("tricep-dips")})
+	require.NoError(t, err)
+	require.Equal(t, "Tricep Dips", replacement.Name)
+	exerciseRepo.AssertExpectations(t)
+}
+
+func TestLocalizedExerciseNames(t *testing.T) {
+	description := "A compound exercise."
+	translated := "Ejercicio compuesto."
+	squat := &internalModel.UniqueExercise{ID: "squat", Name: "Squat", Description: &description, Translations: map[string]internalModel.ExerciseTranslation{
+		"es": {Name: "Sentadilla", Description: &translated},
+		"de": {Name: "Kniebeuge"},
+	}}
+	custom := &internalModel.UniqueExercise{ID: "custom", Name: "Zercher Squat", UserID: stringPtr("user123")}
+
+	query := `{"query": "{ a: getUniqueExercise(id: \"squat\") { name description locale translations { locale name } } b: getUniqueExercise(id: \"custom\") { name locale } }"}`
+	serve := func(t *testing.T, resolver *Resolver, userID string, acceptLanguage string) map[string]any {
+		srv := handler.New(NewExecutableSchema(Config{Resolvers: resolver}))
+		srv.AddTransport(transport.POST{})
+		h := middleware.LocaleMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if userID != "" {
+				ctx = context.WithValue(ctx, middleware.UserIDKey, userID)
+			}
+			srv.ServeHTTP(w, r.WithContext(ctx))
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(query))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Language", acceptLanguage)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var body struct {
+			Data   map[string]any `json:"data"`
+			Errors []any          `json:"errors"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		require.Empty(t, body.Errors)
+		return body.Data
+	}
+
+	t.Run("Accept-Language", func(t *testing.T) {
+		exerciseRepo := new(repository.MockExerciseRepository)
+		exerciseRepo.On("FindByIDs", mock.Anything, mock.Anything).Return([]*internalModel.UniqueExercise{squat, custom}, nil)
+		resolver := NewResolver(Repositories{Exercises: exerciseRepo}, "testsecret", &config.Config{})
+
+		data := serve(t, resolver, "", "es-MX,es;q=0.9,en;q=0.8")
+		a := data["a"].(map[string]any)
+		require.Equal(t, "Sentadilla", a["name"])
+		require.Equal(t, "Ejercicio compuesto.", a["description"])
+		require.Equal(t, "es", a["locale"])
+		require.Equal(t, []any{
+			map[string]any{"locale": "de", "name": "Kniebeuge"},
+			map[string]any{"locale": "es", "name": "Sentadilla"},
+		}, a["translations"])
+
+		// Custom exercises have no translations
+		b := data["b"].(map[string]any)
+		require.Equal(t, "Zercher Squat", b["name"])
+		require.Equal(t, "en", b["locale"])
+	})
+
+	t.Run("profile setting wins and is looked up once", func(t *testing.T) {
+		exerciseRepo := new(repository.MockExerciseRepository)
+		exerciseRepo.On("FindByIDs", mock.Anything, mock.Anything).Return([]*internalModel.UniqueExercise{squat, custom}, nil)
+		userRepo := new(repository.MockUserRepository)
+		userRepo.On("FindByID", mock.Anything, "user123").Return(&internalModel.User{ID: "user123", Locale: stringPtr("de")}, nil).Once()
+		resolver := NewResolver(Repositories{Exercises: exerciseRepo, Users: userRepo}, "testsecret", &config.Config{})
+
+		data := serve(t, resolver, "user123", "es")
+		a := data["a"].(map[string]any)
+		require.Equal(t, "Kniebeuge", a["name"])
+		// The German translation has no description
+		require.Equal(t, "A compound exercise.", a["description"])
+		require.Equal(t, "de", a["locale"])
+		userRepo.AssertExpectations(t)
+	})
+
+	t.Run("search uses the user's language", func(t *testing.T) {
+		exerciseRepo := new(repository.MockExerciseRepository)
+		exerciseRepo.On("ListVisible", mock.Anything, (*string)(nil), internalModel.ExerciseFilter{}).Return([]*internalModel.UniqueExercise{squat, custom}, nil)
+		resolver := NewResolver(Repositories{Exercises: exerciseRepo}, "testsecret", &config.Config{})
+
+		ctx := context.WithValue(context.Background(), middleware.LocalesKey, &middleware.RequestLocales{AcceptLanguage: []string{"es"}})
+		results, err := resolver.Query().UniqueExercises(ctx, stringPtr("sentadilla"), nil, nil, nil)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		require.Equal(t, "squat", results[0].ID)
+	})
+}
+
+func TestDeleteAccount(t *testing.T) {
+	userRepo := new(repository.MockUserRepository)
+	refreshTokenRepo := new(repository.MockRefreshTokenRepository)
+	resolver := NewResolver(Repositories{Users: userRepo, RefreshTokens: refreshTokenRepo}, "testsecret", &config.Config{AccountDeletionGracePeriod: 24 * time.Hour})
+	w := httptest.NewRecorder()
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user123")
+	ctx = context.WithValue(ctx, middleware.ResponseWriterKey, w)
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &internalModel.User{ID: "user123", Email: "test@example.com", PasswordHash: string(hashedPassword)}
+	userRepo.On("FindByID", mock.Anything, "user123").Return(user, nil)
+	userRepo.On("Update", mock.Anything, mock.Anything).Return(nil)
+	refreshTokenRepo.On("RevokeAllForUser", mock.Anything, "user123").Return(nil)
+
+	deleted, err := resolver.Mutation().DeleteAccount(ctx, "password123")
+	require.NoError(t, err)
+	require.NotNil(t, deleted.DeletionScheduledAt)
+	require.WithinDuration(t, time.Now().Add(24*time.Hour), *deleted.DeletionScheduledAt, time.Minute)
+	refreshTokenRepo.AssertExpectations(t)
+
+	// The refresh cookie is cleared
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	require.Equal(t, "refresh_token", cookies[0].Name)
+	require.Equal(t, -1, cookies[0].MaxAge)
+
+	userRepo.On("CancelDeletion", mock.Anything, "user123").Return(true, nil)
+	kept, err := resolver.Mutation().CancelAccountDeletion(ctx)
+	require.NoError(t, err)
+	require.Nil(t, kept.DeletionScheduledAt)
+
+	_, err = resolver.Mutation().DeleteAccount(context.Background(), "password123")
+	require.EqualError(t, err, "unauthorized: must be logged in to delete your account")
+}
+
+func TestSessions(t *testing.T) {
+	refreshTokenRepo := new(repository.MockRefreshTokenRepository)
+	resolver := NewResolver(Repositories{RefreshTokens: refreshTokenRepo}, "testsecret", &config.Config{})
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user123")
+	ctx = context.WithValue(ctx, middleware.SessionIDKey, "family-1")
+
+	refreshTokenRepo.On("ListActiveByUser", mock.Anything, "user123", mock.Anything).Return([]*internalModel.RefreshToken{
+		{ID: "token-2", FamilyID: "family-1", UserAgent: "Mozilla/5.0 (X11; Linux x86_64) Chrome/120.0 Safari/537.36"},
+		{ID: "token-3", FamilyID: "family-2"},
+	}, nil)
+	sessions, err := resolver.Query().MySessions(ctx)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	require.True(t, sessions[0].Current)
+	require.Equal(t, "Chrome on Linux", sessions[0].Device)
+	require.False(t, sessions[1].Current)
+
+	refreshTokenRepo.On("RevokeFamilyForUser", mock.Anything, "user123", "family-2").Return(int64(1), nil).Once()
+	revoked, err := resolver.Mutation().RevokeSession(ctx, "family-2")
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	refreshTokenRepo.On("RevokeFamilyForUser", mock.Anything, "user123", "family-3").Return(int64(0), nil).Once()
+	_, err = resolver.Mutation().RevokeSession(ctx, "family-3")
+	require.EqualError(t, err, "session not found")
+
+	// The session making the request stays signed in
+	refreshTokenRepo.On("RevokeAllForUserExcept", mock.Anything, "user123", "family-1").Return(int64(4), nil).Once()
+	revoked, err = resolver.Mutation().RevokeOtherSessions(ctx)
+	require.NoError(t, err)
+	require.True(t, revoked)
+	refreshTokenRepo.AssertExpectations(t)
+}
+
+func TestLoginTwoFactor(t *testing.T) {
+	userRepo := new(repository.MockUserRepository)
+	refreshTokenRepo := new(repository.MockRefreshTokenRepository)
+	actionTokenRepo := new(repository.MockActionTokenRepository)
+	resolver := NewResolver(Repositories{Users: userRepo, RefreshTokens: refreshTokenRepo, ActionTokens: actionTokenRepo}, "testsecret", &config.Config{})
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	recoveryCodeHash := sha256.Sum256([]byte("aaaabbbbcccc"))
+	user := &internalModel.User{
+		ID:                 "user123",
+		Email:              "test@example.com",
+		PasswordHash:       string(hashedPassword),
+		TOTPEnabled:        true,
+		TOTPSecret:         "JBSWY3DPEHPK3PXP",
+		RecoveryCodeHashes: []string{hex.EncodeToString(recoveryCodeHash[:])},
+	}
+	userRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(user, nil)
+	userRepo.On("FindByID", mock.Anything, "user123").Return(user, nil)
+	userRepo.On("UseRecoveryCode", mock.Anything, "user123", user.RecoveryCodeHashes[0]).Return(true, nil).Once()
+	refreshTokenRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+	challenge := &internalModel.ActionToken{}
+	actionTokenRepo.On("DeleteForUser", mock.Anything, "user123", internalModel.ActionTokenTwoFactorChallenge).Return(nil)
+	actionTokenRepo.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		token := args.Get(1).(*internalModel.ActionToken)
+		token.ID = "challenge-1"
+		*challenge = *token
+	}).Return(nil)
+	actionTokenRepo.On("FindByID", mock.Anything, "challenge-1").Return(challenge, nil)
+	actionTokenRepo.On("Consume", mock.Anything, "challenge-1").Return(true, nil)
+
+	// The password alone does not start a session
+	w := httptest.NewRecorder()
+	ctx := context.WithValue(context.Background(), middleware.ResponseWriterKey, w)
+	payload, err := resolver.Mutation().Login(ctx, model.LoginInput{Email: "test@example.com", Password: "password123"})
+	require.NoError(t, err)
+	require.True(t, payload.TwoFactorRequired)
+	require.NotNil(t, payload.ChallengeToken)
+	require.Empty(t, payload.Token)
+	require.Nil(t, payload.User)
+	require.Empty(t, w.Result().Cookies())
+	refreshTokenRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+	_, err = resolver.Mutation().VerifyTwoFactor(ctx, *payload.ChallengeToken, "000000")
+	require.ErrorIs(t, err, service.ErrInvalidTwoFactorCode)
+
+	w = httptest.NewRecorder()
+	ctx = context.WithValue(context.Background(), middleware.ResponseWriterKey, w)
+	payload, err = resolver.Mutation().VerifyTwoFactor(ctx, *payload.ChallengeToken, "AAAA-BBBB-CCCC")
+	require.NoError(t, err)
+	require.False(t, payload.TwoFactorRequired)
+	require.NotEmpty(t, payload.Token)
+	require.Equal(t, "user123", payload.User.ID)
+	userRepo.AssertExpectations(t)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	require.Equal(t, "refresh_token", cookies[0].Name)
+}
+
+func TestPasskeys(t *testing.T) {
+	userRepo := new(repository.MockUserRepository)
+	refreshTokenRepo := new(repository.MockRefreshTokenRepository)
+	actionTokenRepo := new(repository.MockActionTokenRepository)
+	passkeyRepo := new(repository.MockPasskeyRepository)
+	resolver := NewResolver(Repositories{Users: userRepo, RefreshTokens: refreshTokenRepo, ActionTokens: actionTokenRepo, Passkeys: passkeyRepo},
+		"testsecret", &config.Config{AppURL: "https://fitness.example.com"})
+	authenticator := webauthntest.NewAuthenticator("https://fitness.example.com")
+
+	user := &internalModel.User{ID: "user123", Email: "test@example.com", TOTPEnabled: true}
+	userRepo.On("FindByID", mock.Anything, "user123").Return(user, nil)
+	refreshTokenRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	actionTokenRepo.On("DeleteForUser", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	issued := 0
+	actionTokenRepo.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		token := args.Get(1).(*internalModel.ActionToken)
+		issued++
+		token.ID = fmt.Sprintf("token-%d", issued)
+		actionTokenRepo.On("FindByID", mock.Anything, token.ID).Return(token, nil)
+	}).Return(nil)
+	actionTokenRepo.On("Consume", mock.Anything, mock.Anything).Return(true, nil)
+
+	// Add a passkey while signed in
+	var stored *internalModel.Passkey
+	passkeyRepo.On("ListByUser", mock.Anything, "user123").Return([]*internalModel.Passkey{}, nil)
+	passkeyRepo.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*internalModel.Passkey)
+		stored.ID = "passkey-1"
+	}).Return(nil)
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user123")
+	options, err := resolver.Mutation().BeginPasskeyRegistration(ctx)
+	require.NoError(t, err)
+	credential, err := authenticator.Register(options)
+	require.NoError(t, err)
+	name := "Phone"
+	passkey, err := resolver.Mutation().FinishPasskeyRegistration(ctx, credential, &name)
+	require.NoError(t, err)
+	require.Equal(t, "Phone", passkey.Name)
+
+	_, err = resolver.Mutation().BeginPasskeyRegistration(context.Background())
+	require.EqualError(t, err, "unauthorized: must be logged in to add a passkey")
+
+	// Sign in with it, skipping the second factor
+	passkeyRepo.On("FindByCredentialID", mock.Anything, stored.CredentialID).Return(stored, nil)
+	passkeyRepo.On("RecordUse", mock.Anything, "passkey-1", uint32(1), mock.Anything).Return(nil)
+	w := httptest.NewRecorder()
+	ctx = context.WithValue(context.Background(), middleware.ResponseWriterKey, w)
+	options, err = resolver.Mutation().BeginPasskeyLogin(ctx)
+	require.NoError(t, err)
+	credential, err = authenticator.Login(options)
+	require.NoError(t, err)
+	payload, err := resolver.Mutation().FinishPasskeyLogin(ctx, credential)
+	require.NoError(t, err)
+	require.False(t, payload.TwoFactorRequired)
+	require.NotEmpty(t, payload.Token)
+	require.Equal(t, "user123", payload.User.ID)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	require.Equal(t, "refresh_token", cookies[0].Name)
+
+	_, err = resolver.Mutation().FinishPasskeyLogin(ctx, "{}")
+	require.ErrorIs(t, err, service.ErrInvalidPasskey)
+}
+
+func TestLoginProviders(t *testing.T) {
+	resolver := NewResolver(Repositories{}, "testsecret", &config.Config{
+		AppURL: "https://fitness.example.com/",
+		OIDCProviders: config.OIDCProviders{
+			{ID: "google", Name: "Google", Issuer: "https://accounts.google.com", ClientID: "client"},
+			{ID: "gitlab", Issuer: "https://gitlab.com", ClientID: "client"},
+		},
+	})
+
+	providers, err := resolver.Query().LoginProviders(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []*model.LoginProvider{
+		{ID: "google", Name: "Google"},
+		{ID: "gitlab", Name: "gitlab"},
+	}, providers)
+	require.Equal(t, "https://fitness.example.com/auth/oidc/google/callback", resolver.OIDCService.Providers()[0].Provider.RedirectURL)
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/riverajo/fitness-app/backend/internal/middleware"
+	"github.com/riverajo/fitness-app/backend/internal/model"
 	"github.com/riverajo/fitness-app/backend/internal/service"
 )
 
@@ -14,14 +15,17 @@ type AuthHandler struct {
 	UserService  *service.UserService
 	JWTSecret    string
 	SecureCookie bool
+	// TrustedProxies is the number of reverse proxies whose X-Forwarded-For entries are trusted
+	TrustedProxies int
 }
 
-func NewAuthHandler(tokenService *service.TokenService, userService *service.UserService, jwtSecret string, secureCookie bool) *AuthHandler {
+func NewAuthHandler(tokenService *service.TokenService, userService *service.UserService, jwtSecret string, secureCookie bool, trustedProxies int) *AuthHandler {
 	return &AuthHandler{
-		TokenService: tokenService,
-		UserService:  userService,
-		JWTSecret:    jwtSecret,
-		SecureCookie: secureCookie,
+		TokenService:   tokenService,
+		UserService:    userService,
+		JWTSecret:      jwtSecret,
+		SecureCookie:   secureCookie,
+		TrustedProxies: trustedProxies,
 	}
 }
 
@@ -34,8 +38,9 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	}
 	refreshToken := cookie.Value
 
-	// 2. Validate and Rotate Token
-	newRefreshToken, userID, err := h.TokenService.ValidateRotate(r.Context(), refreshToken)
+	// 2. Validate and Rotate Token, recording the client on the session
+	client := model.SessionClient{UserAgent: r.UserAgent(), IP: middleware.ClientIP(r, h.TrustedProxies)}
+	newRefreshToken, session, err := h.TokenService.ValidateRotate(r.Context(), refreshToken, client)
 	if err != nil {
 		// Log error internally if needed
 		// Clear cookie
@@ -53,6 +58,7 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 3. Fetch User Details
+	userID := session.UserID
 	user, err := h.UserService.GetUserByID(r.Context(), userID)
 	if err != nil {
 		slog.Warn("Failed to fetch user during refresh", "user_id", userID, "error", err)
@@ -61,7 +67,7 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 4. Issue New Access Token
-	token, err := middleware.GenerateJWT(user, h.JWTSecret)
+	token, err := middleware.GenerateSessionJWT(user, session.FamilyID, h.JWTSecret)
 	if err != nil {
 		slog.Error("Failed to generate JWT during refresh", "error", err)
 		http.Error(w, "Internal error", http.StatusInternalServerError)
@@ -69,15 +75,7 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 5. Set New Refresh Cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    newRefreshToken,
-		Path:     "/auth/refresh",
-		HttpOnly: true,
-		Secure:   h.SecureCookie,
-		SameSite: http.SameSiteStrictMode,
-		MaxAge:   7 * 24 * 3600,
-	})
+	setRefreshCookie(w, newRefreshToken, h.SecureCookie)
 
 	// 6. Return JSON Response
 	w.Header().Set("Content-Type", "application/json")
@@ -89,3 +87,16 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		slog.Error("Failed to encode refresh response", "error", err)
 	}
 }
+
+// setRefreshCookie sets the cookie holding the session's refresh token, sent only to /auth/refresh.
+func setRefreshCookie(w http.ResponseWriter, refreshToken string, secure bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    refreshToken,
+		Path:     "/auth/refresh",
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   7 * 24 * 3600,
+	})
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/middleware"
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/service"
+)
+
+// oidcFlowCookie holds the sign-in in progress while the user is at the provider.
+const oidcFlowCookie = "oidc_flow"
+
+// OIDCHandler signs users in with OpenID Connect providers. Start sends the browser to the
+// provider, which sends it back to Callback; that starts a session like a password login, then
+// sends the browser to the app with the result in the URL fragment: "token" (the JWT, with the
+// refresh cookie set), "challengeToken" (for verifyTwoFactor) or "error".
+type OIDCHandler struct {
+	OIDCService      *service.OIDCService
+	TwoFactorService *service.TwoFactorService
+	TokenService     *service.TokenService
+	JWTSecret        string
+	SecureCookie     bool
+	// TrustedProxies is the number of reverse proxies whose X-Forwarded-For entries are trusted
+	TrustedProxies int
+	// AppURL is the frontend to return to after signing in
+	AppURL string
+}
+
+func NewOIDCHandler(oidcService *service.OIDCService, twoFactorService *service.TwoFactorService, tokenService *service.TokenService, jwtSecret string, secureCookie bool, trustedProxies int, appURL string) *OIDCHandler {
+	return &OIDCHandler{
+		OIDCService:      oidcService,
+		TwoFactorService: twoFactorService,
+		TokenService:     tokenService,
+		JWTSecret:        jwtSecret,
+		SecureCookie:     secureCookie,
+		TrustedProxies:   trustedProxies,
+		AppURL:           strings.TrimSuffix(appURL, "/"),
+	}
+}
+
+// Start handles GET /auth/oidc/{provider}: it keeps the new sign-in in a signed cookie and
+// redirects to the provider.
+func (h *OIDCHandler) Start(w http.ResponseWriter, r *http.Request) {
+	flow, authURL, err := h.OIDCService.Start(r.Context(), r.PathValue("provider"))
+	if errors.Is(err, service.ErrUnknownOIDCProvider) {
+		http.Error(w, "Unknown sign-in provider", http.StatusNotFound)
+		return
+	} else if err != nil {
+		slog.Error("Failed to start OIDC sign-in", "provider", r.PathValue("provider"), "error", err)
+		h.redirectToApp(w, r, url.Values{"error": {service.ErrOIDCSignInFailed.Error()}})
+		return
+	}
+
+	value, err := h.encodeFlow(flow)
+	if err != nil {
+		slog.Error("Failed to encode OIDC sign-in", "error", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	// Lax, so the cookie is sent with the provider's redirect back
+	h.setFlowCookie(w, value, int(time.Until(time.Unix(flow.ExpiresAt, 0)).Seconds()))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback handles GET /auth/oidc/{provider}/callback, where the provider sends the browser back
+// with an authorization code.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	// The sign-in can only be completed once
+	h.setFlowCookie(w, "", -1)
+
+	cookie, err := r.Cookie(oidcFlowCookie)
+	if err != nil {
+		h.redirectToApp(w, r, url.Values{"error": {service.ErrOIDCSignInFailed.Error()}})
+		return
+	}
+	flow, ok := h.decodeFlow(cookie.Value)
+	if !ok || flow.Provider != r.PathValue("provider") {
+		h.redirectToApp(w, r, url.Values{"error": {service.ErrOIDCSignInFailed.Error()}})
+		return
+	}
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		// e.g. access_denied, when the user cancels at the provider
+		slog.Info("OIDC provider returned an error", "provider", flow.Provider, "error", providerErr)
+		h.redirectToApp(w, r, url.Values{"error": {service.ErrOIDCSignInFailed.Error()}})
+		return
+	}
+
+	user, err := h.OIDCService.Complete(r.Context(), flow, query.Get("state"), query.Get("code"))
+	if err != nil {
+		message := err.Error()
+		if !errors.Is(err, service.ErrOIDCSignInFailed) && !errors.Is(err, service.ErrOIDCEmailNotVerified) &&
+			!errors.Is(err, service.ErrOIDCAccountNotLinked) && !errors.Is(err, service.ErrUnknownOIDCProvider) {
+			slog.Error("Failed to complete OIDC sign-in", "provider", flow.Provider, "error", err)
+			message = service.ErrOIDCSignInFailed.Error()
+		}
+		h.redirectToApp(w, r, url.Values{"error": {message}})
+		return
+	}
+
+	// With two-factor authentication, the session only starts once verifyTwoFactor checks a code
+	if user.TOTPEnabled {
+		challengeToken, err := h.TwoFactorService.StartChallenge(r.Context(), user)
+		if err != nil {
+			slog.Error("Failed to start two-factor challenge", "user_id", user.ID, "error", err)
+			h.redirectToApp(w, r, url.Values{"error": {service.ErrOIDCSignInFailed.Error()}})
+			return
+		}
+		h.redirectToApp(w, r, url.Values{"challengeToken": {challengeToken}})
+		return
+	}
+
+	client := model.SessionClient{UserAgent: r.UserAgent(), IP: middleware.ClientIP(r, h.TrustedProxies)}
+	refreshToken, session, err := h.TokenService.CreateCompositeRefreshToken(r.Context(), user.ID, client)
+	if err != nil {
+		slog.Error("Failed to generate refresh token", "user_id", user.ID, "error", err)
+		h.redirectToApp(w, r, url.Values{"error": {service.ErrOIDCSignInFailed.Error()}})
+		return
+	}
+	token, err := middleware.GenerateSessionJWT(user, session.FamilyID, h.JWTSecret)
+	if err != nil {
+		slog.Error("CRITICAL: Failed to generate JWT for user", "user_id", user.ID, "error", err)
+		h.redirectToApp(w, r, url.Values{"error": {service.ErrOIDCSignInFailed.Error()}})
+		return
+	}
+	setRefreshCookie(w, refreshToken, h.SecureCookie)
+	h.redirectToApp(w, r, url.Values{"token": {token}})
+}
+
+// redirectToApp sends the browser to the app with params in the URL fragment, which is not sent
+// to servers or in the Referer header.
+func (h *OIDCHandler) redirectToApp(w http.ResponseWriter, r *http.Request, params url.Values) {
+	http.Redirect(w, r, h.AppURL+"/#"+params.Encode(), http.StatusFound)
+}
+
+func (h *OIDCHandler) setFlowCookie(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    value,
+		Path:     "/auth/oidc",
+		HttpOnly: true,
+		Secure:   h.SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   maxAge,
+	})
+}
+
+// encodeFlow encodes the flow for its cookie, signed so the browser cannot change it.
+func (h *OIDCHandler) encodeFlow(flow *service.OIDCFlow) (string, error) {
+	b, err := json.Marshal(flow)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(h.signFlow(payload)), nil
+}
+
+func (h *OIDCHandler) decodeFlow(value string) (*service.OIDCFlow, bool) {
+	payload, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, h.signFlow(payload)) {
+		return nil, false
+	}
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, false
+	}
+	var flow service.OIDCFlow
+	if err := json.Unmarshal(b, &flow); err != nil {
+		return nil, false
+	}
+	return &flow, true
+}
+
+func (h *OIDCHandler) signFlow(payload string) []byte {
+	mac := hmac.New(sha256.New, []byte(h.JWTSecret))
+	// Prefixed, so the signature cannot be mistaken for that of anything else signed with the secret
+	mac.Write([]byte(oidcFlowCookie + ":" + payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverajo/fitness-app/backend/internal/middleware"
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/oidc"
+	"github.com/riverajo/fitness-app/backend/internal/oidc/oidctest"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+	"github.com/riverajo/fitness-app/backend/internal/service"
+)
+
+const testAppURL = "https://fitness.example.com"
+
+type oidcHandlerFixture struct {
+	server        *httptest.Server
+	provider      *oidctest.Provider
+	users         *repository.MockUserRepository
+	identities    *repository.MockExternalIdentityRepository
+	refreshTokens *repository.MockRefreshTokenRepository
+	actionTokens  *repository.MockActionTokenRepository
+	client        *http.Client
+}
+
+func newOIDCHandlerFixture(t *testing.T) *oidcHandlerFixture {
+	f := &oidcHandlerFixture{
+		provider:      oidctest.NewProvider(t, "fitness-app", "secret"),
+		users:         new(repository.MockUserRepository),
+		identities:    new(repository.MockExternalIdentityRepository),
+		refreshTokens: new(repository.MockRefreshTokenRepository),
+		actionTokens:  new(repository.MockActionTokenRepository),
+	}
+	mux := http.NewServeMux()
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+
+	oidcService := service.NewOIDCService([]service.LoginProvider{{
+		ID:   "test",
+		Name: "Test",
+		Provider: &oidc.Provider{
+			Issuer:       f.provider.Issuer(),
+			ClientID:     "fitness-app",
+			ClientSecret: "secret",
+			RedirectURL:  f.server.URL + "/auth/oidc/test/callback",
+		},
+	}}, f.identities, f.users)
+	twoFactor := service.NewTwoFactorService(f.users, f.actionTokens, service.NewRateLimiter(repository.NewMemoryRateLimitRepository()), "Fitness App")
+	h := NewOIDCHandler(oidcService, twoFactor, service.NewTokenService(f.refreshTokens), "test-secret", false, 0, testAppURL)
+	mux.HandleFunc("GET /auth/oidc/{provider}", h.Start)
+	mux.HandleFunc("GET /auth/oidc/{provider}/callback", h.Callback)
+
+	// A browser, stopping when it is sent back to the app
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	f.client = &http.Client{Jar: jar, CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if req.URL.Host == "fitness.example.com" {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}}
+	return f
+}
+
+// signIn starts signing in with the test provider and returns the fragment of the app URL the
+// browser is sent back to, and the callback's response.
+func (f *oidcHandlerFixture) signIn(t *testing.T) (url.Values, *http.Response) {
+	resp, err := f.client.Get(f.server.URL + "/auth/oidc/test")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	return appFragment(t, resp), resp
+}
+
+func appFragment(t *testing.T, resp *http.Response) url.Values {
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, testAppURL+"/", location.Scheme+"://"+location.Host+location.Path)
+	fragment, err := url.ParseQuery(location.EscapedFragment())
+	require.NoError(t, err)
+	return fragment
+}
+
+func cookie(resp *http.Response, name string) *http.Cookie {
+	for _, c := range resp.Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestOIDCHandler(t *testing.T) {
+	t.Run("Signs a new user in with a session", func(t *testing.T) {
+		f := newOIDCHandlerFixture(t)
+		f.identities.On("FindByProviderSubject", mock.Anything, "test", "subject-1").Return(nil, nil)
+		f.users.On("FindByEmail", mock.Anything, "user@example.com").Return(nil, nil)
+		var created model.User
+		f.users.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			created = args.Get(1).(model.User)
+		}).Return(nil)
+		f.identities.On("Create", mock.Anything, mock.Anything).Return(nil)
+		f.refreshTokens.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			token := args.Get(1).(*model.RefreshToken)
+			token.ID, token.FamilyID = "refresh-1", "family-1"
+		}).Return(nil)
+
+		fragment, resp := f.signIn(t)
+		require.Empty(t, fragment.Get("error"))
+
+		claims := &middleware.Claims{}
+		_, err := jwt.ParseWithClaims(fragment.Get("token"), claims, func(*jwt.Token) (any, error) {
+			return []byte("test-secret"), nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, claims.UserID)
+		assert.Equal(t, "family-1", claims.SessionID)
+
+		refresh := cookie(resp, "refresh_token")
+		require.NotNil(t, refresh)
+		assert.Contains(t, refresh.Value, "refresh-1.")
+		assert.Equal(t, "/auth/refresh", refresh.Path)
+		// The sign-in cannot be completed again
+		flow := cookie(resp, oidcFlowCookie)
+		require.NotNil(t, flow)
+		assert.Equal(t, -1, flow.MaxAge)
+	})
+
+	t.Run("Asks for a code with two-factor authentication", func(t *testing.T) {
+		f := newOIDCHandlerFixture(t)
+		user := &model.User{ID: "user-1", Email: "user@example.com", TOTPEnabled: true}
+		f.identities.On("FindByProviderSubject", mock.Anything, "test", "subject-1").
+			Return(&model.ExternalIdentity{ID: "identity-1", UserID: "user-1"}, nil)
+		f.identities.On("RecordUse", mock.Anything, "identity-1", mock.Anything).Return(nil)
+		f.users.On("FindByID", mock.Anything, "user-1").Return(user, nil)
+		f.actionTokens.On("DeleteForUser", mock.Anything, "user-1", model.ActionTokenTwoFactorChallenge).Return(nil)
+		f.actionTokens.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(1).(*model.ActionToken).ID = "challenge-1"
+		}).Return(nil)
+
+		fragment, resp := f.signIn(t)
+		assert.Contains(t, fragment.Get("challengeToken"), "challenge-1.")
+		assert.Empty(t, fragment.Get("token"))
+		assert.Nil(t, cookie(resp, "refresh_token"))
+		f.refreshTokens.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Returns the reason an account is not linked", func(t *testing.T) {
+		f := newOIDCHandlerFixture(t)
+		f.identities.On("FindByProviderSubject", mock.Anything, "test", "subject-1").Return(nil, nil)
+		f.users.On("FindByEmail", mock.Anything, "user@example.com").
+			Return(&model.User{ID: "user-1", Email: "user@example.com"}, nil)
+
+		fragment, _ := f.signIn(t)
+		assert.Equal(t, service.ErrOIDCAccountNotLinked.Error(), fragment.Get("error"))
+		assert.Empty(t, fragment.Get("token"))
+	})
+
+	t.Run("Rejects a callback without the sign-in's cookie", func(t *testing.T) {
+		f := newOIDCHandlerFixture(t)
+
+		resp, err := f.client.Get(f.server.URL + "/auth/oidc/test/callback?state=state&code=code")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, service.ErrOIDCSignInFailed.Error(), appFragment(t, resp).Get("error"))
+	})
+
+	t.Run("Rejects a callback for another sign-in", func(t *testing.T) {
+		f := newOIDCHandlerFixture(t)
+		// Start signing in, but stop before returning from the provider
+		f.client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+		resp, err := f.client.Get(f.server.URL + "/auth/oidc/test")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		require.NotNil(t, cookie(resp, oidcFlowCookie))
+
+		resp, err = f.client.Get(f.server.URL + "/auth/oidc/test/callback?state=forged&code=code")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, service.ErrOIDCSignInFailed.Error(), appFragment(t, resp).Get("error"))
+		f.identities.AssertNotCalled(t, "FindByProviderSubject", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects a tampered sign-in cookie", func(t *testing.T) {
+		h := &OIDCHandler{JWTSecret: "test-secret"}
+		value, err := h.encodeFlow(&service.OIDCFlow{Provider: "test", State: "state"})
+		require.NoError(t, err)
+		_, ok := h.decodeFlow(value)
+		assert.True(t, ok)
+
+		forged, err := (&OIDCHandler{JWTSecret: "another-secret"}).encodeFlow(&service.OIDCFlow{Provider: "test", State: "forged"})
+		require.NoError(t, err)
+		_, ok = h.decodeFlow(forged)
+		assert.False(t, ok)
+	})
+
+	t.Run("Returns not found for an unknown provider", func(t *testing.T) {
+		f := newOIDCHandlerFixture(t)
+
+		resp, err := f.client.Get(f.server.URL + "/auth/oidc/other")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 )
@@ -15,6 +18,81 @@ type Config struct {
 	FaroURL          string `env:"FARO_URL" envDefault:"http://alloy:12347/collect"`
 	PyroscopeURL     string `env:"PYROSCOPE_URL"`
 	PyroscopeAppName string `env:"PYROSCOPE_APP_NAME" envDefault:"fitness-app-backend"`
+	// How often to check whether the system exercise catalogue changed
+	SystemExerciseRefresh time.Duration `env:"SYSTEM_EXERCISE_REFRESH" envDefault:"1m"`
+	// How long startup may spend migrating the database, including waiting for another node's migrations
+	MigrationTimeout time.Duration `env:"MIGRATION_TIMEOUT" envDefault:"5m"`
+	// Number of reverse proxies in front of the server whose X-Forwarded-For entries are trusted
+	// for the client address used to rate limit logins
+	TrustedProxies int `env:"TRUSTED_PROXIES" envDefault:"0"`
+	// Minimum length of new passwords, in characters
+	PasswordMinLength int `env:"PASSWORD_MIN_LENGTH" envDefault:"8"`
+	// Optional list of SHA-1 hashes of breached passwords that new passwords are checked against
+	BreachedPasswordsFile string `env:"BREACHED_PASSWORDS_FILE"`
+	// Public address of the frontend, used in links sent by email
+	AppURL string `env:"APP_URL" envDefault:"http://localhost:5173"`
+	// Email is sent through SMTP_HOST if set, as it must be in production; otherwise its
+	// recipient and subject are logged and, if MAIL_DIR is set, it is written there as .eml files
+	SMTPHost     string `env:"SMTP_HOST"`
+	SMTPPort     int    `env:"SMTP_PORT" envDefault:"587"`
+	SMTPUsername string `env:"SMTP_USERNAME"`
+	SMTPPassword string `env:"SMTP_PASSWORD"`
+	MailFrom     string `env:"MAIL_FROM" envDefault:"Fitness App <no-reply@localhost>"`
+	MailDir      string `env:"MAIL_DIR"`
+	// How long a deleted account can still be restored before its data is removed
+	AccountDeletionGracePeriod time.Duration `env:"ACCOUNT_DELETION_GRACE_PERIOD" envDefault:"720h"`
+	// How often to remove the data of accounts whose grace period has ended
+	AccountDeletionInterval time.Duration `env:"ACCOUNT_DELETION_INTERVAL" envDefault:"1h"`
+	// Name authenticator apps show for two-factor codes
+	TOTPIssuer string `env:"TOTP_ISSUER" envDefault:"Fitness App"`
+	// Passkeys are registered for WEBAUTHN_RP_ID, the domain of the site, and accepted from
+	// WEBAUTHN_ORIGINS. Both default to APP_URL.
+	WebAuthnRPID    string   `env:"WEBAUTHN_RP_ID"`
+	WebAuthnRPName  string   `env:"WEBAUTHN_RP_NAME" envDefault:"Fitness App"`
+	WebAuthnOrigins []string `env:"WEBAUTHN_ORIGINS" envSeparator:","`
+	// OpenID Connect providers users can sign in with, as a JSON array, e.g.
+	// [{"id":"google","name":"Google","issuer":"https://accounts.google.com","clientId":"...","clientSecret":"..."}].
+	// Each is registered with the callback APP_URL/auth/oidc/{id}/callback.
+	OIDCProviders OIDCProviders `env:"OIDC_PROVIDERS"`
+}
+
+// OIDCProvider is an OpenID Connect provider the app is registered with as a client.
+type OIDCProvider struct {
+	// ID names the provider in its URLs, e.g. "google"
+	ID string `json:"id"`
+	// Name is shown on the sign-in button
+	Name         string `json:"name"`
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+	// Scopes are requested besides "openid"; they default to "email" and "profile"
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// OIDCProviders is the OIDC_PROVIDERS list.
+type OIDCProviders []OIDCProvider
+
+// UnmarshalText parses the providers from JSON.
+func (p *OIDCProviders) UnmarshalText(text []byte) error {
+	var providers []OIDCProvider
+	if err := json.Unmarshal(text, &providers); err != nil {
+		return fmt.Errorf("invalid OIDC providers: %w", err)
+	}
+	seen := map[string]bool{}
+	for _, provider := range providers {
+		if provider.ID == "" || provider.Issuer == "" || provider.ClientID == "" {
+			return fmt.Errorf("invalid OIDC providers: id, issuer and clientId are required")
+		}
+		if url.PathEscape(provider.ID) != provider.ID {
+			return fmt.Errorf("invalid OIDC providers: id %q must be usable in a URL path", provider.ID)
+		}
+		if seen[provider.ID] {
+			return fmt.Errorf("invalid OIDC providers: id %q is used twice", provider.ID)
+		}
+		seen[provider.ID] = true
+	}
+	*p = providers
+	return nil
 }
 
 func Load() (*Config, error) {
@@ -22,5 +100,9 @@ func Load() (*Config, error) {
 	if err := env.Parse(cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse configuration: %w", err)
 	}
+	// Without SMTP, email is only kept locally, so users would never get their links
+	if cfg.AppEnv == "production" && !cfg.CI && cfg.SMTPHost == "" {
+		return nil, fmt.Errorf("SMTP_HOST is required in production")
+	}
 	return cfg, nil
 }
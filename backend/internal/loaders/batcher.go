@@ -0,0 +1,107 @@
+package loaders
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// fetchFunc loads the values for a batch of keys. It must return one value per key, in key order.
+type fetchFunc[K comparable, V any] func(ctx context.Context, keys []K) ([]V, error)
+
+// batcher collects the keys requested within a short window and loads them with a single fetch.
+// Results are cached for the batcher's lifetime, so it must be scoped to one request.
+type batcher[K comparable, V any] struct {
+	fetch   fetchFunc[K, V]
+	wait    time.Duration
+	maxSize int
+
+	mu      sync.Mutex
+	pending *batch[K, V]
+	cache   map[K]*result[V]
+}
+
+type batch[K comparable, V any] struct {
+	keys    []K
+	results []*result[V]
+}
+
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+func newBatcher[K comparable, V any](fetch fetchFunc[K, V], wait time.Duration, maxSize int) *batcher[K, V] {
+	return &batcher[K, V]{
+		fetch:   fetch,
+		wait:    wait,
+		maxSize: maxSize,
+		cache:   make(map[K]*result[V]),
+	}
+}
+
+// Load returns the value for key, waiting for the batch it joins to be fetched.
+func (b *batcher[K, V]) Load(ctx context.Context, key K) (V, error) {
+	b.mu.Lock()
+	res, ok := b.cache[key]
+	if !ok {
+		res = &result[V]{done: make(chan struct{})}
+		b.cache[key] = res
+		b.enqueue(ctx, key, res)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-res.done:
+		return res.value, res.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// enqueue adds the key to the pending batch, starting one if needed. b.mu must be held.
+func (b *batcher[K, V]) enqueue(ctx context.Context, key K, res *result[V]) {
+	if b.pending == nil {
+		pending := &batch[K, V]{}
+		b.pending = pending
+		time.AfterFunc(b.wait, func() {
+			b.mu.Lock()
+			if b.pending != pending {
+				// Already dispatched because it filled up
+				b.mu.Unlock()
+				return
+			}
+			b.pending = nil
+			b.mu.Unlock()
+			b.run(ctx, pending)
+		})
+	}
+
+	b.pending.keys = append(b.pending.keys, key)
+	b.pending.results = append(b.pending.results, res)
+	if len(b.pending.keys) >= b.maxSize {
+		full := b.pending
+		b.pending = nil
+		go b.run(ctx, full)
+	}
+}
+
+func (b *batcher[K, V]) run(ctx context.Context, bt *batch[K, V]) {
+	// The batch outlives whichever resolver happened to start it
+	values, err := b.fetch(context.WithoutCancel(ctx), bt.keys)
+	if err == nil && len(values) != len(bt.keys) {
+		err = fmt.Errorf("batch loaded %d values for %d keys", len(values), len(bt.keys))
+	}
+
+	for i, res := range bt.results {
+		if err != nil {
+			res.err = err
+		} else {
+			res.value = values[i]
+		}
+		close(res.done)
+	}
+}
@@ -0,0 +1,69 @@
+// Package loaders batches the per-object lookups made while resolving a single GraphQL request,
+// so that resolving a field on every item of a list costs one query instead of one per item.
+package loaders
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/service"
+)
+
+type contextKey string
+
+const loadersKey contextKey = "loaders"
+
+const (
+	// batchWait is how long a batch stays open for more keys. Sibling fields are resolved
+	// concurrently, so their lookups arrive well within it.
+	batchWait    = 2 * time.Millisecond
+	maxBatchSize = 100
+)
+
+// Loaders holds the request-scoped batchers.
+type Loaders struct {
+	exercises        *batcher[string, *model.UniqueExercise]
+	previousSessions *batcher[model.PreviousSessionKey, *model.ExerciseSession]
+	bodyweights      *batcher[model.BodyweightKey, *float64]
+}
+
+// New creates a fresh set of loaders. Each request needs its own, as results are cached.
+func New(workoutService *service.WorkoutService, exerciseService *service.ExerciseService, bodyMetricService *service.BodyMetricService) *Loaders {
+	return &Loaders{
+		exercises:        newBatcher(exerciseService.GetExercises, batchWait, maxBatchSize),
+		previousSessions: newBatcher(workoutService.PreviousSessions, batchWait, maxBatchSize),
+		bodyweights:      newBatcher(bodyMetricService.BodyweightsAt, batchWait, maxBatchSize),
+	}
+}
+
+// Middleware attaches a fresh set of loaders to each request's context.
+func Middleware(next http.Handler, workoutService *service.WorkoutService, exerciseService *service.ExerciseService, bodyMetricService *service.BodyMetricService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), loadersKey, New(workoutService, exerciseService, bodyMetricService))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// For returns the loaders attached to the context by Middleware, or nil.
+func For(ctx context.Context) *Loaders {
+	l, _ := ctx.Value(loadersKey).(*Loaders)
+	return l
+}
+
+// Exercise returns the exercise with the given ID, or nil if it does not exist.
+func (l *Loaders) Exercise(ctx context.Context, id string) (*model.UniqueExercise, error) {
+	return l.exercises.Load(ctx, id)
+}
+
+// PreviousSession returns the user's latest session of the exercise before the key's workout, or nil.
+func (l *Loaders) PreviousSession(ctx context.Context, key model.PreviousSessionKey) (*model.ExerciseSession, error) {
+	return l.previousSessions.Load(ctx, key)
+}
+
+// Bodyweight returns the user's bodyweight in kilograms as of the given time, or nil if none has
+// been recorded yet.
+func (l *Loaders) Bodyweight(ctx context.Context, userID string, at time.Time) (*float64, error) {
+	return l.bodyweights.Load(ctx, model.BodyweightKey{UserID: userID, At: at})
+}
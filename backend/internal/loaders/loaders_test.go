@@ -0,0 +1,229 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+	"github.com/riverajo/fitness-app/backend/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatcherBatchesConcurrentLoads(t *testing.T) {
+	var calls atomic.Int32
+	var batches [][]int
+	var mu sync.Mutex
+	b := newBatcher(func(ctx context.Context, keys []int) ([]string, error) {
+		calls.Add(1)
+		mu.Lock()
+		batches = append(batches, keys)
+		mu.Unlock()
+		values := make([]string, len(keys))
+		for i, k := range keys {
+			values[i] = string(rune('a' + k))
+		}
+		return values, nil
+	}, 5*time.Millisecond, 100)
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	results := make([]string, 10)
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Every key is requested twice
+			v, err := b.Load(ctx, i%5)
+			assert.NoError(t, err)
+			results[i] = v
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Len(t, batches[0], 5)
+	assert.Equal(t, []string{"a", "b", "c", "d", "e", "a", "b", "c", "d", "e"}, results)
+
+	// Later loads of the same key are served from the cache
+	v, err := b.Load(ctx, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "d", v)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestBatcherSplitsFullBatches(t *testing.T) {
+	var calls atomic.Int32
+	b := newBatcher(func(ctx context.Context, keys []int) ([]int, error) {
+		calls.Add(1)
+		assert.LessOrEqual(t, len(keys), 2)
+		return keys, nil
+	}, 5*time.Millisecond, 2)
+
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := b.Load(context.Background(), i)
+			assert.NoError(t, err)
+			assert.Equal(t, i, v)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestBatcherErrors(t *testing.T) {
+	ctx := context.Background()
+
+	failing := newBatcher(func(ctx context.Context, keys []int) ([]int, error) {
+		return nil, errors.New("db error")
+	}, time.Millisecond, 100)
+	_, err := failing.Load(ctx, 1)
+	assert.EqualError(t, err, "db error")
+
+	short := newBatcher(func(ctx context.Context, keys []int) ([]int, error) {
+		return []int{}, nil
+	}, time.Millisecond, 100)
+	_, err = short.Load(ctx, 1)
+	assert.EqualError(t, err, "batch loaded 0 values for 1 keys")
+}
+
+func TestPreviousSessionBatching(t *testing.T) {
+	workoutRepo := new(repository.MockWorkoutRepository)
+	l := New(service.NewWorkoutService(workoutRepo), nil, nil)
+	before := model.SessionCursor{StartTime: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), WorkoutID: "w2"}
+	bench := model.PreviousSessionKey{UserID: "user-1", ExerciseID: "bench", Before: before}
+	squat := model.PreviousSessionKey{UserID: "user-1", ExerciseID: "squat", Before: before}
+
+	previous := &model.ExerciseSession{WorkoutID: "w1"}
+	workoutRepo.On("FindPreviousSessions", mock.Anything, mock.MatchedBy(func(keys []model.PreviousSessionKey) bool {
+		return assert.ElementsMatch(t, []model.PreviousSessionKey{bench, squat}, keys)
+	})).Return([]*model.ExerciseSession{previous, previous}, nil).Once()
+
+	var wg sync.WaitGroup
+	var benchPrev, squatPrev *model.ExerciseSession
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		var err error
+		benchPrev, err = l.PreviousSession(context.Background(), bench)
+		assert.NoError(t, err)
+	}()
+	go func() {
+		defer wg.Done()
+		var err error
+		squatPrev, err = l.PreviousSession(context.Background(), squat)
+		assert.NoError(t, err)
+	}()
+	wg.Wait()
+
+	assert.Same(t, previous, benchPrev)
+	assert.Same(t, previous, squatPrev)
+	workoutRepo.AssertExpectations(t)
+}
+
+func TestExerciseBatching(t *testing.T) {
+	exerciseRepo := new(repository.MockExerciseRepository)
+	l := New(nil, newExerciseService(exerciseRepo), nil)
+
+	exerciseRepo.On("FindByIDs", mock.Anything, mock.MatchedBy(func(ids []string) bool {
+		return assert.ElementsMatch(t, []string{"bench", "squat", "missing"}, ids)
+	})).Return([]*model.UniqueExercise{{ID: "squat", Name: "Squat"}, {ID: "bench", Name: "Bench Press"}}, nil).Once()
+
+	ids := []string{"bench", "squat", "bench", "missing"}
+	found := make([]*model.UniqueExercise, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			found[i], err = l.Exercise(context.Background(), id)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, "Bench Press", found[0].Name)
+	assert.Equal(t, "Squat", found[1].Name)
+	assert.Same(t, found[0], found[2])
+	assert.Nil(t, found[3])
+	exerciseRepo.AssertExpectations(t)
+}
+
+func TestBodyweightBatching(t *testing.T) {
+	bodyMetricRepo := new(repository.MockBodyMetricRepository)
+	l := New(nil, nil, service.NewBodyMetricService(bodyMetricRepo))
+	first := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	second := first.AddDate(0, 0, 2)
+
+	weight := 80.5
+	call := bodyMetricRepo.On("FindLatestWeightsBefore", mock.Anything, mock.MatchedBy(func(keys []model.BodyweightKey) bool {
+		return assert.ElementsMatch(t, []model.BodyweightKey{{UserID: "user-1", At: first}, {UserID: "user-1", At: second}}, keys)
+	})).Once()
+	call.Run(func(args mock.Arguments) {
+		// No weigh-in before the first workout
+		keys := args.Get(1).([]model.BodyweightKey)
+		metrics := make([]*model.BodyMetric, len(keys))
+		for i, key := range keys {
+			if key.At.Equal(second) {
+				metrics[i] = &model.BodyMetric{Weight: &weight}
+			}
+		}
+		call.ReturnArguments = mock.Arguments{metrics, nil}
+	})
+
+	var wg sync.WaitGroup
+	var firstWeight, secondWeight *float64
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		var err error
+		firstWeight, err = l.Bodyweight(context.Background(), "user-1", first)
+		assert.NoError(t, err)
+	}()
+	go func() {
+		defer wg.Done()
+		var err error
+		secondWeight, err = l.Bodyweight(context.Background(), "user-1", second)
+		assert.NoError(t, err)
+	}()
+	wg.Wait()
+
+	assert.Nil(t, firstWeight)
+	require.NotNil(t, secondWeight)
+	assert.Equal(t, 80.5, *secondWeight)
+	bodyMetricRepo.AssertExpectations(t)
+}
+
+func newExerciseService(repo repository.ExerciseRepository) *service.ExerciseService {
+	return service.NewExerciseService(repo, nil, nil, &repository.MockTransactor{})
+}
+
+func TestMiddleware(t *testing.T) {
+	var seen []*Loaders
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, For(r.Context()))
+	}), service.NewWorkoutService(new(repository.MockWorkoutRepository)), newExerciseService(new(repository.MockExerciseRepository)),
+		service.NewBodyMetricService(new(repository.MockBodyMetricRepository)))
+
+	for range 2 {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/query", nil))
+	}
+
+	require.Len(t, seen, 2)
+	assert.NotNil(t, seen[0])
+	assert.NotSame(t, seen[0], seen[1], "each request gets its own loaders")
+	assert.Nil(t, For(context.Background()))
+}
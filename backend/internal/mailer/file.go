@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileMailer keeps messages instead of sending them, for development and tests. Each message
+// is written to Dir as an .eml file if Dir is set, logged, and kept in memory for Sent. Only the
+// recipient and subject are logged: bodies hold single-use links that sign in to the account.
+type FileMailer struct {
+	Dir  string
+	From string
+
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := validate(msg); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if m.Dir != "" {
+		if err := os.MkdirAll(m.Dir, 0o750); err != nil {
+			return fmt.Errorf("failed to create mail directory: %w", err)
+		}
+		name := fmt.Sprintf("%s-%d.eml", now.UTC().Format("20060102T150405"), now.UnixNano())
+		if err := os.WriteFile(filepath.Join(m.Dir, name), format(m.From, msg, now), 0o600); err != nil {
+			return fmt.Errorf("failed to write message: %w", err)
+		}
+	}
+	slog.Info("Email not sent (file mailer)", "to", msg.To, "subject", msg.Subject)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Sent returns the messages sent so far, oldest first.
+func (m *FileMailer) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.sent...)
+}
@@ -0,0 +1,128 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := &FileMailer{Dir: dir, From: "Fitness App <no-reply@example.com>"}
+
+	msg := Message{To: "jane@example.com", Subject: "Hello", Body: "Line one\nLine two\n"}
+	require.NoError(t, m.Send(context.Background(), msg))
+	assert.Equal(t, []Message{msg}, m.Sent())
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "To: jane@example.com\r\n")
+	assert.Contains(t, string(content), "Subject: Hello\r\n")
+	assert.True(t, strings.HasSuffix(string(content), "\r\n\r\nLine one\r\nLine two\r\n"))
+}
+
+func TestFileMailerDoesNotLogBody(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	m := &FileMailer{}
+	msg := Message{To: "jane@example.com", Subject: "Reset your password", Body: "https://fitness.example.com/reset-password?token=secret-token"}
+	require.NoError(t, m.Send(context.Background(), msg))
+	assert.Contains(t, logs.String(), "jane@example.com")
+	assert.NotContains(t, logs.String(), "secret-token")
+}
+
+func TestMailersRejectHeaderInjection(t *testing.T) {
+	for _, m := range []Mailer{&FileMailer{}, &SMTPMailer{Host: "localhost", Port: 1}} {
+		err := m.Send(context.Background(), Message{To: "jane@example.com\r\nBcc: everyone@example.com", Subject: "Hi"})
+		assert.EqualError(t, err, "message headers must not contain line breaks")
+		err = m.Send(context.Background(), Message{Subject: "Hi"})
+		assert.EqualError(t, err, "message has no recipient")
+	}
+}
+
+// fakeSMTPServer accepts one session and records the commands and message it receives.
+func fakeSMTPServer(t *testing.T) (addr string, received <-chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	lines := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		var got []string
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				lines <- got
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			got = append(got, line)
+			switch {
+			case inData && line == ".":
+				inData = false
+				reply("250 queued")
+			case inData:
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 localhost")
+			case strings.HasPrefix(line, "DATA"):
+				inData = true
+				reply("354 go ahead")
+			case strings.HasPrefix(line, "QUIT"):
+				reply("221 bye")
+				lines <- got
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return ln.Addr().String(), lines
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+	portNumber, err := net.LookupPort("tcp", port)
+	require.NoError(t, err)
+
+	m := &SMTPMailer{Host: host, Port: portNumber, From: "Fitness App <no-reply@example.com>"}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, m.Send(ctx, Message{To: "jane@example.com", Subject: "Hello", Body: "Hi Jane\n"}))
+
+	got := <-received
+	assert.Contains(t, got, "MAIL FROM:<no-reply@example.com>")
+	assert.Contains(t, got, "RCPT TO:<jane@example.com>")
+	assert.Contains(t, got, "From: Fitness App <no-reply@example.com>")
+	assert.Contains(t, got, "Subject: Hello")
+	assert.Contains(t, got, "Hi Jane")
+}
@@ -16,6 +16,7 @@ type ContextKey string
 
 const (
 	UserIDKey         ContextKey = "user_id"
+	SessionIDKey      ContextKey = "session_id"
 	AuthCookieName    string     = "auth_token"
 	ResponseWriterKey ContextKey = "ResponseWriterKey"
 	RequestKey        ContextKey = "RequestKey"
@@ -64,8 +65,11 @@ func AuthMiddleware(next http.Handler, jwtSecret string) http.Handler {
 			return
 		}
 
-		// 5. Token is valid: Inject UserID into the context
+		// 5. Token is valid: Inject UserID (and the session it was issued to, if any) into the context
 		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+		if claims.SessionID != "" {
+			ctx = context.WithValue(ctx, SessionIDKey, claims.SessionID)
+		}
 
 		r = r.WithContext(ctx)
 		next.ServeHTTP(w, r)
@@ -104,11 +108,18 @@ func GetResponseWriter(ctx context.Context) http.ResponseWriter {
 // Define the JWT claims structure
 type Claims struct {
 	UserID string `json:"user_id"`
+	// SessionID is the refresh token family the access token was issued with
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT creates a new signed JWT for the user.
+// GenerateJWT creates a new signed JWT for the user, not tied to a session.
 func GenerateJWT(user *model.User, jwtSecret string) (string, error) {
+	return GenerateSessionJWT(user, "", jwtSecret)
+}
+
+// GenerateSessionJWT creates a new signed JWT for the user's session.
+func GenerateSessionJWT(user *model.User, sessionID string, jwtSecret string) (string, error) {
 	if jwtSecret == "" {
 		return "", fmt.Errorf("jwtSecret is required")
 	}
@@ -118,7 +129,8 @@ func GenerateJWT(user *model.User, jwtSecret string) (string, error) {
 
 	// Create the Claims
 	claims := &Claims{
-		UserID: user.ID, // Use the MongoDB ObjectID as the user ID in the token
+		UserID:    user.ID, // Use the MongoDB ObjectID as the user ID in the token
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -135,3 +147,9 @@ func GenerateJWT(user *model.User, jwtSecret string) (string, error) {
 
 	return tokenString, nil
 }
+
+// GetSessionID returns the session of the request's access token, or "" if it has none.
+func GetSessionID(ctx context.Context) string {
+	sessionID, _ := ctx.Value(SessionIDKey).(string)
+	return sessionID
+}
@@ -57,6 +57,7 @@ func TestAuthMiddleware(t *testing.T) {
 		if userID != nil {
 			w.Header().Set("X-User-ID", userID.(string))
 		}
+		w.Header().Set("X-Session-ID", GetSessionID(r.Context()))
 	})
 
 	handler := AuthMiddleware(nextHandler, jwtSecret)
@@ -97,6 +98,24 @@ func TestAuthMiddleware(t *testing.T) {
 		if w.Header().Get("X-User-ID") != "user123" {
 			t.Errorf("Expected user ID 'user123', got '%s'", w.Header().Get("X-User-ID"))
 		}
+		if w.Header().Get("X-Session-ID") != "" {
+			t.Errorf("Expected no session ID, got '%s'", w.Header().Get("X-Session-ID"))
+		}
+	})
+
+	t.Run("Valid Session Token", func(t *testing.T) {
+		user := &model.User{ID: "user123"}
+		token, _ := GenerateSessionJWT(user, "session456", jwtSecret)
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		if w.Header().Get("X-Session-ID") != "session456" {
+			t.Errorf("Expected session ID 'session456', got '%s'", w.Header().Get("X-Session-ID"))
+		}
 	})
 }
 
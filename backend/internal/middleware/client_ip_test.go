@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		forwardedFor   []string
+		trustedProxies int
+		want           string
+	}{
+		{name: "Remote address", remoteAddr: "203.0.113.7:5123", want: "203.0.113.7"},
+		{name: "IPv6 remote address", remoteAddr: "[2001:db8::1]:443", want: "2001:db8::1"},
+		{name: "Ignores X-Forwarded-For without trusted proxies", remoteAddr: "10.0.0.2:80", forwardedFor: []string{"198.51.100.1"}, want: "10.0.0.2"},
+		{name: "One trusted proxy", remoteAddr: "10.0.0.2:80", forwardedFor: []string{"198.51.100.1, 203.0.113.7"}, trustedProxies: 1, want: "203.0.113.7"},
+		{name: "Two trusted proxies across headers", remoteAddr: "10.0.0.2:80", forwardedFor: []string{"198.51.100.1, 203.0.113.7", "10.0.0.1"}, trustedProxies: 2, want: "203.0.113.7"},
+		{name: "Fewer entries than proxies", remoteAddr: "10.0.0.2:80", forwardedFor: []string{"203.0.113.7"}, trustedProxies: 2, want: "203.0.113.7"},
+		{name: "Trusted proxy without header", remoteAddr: "10.0.0.2:80", trustedProxies: 1, want: "10.0.0.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/query", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			assert.Equal(t, tt.want, ClientIP(r, tt.trustedProxies))
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+const LocalesKey ContextKey = "LocalesKey"
+
+// RequestLocales holds the languages a request prefers. The user's profile setting takes
+// precedence over Accept-Language, but looking it up needs the database, so it is only
+// resolved when a response actually contains localized text.
+type RequestLocales struct {
+	AcceptLanguage []string
+
+	once     sync.Once
+	resolved []string
+}
+
+// Resolve returns the preferred locales, most preferred first: the profile locale (if
+// profileLocale returns one), then Accept-Language. profileLocale is called at most once.
+func (l *RequestLocales) Resolve(profileLocale func() *string) []string {
+	l.once.Do(func() {
+		l.resolved = l.AcceptLanguage
+		if locale := profileLocale(); locale != nil {
+			l.resolved = append([]string{*locale}, l.AcceptLanguage...)
+		}
+	})
+	return l.resolved
+}
+
+// LocaleMiddleware attaches the request's Accept-Language preferences to the context.
+func LocaleMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locales := &RequestLocales{AcceptLanguage: model.ParseAcceptLanguage(r.Header.Get("Accept-Language"))}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), LocalesKey, locales)))
+	})
+}
+
+// GetRequestLocales returns the locales attached by LocaleMiddleware. Outside a request
+// (e.g. in tests) it returns an empty set, so only the profile setting applies.
+func GetRequestLocales(ctx context.Context) *RequestLocales {
+	if locales, ok := ctx.Value(LocalesKey).(*RequestLocales); ok {
+		return locales
+	}
+	return &RequestLocales{}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+func TestLocaleMiddleware(t *testing.T) {
+	var locales *RequestLocales
+	handler := LocaleMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locales = GetRequestLocales(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/query", nil)
+	req.Header.Set("Accept-Language", "de;q=0.5, es-mx, en;q=0.8, *;q=0.1, fr;q=0")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, []string{"es-MX", "en", "de"}, locales.AcceptLanguage)
+
+	// The profile setting goes first, and is only looked up once per request
+	calls := 0
+	profile := func() *string {
+		calls++
+		locale := "pt-BR"
+		return &locale
+	}
+	assert.Equal(t, []string{"pt-BR", "es-MX", "en", "de"}, locales.Resolve(profile))
+	assert.Equal(t, []string{"pt-BR", "es-MX", "en", "de"}, locales.Resolve(profile))
+	assert.Equal(t, 1, calls)
+}
+
+func TestGetRequestLocalesOutsideRequest(t *testing.T) {
+	locales := GetRequestLocales(context.Background())
+	assert.Empty(t, locales.Resolve(func() *string { return nil }))
+}
+
+func TestNormalizeLocale(t *testing.T) {
+	for input, expected := range map[string]string{
+		"es":         "es",
+		"pt_br":      "pt-BR",
+		"ZH-hant-tw": "zh-Hant-TW",
+		" de-DE ":    "de-DE",
+	} {
+		locale, ok := model.NormalizeLocale(input)
+		assert.True(t, ok, input)
+		assert.Equal(t, expected, locale)
+	}
+	for _, input := range []string{"", "*", "e", "english!", "es--MX"} {
+		_, ok := model.NormalizeLocale(input)
+		assert.False(t, ok, input)
+	}
+}
@@ -0,0 +1,312 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	workoutsCollection      = "workout_logs"
+	refreshTokensCollection = "refreshtokens"
+	usersCollection         = "users"
+	exercisesCollection     = "unique_exercises"
+)
+
+// All lists every migration in order. Append new migrations to the end; never renumber,
+// edit or remove one that has shipped.
+var All = []Migration{
+	{
+		Version: 1,
+		Name:    "add_workout_log_version_and_deleted_at",
+		Up:      addWorkoutLogVersionAndDeletedAt,
+		Pending: countWorkoutLogsMissingVersionOrDeletedAt,
+	},
+	{
+		Version: 2,
+		Name:    "add_refresh_token_family",
+		Up:      addRefreshTokenFamily,
+		Pending: countRefreshTokensMissingFamily,
+	},
+	{
+		Version: 3,
+		Name:    "normalize_user_emails",
+		Up:      normalizeUserEmails,
+		Pending: countUsersWithUnnormalizedEmail,
+	},
+	{
+		Version: 4,
+		Name:    "normalize_exercise_names",
+		Up:      normalizeExerciseNames,
+		Pending: countExercisesToNormalize,
+	},
+}
+
+// workoutLogsMissingVersion matches workouts stored before they had a version; likewise for deletedAt.
+var (
+	workoutLogsMissingVersion   = bson.M{"version": bson.M{"$exists": false}}
+	workoutLogsMissingDeletedAt = bson.M{"deletedAt": bson.M{"$exists": false}}
+)
+
+// addWorkoutLogVersionAndDeletedAt gives existing workouts the fields new ones are created with:
+// version 1, and a null deletedAt (not deleted).
+func addWorkoutLogVersionAndDeletedAt(ctx context.Context, db *mongo.Database) error {
+	workouts := db.Collection(workoutsCollection)
+	if _, err := workouts.UpdateMany(ctx, workoutLogsMissingVersion, bson.M{"$set": bson.M{"version": 1}}); err != nil {
+		return fmt.Errorf("failed to backfill workout versions: %w", err)
+	}
+	if _, err := workouts.UpdateMany(ctx, workoutLogsMissingDeletedAt, bson.M{"$set": bson.M{"deletedAt": nil}}); err != nil {
+		return fmt.Errorf("failed to backfill workout deletedAt: %w", err)
+	}
+	return nil
+}
+
+func countWorkoutLogsMissingVersionOrDeletedAt(ctx context.Context, db *mongo.Database) (int64, error) {
+	return db.Collection(workoutsCollection).CountDocuments(ctx, bson.M{
+		"$or": bson.A{workoutLogsMissingVersion, workoutLogsMissingDeletedAt},
+	})
+}
+
+// refreshTokensMissingFamily matches tokens issued before rotation kept tokens in families.
+var refreshTokensMissingFamily = bson.M{"familyId": bson.M{"$exists": false}}
+
+// addRefreshTokenFamily starts a family for each existing token, named after its ID as new
+// families are.
+func addRefreshTokenFamily(ctx context.Context, db *mongo.Database) error {
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"familyId": "$_id"}}}}
+	if _, err := db.Collection(refreshTokensCollection).UpdateMany(ctx, refreshTokensMissingFamily, update); err != nil {
+		return fmt.Errorf("failed to backfill refresh token families: %w", err)
+	}
+	return nil
+}
+
+func countRefreshTokensMissingFamily(ctx context.Context, db *mongo.Database) (int64, error) {
+	return db.Collection(refreshTokensCollection).CountDocuments(ctx, refreshTokensMissingFamily)
+}
+
+// normalizedEmail is the stored form of an email field, as model.NormalizeEmail computes it.
+func normalizedEmail(field string) bson.M {
+	return bson.M{"$toLower": bson.M{"$trim": bson.M{"input": field}}}
+}
+
+// usersWithUnnormalizedEmail matches users whose email or pending email is not in its normalized
+// form, as saved before emails were compared case-insensitively.
+var usersWithUnnormalizedEmail = bson.M{"$or": bson.A{
+	bson.M{"$expr": bson.M{"$ne": bson.A{"$email", normalizedEmail("$email")}}},
+	bson.M{"pendingEmail": bson.M{"$type": "string"}, "$expr": bson.M{"$ne": bson.A{"$pendingEmail", normalizedEmail("$pendingEmail")}}},
+}}
+
+// normalizeUserEmails stores every user's email and pending email trimmed and lowercase. It fails,
+// before changing anything, if two accounts have the same email or emails that differ only in
+// case; those must be merged or renamed by hand first, as the unique index on email is built
+// once migrations have run.
+func normalizeUserEmails(ctx context.Context, db *mongo.Database) error {
+	if err := checkDuplicateUserEmails(ctx, db); err != nil {
+		return err
+	}
+
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"email": normalizedEmail("$email"),
+		"pendingEmail": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$type": "$pendingEmail"}, "string"}},
+			normalizedEmail("$pendingEmail"),
+			"$pendingEmail",
+		}},
+	}}}}
+	_, err := db.Collection(usersCollection).UpdateMany(ctx, usersWithUnnormalizedEmail, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to normalize user emails: some accounts' emails differ only in case: %w", err)
+	} else if err != nil {
+		return fmt.Errorf("failed to normalize user emails: %w", err)
+	}
+	return nil
+}
+
+// countUsersWithUnnormalizedEmail also fails on duplicate emails, so dry runs report them.
+func countUsersWithUnnormalizedEmail(ctx context.Context, db *mongo.Database) (int64, error) {
+	if err := checkDuplicateUserEmails(ctx, db); err != nil {
+		return 0, err
+	}
+	return db.Collection(usersCollection).CountDocuments(ctx, usersWithUnnormalizedEmail)
+}
+
+// checkDuplicateUserEmails fails, listing them, if several accounts share an email ignoring case.
+func checkDuplicateUserEmails(ctx context.Context, db *mongo.Database) error {
+	duplicates, err := findDuplicateUserEmails(ctx, db)
+	if err != nil {
+		return err
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("several accounts share each of these emails, ignoring case; merge or rename them first: %s",
+			strings.Join(duplicates, ", "))
+	}
+	return nil
+}
+
+// maxReportedDuplicateEmails limits how many duplicate emails an error lists.
+const maxReportedDuplicateEmails = 20
+
+// findDuplicateUserEmails returns the normalized emails that more than one account has.
+func findDuplicateUserEmails(ctx context.Context, db *mongo.Database) ([]string, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": normalizedEmail("$email"), "count": bson.M{"$sum": 1}}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+		{{Key: "$limit", Value: maxReportedDuplicateEmails}},
+	}
+	cursor, err := db.Collection(usersCollection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate user emails: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	var emails []string
+	for cursor.Next(ctx) {
+		var row struct {
+			Email string `bson:"_id"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, fmt.Errorf("failed to decode duplicate user email: %w", err)
+		}
+		emails = append(emails, row.Email)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return emails, nil
+}
+
+// exercisesMissingNormalizedNames matches exercises stored before their name, or aliases, were
+// saved in normalized form for lookups and searches.
+var exercisesMissingNormalizedNames = bson.M{"$or": bson.A{
+	bson.M{"normalizedName": bson.M{"$exists": false}},
+	bson.M{"aliases.0": bson.M{"$exists": true}, "normalizedAliases": bson.M{"$exists": false}},
+}}
+
+// normalizeExerciseName is the stored form of an exercise name, as model.NormalizeExerciseName
+// computes it.
+func normalizeExerciseName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// normalizeExerciseNames renames custom exercises whose names duplicate another of the user's
+// exercises once normalized, so the unique index on them can be built, then saves every
+// exercise's normalized name and aliases. The oldest exercise keeps its name; the others get a
+// numbered suffix, e.g. "Curl (2)".
+func normalizeExerciseNames(ctx context.Context, db *mongo.Database) error {
+	exercises := db.Collection(exercisesCollection)
+	duplicates, taken, err := findDuplicateExercises(ctx, exercises)
+	if err != nil {
+		return err
+	}
+	for _, dup := range duplicates {
+		var name string
+		for n := 2; ; n++ {
+			name = fmt.Sprintf("%s (%d)", strings.TrimSpace(dup.Name), n)
+			if !taken[dup.UserID][normalizeExerciseName(name)] {
+				break
+			}
+		}
+		taken[dup.UserID][normalizeExerciseName(name)] = true
+		_, err := exercises.UpdateOne(ctx, bson.M{"_id": dup.ID},
+			bson.M{"$set": bson.M{"name": name, "normalizedName": normalizeExerciseName(name)}})
+		if err != nil {
+			return fmt.Errorf("failed to rename duplicate exercise: %w", err)
+		}
+		slog.Info("Renamed duplicate exercise", "user_id", dup.UserID, "exercise_id", dup.ID, "name", name)
+	}
+
+	cursor, err := exercises.Find(ctx, exercisesMissingNormalizedNames,
+		options.Find().SetProjection(bson.M{"name": 1, "aliases": 1}))
+	if err != nil {
+		return fmt.Errorf("failed to find exercises to normalize: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID      any      `bson:"_id"`
+			Name    string   `bson:"name"`
+			Aliases []string `bson:"aliases"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return fmt.Errorf("failed to decode exercise: %w", err)
+		}
+		aliases := make([]string, len(doc.Aliases))
+		for i, alias := range doc.Aliases {
+			aliases[i] = normalizeExerciseName(alias)
+		}
+		_, err := exercises.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{
+			"normalizedName":    normalizeExerciseName(doc.Name),
+			"normalizedAliases": aliases,
+		}})
+		if err != nil {
+			return fmt.Errorf("failed to normalize exercise name: %w", err)
+		}
+	}
+	return cursor.Err()
+}
+
+func countExercisesToNormalize(ctx context.Context, db *mongo.Database) (int64, error) {
+	exercises := db.Collection(exercisesCollection)
+	missing, err := exercises.CountDocuments(ctx, exercisesMissingNormalizedNames)
+	if err != nil {
+		return 0, err
+	}
+	duplicates, _, err := findDuplicateExercises(ctx, exercises)
+	if err != nil {
+		return 0, err
+	}
+	return missing + int64(len(duplicates)), nil
+}
+
+// duplicateExercise is a custom exercise whose normalized name an older exercise of the user has.
+type duplicateExercise struct {
+	ID     any    `bson:"_id"`
+	UserID string `bson:"userId"`
+	Name   string `bson:"name"`
+}
+
+// findDuplicateExercises returns the custom exercises to rename, oldest first, and the normalized
+// names each user's exercises have, keyed by user ID.
+func findDuplicateExercises(ctx context.Context, exercises *mongo.Collection) ([]duplicateExercise, map[string]map[string]bool, error) {
+	cursor, err := exercises.Find(ctx, bson.M{"userId": bson.M{"$type": "string"}},
+		options.Find().SetProjection(bson.M{"userId": 1, "name": 1}).SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list custom exercises: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	var duplicates []duplicateExercise
+	taken := make(map[string]map[string]bool)
+	for cursor.Next(ctx) {
+		var doc duplicateExercise
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode exercise: %w", err)
+		}
+		if taken[doc.UserID] == nil {
+			taken[doc.UserID] = make(map[string]bool)
+		}
+		normalized := normalizeExerciseName(doc.Name)
+		if taken[doc.UserID][normalized] {
+			duplicates = append(duplicates, doc)
+			continue
+		}
+		taken[doc.UserID][normalized] = true
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return duplicates, taken, nil
+}
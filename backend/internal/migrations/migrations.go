@@ -0,0 +1,334 @@
+// Package migrations applies ordered schema changes to existing documents. Applied migrations
+// are recorded in system_metadata, and a lock in system_locks makes sure only one node runs them.
+package migrations
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const MetadataCollection = "system_metadata"
+const MetadataID = "schema_migrations"
+const LocksCollection = "system_locks"
+const LockID = "migrations_lock"
+
+const (
+	// lockTTL must match the TTL index on system_locks (see ensureLockIndex), so a crashed
+	// node's lock expires. The holder refreshes it well within the TTL while migrating.
+	lockTTL       = 60 * time.Second
+	lockHeartbeat = lockTTL / 3
+	// lockRetry is how often a node waiting for another node's migrations checks the lock again.
+	lockRetry = time.Second
+)
+
+// ErrLockLost is returned when the lock expired or was taken over while migrations were running.
+var ErrLockLost = errors.New("migration lock lost")
+
+// Migration changes existing documents from one schema version to the next.
+//
+// Up may be interrupted after changing some documents but before the migration is recorded,
+// and is then run again, so it must be idempotent (e.g. only update documents missing a field).
+type Migration struct {
+	// Version orders the migrations; versions must be positive and increasing.
+	Version int
+	// Name is a short snake_case description, recorded alongside the version.
+	Name string
+	Up   func(ctx context.Context, db *mongo.Database) error
+	// Pending optionally counts the documents Up would change, reported by dry runs.
+	Pending func(ctx context.Context, db *mongo.Database) (int64, error)
+}
+
+// IndexEnsurer creates the indexes a repository relies on. Repositories implement it.
+type IndexEnsurer interface {
+	EnsureIndexes(ctx context.Context) error
+}
+
+// indexReporter is implemented by IndexEnsurers that can list the indexes they would create.
+type indexReporter interface {
+	MissingIndexes(ctx context.Context) ([]string, error)
+}
+
+// AppliedMigration is the record of a migration in system_metadata.
+type AppliedMigration struct {
+	Version   int       `bson:"version"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"appliedAt"`
+}
+
+type metadataDoc struct {
+	ID      string             `bson:"_id"`
+	Version int                `bson:"version"`
+	Applied []AppliedMigration `bson:"applied"`
+}
+
+// Result describes a pending migration handled by Run.
+type Result struct {
+	Version int
+	Name    string
+	// Pending is the number of documents to change, counted by dry runs; -1 if the migration
+	// cannot count them.
+	Pending int64
+	// Applied is false for dry runs.
+	Applied  bool
+	Duration time.Duration
+}
+
+// Runner applies migrations to a database.
+type Runner struct {
+	db         *mongo.Database
+	migrations []Migration
+	indexes    []IndexEnsurer
+	// DryRun reports the pending migrations without taking the lock, creating indexes or
+	// changing documents.
+	DryRun bool
+}
+
+// NewRunner creates a runner for the given migrations. The indexes are created once the
+// migrations have run, so a unique index is only built over data they have cleaned up.
+func NewRunner(db *mongo.Database, migrations []Migration, indexes ...IndexEnsurer) *Runner {
+	return &Runner{db: db, migrations: migrations, indexes: indexes}
+}
+
+// Run applies the migrations newer than the recorded version, in order, and returns them.
+// If another node is already migrating, Run waits for it to finish (or ctx to expire) and then
+// applies whatever is still pending.
+func (r *Runner) Run(ctx context.Context) ([]Result, error) {
+	if err := validate(r.migrations); err != nil {
+		return nil, err
+	}
+
+	current, err := r.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if r.DryRun {
+		return r.plan(ctx, current)
+	}
+
+	release, lockCtx, err := r.acquireLock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Another node may have migrated while this one waited for the lock
+	current, err = r.Version(lockCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, m := range pending(r.migrations, current) {
+		slog.Info("Applying migration", "version", m.Version, "name", m.Name)
+		start := time.Now()
+		if err := m.Up(lockCtx, r.db); err != nil {
+			if errors.Is(context.Cause(lockCtx), ErrLockLost) {
+				err = ErrLockLost
+			}
+			return results, fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		if err := r.record(lockCtx, m); err != nil {
+			return results, err
+		}
+		results = append(results, Result{Version: m.Version, Name: m.Name, Pending: -1, Applied: true, Duration: time.Since(start)})
+	}
+
+	for _, ix := range r.indexes {
+		if err := ix.EnsureIndexes(lockCtx); err != nil {
+			return results, fmt.Errorf("failed to ensure indexes: %w", err)
+		}
+	}
+	return results, nil
+}
+
+// Version returns the version of the latest applied migration, or 0 if none has been applied.
+func (r *Runner) Version(ctx context.Context) (int, error) {
+	var doc metadataDoc
+	err := r.db.Collection(MetadataCollection).FindOne(ctx, bson.M{"_id": MetadataID}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch migration metadata: %w", err)
+	}
+	return doc.Version, nil
+}
+
+// Applied returns the record of every applied migration, oldest first.
+func (r *Runner) Applied(ctx context.Context) ([]AppliedMigration, error) {
+	var doc metadataDoc
+	err := r.db.Collection(MetadataCollection).FindOne(ctx, bson.M{"_id": MetadataID}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch migration metadata: %w", err)
+	}
+	return doc.Applied, nil
+}
+
+// MissingIndexes lists the indexes Run would create, for dry runs.
+func (r *Runner) MissingIndexes(ctx context.Context) ([]string, error) {
+	var missing []string
+	for _, ix := range r.indexes {
+		reporter, ok := ix.(indexReporter)
+		if !ok {
+			continue
+		}
+		names, err := reporter.MissingIndexes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		missing = append(missing, names...)
+	}
+	return missing, nil
+}
+
+func (r *Runner) plan(ctx context.Context, current int) ([]Result, error) {
+	var results []Result
+	for _, m := range pending(r.migrations, current) {
+		result := Result{Version: m.Version, Name: m.Name, Pending: -1}
+		if m.Pending != nil {
+			count, err := m.Pending(ctx, r.db)
+			if err != nil {
+				return nil, fmt.Errorf("migration %d (%s) failed to count pending documents: %w", m.Version, m.Name, err)
+			}
+			result.Pending = count
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (r *Runner) record(ctx context.Context, m Migration) error {
+	_, err := r.db.Collection(MetadataCollection).UpdateOne(ctx,
+		bson.M{"_id": MetadataID},
+		bson.M{
+			"$set":  bson.M{"version": m.Version},
+			"$push": bson.M{"applied": AppliedMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}},
+		},
+		options.UpdateOne().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d (%s): %w", m.Version, m.Name, err)
+	}
+	return nil
+}
+
+// acquireLock takes the migration lock, waiting while another node holds it. The returned
+// context is cancelled with ErrLockLost if the lock cannot be kept; release stops the
+// heartbeat and deletes the lock.
+func (r *Runner) acquireLock(ctx context.Context) (release func(), lockCtx context.Context, err error) {
+	if err := ensureLockIndex(ctx, r.db); err != nil {
+		return nil, nil, fmt.Errorf("failed to ensure lock index: %w", err)
+	}
+
+	owner, err := newOwnerID()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	locks := r.db.Collection(LocksCollection)
+	for {
+		_, err = locks.InsertOne(ctx, bson.M{"_id": LockID, "owner": owner, "createdAt": time.Now()})
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+
+		slog.Info("Migrations in progress by another node. Waiting.")
+		select {
+		case <-ctx.Done():
+			return nil, nil, fmt.Errorf("failed to acquire migration lock: %w", ctx.Err())
+		case <-time.After(lockRetry):
+		}
+	}
+
+	lockCtx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(lockHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				result, err := locks.UpdateOne(lockCtx,
+					bson.M{"_id": LockID, "owner": owner},
+					bson.M{"$set": bson.M{"createdAt": time.Now()}})
+				if err == nil && result.MatchedCount == 0 {
+					cancel(ErrLockLost)
+					return
+				}
+				if err != nil {
+					slog.Warn("Failed to refresh migration lock", "error", err)
+				}
+			}
+		}
+	}()
+
+	release = func() {
+		close(done)
+		cancel(nil)
+		_, _ = locks.DeleteOne(context.Background(), bson.M{"_id": LockID, "owner": owner})
+	}
+	return release, lockCtx, nil
+}
+
+// ensureLockIndex creates the TTL index on the locks collection that expires crashed nodes' locks.
+// It is the same index the seeder creates.
+func ensureLockIndex(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(LocksCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"createdAt": 1},
+		Options: options.Index().SetExpireAfterSeconds(int32(lockTTL.Seconds())),
+	})
+	return err
+}
+
+func newOwnerID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate lock owner: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validate checks that versions are positive and strictly increasing and that every
+// migration is named and has an Up function.
+func validate(migrations []Migration) error {
+	previous := 0
+	for _, m := range migrations {
+		if m.Version <= previous {
+			return fmt.Errorf("migration %d (%s) is out of order: versions must be positive and increasing", m.Version, m.Name)
+		}
+		if m.Name == "" {
+			return fmt.Errorf("migration %d has no name", m.Version)
+		}
+		if m.Up == nil {
+			return fmt.Errorf("migration %d (%s) has no up function", m.Version, m.Name)
+		}
+		previous = m.Version
+	}
+	return nil
+}
+
+func pending(migrations []Migration, current int) []Migration {
+	for i, m := range migrations {
+		if m.Version > current {
+			return migrations[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,483 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	mobyclient "github.com/moby/moby/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"github.com/testcontainers/testcontainers-go/network"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var testDB *mongo.Database
+
+func getMyNetworkName(ctx context.Context, containerID string) string {
+	if containerID == "" {
+		return ""
+	}
+
+	// Create a provider to talk to the socket
+	provider, err := testcontainers.NewDockerProvider()
+	if err != nil {
+		return ""
+	}
+	defer func() {
+		if err := provider.Close(); err != nil {
+			log.Printf("failed to close provider: %s", err)
+		}
+	}()
+
+	// Inspect "this" container
+	client, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return ""
+	}
+
+	inspect, err := client.ContainerInspect(ctx, containerID, mobyclient.ContainerInspectOptions{})
+	if err != nil {
+		return ""
+	}
+
+	// Grab the first network name attached to this container
+	for netName := range inspect.Container.NetworkSettings.Networks {
+		return netName
+	}
+	return ""
+}
+
+func getMongoURI(ctx context.Context, container *mongodb.MongoDBContainer, networkName string) string {
+	// If we are in a shared network (CI), use the internal alias and port
+	if networkName != "" && networkName != "bridge" {
+		return "mongodb://mongodb_migrations:27017/fitness_db?authSource=admin"
+	}
+
+	// Otherwise (Local Dev), use the official helper
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		return "mongodb://localhost:27017/fitness_db?authSource=admin"
+	}
+	return uri
+}
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+	myID := os.Getenv("MY_CONTAINER_ID")
+	networkName := getMyNetworkName(ctx, myID)
+
+	var opts []testcontainers.ContainerCustomizer
+
+	if networkName != "" {
+		// Use the network package's helper to join by name
+		opts = append(opts, network.WithNetworkName([]string{"mongodb_migrations"}, networkName),
+			testcontainers.WithWaitStrategy(
+				wait.ForAll(
+					wait.ForListeningPort("27017/tcp").SkipExternalCheck(),
+				).WithDeadline(2*time.Minute), // Changed from WithStartupTimeout
+			),
+		)
+	}
+	mongodbContainer, err := mongodb.Run(ctx, "mongo:6", opts...)
+	if err != nil {
+		log.Fatalf("failed to start container: %s", err)
+	}
+	defer func() {
+		if err := mongodbContainer.Terminate(ctx); err != nil {
+			log.Printf("failed to terminate container: %s", err)
+		}
+	}()
+
+	endpoint := getMongoURI(ctx, mongodbContainer, networkName)
+	if err != nil {
+		log.Fatalf("failed to get connection string: %s", err)
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(endpoint))
+	if err != nil {
+		log.Fatalf("failed to connect to mongo: %s", err)
+	}
+
+	testDB = client.Database("fitness_db_test")
+
+	code := m.Run()
+
+	if err := mongodbContainer.Terminate(ctx); err != nil {
+		log.Fatalf("failed to terminate container: %s", err)
+	}
+
+	os.Exit(code)
+}
+
+type fakeIndexes struct {
+	calls int
+	// ran, if set, is copied into ranBefore when the indexes are ensured
+	ran       *[]int
+	ranBefore []int
+}
+
+func (f *fakeIndexes) EnsureIndexes(ctx context.Context) error {
+	f.calls++
+	if f.ran != nil {
+		f.ranBefore = append([]int(nil), *f.ran...)
+	}
+	return nil
+}
+
+// recordingMigrations returns two migrations that append their version to *ran.
+func recordingMigrations(ran *[]int) []Migration {
+	up := func(version int) func(context.Context, *mongo.Database) error {
+		return func(ctx context.Context, db *mongo.Database) error {
+			*ran = append(*ran, version)
+			return nil
+		}
+	}
+	return []Migration{
+		{Version: 1, Name: "first", Up: up(1)},
+		{Version: 2, Name: "second", Up: up(2)},
+	}
+}
+
+func TestRunner(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Applies pending migrations in order and records them", func(t *testing.T) {
+		require.NoError(t, testDB.Drop(ctx))
+
+		var ran []int
+		indexes := &fakeIndexes{ran: &ran}
+		runner := NewRunner(testDB, recordingMigrations(&ran), indexes)
+
+		results, err := runner.Run(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, ran)
+		assert.Equal(t, 1, indexes.calls)
+		// Indexes are built over the migrated data
+		assert.Equal(t, []int{1, 2}, indexes.ranBefore)
+		require.Len(t, results, 2)
+		assert.Equal(t, "first", results[0].Name)
+		assert.True(t, results[1].Applied)
+
+		version, err := runner.Version(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, version)
+		applied, err := runner.Applied(ctx)
+		require.NoError(t, err)
+		require.Len(t, applied, 2)
+		assert.Equal(t, "second", applied[1].Name)
+
+		// The lock is released
+		count, err := testDB.Collection(LocksCollection).CountDocuments(ctx, bson.M{"_id": LockID})
+		require.NoError(t, err)
+		assert.Zero(t, count)
+
+		// Running again only re-ensures indexes
+		results, err = runner.Run(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+		assert.Equal(t, []int{1, 2}, ran)
+		assert.Equal(t, 2, indexes.calls)
+
+		// A new migration is applied on its own
+		runner.migrations = append(runner.migrations, Migration{Version: 5, Name: "fifth", Up: func(ctx context.Context, db *mongo.Database) error {
+			ran = append(ran, 5)
+			return nil
+		}})
+		results, err = runner.Run(ctx)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, []int{1, 2, 5}, ran)
+	})
+
+	t.Run("Stops at a failing migration", func(t *testing.T) {
+		require.NoError(t, testDB.Drop(ctx))
+
+		var ran []int
+		ms := recordingMigrations(&ran)
+		ms[1].Up = func(ctx context.Context, db *mongo.Database) error {
+			return errors.New("boom")
+		}
+		ms = append(ms, Migration{Version: 3, Name: "third", Up: func(ctx context.Context, db *mongo.Database) error {
+			ran = append(ran, 3)
+			return nil
+		}})
+		indexes := &fakeIndexes{}
+		runner := NewRunner(testDB, ms, indexes)
+
+		results, err := runner.Run(ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "migration 2 (second) failed: boom")
+		assert.Len(t, results, 1)
+		assert.Equal(t, []int{1}, ran)
+		assert.Zero(t, indexes.calls)
+
+		version, err := runner.Version(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, version)
+	})
+
+	t.Run("Dry run reports pending migrations without applying them", func(t *testing.T) {
+		require.NoError(t, testDB.Drop(ctx))
+
+		var ran []int
+		ms := recordingMigrations(&ran)
+		ms[0].Pending = func(ctx context.Context, db *mongo.Database) (int64, error) {
+			return 7, nil
+		}
+		indexes := &fakeIndexes{}
+		runner := NewRunner(testDB, ms, indexes)
+		runner.DryRun = true
+
+		results, err := runner.Run(ctx)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, int64(7), results[0].Pending)
+		assert.Equal(t, int64(-1), results[1].Pending)
+		assert.False(t, results[0].Applied)
+		assert.Empty(t, ran)
+		assert.Zero(t, indexes.calls)
+
+		version, err := runner.Version(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, version)
+	})
+
+	t.Run("Waits for another node's lock", func(t *testing.T) {
+		require.NoError(t, testDB.Drop(ctx))
+
+		_, err := testDB.Collection(LocksCollection).InsertOne(ctx, bson.M{"_id": LockID, "owner": "other", "createdAt": time.Now()})
+		require.NoError(t, err)
+
+		var ran []int
+		runner := NewRunner(testDB, recordingMigrations(&ran))
+
+		// Gives up when the lock is not released in time
+		shortCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+		_, err = runner.Run(shortCtx)
+		cancel()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to acquire migration lock")
+		assert.Empty(t, ran)
+
+		// Proceeds once the other node finishes
+		go func() {
+			time.Sleep(500 * time.Millisecond)
+			_, _ = testDB.Collection(LocksCollection).DeleteOne(context.Background(), bson.M{"_id": LockID})
+		}()
+		results, err := runner.Run(ctx)
+		require.NoError(t, err)
+		assert.Len(t, results, 2)
+		assert.Equal(t, []int{1, 2}, ran)
+	})
+
+	t.Run("Rejects invalid migration lists", func(t *testing.T) {
+		noop := func(ctx context.Context, db *mongo.Database) error { return nil }
+		cases := map[string][]Migration{
+			"out of order": {{Version: 2, Name: "b", Up: noop}, {Version: 1, Name: "a", Up: noop}},
+			"duplicate":    {{Version: 1, Name: "a", Up: noop}, {Version: 1, Name: "b", Up: noop}},
+			"zero":         {{Version: 0, Name: "a", Up: noop}},
+			"unnamed":      {{Version: 1, Up: noop}},
+			"no up":        {{Version: 1, Name: "a"}},
+		}
+		for name, ms := range cases {
+			_, err := NewRunner(testDB, ms).Run(ctx)
+			assert.Error(t, err, name)
+		}
+		assert.NoError(t, validate(All))
+	})
+}
+
+func TestAddWorkoutLogVersionAndDeletedAt(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, testDB.Drop(ctx))
+
+	workouts := testDB.Collection(workoutsCollection)
+	deletedAt := time.Now().Truncate(time.Millisecond).UTC()
+	_, err := workouts.InsertMany(ctx, []any{
+		bson.M{"_id": "legacy", "name": "Legacy"},
+		bson.M{"_id": "current", "name": "Current", "version": 4, "deletedAt": nil},
+		bson.M{"_id": "deleted", "name": "Deleted", "version": 2, "deletedAt": deletedAt},
+	})
+	require.NoError(t, err)
+
+	runner := NewRunner(testDB, All)
+	runner.DryRun = true
+	results, err := runner.Run(ctx)
+	require.NoError(t, err)
+	require.Len(t, results, len(All))
+	assert.Equal(t, "add_workout_log_version_and_deleted_at", results[0].Name)
+	assert.Equal(t, int64(1), results[0].Pending)
+
+	runner.DryRun = false
+	_, err = runner.Run(ctx)
+	require.NoError(t, err)
+
+	type workoutDoc struct {
+		ID        string     `bson:"_id"`
+		Version   int        `bson:"version"`
+		DeletedAt *time.Time `bson:"deletedAt"`
+	}
+	get := func(id string) (workoutDoc, bson.Raw) {
+		raw, err := workouts.FindOne(ctx, bson.M{"_id": id}).Raw()
+		require.NoError(t, err)
+		var doc workoutDoc
+		require.NoError(t, bson.Unmarshal(raw, &doc))
+		return doc, raw
+	}
+
+	legacy, raw := get("legacy")
+	assert.Equal(t, 1, legacy.Version)
+	assert.Nil(t, legacy.DeletedAt)
+	_, err = raw.LookupErr("deletedAt")
+	assert.NoError(t, err, "deletedAt should be stored as null")
+
+	current, _ := get("current")
+	assert.Equal(t, 4, current.Version)
+
+	deleted, _ := get("deleted")
+	assert.Equal(t, 2, deleted.Version)
+	require.NotNil(t, deleted.DeletedAt)
+	assert.True(t, deletedAt.Equal(*deleted.DeletedAt))
+
+	count, err := countWorkoutLogsMissingVersionOrDeletedAt(ctx, testDB)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestAddRefreshTokenFamily(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, testDB.Drop(ctx))
+
+	tokens := testDB.Collection(refreshTokensCollection)
+	_, err := tokens.InsertMany(ctx, []any{
+		bson.M{"_id": "legacy", "userId": "user-1"},
+		bson.M{"_id": "rotated", "userId": "user-1", "familyId": "root"},
+	})
+	require.NoError(t, err)
+
+	pending, err := countRefreshTokensMissingFamily(ctx, testDB)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pending)
+
+	require.NoError(t, addRefreshTokenFamily(ctx, testDB))
+
+	familyOf := func(id string) string {
+		var doc struct {
+			FamilyID string `bson:"familyId"`
+		}
+		require.NoError(t, tokens.FindOne(ctx, bson.M{"_id": id}).Decode(&doc))
+		return doc.FamilyID
+	}
+	assert.Equal(t, "legacy", familyOf("legacy"))
+	assert.Equal(t, "root", familyOf("rotated"))
+}
+
+func TestNormalizeUserEmails(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, testDB.Drop(ctx))
+
+	users := testDB.Collection(usersCollection)
+	_, err := users.InsertMany(ctx, []any{
+		bson.M{"_id": "mixed", "email": " Jane@Example.com", "pendingEmail": "New@Example.com"},
+		bson.M{"_id": "lower", "email": "john@example.com", "pendingEmail": nil},
+	})
+	require.NoError(t, err)
+
+	pending, err := countUsersWithUnnormalizedEmail(ctx, testDB)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pending)
+
+	require.NoError(t, normalizeUserEmails(ctx, testDB))
+
+	get := func(id string) bson.Raw {
+		raw, err := users.FindOne(ctx, bson.M{"_id": id}).Raw()
+		require.NoError(t, err)
+		return raw
+	}
+	mixed := get("mixed")
+	assert.Equal(t, "jane@example.com", mixed.Lookup("email").StringValue())
+	assert.Equal(t, "new@example.com", mixed.Lookup("pendingEmail").StringValue())
+	assert.Equal(t, bson.TypeNull, get("lower").Lookup("pendingEmail").Type)
+
+	pending, err = countUsersWithUnnormalizedEmail(ctx, testDB)
+	require.NoError(t, err)
+	assert.Zero(t, pending)
+}
+
+func TestNormalizeUserEmailsReportsDuplicates(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, testDB.Drop(ctx))
+
+	users := testDB.Collection(usersCollection)
+	_, err := users.InsertMany(ctx, []any{
+		bson.M{"_id": "exact-1", "email": "same@example.com"},
+		bson.M{"_id": "exact-2", "email": "same@example.com"},
+		bson.M{"_id": "case-1", "email": "Jane@Example.com"},
+		bson.M{"_id": "case-2", "email": "jane@example.com"},
+		bson.M{"_id": "unique", "email": "John@Example.com"},
+	})
+	require.NoError(t, err)
+
+	// Dry runs report them too
+	_, err = countUsersWithUnnormalizedEmail(ctx, testDB)
+	assert.ErrorContains(t, err, "jane@example.com, same@example.com")
+
+	err = normalizeUserEmails(ctx, testDB)
+	assert.ErrorContains(t, err, "jane@example.com, same@example.com")
+
+	// Nothing was changed
+	raw, err := users.FindOne(ctx, bson.M{"_id": "unique"}).Raw()
+	require.NoError(t, err)
+	assert.Equal(t, "John@Example.com", raw.Lookup("email").StringValue())
+}
+
+func TestNormalizeExerciseNames(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, testDB.Drop(ctx))
+
+	exercises := testDB.Collection(exercisesCollection)
+	_, err := exercises.InsertMany(ctx, []any{
+		// Stored before names were normalized
+		bson.M{"_id": "a", "userId": "user-1", "name": "Legacy  Curl", "aliases": bson.A{"Old  Curl"}},
+		bson.M{"_id": "b", "userId": "user-1", "name": "legacy curl", "normalizedName": "legacy curl"},
+		bson.M{"_id": "c", "userId": "user-1", "name": "Legacy Curl (2)", "normalizedName": "legacy curl (2)"},
+		// Other users and system exercises may share the name
+		bson.M{"_id": "d", "userId": "user-2", "name": "Legacy Curl"},
+		bson.M{"_id": "e", "userId": nil, "name": "Legacy Curl", "normalizedName": "legacy curl"},
+	})
+	require.NoError(t, err)
+
+	pending, err := countExercisesToNormalize(ctx, testDB)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), pending)
+
+	require.NoError(t, normalizeExerciseNames(ctx, testDB))
+
+	get := func(id string) bson.Raw {
+		raw, err := exercises.FindOne(ctx, bson.M{"_id": id}).Raw()
+		require.NoError(t, err)
+		return raw
+	}
+	legacy := get("a")
+	assert.Equal(t, "Legacy  Curl", legacy.Lookup("name").StringValue())
+	assert.Equal(t, "legacy curl", legacy.Lookup("normalizedName").StringValue())
+	assert.Equal(t, "old curl", legacy.Lookup("normalizedAliases").Array().Index(0).StringValue())
+	// The newer duplicate is renamed past the name already taken
+	assert.Equal(t, "legacy curl (3)", get("b").Lookup("name").StringValue())
+	assert.Equal(t, "legacy curl (3)", get("b").Lookup("normalizedName").StringValue())
+	assert.Equal(t, "Legacy Curl (2)", get("c").Lookup("name").StringValue())
+	assert.Equal(t, "legacy curl", get("d").Lookup("normalizedName").StringValue())
+
+	pending, err = countExercisesToNormalize(ctx, testDB)
+	require.NoError(t, err)
+	assert.Zero(t, pending)
+}
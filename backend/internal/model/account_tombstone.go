@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// AccountTombstone is the audit record left behind by a deleted account. It deliberately holds
+// no personal data: only when the account existed and how much data was removed.
+type AccountTombstone struct {
+	UserID              string    `bson:"_id"`
+	AccountCreatedAt    time.Time `bson:"accountCreatedAt"`
+	DeletionRequestedAt time.Time `bson:"deletionRequestedAt"`
+	DeletedAt           time.Time `bson:"deletedAt"`
+	// Deleted counts the removed documents per kind of data, e.g. "workouts"
+	Deleted map[string]int64 `bson:"deleted"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// ActionTokenPurpose is what an emailed token allows its holder to do.
+type ActionTokenPurpose string
+
+const (
+	ActionTokenPasswordReset     ActionTokenPurpose = "PASSWORD_RESET"
+	ActionTokenEmailVerification ActionTokenPurpose = "EMAIL_VERIFICATION"
+	ActionTokenEmailChange       ActionTokenPurpose = "EMAIL_CHANGE"
+	// ActionTokenTwoFactorChallenge is returned by login, not emailed: it stands for the verified
+	// password while the user enters their second factor.
+	ActionTokenTwoFactorChallenge ActionTokenPurpose = "TWO_FACTOR_CHALLENGE"
+	// Passkey ceremonies use the token as their WebAuthn challenge. Sign-in tokens have no user,
+	// who is only known from the passkey.
+	ActionTokenPasskeyRegistration ActionTokenPurpose = "PASSKEY_REGISTRATION"
+	ActionTokenPasskeyLogin        ActionTokenPurpose = "PASSKEY_LOGIN"
+)
+
+// ActionToken is a single-use token sent by email, e.g. in a password reset link. Like
+// RefreshToken, only a hash of its secret is stored.
+type ActionToken struct {
+	ID string `bson:"_id,omitempty"`
+
+	UserID  string             `bson:"userId"`
+	Purpose ActionTokenPurpose `bson:"purpose"`
+	// Email is the address the token was sent to; verifying it only verifies that address.
+	Email     string    `bson:"email"`
+	TokenHash string    `bson:"tokenHash"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+	CreatedAt time.Time `bson:"createdAt"`
+}
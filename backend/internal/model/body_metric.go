@@ -0,0 +1,74 @@
+package model
+
+import (
+	"time"
+)
+
+// MeasurementSite identifies where a body circumference was measured.
+type MeasurementSite string
+
+const (
+	MeasurementSiteNeck    MeasurementSite = "NECK"
+	MeasurementSiteChest   MeasurementSite = "CHEST"
+	MeasurementSiteWaist   MeasurementSite = "WAIST"
+	MeasurementSiteHips    MeasurementSite = "HIPS"
+	MeasurementSiteArm     MeasurementSite = "ARM"
+	MeasurementSiteForearm MeasurementSite = "FOREARM"
+	MeasurementSiteThigh   MeasurementSite = "THIGH"
+	MeasurementSiteCalf    MeasurementSite = "CALF"
+)
+
+// IsValid reports whether the site is one of the known measurement sites.
+func (s MeasurementSite) IsValid() bool {
+	switch s {
+	case MeasurementSiteNeck, MeasurementSiteChest, MeasurementSiteWaist, MeasurementSiteHips,
+		MeasurementSiteArm, MeasurementSiteForearm, MeasurementSiteThigh, MeasurementSiteCalf:
+		return true
+	}
+	return false
+}
+
+// BodyMetric is a timestamped body composition entry.
+// Weight is ALWAYS stored in kilograms and circumferences in centimeters.
+type BodyMetric struct {
+	ID                string             `json:"id" bson:"_id,omitempty"`
+	UserID            string             `json:"userId" bson:"userId"`
+	RecordedAt        time.Time          `json:"recordedAt" bson:"recordedAt"`
+	Weight            *float64           `json:"weight" bson:"weight,omitempty"`
+	BodyFatPercentage *float64           `json:"bodyFatPercentage" bson:"bodyFatPercentage,omitempty"`
+	Measurements      []*BodyMeasurement `json:"measurements" bson:"measurements"`
+	Notes             *string            `json:"notes" bson:"notes,omitempty"`
+	CreatedAt         time.Time          `json:"-" bson:"createdAt"`
+	UpdatedAt         time.Time          `json:"-" bson:"updatedAt"`
+}
+
+// BodyMeasurement is a single circumference, stored in centimeters.
+type BodyMeasurement struct {
+	Site  MeasurementSite `json:"site" bson:"site"`
+	Value float64         `json:"value" bson:"value"`
+}
+
+// BodyweightKey identifies the user's bodyweight as of a time: the latest weigh-in at or before it.
+type BodyweightKey struct {
+	UserID string
+	At     time.Time
+}
+
+// BodyweightTrendPoint is a bodyweight entry paired with its trailing moving average (both in kilograms).
+type BodyweightTrendPoint struct {
+	Date          time.Time `json:"date"`
+	Weight        float64   `json:"weight"`
+	MovingAverage float64   `json:"movingAverage"`
+}
+
+// BodyMetricInput carries the values supplied when logging or updating a body metric entry.
+// Values are expressed in Unit; the service converts them to storage units.
+// On update, nil fields are left unchanged and a non-nil Measurements slice replaces the existing list.
+type BodyMetricInput struct {
+	RecordedAt        *time.Time
+	Unit              WeightUnit
+	Weight            *float64
+	BodyFatPercentage *float64
+	Measurements      []*BodyMeasurement
+	Notes             *string
+}
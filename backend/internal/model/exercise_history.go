@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// ExerciseSession is one workout in which an exercise was performed.
+// Sets from every exercise log of the exercise in that workout are combined.
+type ExerciseSession struct {
+	WorkoutID   string    `json:"workoutId"`
+	WorkoutName string    `json:"workoutName"`
+	Date        time.Time `json:"date"` // The workout's start time
+	Sets        []*Set    `json:"sets"`
+	Notes       *string   `json:"notes,omitempty"`
+}
+
+// SessionCursor is the position of a session in the user's history, which is ordered
+// newest first by workout start time and then by workout ID.
+type SessionCursor struct {
+	StartTime time.Time
+	WorkoutID string
+}
+
+// PreviousSessionKey identifies the user's latest session of an exercise before a workout.
+type PreviousSessionKey struct {
+	UserID     string
+	ExerciseID string
+	Before     SessionCursor
+}
+
+// ExerciseBestSet is the heaviest set ever logged for an exercise (most reps breaks ties).
+type ExerciseBestSet struct {
+	Set       *Set      `json:"set"`
+	WorkoutID string    `json:"workoutId"`
+	Date      time.Time `json:"date"`
+}
+
+// ExerciseHistorySummary covers every session of the exercise, not just the returned page.
+type ExerciseHistorySummary struct {
+	LastPerformed *time.Time       `json:"lastPerformed,omitempty"`
+	TotalSessions int32            `json:"totalSessions"`
+	BestSet       *ExerciseBestSet `json:"bestSet,omitempty"`
+}
+
+// ExerciseHistory is a page of a user's sessions of one exercise, newest first.
+type ExerciseHistory struct {
+	Sessions    []*ExerciseSession      `json:"sessions"`
+	EndCursor   *string                 `json:"endCursor,omitempty"`
+	HasNextPage bool                    `json:"hasNextPage"`
+	Summary     *ExerciseHistorySummary `json:"summary"`
+}
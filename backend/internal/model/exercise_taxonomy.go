@@ -0,0 +1,168 @@
+package model
+
+import (
+	"slices"
+	"strings"
+)
+
+// NormalizeExerciseName lower-cases the name and collapses whitespace, so that
+// "Bench  press" and "bench press " are treated as the same exercise.
+func NormalizeExerciseName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// NormalizeExerciseAliases normalizes each alias like NormalizeExerciseName.
+func NormalizeExerciseAliases(aliases []string) []string {
+	normalized := make([]string, len(aliases))
+	for i, alias := range aliases {
+		normalized[i] = NormalizeExerciseName(alias)
+	}
+	return normalized
+}
+
+// ExerciseCategory is the broad training modality of an exercise.
+type ExerciseCategory string
+
+const (
+	ExerciseCategoryStrength ExerciseCategory = "STRENGTH"
+	ExerciseCategoryCore     ExerciseCategory = "CORE"
+	ExerciseCategoryCardio   ExerciseCategory = "CARDIO"
+	ExerciseCategoryMobility ExerciseCategory = "MOBILITY"
+	ExerciseCategoryOther    ExerciseCategory = "OTHER"
+)
+
+func (c ExerciseCategory) IsValid() bool {
+	switch c {
+	case ExerciseCategoryStrength, ExerciseCategoryCore, ExerciseCategoryCardio, ExerciseCategoryMobility, ExerciseCategoryOther:
+		return true
+	}
+	return false
+}
+
+// MuscleGroup is a muscle (or group of muscles) trained by an exercise.
+type MuscleGroup string
+
+const (
+	MuscleGroupChest      MuscleGroup = "CHEST"
+	MuscleGroupShoulders  MuscleGroup = "SHOULDERS"
+	MuscleGroupTriceps    MuscleGroup = "TRICEPS"
+	MuscleGroupBiceps     MuscleGroup = "BICEPS"
+	MuscleGroupForearms   MuscleGroup = "FOREARMS"
+	MuscleGroupLats       MuscleGroup = "LATS"
+	MuscleGroupUpperBack  MuscleGroup = "UPPER_BACK"
+	MuscleGroupTraps      MuscleGroup = "TRAPS"
+	MuscleGroupLowerBack  MuscleGroup = "LOWER_BACK"
+	MuscleGroupAbs        MuscleGroup = "ABS"
+	MuscleGroupObliques   MuscleGroup = "OBLIQUES"
+	MuscleGroupGlutes     MuscleGroup = "GLUTES"
+	MuscleGroupQuadriceps MuscleGroup = "QUADRICEPS"
+	MuscleGroupHamstrings MuscleGroup = "HAMSTRINGS"
+	MuscleGroupAdductors  MuscleGroup = "ADDUCTORS"
+	MuscleGroupCalves     MuscleGroup = "CALVES"
+)
+
+func (m MuscleGroup) IsValid() bool {
+	switch m {
+	case MuscleGroupChest, MuscleGroupShoulders, MuscleGroupTriceps, MuscleGroupBiceps, MuscleGroupForearms,
+		MuscleGroupLats, MuscleGroupUpperBack, MuscleGroupTraps, MuscleGroupLowerBack, MuscleGroupAbs,
+		MuscleGroupObliques, MuscleGroupGlutes, MuscleGroupQuadriceps, MuscleGroupHamstrings,
+		MuscleGroupAdductors, MuscleGroupCalves:
+		return true
+	}
+	return false
+}
+
+// Equipment is the main implement an exercise is performed with.
+type Equipment string
+
+const (
+	EquipmentBarbell    Equipment = "BARBELL"
+	EquipmentDumbbell   Equipment = "DUMBBELL"
+	EquipmentKettlebell Equipment = "KETTLEBELL"
+	EquipmentCable      Equipment = "CABLE"
+	EquipmentMachine    Equipment = "MACHINE"
+	EquipmentBodyweight Equipment = "BODYWEIGHT"
+	EquipmentBand       Equipment = "BAND"
+	EquipmentOther      Equipment = "OTHER"
+)
+
+func (e Equipment) IsValid() bool {
+	switch e {
+	case EquipmentBarbell, EquipmentDumbbell, EquipmentKettlebell, EquipmentCable, EquipmentMachine,
+		EquipmentBodyweight, EquipmentBand, EquipmentOther:
+		return true
+	}
+	return false
+}
+
+// MovementPattern classifies an exercise by the fundamental movement it trains.
+type MovementPattern string
+
+const (
+	MovementPatternHorizontalPush MovementPattern = "HORIZONTAL_PUSH"
+	MovementPatternVerticalPush   MovementPattern = "VERTICAL_PUSH"
+	MovementPatternHorizontalPull MovementPattern = "HORIZONTAL_PULL"
+	MovementPatternVerticalPull   MovementPattern = "VERTICAL_PULL"
+	MovementPatternSquat          MovementPattern = "SQUAT"
+	MovementPatternHinge          MovementPattern = "HINGE"
+	MovementPatternLunge          MovementPattern = "LUNGE"
+	MovementPatternCarry          MovementPattern = "CARRY"
+	MovementPatternCore           MovementPattern = "CORE"
+	MovementPatternIsolation      MovementPattern = "ISOLATION"
+)
+
+func (p MovementPattern) IsValid() bool {
+	switch p {
+	case MovementPatternHorizontalPush, MovementPatternVerticalPush, MovementPatternHorizontalPull,
+		MovementPatternVerticalPull, MovementPatternSquat, MovementPatternHinge, MovementPatternLunge,
+		MovementPatternCarry, MovementPatternCore, MovementPatternIsolation:
+		return true
+	}
+	return false
+}
+
+// ExerciseInput holds the user-editable fields of a UniqueExercise.
+// On update, nil fields are left unchanged.
+type ExerciseInput struct {
+	Name             *string
+	Description      *string
+	Aliases          []string
+	Category         *ExerciseCategory
+	PrimaryMuscles   []MuscleGroup
+	SecondaryMuscles []MuscleGroup
+	Equipment        *Equipment
+	MovementPattern  *MovementPattern
+	Unilateral       *bool
+}
+
+// ExerciseFilter narrows an exercise search by taxonomy. Zero values mean "any".
+type ExerciseFilter struct {
+	Category        *ExerciseCategory
+	MuscleGroups    []MuscleGroup // Matches exercises whose primary muscles include any of these
+	Equipment       []Equipment   // Matches any of these
+	MovementPattern *MovementPattern
+	Unilateral      *bool
+}
+
+// Matches reports whether the exercise satisfies every condition of the filter.
+// It mirrors the database query used for the same filter.
+func (f ExerciseFilter) Matches(e *UniqueExercise) bool {
+	if f.Category != nil && (e.Category == nil || *e.Category != *f.Category) {
+		return false
+	}
+	if len(f.MuscleGroups) > 0 && !slices.ContainsFunc(e.PrimaryMuscles, func(m MuscleGroup) bool {
+		return slices.Contains(f.MuscleGroups, m)
+	}) {
+		return false
+	}
+	if len(f.Equipment) > 0 && (e.Equipment == nil || !slices.Contains(f.Equipment, *e.Equipment)) {
+		return false
+	}
+	if f.MovementPattern != nil && (e.MovementPattern == nil || *e.MovementPattern != *f.MovementPattern) {
+		return false
+	}
+	if f.Unilateral != nil && e.Unilateral != *f.Unilateral {
+		return false
+	}
+	return true
+}
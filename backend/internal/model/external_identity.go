@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// ExternalIdentity links a user to their account at an OpenID Connect provider, which they can
+// then sign in with.
+type ExternalIdentity struct {
+	ID     string `json:"id" bson:"_id,omitempty"`
+	UserID string `json:"-" bson:"userId"`
+	// Provider is the ID of the provider in the configuration, e.g. "google"
+	Provider string `json:"provider" bson:"provider"`
+	// Subject identifies the user at the provider
+	Subject string `json:"-" bson:"subject"`
+	// Email is the address the provider had for the user when the identity was linked
+	Email string `json:"email" bson:"email"`
+
+	CreatedAt  time.Time  `json:"createdAt" bson:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" bson:"lastUsedAt,omitempty"`
+}
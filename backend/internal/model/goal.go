@@ -0,0 +1,86 @@
+package model
+
+import (
+	"time"
+)
+
+// GoalType determines how a goal's target is interpreted and evaluated.
+type GoalType string
+
+const (
+	// GoalTypeLift targets a weight (kg) lifted for TargetReps on a specific exercise.
+	GoalTypeLift GoalType = "LIFT"
+	// GoalTypeVolume targets total volume (kg × reps) between the start date and the deadline,
+	// optionally restricted to one exercise.
+	GoalTypeVolume GoalType = "VOLUME"
+	// GoalTypeFrequency targets an average number of sessions per week.
+	GoalTypeFrequency GoalType = "FREQUENCY"
+	// GoalTypeBodyweight targets a bodyweight (kg), either gaining or losing towards it.
+	GoalTypeBodyweight GoalType = "BODYWEIGHT"
+)
+
+// IsValid reports whether the goal type is known.
+func (t GoalType) IsValid() bool {
+	switch t {
+	case GoalTypeLift, GoalTypeVolume, GoalTypeFrequency, GoalTypeBodyweight:
+		return true
+	}
+	return false
+}
+
+// IsWeightBased reports whether the goal's values are weights stored in kilograms.
+func (t GoalType) IsWeightBased() bool {
+	return t != GoalTypeFrequency
+}
+
+type GoalStatus string
+
+const (
+	GoalStatusOnTrack  GoalStatus = "ON_TRACK"
+	GoalStatusBehind   GoalStatus = "BEHIND"
+	GoalStatusAchieved GoalStatus = "ACHIEVED"
+)
+
+type Goal struct {
+	ID               string     `json:"id" bson:"_id,omitempty"`
+	UserID           string     `json:"userId" bson:"userId"`
+	Type             GoalType   `json:"type" bson:"type"`
+	Name             string     `json:"name" bson:"name"`
+	UniqueExerciseID *string    `json:"uniqueExerciseId" bson:"uniqueExerciseId,omitempty"`
+	TargetValue      float64    `json:"targetValue" bson:"targetValue"` // KGS for weight-based goals, sessions/week for FREQUENCY
+	TargetReps       *int32     `json:"targetReps" bson:"targetReps,omitempty"`
+	StartDate        time.Time  `json:"startDate" bson:"startDate"`
+	Deadline         *time.Time `json:"deadline" bson:"deadline,omitempty"`
+	// StartingValue is the baseline captured at creation (best lift or bodyweight), used to measure progress.
+	StartingValue *float64   `json:"-" bson:"startingValue,omitempty"`
+	AchievedAt    *time.Time `json:"achievedAt" bson:"achievedAt,omitempty"`
+	CreatedAt     time.Time  `json:"-" bson:"createdAt"`
+	UpdatedAt     time.Time  `json:"-" bson:"updatedAt"`
+}
+
+// GoalProgress is the evaluated state of a goal at a point in time.
+type GoalProgress struct {
+	// GoalType determines whether the values are weights (kilograms) or sessions per week.
+	GoalType GoalType   `json:"-"`
+	Status   GoalStatus `json:"status"`
+	// CurrentValue is nil when there is no data yet (e.g. the exercise was never performed).
+	CurrentValue    *float64 `json:"currentValue"`
+	StartingValue   *float64 `json:"startingValue"`
+	TargetValue     float64  `json:"targetValue"`
+	PercentComplete float64  `json:"percentComplete"`
+	// ExpectedPercent is how far along the goal should be by now given its deadline.
+	ExpectedPercent *float64 `json:"expectedPercent"`
+}
+
+// GoalInput carries the values supplied when creating or updating a goal.
+// TargetValue is expressed in Unit for weight-based goals.
+type GoalInput struct {
+	Type             GoalType
+	Name             *string
+	UniqueExerciseID *string
+	TargetValue      *float64
+	TargetReps       *int32
+	Unit             WeightUnit
+	StartDate        *time.Time
+	Deadline         *time.Time
+}
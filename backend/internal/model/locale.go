@@ -0,0 +1,85 @@
+package model
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale is the language exercise names and descriptions are written in.
+// Translations provide the other languages.
+const DefaultLocale = "en"
+
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// NormalizeLocale canonicalizes a BCP 47 language tag, e.g. "pt_br" -> "pt-BR" and
+// "zh-hant" -> "zh-Hant". It reports false if the tag is malformed.
+func NormalizeLocale(tag string) (string, bool) {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	if !localePattern.MatchString(tag) {
+		return "", false
+	}
+	parts := strings.Split(tag, "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		switch len(parts[i]) {
+		case 2: // Region
+			parts[i] = strings.ToUpper(parts[i])
+		case 4: // Script
+			parts[i] = strings.ToUpper(parts[i][:1]) + strings.ToLower(parts[i][1:])
+		default:
+			parts[i] = strings.ToLower(parts[i])
+		}
+	}
+	return strings.Join(parts, "-"), true
+}
+
+// baseLanguage returns the language subtag of a normalized locale, e.g. "pt" for "pt-BR".
+func baseLanguage(locale string) string {
+	language, _, _ := strings.Cut(locale, "-")
+	return language
+}
+
+// ParseAcceptLanguage returns the languages of an Accept-Language header, most preferred first.
+// Wildcards, malformed tags and languages with q=0 are dropped.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+	var languages []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		locale, ok := NormalizeLocale(tag)
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			languages = append(languages, weighted{locale: locale, q: q})
+		}
+	}
+
+	slices.SortStableFunc(languages, func(a, b weighted) int {
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		}
+		return 0
+	})
+	locales := make([]string, len(languages))
+	for i, l := range languages {
+		locales[i] = l.locale
+	}
+	return locales
+}
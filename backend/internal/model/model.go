@@ -1,6 +1,8 @@
 package model
 
 import (
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -26,6 +28,19 @@ type ExerciseLog struct {
 	UniqueExerciseID string  `json:"uniqueExerciseId" bson:"uniqueExerciseId"`
 	Sets             []*Set  `json:"sets" bson:"sets"`
 	Notes            *string `json:"notes" bson:"notes"`
+
+	// The enclosing workout, set by LinkExerciseLogs (not stored)
+	Workout SessionCursor `json:"-" bson:"-"`
+	UserID  string        `json:"-" bson:"-"`
+}
+
+// LinkExerciseLogs records the workout's ID, start time and owner on each of its exercise logs
+// so fields resolved per log (e.g. the previous session) know which workout they belong to.
+func (w *WorkoutLog) LinkExerciseLogs() {
+	for _, l := range w.ExerciseLogs {
+		l.Workout = SessionCursor{StartTime: w.StartTime, WorkoutID: w.ID}
+		l.UserID = w.UserID
+	}
 }
 
 type Set struct {
@@ -36,6 +51,29 @@ type Set struct {
 	Order     int32   `json:"order" bson:"order"`
 }
 
+// Volume is the set's load multiplied by its reps, in kilograms.
+func (s *Set) Volume() float64 {
+	return float64(s.Reps) * s.Weight
+}
+
+// Volume is the total volume of all sets in the exercise log, in kilograms.
+func (l *ExerciseLog) Volume() float64 {
+	total := 0.0
+	for _, s := range l.Sets {
+		total += s.Volume()
+	}
+	return total
+}
+
+// Volume is the total volume of all exercises in the workout, in kilograms.
+func (w *WorkoutLog) Volume() float64 {
+	total := 0.0
+	for _, l := range w.ExerciseLogs {
+		total += l.Volume()
+	}
+	return total
+}
+
 type WeightUnit string
 
 const (
@@ -43,9 +81,128 @@ const (
 	WeightUnitPounds    WeightUnit = "POUNDS"
 )
 
+const (
+	kilogramsPerPound  = 0.45359237
+	centimetersPerInch = 2.54
+)
+
+// ToKilograms converts a weight expressed in this unit to kilograms (the storage unit).
+func (u WeightUnit) ToKilograms(value float64) float64 {
+	if u == WeightUnitPounds {
+		return value * kilogramsPerPound
+	}
+	return value
+}
+
+// FromKilograms converts a stored weight in kilograms to this unit.
+func (u WeightUnit) FromKilograms(value float64) float64 {
+	if u == WeightUnitPounds {
+		return value / kilogramsPerPound
+	}
+	return value
+}
+
+// ToCentimeters converts a length to centimeters (the storage unit).
+// Lengths follow the measurement system of the weight unit: KILOGRAMS means centimeters, POUNDS means inches.
+func (u WeightUnit) ToCentimeters(value float64) float64 {
+	if u == WeightUnitPounds {
+		return value * centimetersPerInch
+	}
+	return value
+}
+
+// FromCentimeters converts a stored length in centimeters to this unit's measurement system.
+func (u WeightUnit) FromCentimeters(value float64) float64 {
+	if u == WeightUnitPounds {
+		return value / centimetersPerInch
+	}
+	return value
+}
+
 type UniqueExercise struct {
-	ID          string  `json:"id" bson:"_id,omitempty"`
-	Name        string  `json:"name" bson:"name"`
-	UserID      *string `json:"userId,omitempty" bson:"userId,omitempty"` // nil for System exercises
-	Description *string `json:"description,omitempty" bson:"description,omitempty"`
+	ID          string   `json:"id" bson:"_id,omitempty"`
+	Name        string   `json:"name" bson:"name"`
+	UserID      *string  `json:"userId,omitempty" bson:"userId,omitempty"` // nil for System exercises
+	Description *string  `json:"description,omitempty" bson:"description,omitempty"`
+	Aliases     []string `json:"aliases" bson:"aliases"` // Alternative names and abbreviations, e.g. "OHP"
+
+	// Taxonomy (optional for custom exercises)
+	Category         *ExerciseCategory `json:"category,omitempty" bson:"category,omitempty"`
+	PrimaryMuscles   []MuscleGroup     `json:"primaryMuscles" bson:"primaryMuscles"`
+	SecondaryMuscles []MuscleGroup     `json:"secondaryMuscles" bson:"secondaryMuscles"`
+	Equipment        *Equipment        `json:"equipment,omitempty" bson:"equipment,omitempty"`
+	MovementPattern  *MovementPattern  `json:"movementPattern,omitempty" bson:"movementPattern,omitempty"`
+	Unilateral       bool              `json:"unilateral" bson:"unilateral"`
+
+	// Archived exercises are hidden from search but still resolve from old logs.
+	// System exercises are archived when they are deprecated in the catalogue.
+	ArchivedAt *time.Time `json:"archivedAt,omitempty" bson:"archivedAt,omitempty"`
+
+	// System exercises only: the catalogue's stable identifier, and the exercise that
+	// replaced this one if it was deprecated in favour of another
+	Slug         *string `json:"slug,omitempty" bson:"slug,omitempty"`
+	ReplacedByID *string `json:"replacedById,omitempty" bson:"replacedBy,omitempty"`
+
+	// Name, description and aliases in other languages, keyed by locale (e.g. "es", "pt-BR").
+	// The fields above are in DefaultLocale.
+	Translations map[string]ExerciseTranslation `json:"translations,omitempty" bson:"translations,omitempty"`
+}
+
+// ExerciseTranslation is an exercise's text in one language.
+type ExerciseTranslation struct {
+	Locale      string   `json:"locale" bson:"-"` // Set from the Translations key
+	Name        string   `json:"name" bson:"name"`
+	Description *string  `json:"description,omitempty" bson:"description,omitempty"`
+	Aliases     []string `json:"aliases" bson:"aliases"`
+}
+
+// Translation returns the exercise's translation for the first of the locales it has one for,
+// falling back from a regional locale to its language ("es-MX" to "es"). It returns nil when
+// the exercise's own (DefaultLocale) text should be used.
+func (e *UniqueExercise) Translation(locales []string) *ExerciseTranslation {
+	for _, locale := range locales {
+		for _, candidate := range []string{locale, baseLanguage(locale)} {
+			if candidate == DefaultLocale {
+				return nil
+			}
+			if t, ok := e.Translations[candidate]; ok {
+				t.Locale = candidate
+				return &t
+			}
+		}
+	}
+	return nil
+}
+
+// LocalizedName returns the exercise's name in the first of the locales it is translated into.
+func (e *UniqueExercise) LocalizedName(locales []string) string {
+	if t := e.Translation(locales); t != nil {
+		return t.Name
+	}
+	return e.Name
+}
+
+// LocalizedDescription returns the exercise's description in the first of the locales it is
+// translated into, falling back to the default description if the translation has none.
+func (e *UniqueExercise) LocalizedDescription(locales []string) *string {
+	if t := e.Translation(locales); t != nil && t.Description != nil {
+		return t.Description
+	}
+	return e.Description
+}
+
+// TranslationList returns the exercise's translations sorted by locale.
+func (e *UniqueExercise) TranslationList() []*ExerciseTranslation {
+	translations := make([]*ExerciseTranslation, 0, len(e.Translations))
+	for locale, t := range e.Translations {
+		t.Locale = locale
+		if t.Aliases == nil {
+			t.Aliases = []string{}
+		}
+		translations = append(translations, &t)
+	}
+	slices.SortFunc(translations, func(a, b *ExerciseTranslation) int {
+		return strings.Compare(a.Locale, b.Locale)
+	})
+	return translations
 }
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// Passkey is a WebAuthn credential a user can sign in with instead of their password.
+type Passkey struct {
+	ID     string `json:"id" bson:"_id,omitempty"`
+	UserID string `json:"-" bson:"userId"`
+	// Name tells the user's passkeys apart, e.g. "Work laptop"
+	Name string `json:"name" bson:"name"`
+
+	CredentialID []byte `json:"-" bson:"credentialId"`
+	// PublicKey is the COSE_Key encoding of the credential's public key
+	PublicKey []byte `json:"-" bson:"publicKey"`
+	// SignCount is the authenticator's signature counter at the last sign-in; a lower one
+	// suggests the passkey was cloned
+	SignCount  uint32   `json:"-" bson:"signCount"`
+	Transports []string `json:"-" bson:"transports,omitempty"`
+
+	CreatedAt  time.Time  `json:"createdAt" bson:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" bson:"lastUsedAt,omitempty"`
+}
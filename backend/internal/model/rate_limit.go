@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// RateLimitState tracks the recent failed attempts made under one rate limit key,
+// e.g. logins from one IP address or for one account.
+type RateLimitState struct {
+	Key           string    `json:"key" bson:"_id"`
+	Failures      int       `json:"failures" bson:"failures"`
+	LastFailureAt time.Time `json:"lastFailureAt" bson:"lastFailureAt"`
+	// The state is deleted once it can no longer delay an attempt
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+}
@@ -13,4 +13,22 @@ type RefreshToken struct {
 	TokenHash string    `bson:"tokenHash"`
 	ExpiresAt time.Time `bson:"expiresAt"`
 	CreatedAt time.Time `bson:"createdAt"`
+
+	// FamilyID is shared by every token rotated from the same login; it is the first token's ID.
+	FamilyID string `bson:"familyId"`
+	// RotatedAt is set once the token has been exchanged for a new one. It is kept until it
+	// expires so that presenting it again can be detected as reuse.
+	RotatedAt *time.Time `bson:"rotatedAt,omitempty"`
+
+	// The client the token was issued to, and when its session started and was last refreshed
+	UserAgent  string    `bson:"userAgent,omitempty"`
+	IP         string    `bson:"ip,omitempty"`
+	SignedInAt time.Time `bson:"signedInAt"`
+	LastUsedAt time.Time `bson:"lastUsedAt"`
+}
+
+// SessionClient describes the client signing in or refreshing its session.
+type SessionClient struct {
+	UserAgent string
+	IP        string
 }
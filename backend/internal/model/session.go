@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// Session is a signed-in device: a family of refresh tokens, described by its latest token.
+type Session struct {
+	ID string `json:"id"`
+	// Device is a short description of the browser and OS, e.g. "Firefox on Windows"
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	SignedInAt time.Time `json:"signedInAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	// Current is true for the session making the request
+	Current bool `json:"current"`
+}
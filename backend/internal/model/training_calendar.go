@@ -0,0 +1,27 @@
+package model
+
+// TrainingCalendarDay aggregates the workouts started on one local calendar day.
+type TrainingCalendarDay struct {
+	Date            string  `json:"date"` // YYYY-MM-DD in the user's time zone
+	WorkoutCount    int32   `json:"workoutCount"`
+	DurationMinutes int32   `json:"durationMinutes"`
+	Volume          float64 `json:"volume"` // Stored in KG
+}
+
+// TrainingStreaks holds the user's consistency streaks.
+// Daily streaks count consecutive scheduled training days that were trained;
+// weekly streaks count consecutive weeks in which the schedule was met.
+type TrainingStreaks struct {
+	CurrentDaily  int32 `json:"currentDaily"`
+	LongestDaily  int32 `json:"longestDaily"`
+	CurrentWeekly int32 `json:"currentWeekly"`
+	LongestWeekly int32 `json:"longestWeekly"`
+}
+
+// TrainingCalendar is the heatmap data for a single year plus streak information.
+type TrainingCalendar struct {
+	Year     int32                  `json:"year"`
+	Timezone string                 `json:"timezone"`
+	Days     []*TrainingCalendarDay `json:"days"` // Only days with at least one workout, oldest first
+	Streaks  *TrainingStreaks       `json:"streaks"`
+}
@@ -1,31 +1,79 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+// DefaultTimezone is used for users who have not configured one.
+const DefaultTimezone = "UTC"
+
 type User struct {
 	// Use string for ID to match GraphQL and Auto-Bind.
 	// We will handle ObjectID conversion in the repository.
 	ID string `json:"id" bson:"_id,omitempty"`
 
-	Email        string    `json:"email" bson:"email"`
+	Email         string `json:"email" bson:"email"`
+	EmailVerified bool   `json:"emailVerified" bson:"emailVerified"`
+	// PendingEmail replaces Email once the user confirms it through the link sent to it
+	PendingEmail *string   `json:"pendingEmail,omitempty" bson:"pendingEmail"`
 	PasswordHash string    `json:"-" bson:"passwordHash"` // Hide from JSON/GraphQL
 	CreatedAt    time.Time `json:"-" bson:"createdAt"`
 	UpdatedAt    time.Time `json:"-" bson:"updatedAt"`
 
 	// Add other internal fields
 	PreferredUnit WeightUnit `json:"preferredUnit" bson:"preferredUnit"` // e.g., "KILOGRAMS" or "POUNDS"
+	Timezone      string     `json:"timezone" bson:"timezone"`           // IANA name, e.g. "Europe/Madrid"
+	TrainingDays  []Weekday  `json:"trainingDays" bson:"trainingDays"`   // Empty means every day is a training day
+	Locale        *string    `json:"locale,omitempty" bson:"locale"`     // Language for exercise names, e.g. "es"; nil follows the browser
+
+	// Set while the account is waiting to be deleted; it can be cancelled until DeletionScheduledAt
+	DeletionRequestedAt *time.Time `json:"-" bson:"deletionRequestedAt"`
+	DeletionScheduledAt *time.Time `json:"deletionScheduledAt,omitempty" bson:"deletionScheduledAt"`
+	// Set once the purge has claimed the account; from then on the deletion cannot be cancelled
+	DeletionStartedAt *time.Time `json:"-" bson:"deletionStartedAt"`
+
+	// Two-factor authentication. TOTPSecret is set on enrolment but only required once confirmed
+	// (TOTPEnabled); TOTPLastStep is the time step of the last accepted code, so codes cannot be replayed.
+	TOTPEnabled        bool     `json:"totpEnabled" bson:"totpEnabled"`
+	TOTPSecret         string   `json:"-" bson:"totpSecret"`
+	TOTPLastStep       int64    `json:"-" bson:"totpLastStep"`
+	RecoveryCodeHashes []string `json:"-" bson:"recoveryCodeHashes"`
+}
+
+// Location returns the user's configured time zone, falling back to UTC.
+func (u *User) Location() *time.Location {
+	if u.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
 
 // UserUpdateInput represents the fields provided for a user update.
 type UserUpdateInput struct {
 	CurrentPassword *string
+	Email           *string // Becomes the pending email until confirmed; the current email cancels a pending change
 	NewPassword     *string
 	PreferredUnit   *WeightUnit
+	Timezone        *string
+	TrainingDays    []Weekday // nil means unchanged
+	Locale          *string   // Empty clears the setting
 	// ... add any other updatable fields here
+
+	// CurrentSessionID is the session making the change, which stays signed in when the password changes
+	CurrentSessionID string
+}
+
+// NormalizeEmail returns the form emails are stored and looked up in: trimmed and lowercase, so
+// "Jane@Example.com" and "jane@example.com" are the same account.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
 
 // NewUser creates a new internal User model.
@@ -34,10 +82,12 @@ func NewUser(email, hashedPassword string) *User {
 
 	return &User{
 		ID:            bson.NewObjectID().Hex(), // Generate DB ID
-		Email:         email,
+		Email:         NormalizeEmail(email),
 		PasswordHash:  hashedPassword, // Takes the already-hashed password
 		CreatedAt:     now,
 		UpdatedAt:     now,
 		PreferredUnit: WeightUnitKilograms, // Set default value
+		Timezone:      DefaultTimezone,
+		TrainingDays:  []Weekday{},
 	}
 }
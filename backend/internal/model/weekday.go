@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+)
+
+type Weekday string
+
+const (
+	WeekdayMonday    Weekday = "MONDAY"
+	WeekdayTuesday   Weekday = "TUESDAY"
+	WeekdayWednesday Weekday = "WEDNESDAY"
+	WeekdayThursday  Weekday = "THURSDAY"
+	WeekdayFriday    Weekday = "FRIDAY"
+	WeekdaySaturday  Weekday = "SATURDAY"
+	WeekdaySunday    Weekday = "SUNDAY"
+)
+
+var weekdays = map[Weekday]time.Weekday{
+	WeekdayMonday:    time.Monday,
+	WeekdayTuesday:   time.Tuesday,
+	WeekdayWednesday: time.Wednesday,
+	WeekdayThursday:  time.Thursday,
+	WeekdayFriday:    time.Friday,
+	WeekdaySaturday:  time.Saturday,
+	WeekdaySunday:    time.Sunday,
+}
+
+// IsValid reports whether the weekday is known.
+func (d Weekday) IsValid() bool {
+	_, ok := weekdays[d]
+	return ok
+}
+
+// TimeWeekday converts to the standard library representation.
+func (d Weekday) TimeWeekday() time.Weekday {
+	return weekdays[d]
+}
@@ -0,0 +1,97 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"time"
+)
+
+// minKeyRefresh is how often, at most, the signing keys are fetched again for an unknown key ID,
+// so tokens with made-up key IDs cannot make every sign-in call the provider.
+const minKeyRefresh = time.Minute
+
+// jsonWebKeySet is a JWK Set (RFC 7517) as served at the provider's jwks_uri.
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// keySet holds the provider's signing keys by key ID.
+type keySet struct {
+	keys      map[string]any
+	fetchedAt time.Time
+}
+
+// parse returns the set's RSA and P-256 signing keys; others, and keys that do not parse, are skipped.
+func (s jsonWebKeySet) parse() *keySet {
+	keys := &keySet{keys: map[string]any{}, fetchedAt: time.Now()}
+	for _, k := range s.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key := k.publicKey(); key != nil {
+			keys.keys[k.Kid] = key
+		}
+	}
+	return keys
+}
+
+// find returns the key kid or, for a token without a key ID, the only key.
+func (s *keySet) find(kid string) (any, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func (k jsonWebKey) publicKey() any {
+	switch k.Kty {
+	case "RSA":
+		n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+		e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+		if err1 != nil || err2 != nil || len(e) == 0 || len(e) > 4 {
+			return nil
+		}
+		exponent := int(new(big.Int).SetBytes(e).Int64())
+		modulus := new(big.Int).SetBytes(n)
+		if exponent < 3 || modulus.BitLen() < 2048 {
+			return nil
+		}
+		return &rsa.PublicKey{N: modulus, E: exponent}
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil
+		}
+		x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+		y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+		if err1 != nil || err2 != nil || len(x) != 32 || len(y) != 32 {
+			return nil
+		}
+		// Parsing checks that the point is on the curve
+		point := append(append([]byte{4}, x...), y...)
+		key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+		if err != nil {
+			return nil
+		}
+		return key
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverajo/fitness-app/backend/internal/oidc"
+	"github.com/riverajo/fitness-app/backend/internal/oidc/oidctest"
+)
+
+const redirectURL = "https://fitness.example.com/auth/oidc/test/callback"
+
+// authorize follows the provider's authorization redirect, returning the code and state it
+// redirects back with.
+func authorize(t *testing.T, provider *oidc.Provider, state, nonce, verifier string) (string, string) {
+	t.Helper()
+	authURL, err := provider.AuthCodeURL(context.Background(), state, nonce, oidc.CodeChallenge(verifier))
+	require.NoError(t, err)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, redirectURL, location.Scheme+"://"+location.Host+location.Path)
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func newProvider(t *testing.T) (*oidctest.Provider, *oidc.Provider) {
+	stub := oidctest.NewProvider(t, "fitness-app", "secret")
+	return stub, &oidc.Provider{
+		Issuer:       stub.Issuer(),
+		ClientID:     "fitness-app",
+		ClientSecret: "secret",
+		RedirectURL:  redirectURL,
+	}
+}
+
+func TestProvider_Exchange(t *testing.T) {
+	stub, provider := newProvider(t)
+	verifier, err := oidc.RandomString()
+	require.NoError(t, err)
+
+	code, state := authorize(t, provider, "state-1", "nonce-1", verifier)
+	assert.Equal(t, "state-1", state)
+
+	identity, err := provider.Exchange(context.Background(), code, verifier, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, stub.Subject, identity.Subject)
+	assert.Equal(t, stub.Email, identity.Email)
+	assert.True(t, identity.EmailVerified)
+
+	// Codes can only be redeemed once
+	_, err = provider.Exchange(context.Background(), code, verifier, "nonce-1")
+	assert.Error(t, err)
+}
+
+func TestProvider_ExchangeUnverifiedEmail(t *testing.T) {
+	stub, provider := newProvider(t)
+	stub.EmailVerified = false
+
+	code, _ := authorize(t, provider, "state", "nonce", "verifier-verifier-verifier-verifier-verifier")
+	identity, err := provider.Exchange(context.Background(), code, "verifier-verifier-verifier-verifier-verifier", "nonce")
+	require.NoError(t, err)
+	assert.False(t, identity.EmailVerified)
+}
+
+func TestProvider_ExchangeWrongVerifier(t *testing.T) {
+	_, provider := newProvider(t)
+
+	code, _ := authorize(t, provider, "state", "nonce", "verifier-verifier-verifier-verifier-verifier")
+	_, err := provider.Exchange(context.Background(), code, "another-verifier-another-verifier-another", "nonce")
+	assert.Error(t, err)
+}
+
+func TestProvider_ExchangeWrongNonce(t *testing.T) {
+	stub, provider := newProvider(t)
+	stub.Nonce = "replayed-nonce"
+
+	code, _ := authorize(t, provider, "state", "nonce", "verifier-verifier-verifier-verifier-verifier")
+	_, err := provider.Exchange(context.Background(), code, "verifier-verifier-verifier-verifier-verifier", "nonce")
+	assert.True(t, errors.Is(err, oidc.ErrInvalidIDToken), "got %v", err)
+}
+
+func TestProvider_ExchangeWrongClientSecret(t *testing.T) {
+	_, provider := newProvider(t)
+	provider.ClientSecret = "wrong"
+
+	code, _ := authorize(t, provider, "state", "nonce", "verifier-verifier-verifier-verifier-verifier")
+	_, err := provider.Exchange(context.Background(), code, "verifier-verifier-verifier-verifier-verifier", "nonce")
+	assert.ErrorContains(t, err, "invalid_client")
+}
+
+func TestProvider_ExchangeOtherAudience(t *testing.T) {
+	stub, provider := newProvider(t)
+	// A token the provider issued to another client must not sign in to this one
+	stub.Audience = "another-app"
+
+	code, _ := authorize(t, provider, "state", "nonce", "verifier-verifier-verifier-verifier-verifier")
+	_, err := provider.Exchange(context.Background(), code, "verifier-verifier-verifier-verifier-verifier", "nonce")
+	assert.True(t, errors.Is(err, oidc.ErrInvalidIDToken), "got %v", err)
+}
+
+func TestProvider_DiscoveryIssuerMismatch(t *testing.T) {
+	stub, provider := newProvider(t)
+	provider.Issuer = stub.Issuer() + "/other"
+
+	_, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "challenge")
+	assert.Error(t, err)
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// RFC 7636, appendix B
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		oidc.CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+type AccountTombstoneRepository interface {
+	// Record saves the tombstone, replacing an earlier one for the same user, so an interrupted
+	// deletion can be retried.
+	Record(ctx context.Context, tombstone *model.AccountTombstone) error
+	FindByUserID(ctx context.Context, userID string) (*model.AccountTombstone, error)
+}
+
+type MongoAccountTombstoneRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoAccountTombstoneRepository(db *mongo.Database) *MongoAccountTombstoneRepository {
+	return &MongoAccountTombstoneRepository{
+		collection: db.Collection("account_tombstones"),
+	}
+}
+
+func (r *MongoAccountTombstoneRepository) Record(ctx context.Context, tombstone *model.AccountTombstone) error {
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": tombstone.UserID}, tombstone, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to record account tombstone: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoAccountTombstoneRepository) FindByUserID(ctx context.Context, userID string) (*model.AccountTombstone, error) {
+	var tombstone model.AccountTombstone
+	err := r.collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&tombstone)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find account tombstone: %w", err)
+	}
+	return &tombstone, nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+func TestMongoAccountTombstoneRepository(t *testing.T) {
+	cleanupCollection(t, "account_tombstones")
+	repo := NewMongoAccountTombstoneRepository(testDB)
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	tombstone := &model.AccountTombstone{
+		UserID:              bson.NewObjectID().Hex(),
+		AccountCreatedAt:    now.Add(-24 * time.Hour),
+		DeletionRequestedAt: now.Add(-time.Hour),
+		DeletedAt:           now,
+		Deleted:             map[string]int64{},
+	}
+	require.NoError(t, repo.Record(ctx, tombstone))
+
+	// Recording again replaces the earlier tombstone
+	tombstone.Deleted = map[string]int64{"workouts": 3}
+	require.NoError(t, repo.Record(ctx, tombstone))
+
+	found, err := repo.FindByUserID(ctx, tombstone.UserID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.True(t, tombstone.DeletedAt.Equal(found.DeletedAt))
+	assert.Equal(t, map[string]int64{"workouts": 3}, found.Deleted)
+
+	found, err = repo.FindByUserID(ctx, bson.NewObjectID().Hex())
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
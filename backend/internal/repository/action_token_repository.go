@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+type ActionTokenRepository interface {
+	Create(ctx context.Context, token *model.ActionToken) error
+	FindByID(ctx context.Context, id string) (*model.ActionToken, error)
+	// Consume deletes the token, reporting false if it was already used, so that of two
+	// concurrent uses only one succeeds.
+	Consume(ctx context.Context, id string) (bool, error)
+	// DeleteForUser deletes the user's outstanding tokens for a purpose.
+	DeleteForUser(ctx context.Context, userID string, purpose model.ActionTokenPurpose) error
+	// DeleteAllByUser deletes every token of the user, for account deletion.
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+}
+
+type MongoActionTokenRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoActionTokenRepository(db *mongo.Database) *MongoActionTokenRepository {
+	return &MongoActionTokenRepository{
+		collection: db.Collection("actiontokens"),
+	}
+}
+
+// EnsureIndexes creates the TTL index that deletes expired tokens and the per-user index used to replace them.
+func (r *MongoActionTokenRepository) EnsureIndexes(ctx context.Context) error {
+	return actionTokenIndexes.ensure(ctx, r.collection)
+}
+
+// MissingIndexes lists the declared indexes that EnsureIndexes would create.
+func (r *MongoActionTokenRepository) MissingIndexes(ctx context.Context) ([]string, error) {
+	return actionTokenIndexes.missing(ctx, r.collection)
+}
+
+func (r *MongoActionTokenRepository) Create(ctx context.Context, token *model.ActionToken) error {
+	if token.ID == "" {
+		token.ID = bson.NewObjectID().Hex()
+	}
+	_, err := r.collection.InsertOne(ctx, token)
+	if err != nil {
+		return fmt.Errorf("failed to create action token: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoActionTokenRepository) FindByID(ctx context.Context, id string) (*model.ActionToken, error) {
+	var token model.ActionToken
+	err := r.collection.FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find action token: %w", err)
+	}
+	return &token, nil
+}
+
+func (r *MongoActionTokenRepository) Consume(ctx context.Context, id string) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: id}})
+	if err != nil {
+		return false, fmt.Errorf("failed to consume action token: %w", err)
+	}
+	return result.DeletedCount == 1, nil
+}
+
+func (r *MongoActionTokenRepository) DeleteForUser(ctx context.Context, userID string, purpose model.ActionTokenPurpose) error {
+	_, err := r.collection.DeleteMany(ctx, bson.D{{Key: "userId", Value: userID}, {Key: "purpose", Value: purpose}})
+	if err != nil {
+		return fmt.Errorf("failed to delete action tokens: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoActionTokenRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.D{{Key: "userId", Value: userID}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete user's action tokens: %w", err)
+	}
+	return result.DeletedCount, nil
+}
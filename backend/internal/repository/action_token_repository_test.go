@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+func TestMongoActionTokenRepository(t *testing.T) {
+	cleanupCollection(t, "actiontokens")
+	repo := NewMongoActionTokenRepository(testDB)
+	ctx := context.Background()
+	require.NoError(t, repo.EnsureIndexes(ctx))
+
+	userID := bson.NewObjectID().Hex()
+	newToken := func(purpose model.ActionTokenPurpose) *model.ActionToken {
+		return &model.ActionToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			Email:     "jane@example.com",
+			TokenHash: "hash",
+			ExpiresAt: time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond),
+			CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+		}
+	}
+
+	reset := newToken(model.ActionTokenPasswordReset)
+	require.NoError(t, repo.Create(ctx, reset))
+	require.NotEmpty(t, reset.ID)
+	verification := newToken(model.ActionTokenEmailVerification)
+	require.NoError(t, repo.Create(ctx, verification))
+
+	found, err := repo.FindByID(ctx, reset.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, model.ActionTokenPasswordReset, found.Purpose)
+	assert.Equal(t, "jane@example.com", found.Email)
+	assert.True(t, reset.ExpiresAt.Equal(found.ExpiresAt))
+
+	// Only the first use succeeds
+	consumed, err := repo.Consume(ctx, reset.ID)
+	require.NoError(t, err)
+	assert.True(t, consumed)
+	consumed, err = repo.Consume(ctx, reset.ID)
+	require.NoError(t, err)
+	assert.False(t, consumed)
+	found, err = repo.FindByID(ctx, reset.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+
+	// DeleteForUser only deletes the given purpose
+	another := newToken(model.ActionTokenPasswordReset)
+	require.NoError(t, repo.Create(ctx, another))
+	require.NoError(t, repo.DeleteForUser(ctx, userID, model.ActionTokenPasswordReset))
+	found, err = repo.FindByID(ctx, another.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+	found, err = repo.FindByID(ctx, verification.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, found)
+}
+
+func TestMongoActionTokenRepository_DeleteAllByUser(t *testing.T) {
+	cleanupCollection(t, "actiontokens")
+	repo := NewMongoActionTokenRepository(testDB)
+	ctx := context.Background()
+
+	userID := bson.NewObjectID().Hex()
+	for _, purpose := range []model.ActionTokenPurpose{model.ActionTokenPasswordReset, model.ActionTokenEmailVerification} {
+		require.NoError(t, repo.Create(ctx, &model.ActionToken{UserID: userID, Purpose: purpose, TokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}))
+	}
+	require.NoError(t, repo.Create(ctx, &model.ActionToken{UserID: bson.NewObjectID().Hex(), Purpose: model.ActionTokenPasswordReset, TokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}))
+
+	deleted, err := repo.DeleteAllByUser(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+// BodyMetricRepository defines the interface for body metric data access.
+type BodyMetricRepository interface {
+	Create(ctx context.Context, metric *model.BodyMetric) error
+	GetByID(ctx context.Context, id string) (*model.BodyMetric, error)
+	// ListByUser returns entries recorded within [from, to] (either bound optional), newest first.
+	ListByUser(ctx context.Context, userID string, from, to *time.Time, limit, offset int) ([]*model.BodyMetric, error)
+	Update(ctx context.Context, metric *model.BodyMetric) error
+	Delete(ctx context.Context, userID, id string) error
+	// DeleteAllByUser deletes every entry of the user, for account deletion.
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+	// FindLatestWeightBefore returns the most recent entry with a bodyweight recorded at or before the given time.
+	FindLatestWeightBefore(ctx context.Context, userID string, at time.Time) (*model.BodyMetric, error)
+	// FindLatestWeightsBefore looks up FindLatestWeightBefore for each key (nil if there is no
+	// weigh-in yet) in a single query. Results are aligned with keys.
+	FindLatestWeightsBefore(ctx context.Context, keys []model.BodyweightKey) ([]*model.BodyMetric, error)
+}
@@ -2,12 +2,38 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/riverajo/fitness-app/backend/internal/model"
 )
 
+// ErrDuplicateExercise is returned when the user already has an exercise with the same normalized name.
+var ErrDuplicateExercise = errors.New("an exercise with this name already exists")
+
 type ExerciseRepository interface {
 	Create(ctx context.Context, exercise *model.UniqueExercise) error
-	Search(ctx context.Context, userID *string, query string, limit int, offset int) ([]*model.UniqueExercise, error)
+	// DeleteAllByUser deletes every custom exercise of the user, for account deletion.
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+	Search(ctx context.Context, userID *string, query string, filter model.ExerciseFilter, limit int, offset int) ([]*model.UniqueExercise, error)
 	FindByID(ctx context.Context, id string) (*model.UniqueExercise, error)
+	// FindByIDs returns the exercises with the given IDs in a single query, in no particular order.
+	// IDs that do not exist (or are not valid IDs) are skipped.
+	FindByIDs(ctx context.Context, ids []string) ([]*model.UniqueExercise, error)
+	// FindByName returns the user's custom exercise (or, for a nil user, the system exercise) whose
+	// normalized name matches, including archived ones. It returns nil if there is none.
+	FindByName(ctx context.Context, userID *string, name string) (*model.UniqueExercise, error)
+	// ListVisible returns every system exercise plus the user's own custom exercises that match the filter.
+	ListVisible(ctx context.Context, userID *string, filter model.ExerciseFilter) ([]*model.UniqueExercise, error)
+	// ListSystem returns every system exercise, including archived ones, sorted by name.
+	ListSystem(ctx context.Context) ([]*model.UniqueExercise, error)
+	// ListCustom returns the user's own custom exercises that match the filter, excluding archived ones.
+	ListCustom(ctx context.Context, userID string, filter model.ExerciseFilter) ([]*model.UniqueExercise, error)
+	// SystemExercisesVersion returns the seeded catalogue version, which changes whenever system exercises do.
+	SystemExercisesVersion(ctx context.Context) (int, error)
+	// Update saves the editable fields of one of the user's custom exercises.
+	Update(ctx context.Context, exercise *model.UniqueExercise) error
+	// SetArchived archives (archivedAt set) or restores (nil) one of the user's custom exercises.
+	SetArchived(ctx context.Context, userID, id string, archivedAt *time.Time) error
+	Delete(ctx context.Context, userID, id string) error
 }
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+var ErrDuplicateExternalIdentity = errors.New("this account is already linked")
+
+type ExternalIdentityRepository interface {
+	// Create saves the identity, failing with ErrDuplicateExternalIdentity if the provider's
+	// account is already linked.
+	Create(ctx context.Context, identity *model.ExternalIdentity) error
+	FindByProviderSubject(ctx context.Context, provider, subject string) (*model.ExternalIdentity, error)
+	// RecordUse saves the time of a sign-in.
+	RecordUse(ctx context.Context, id string, at time.Time) error
+	// DeleteAllByUser deletes every identity linked to the user, for account deletion.
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+}
+
+type MongoExternalIdentityRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoExternalIdentityRepository(db *mongo.Database) *MongoExternalIdentityRepository {
+	return &MongoExternalIdentityRepository{
+		collection: db.Collection("external_identities"),
+	}
+}
+
+// EnsureIndexes creates the unique index on provider accounts, used to sign in, and the per-user index.
+func (r *MongoExternalIdentityRepository) EnsureIndexes(ctx context.Context) error {
+	return externalIdentityIndexes.ensure(ctx, r.collection)
+}
+
+// MissingIndexes lists the declared indexes that EnsureIndexes would create.
+func (r *MongoExternalIdentityRepository) MissingIndexes(ctx context.Context) ([]string, error) {
+	return externalIdentityIndexes.missing(ctx, r.collection)
+}
+
+func (r *MongoExternalIdentityRepository) Create(ctx context.Context, identity *model.ExternalIdentity) error {
+	if identity.ID == "" {
+		identity.ID = bson.NewObjectID().Hex()
+	}
+	_, err := r.collection.InsertOne(ctx, identity)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateExternalIdentity
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create external identity: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoExternalIdentityRepository) FindByProviderSubject(ctx context.Context, provider, subject string) (*model.ExternalIdentity, error) {
+	var identity model.ExternalIdentity
+	err := r.collection.FindOne(ctx, bson.M{"provider": provider, "subject": subject}).Decode(&identity)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find external identity: %w", err)
+	}
+	return &identity, nil
+}
+
+func (r *MongoExternalIdentityRepository) RecordUse(ctx context.Context, id string, at time.Time) error {
+	_, err := r.collection.UpdateByID(ctx, id, bson.M{"$set": bson.M{"lastUsedAt": at}})
+	if err != nil {
+		return fmt.Errorf("failed to record external identity use: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoExternalIdentityRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete user's external identities: %w", err)
+	}
+	return result.DeletedCount, nil
+}
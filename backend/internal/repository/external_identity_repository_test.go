@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+func TestMongoExternalIdentityRepository(t *testing.T) {
+	cleanupCollection(t, "external_identities")
+	repo := NewMongoExternalIdentityRepository(testDB)
+	ctx := context.Background()
+	require.NoError(t, repo.EnsureIndexes(ctx))
+
+	userID := bson.NewObjectID().Hex()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	newIdentity := func(provider, subject string) *model.ExternalIdentity {
+		return &model.ExternalIdentity{
+			UserID:    userID,
+			Provider:  provider,
+			Subject:   subject,
+			Email:     "user@example.com",
+			CreatedAt: now,
+		}
+	}
+
+	google := newIdentity("google", "1234")
+	require.NoError(t, repo.Create(ctx, google))
+	require.NotEmpty(t, google.ID)
+	// The same subject at another provider is another account
+	require.NoError(t, repo.Create(ctx, newIdentity("gitlab", "1234")))
+	assert.ErrorIs(t, repo.Create(ctx, newIdentity("google", "1234")), ErrDuplicateExternalIdentity)
+
+	found, err := repo.FindByProviderSubject(ctx, "google", "1234")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, google.ID, found.ID)
+	assert.Equal(t, userID, found.UserID)
+	assert.Nil(t, found.LastUsedAt)
+
+	found, err = repo.FindByProviderSubject(ctx, "google", "5678")
+	require.NoError(t, err)
+	assert.Nil(t, found)
+
+	require.NoError(t, repo.RecordUse(ctx, google.ID, now))
+	found, err = repo.FindByProviderSubject(ctx, "google", "1234")
+	require.NoError(t, err)
+	require.NotNil(t, found.LastUsedAt)
+	assert.True(t, now.Equal(*found.LastUsedAt))
+
+	count, err := repo.DeleteAllByUser(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+// GoalRepository defines the interface for goal data access.
+type GoalRepository interface {
+	Create(ctx context.Context, goal *model.Goal) error
+	GetByID(ctx context.Context, id string) (*model.Goal, error)
+	ListByUser(ctx context.Context, userID string) ([]*model.Goal, error)
+	Update(ctx context.Context, goal *model.Goal) error
+	Delete(ctx context.Context, userID, id string) error
+	// DeleteAllByUser deletes every goal of the user, for account deletion.
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+	// CountByExercise returns how many of the user's goals track the exercise.
+	CountByExercise(ctx context.Context, userID, exerciseID string) (int64, error)
+	// ReplaceExercise points the user's goals tracking sourceID at targetID.
+	ReplaceExercise(ctx context.Context, userID, sourceID, targetID string) (int64, error)
+	// DeleteByExercise deletes the user's goals tracking the exercise.
+	DeleteByExercise(ctx context.Context, userID, exerciseID string) (int64, error)
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Index declarations for each repository's collection. They are created by EnsureIndexes, which
+// the migration runner calls after applying migrations (at startup and from cmd/migrate).
+//
+// Indexes are left to take MongoDB's default names (e.g. "userId_1_startTime_-1"), so indexes
+// created before they were declared here are recognised rather than duplicated. Keys must be
+// a bson.D to keep their order.
+var (
+	userIndexes = indexSet{
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+		// Accounts waiting to be deleted
+		{
+			Keys:    bson.D{{Key: "deletionScheduledAt", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"deletionScheduledAt": bson.M{"$type": "date"}}),
+		},
+	}
+	workoutIndexes = indexSet{
+		// Workout lists and calendar ranges
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "startTime", Value: -1}}},
+		// Per-exercise history, newest first
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "exerciseLogs.uniqueExerciseId", Value: 1}, {Key: "startTime", Value: -1}}},
+	}
+	exerciseIndexes = indexSet{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "name", Value: 1}}},
+		// Name and alias prefix searches
+		{Keys: bson.D{{Key: "normalizedName", Value: 1}}},
+		{Keys: bson.D{{Key: "normalizedAliases", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}, {Key: "normalizedName", Value: 1}},
+			// Only custom exercises; system exercise names are curated in the seed data
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"userId": bson.M{"$type": "string"}}),
+		},
+	}
+	refreshTokenIndexes = indexSet{
+		// Expired tokens are deleted by MongoDB
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+		{Keys: bson.D{{Key: "familyId", Value: 1}}},
+	}
+	actionTokenIndexes = indexSet{
+		// Expired tokens are deleted by MongoDB
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "purpose", Value: 1}}},
+	}
+	bodyMetricIndexes = indexSet{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "recordedAt", Value: -1}}},
+	}
+	rateLimitIndexes = indexSet{
+		// States are deleted once they can no longer delay an attempt
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}
+	leaseIndexes = indexSet{
+		// Leases of crashed replicas expire; the same index the migration runner and seeder create
+		{Keys: bson.D{{Key: "createdAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(LeaseTTL.Seconds()))},
+	}
+	goalIndexes = indexSet{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	}
+	passkeyIndexes = indexSet{
+		// Sign-ins look passkeys up by credential, which may only be registered once
+		{Keys: bson.D{{Key: "credentialId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: 1}}},
+	}
+	externalIdentityIndexes = indexSet{
+		// Sign-ins look identities up by provider account, which may only be linked once
+		{Keys: bson.D{{Key: "provider", Value: 1}, {Key: "subject", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+	}
+)
+
+// indexSet declares the indexes of one collection.
+type indexSet []mongo.IndexModel
+
+// ensure creates the indexes that do not exist yet. It fails if an existing index has the same
+// keys but different options, or if the data violates a unique index.
+func (s indexSet) ensure(ctx context.Context, coll *mongo.Collection) error {
+	_, err := coll.Indexes().CreateMany(ctx, s)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to create %s indexes: some documents share a value that a unique index requires to be distinct; remove the duplicates and restart: %w", coll.Name(), err)
+	} else if err != nil {
+		return fmt.Errorf("failed to create %s indexes: %w", coll.Name(), err)
+	}
+	return nil
+}
+
+// missing returns the names of the declared indexes that do not exist yet, as "collection.index".
+func (s indexSet) missing(ctx context.Context, coll *mongo.Collection) ([]string, error) {
+	specs, err := coll.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s indexes: %w", coll.Name(), err)
+	}
+	existing := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		existing[spec.Name] = true
+	}
+
+	var missing []string
+	for _, index := range s {
+		name := indexName(index.Keys.(bson.D))
+		if !existing[name] {
+			missing = append(missing, coll.Name()+"."+name)
+		}
+	}
+	return missing, nil
+}
+
+// indexName returns the name MongoDB gives an index with these keys.
+func indexName(keys bson.D) string {
+	parts := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		parts = append(parts, key.Key, fmt.Sprint(key.Value))
+	}
+	return strings.Join(parts, "_")
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestIndexName(t *testing.T) {
+	assert.Equal(t, "email_1", indexName(bson.D{{Key: "email", Value: 1}}))
+	assert.Equal(t, "userId_1_exerciseLogs.uniqueExerciseId_1_startTime_-1", indexName(bson.D{
+		{Key: "userId", Value: 1}, {Key: "exerciseLogs.uniqueExerciseId", Value: 1}, {Key: "startTime", Value: -1},
+	}))
+}
+
+func TestEnsureIndexes(t *testing.T) {
+	ctx := context.Background()
+
+	type indexedRepository interface {
+		EnsureIndexes(ctx context.Context) error
+		MissingIndexes(ctx context.Context) ([]string, error)
+	}
+	repos := map[string]indexedRepository{
+		"users":               NewMongoUserRepository(testDB),
+		"workout_logs":        NewMongoWorkoutRepository(testDB),
+		"unique_exercises":    NewMongoExerciseRepository(testDB),
+		"refreshtokens":       NewMongoRefreshTokenRepository(testDB),
+		"body_metrics":        NewMongoBodyMetricRepository(testDB),
+		"goals":               NewMongoGoalRepository(testDB),
+		"passkeys":            NewMongoPasskeyRepository(testDB),
+		"external_identities": NewMongoExternalIdentityRepository(testDB),
+	}
+
+	for collection, repo := range repos {
+		t.Run(collection, func(t *testing.T) {
+			cleanupCollection(t, collection)
+
+			missing, err := repo.MissingIndexes(ctx)
+			require.NoError(t, err)
+			assert.NotEmpty(t, missing)
+			for _, name := range missing {
+				assert.Contains(t, name, collection+".")
+			}
+
+			require.NoError(t, repo.EnsureIndexes(ctx))
+			missing, err = repo.MissingIndexes(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, missing)
+
+			// Idempotent
+			require.NoError(t, repo.EnsureIndexes(ctx))
+		})
+	}
+
+	t.Run("adopts indexes created before they were declared", func(t *testing.T) {
+		cleanupCollection(t, "workout_logs")
+		repo := NewMongoWorkoutRepository(testDB)
+
+		_, err := testDB.Collection("workout_logs").Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{{Key: "userId", Value: 1}, {Key: "startTime", Value: -1}},
+		})
+		require.NoError(t, err)
+
+		missing, err := repo.MissingIndexes(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"workout_logs.userId_1_exerciseLogs.uniqueExerciseId_1_startTime_-1"}, missing)
+		require.NoError(t, repo.EnsureIndexes(ctx))
+	})
+}
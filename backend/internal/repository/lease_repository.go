@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// LeaseTTL is how long a lease lasts unless its owner acquires it again. It matches the TTL
+// index on system_locks, which the migration runner and seeder also use.
+const LeaseTTL = 60 * time.Second
+
+// LeaseRepository grants named leases, so that only one replica at a time runs a background job.
+type LeaseRepository interface {
+	// Acquire takes the named lease for owner, or extends it if owner already holds it, and
+	// reports false if another owner holds it.
+	Acquire(ctx context.Context, name, owner string) (bool, error)
+	// Release gives up the lease if owner still holds it.
+	Release(ctx context.Context, name, owner string) error
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMongoLeaseRepository(t *testing.T) {
+	cleanupCollection(t, "system_locks")
+	repo := NewMongoLeaseRepository(testDB)
+	require.NoError(t, repo.EnsureIndexes(context.Background()))
+	testLeaseRepository(t, repo)
+}
+
+func TestMemoryLeaseRepository(t *testing.T) {
+	testLeaseRepository(t, NewMemoryLeaseRepository())
+
+	t.Run("Expired leases can be taken over", func(t *testing.T) {
+		ctx := context.Background()
+		now := time.Now()
+		repo := NewMemoryLeaseRepository()
+		repo.now = func() time.Time { return now }
+
+		acquired, err := repo.Acquire(ctx, "purge", "replica-a")
+		require.NoError(t, err)
+		require.True(t, acquired)
+
+		now = now.Add(LeaseTTL)
+		acquired, err = repo.Acquire(ctx, "purge", "replica-b")
+		require.NoError(t, err)
+		assert.True(t, acquired)
+	})
+}
+
+// testLeaseRepository checks the behaviour both implementations must share.
+func testLeaseRepository(t *testing.T, repo LeaseRepository) {
+	ctx := context.Background()
+
+	acquired, err := repo.Acquire(ctx, "purge", "replica-a")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// The holder can extend its lease, while others must wait
+	acquired, err = repo.Acquire(ctx, "purge", "replica-a")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	acquired, err = repo.Acquire(ctx, "purge", "replica-b")
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	// Leases are independent
+	acquired, err = repo.Acquire(ctx, "other", "replica-b")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// Only the holder can release a lease
+	require.NoError(t, repo.Release(ctx, "purge", "replica-b"))
+	acquired, err = repo.Acquire(ctx, "purge", "replica-b")
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	require.NoError(t, repo.Release(ctx, "purge", "replica-a"))
+	acquired, err = repo.Acquire(ctx, "purge", "replica-b")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"log"
+	"net/url"
 	"os"
 	"testing"
 	"time"
@@ -51,17 +52,28 @@ func getMyNetworkName(ctx context.Context, containerID string) string {
 }
 
 func getMongoURI(ctx context.Context, container *mongodb.MongoDBContainer, networkName string) string {
+	// The container runs a single-node replica set (needed for transactions). Connect directly
+	// to it, since the member's advertised host isn't reachable through the mapped port.
+
 	// If we are in a shared network (CI), use the internal alias and port
 	if networkName != "" && networkName != "bridge" {
-		return "mongodb://mongodb_repo:27017/fitness_db?authSource=admin"
+		return "mongodb://mongodb_repo:27017/fitness_db?authSource=admin&directConnection=true"
 	}
 
 	// Otherwise (Local Dev), use the official helper
 	uri, err := container.ConnectionString(ctx)
 	if err != nil {
-		return "mongodb://localhost:27017/fitness_db?authSource=admin"
+		return "mongodb://localhost:27017/fitness_db?authSource=admin&directConnection=true"
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return uri
 	}
-	return uri
+	query := parsed.Query()
+	query.Del("replicaSet")
+	query.Set("directConnection", "true")
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
 }
 
 var testDB *mongo.Database
@@ -84,6 +96,7 @@ func TestMain(m *testing.M) {
 			),
 		)
 	}
+	opts = append(opts, mongodb.WithReplicaSet("rs0"))
 	mongodbContainer, err := mongodb.Run(ctx, "mongo:6", opts...)
 	if err != nil {
 		log.Fatalf("failed to start container: %s", err)
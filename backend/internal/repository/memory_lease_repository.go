@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryLeaseRepository keeps leases in process memory. Leases are not shared between replicas,
+// so it is only suitable for tests and single-instance development.
+type MemoryLeaseRepository struct {
+	mu     sync.Mutex
+	leases map[string]memoryLease
+	now    func() time.Time
+}
+
+type memoryLease struct {
+	owner      string
+	acquiredAt time.Time
+}
+
+func NewMemoryLeaseRepository() *MemoryLeaseRepository {
+	return &MemoryLeaseRepository{leases: make(map[string]memoryLease), now: time.Now}
+}
+
+func (r *MemoryLeaseRepository) Acquire(ctx context.Context, name, owner string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	lease, ok := r.leases[name]
+	if ok && lease.owner != owner && now.Sub(lease.acquiredAt) < LeaseTTL {
+		return false, nil
+	}
+	r.leases[name] = memoryLease{owner: owner, acquiredAt: now}
+	return true, nil
+}
+
+func (r *MemoryLeaseRepository) Release(ctx context.Context, name, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if lease, ok := r.leases[name]; ok && lease.owner == owner {
+		delete(r.leases, name)
+	}
+	return nil
+}
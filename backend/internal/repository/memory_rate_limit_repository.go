@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+// MemoryRateLimitRepository keeps rate limits in process memory. Limits are not shared between
+// replicas, so it is only suitable for tests and single-instance development.
+type MemoryRateLimitRepository struct {
+	mu     sync.Mutex
+	states map[string]model.RateLimitState
+}
+
+func NewMemoryRateLimitRepository() *MemoryRateLimitRepository {
+	return &MemoryRateLimitRepository{states: make(map[string]model.RateLimitState)}
+}
+
+func (r *MemoryRateLimitRepository) Get(ctx context.Context, key string) (*model.RateLimitState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.states[key]
+	if !ok {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+func (r *MemoryRateLimitRepository) RecordFailure(ctx context.Context, key string, at time.Time, resetAfter time.Duration, expiresAt time.Time) (*model.RateLimitState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.states[key]
+	if !ok || state.LastFailureAt.Before(at.Add(-resetAfter)) {
+		state = model.RateLimitState{Key: key}
+	}
+	state.Failures++
+	state.LastFailureAt = at
+	state.ExpiresAt = expiresAt
+	r.states[key] = state
+	return &state, nil
+}
+
+func (r *MemoryRateLimitRepository) RecordAttempt(ctx context.Context, key string, seen *model.RateLimitState, failures int, at time.Time, expiresAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.states[key]
+	if seen == nil && ok {
+		return false, nil
+	}
+	if seen != nil && (!ok || state.Failures != seen.Failures || !state.LastFailureAt.Equal(seen.LastFailureAt)) {
+		return false, nil
+	}
+	r.states[key] = model.RateLimitState{Key: key, Failures: failures, LastFailureAt: at, ExpiresAt: expiresAt}
+	return true, nil
+}
+
+func (r *MemoryRateLimitRepository) Forgive(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if state, ok := r.states[key]; ok && state.Failures > 0 {
+		state.Failures--
+		r.states[key] = state
+	}
+	return nil
+}
+
+func (r *MemoryRateLimitRepository) Reset(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.states, key)
+	return nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/riverajo/fitness-app/backend/internal/model"
 	"github.com/stretchr/testify/mock"
@@ -33,3 +34,31 @@ func (m *MockRefreshTokenRepository) RevokeAllForUser(ctx context.Context, userI
 	args := m.Called(ctx, userID)
 	return args.Error(0)
 }
+
+func (m *MockRefreshTokenRepository) MarkRotated(ctx context.Context, id string, at time.Time) (bool, error) {
+	args := m.Called(ctx, id, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) (int64, error) {
+	args := m.Called(ctx, familyID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) ListActiveByUser(ctx context.Context, userID string, now time.Time) ([]*model.RefreshToken, error) {
+	args := m.Called(ctx, userID, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) RevokeFamilyForUser(ctx context.Context, userID, familyID string) (int64, error) {
+	args := m.Called(ctx, userID, familyID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) RevokeAllForUserExcept(ctx context.Context, userID, keepFamilyID string) (int64, error) {
+	args := m.Called(ctx, userID, keepFamilyID)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/riverajo/fitness-app/backend/internal/model"
 	"github.com/stretchr/testify/mock"
@@ -38,11 +39,64 @@ func (m *MockUserRepository) Update(ctx context.Context, user *model.User) error
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) SetTOTPSecret(ctx context.Context, id, secret string) (bool, error) {
+	args := m.Called(ctx, id, secret)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) EnableTwoFactor(ctx context.Context, id, secret string, recoveryCodeHashes []string) (bool, error) {
+	args := m.Called(ctx, id, secret, recoveryCodeHashes)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) SetRecoveryCodes(ctx context.Context, id string, recoveryCodeHashes []string) (bool, error) {
+	args := m.Called(ctx, id, recoveryCodeHashes)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) DisableTwoFactor(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UseTOTPStep(ctx context.Context, id, secret string, step int64) (bool, error) {
+	args := m.Called(ctx, id, secret, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) UseRecoveryCode(ctx context.Context, id, hash string) (bool, error) {
+	args := m.Called(ctx, id, hash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ClaimDueForDeletion(ctx context.Context, now, staleBefore time.Time) (*model.User, error) {
+	args := m.Called(ctx, now, staleBefore)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
+func (m *MockUserRepository) CancelDeletion(ctx context.Context, id string) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
 // MockWorkoutRepository is a mock implementation of WorkoutRepository
 type MockWorkoutRepository struct {
 	mock.Mock
 }
 
+func (m *MockWorkoutRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (m *MockWorkoutRepository) Create(ctx context.Context, log model.WorkoutLog) (*model.WorkoutLog, error) {
 	args := m.Called(ctx, log)
 	if args.Get(0) == nil {
@@ -67,6 +121,90 @@ func (m *MockWorkoutRepository) ListByUser(ctx context.Context, userID string, l
 	return args.Get(0).([]*model.WorkoutLog), args.Error(1)
 }
 
+func (m *MockWorkoutRepository) ListByUserInRange(ctx context.Context, userID string, from, to time.Time) ([]*model.WorkoutLog, error) {
+	args := m.Called(ctx, userID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.WorkoutLog), args.Error(1)
+}
+
+func (m *MockWorkoutRepository) CountExerciseUsage(ctx context.Context, userID string, since time.Time) (map[string]int, error) {
+	args := m.Called(ctx, userID, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int), args.Error(1)
+}
+
+func (m *MockWorkoutRepository) CountByExercise(ctx context.Context, userID, exerciseID string) (int64, error) {
+	args := m.Called(ctx, userID, exerciseID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockWorkoutRepository) ListExerciseSessions(ctx context.Context, userID, exerciseID string, after *model.SessionCursor, limit int) ([]*model.ExerciseSession, error) {
+	args := m.Called(ctx, userID, exerciseID, after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.ExerciseSession), args.Error(1)
+}
+
+func (m *MockWorkoutRepository) FindPreviousSessions(ctx context.Context, keys []model.PreviousSessionKey) ([]*model.ExerciseSession, error) {
+	args := m.Called(ctx, keys)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.ExerciseSession), args.Error(1)
+}
+
+func (m *MockWorkoutRepository) SummarizeDays(ctx context.Context, userID string, from, to time.Time, timezone string) ([]*model.TrainingCalendarDay, error) {
+	args := m.Called(ctx, userID, from, to, timezone)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.TrainingCalendarDay), args.Error(1)
+}
+
+func (m *MockWorkoutRepository) CountByUserInRange(ctx context.Context, userID string, from, to time.Time) (int64, error) {
+	args := m.Called(ctx, userID, from, to)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockWorkoutRepository) ListStartTimes(ctx context.Context, userID string, to time.Time) ([]time.Time, error) {
+	args := m.Called(ctx, userID, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]time.Time), args.Error(1)
+}
+
+func (m *MockWorkoutRepository) BestLift(ctx context.Context, userID, exerciseID string, minReps int32, to time.Time) (*float64, error) {
+	args := m.Called(ctx, userID, exerciseID, minReps, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*float64), args.Error(1)
+}
+
+func (m *MockWorkoutRepository) SummarizeExercise(ctx context.Context, userID, exerciseID string) (*model.ExerciseHistorySummary, error) {
+	args := m.Called(ctx, userID, exerciseID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ExerciseHistorySummary), args.Error(1)
+}
+
+func (m *MockWorkoutRepository) ReplaceExercise(ctx context.Context, userID, sourceID, targetID string) (int64, error) {
+	args := m.Called(ctx, userID, sourceID, targetID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockWorkoutRepository) RemoveExercise(ctx context.Context, userID, exerciseID string) (int64, error) {
+	args := m.Called(ctx, userID, exerciseID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (m *MockWorkoutRepository) Update(ctx context.Context, log model.WorkoutLog) (*model.WorkoutLog, error) {
 	args := m.Called(ctx, log)
 	if args.Get(0) == nil {
@@ -80,13 +218,18 @@ type MockExerciseRepository struct {
 	mock.Mock
 }
 
+func (m *MockExerciseRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (m *MockExerciseRepository) Create(ctx context.Context, exercise *model.UniqueExercise) error {
 	args := m.Called(ctx, exercise)
 	return args.Error(0)
 }
 
-func (m *MockExerciseRepository) Search(ctx context.Context, userID *string, query string, limit int, offset int) ([]*model.UniqueExercise, error) {
-	args := m.Called(ctx, userID, query, limit, offset)
+func (m *MockExerciseRepository) Search(ctx context.Context, userID *string, query string, filter model.ExerciseFilter, limit int, offset int) ([]*model.UniqueExercise, error) {
+	args := m.Called(ctx, userID, query, filter, limit, offset)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -100,3 +243,303 @@ func (m *MockExerciseRepository) FindByID(ctx context.Context, id string) (*mode
 	}
 	return args.Get(0).(*model.UniqueExercise), args.Error(1)
 }
+
+func (m *MockExerciseRepository) FindByIDs(ctx context.Context, ids []string) ([]*model.UniqueExercise, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.UniqueExercise), args.Error(1)
+}
+
+func (m *MockExerciseRepository) ListSystem(ctx context.Context) ([]*model.UniqueExercise, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.UniqueExercise), args.Error(1)
+}
+
+func (m *MockExerciseRepository) ListCustom(ctx context.Context, userID string, filter model.ExerciseFilter) ([]*model.UniqueExercise, error) {
+	args := m.Called(ctx, userID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.UniqueExercise), args.Error(1)
+}
+
+func (m *MockExerciseRepository) SystemExercisesVersion(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockExerciseRepository) FindByName(ctx context.Context, userID *string, name string) (*model.UniqueExercise, error) {
+	args := m.Called(ctx, userID, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.UniqueExercise), args.Error(1)
+}
+
+func (m *MockExerciseRepository) ListVisible(ctx context.Context, userID *string, filter model.ExerciseFilter) ([]*model.UniqueExercise, error) {
+	args := m.Called(ctx, userID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.UniqueExercise), args.Error(1)
+}
+
+func (m *MockExerciseRepository) Update(ctx context.Context, exercise *model.UniqueExercise) error {
+	args := m.Called(ctx, exercise)
+	return args.Error(0)
+}
+
+func (m *MockExerciseRepository) SetArchived(ctx context.Context, userID, id string, archivedAt *time.Time) error {
+	args := m.Called(ctx, userID, id, archivedAt)
+	return args.Error(0)
+}
+
+func (m *MockExerciseRepository) Delete(ctx context.Context, userID, id string) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+// MockBodyMetricRepository is a mock implementation of BodyMetricRepository
+type MockBodyMetricRepository struct {
+	mock.Mock
+}
+
+func (m *MockBodyMetricRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockBodyMetricRepository) Create(ctx context.Context, metric *model.BodyMetric) error {
+	args := m.Called(ctx, metric)
+	return args.Error(0)
+}
+
+func (m *MockBodyMetricRepository) GetByID(ctx context.Context, id string) (*model.BodyMetric, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.BodyMetric), args.Error(1)
+}
+
+func (m *MockBodyMetricRepository) ListByUser(ctx context.Context, userID string, from, to *time.Time, limit, offset int) ([]*model.BodyMetric, error) {
+	args := m.Called(ctx, userID, from, to, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.BodyMetric), args.Error(1)
+}
+
+func (m *MockBodyMetricRepository) Update(ctx context.Context, metric *model.BodyMetric) error {
+	args := m.Called(ctx, metric)
+	return args.Error(0)
+}
+
+func (m *MockBodyMetricRepository) Delete(ctx context.Context, userID, id string) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockBodyMetricRepository) FindLatestWeightBefore(ctx context.Context, userID string, at time.Time) (*model.BodyMetric, error) {
+	args := m.Called(ctx, userID, at)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.BodyMetric), args.Error(1)
+}
+
+func (m *MockBodyMetricRepository) FindLatestWeightsBefore(ctx context.Context, keys []model.BodyweightKey) ([]*model.BodyMetric, error) {
+	args := m.Called(ctx, keys)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.BodyMetric), args.Error(1)
+}
+
+// MockGoalRepository is a mock implementation of GoalRepository
+type MockGoalRepository struct {
+	mock.Mock
+}
+
+func (m *MockGoalRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockGoalRepository) Create(ctx context.Context, goal *model.Goal) error {
+	args := m.Called(ctx, goal)
+	return args.Error(0)
+}
+
+func (m *MockGoalRepository) GetByID(ctx context.Context, id string) (*model.Goal, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Goal), args.Error(1)
+}
+
+func (m *MockGoalRepository) ListByUser(ctx context.Context, userID string) ([]*model.Goal, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Goal), args.Error(1)
+}
+
+func (m *MockGoalRepository) Update(ctx context.Context, goal *model.Goal) error {
+	args := m.Called(ctx, goal)
+	return args.Error(0)
+}
+
+func (m *MockGoalRepository) Delete(ctx context.Context, userID, id string) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockGoalRepository) CountByExercise(ctx context.Context, userID, exerciseID string) (int64, error) {
+	args := m.Called(ctx, userID, exerciseID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockGoalRepository) ReplaceExercise(ctx context.Context, userID, sourceID, targetID string) (int64, error) {
+	args := m.Called(ctx, userID, sourceID, targetID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockGoalRepository) DeleteByExercise(ctx context.Context, userID, exerciseID string) (int64, error) {
+	args := m.Called(ctx, userID, exerciseID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// MockTransactor runs the function directly, without a transaction.
+type MockTransactor struct{}
+
+func (m *MockTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// MockActionTokenRepository is a mock implementation of ActionTokenRepository
+type MockActionTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockActionTokenRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockActionTokenRepository) Create(ctx context.Context, token *model.ActionToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockActionTokenRepository) FindByID(ctx context.Context, id string) (*model.ActionToken, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ActionToken), args.Error(1)
+}
+
+func (m *MockActionTokenRepository) Consume(ctx context.Context, id string) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockActionTokenRepository) DeleteForUser(ctx context.Context, userID string, purpose model.ActionTokenPurpose) error {
+	args := m.Called(ctx, userID, purpose)
+	return args.Error(0)
+}
+
+// MockAccountTombstoneRepository is a mock implementation of AccountTombstoneRepository
+type MockAccountTombstoneRepository struct {
+	mock.Mock
+}
+
+func (m *MockAccountTombstoneRepository) Record(ctx context.Context, tombstone *model.AccountTombstone) error {
+	args := m.Called(ctx, tombstone)
+	return args.Error(0)
+}
+
+func (m *MockAccountTombstoneRepository) FindByUserID(ctx context.Context, userID string) (*model.AccountTombstone, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.AccountTombstone), args.Error(1)
+}
+
+// MockPasskeyRepository is a mock implementation of PasskeyRepository
+type MockPasskeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasskeyRepository) Create(ctx context.Context, passkey *model.Passkey) error {
+	args := m.Called(ctx, passkey)
+	return args.Error(0)
+}
+
+func (m *MockPasskeyRepository) FindByCredentialID(ctx context.Context, credentialID []byte) (*model.Passkey, error) {
+	args := m.Called(ctx, credentialID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Passkey), args.Error(1)
+}
+
+func (m *MockPasskeyRepository) ListByUser(ctx context.Context, userID string) ([]*model.Passkey, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Passkey), args.Error(1)
+}
+
+func (m *MockPasskeyRepository) RecordUse(ctx context.Context, id string, signCount uint32, at time.Time) error {
+	args := m.Called(ctx, id, signCount, at)
+	return args.Error(0)
+}
+
+func (m *MockPasskeyRepository) DeleteForUser(ctx context.Context, userID, id string) (bool, error) {
+	args := m.Called(ctx, userID, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPasskeyRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// MockExternalIdentityRepository is a mock implementation of ExternalIdentityRepository
+type MockExternalIdentityRepository struct {
+	mock.Mock
+}
+
+func (m *MockExternalIdentityRepository) Create(ctx context.Context, identity *model.ExternalIdentity) error {
+	args := m.Called(ctx, identity)
+	return args.Error(0)
+}
+
+func (m *MockExternalIdentityRepository) FindByProviderSubject(ctx context.Context, provider, subject string) (*model.ExternalIdentity, error) {
+	args := m.Called(ctx, provider, subject)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ExternalIdentity), args.Error(1)
+}
+
+func (m *MockExternalIdentityRepository) RecordUse(ctx context.Context, id string, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockExternalIdentityRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
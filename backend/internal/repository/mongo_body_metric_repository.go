@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+type MongoBodyMetricRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoBodyMetricRepository(database *mongo.Database) *MongoBodyMetricRepository {
+	return &MongoBodyMetricRepository{
+		collection: database.Collection("body_metrics"),
+	}
+}
+
+// EnsureIndexes creates the index used to list a user's metrics by date.
+func (r *MongoBodyMetricRepository) EnsureIndexes(ctx context.Context) error {
+	return bodyMetricIndexes.ensure(ctx, r.collection)
+}
+
+// MissingIndexes lists the declared indexes that EnsureIndexes would create.
+func (r *MongoBodyMetricRepository) MissingIndexes(ctx context.Context) ([]string, error) {
+	return bodyMetricIndexes.missing(ctx, r.collection)
+}
+
+// bodyMetricDoc mirrors the stored document so _id can be decoded as an ObjectID.
+type bodyMetricDoc struct {
+	ID                bson.ObjectID            `bson:"_id"`
+	UserID            string                   `bson:"userId"`
+	RecordedAt        time.Time                `bson:"recordedAt"`
+	Weight            *float64                 `bson:"weight,omitempty"`
+	BodyFatPercentage *float64                 `bson:"bodyFatPercentage,omitempty"`
+	Measurements      []*model.BodyMeasurement `bson:"measurements"`
+	Notes             *string                  `bson:"notes,omitempty"`
+	CreatedAt         time.Time                `bson:"createdAt"`
+	UpdatedAt         time.Time                `bson:"updatedAt"`
+}
+
+func (d *bodyMetricDoc) toModel() *model.BodyMetric {
+	return &model.BodyMetric{
+		ID:                d.ID.Hex(),
+		UserID:            d.UserID,
+		RecordedAt:        d.RecordedAt,
+		Weight:            d.Weight,
+		BodyFatPercentage: d.BodyFatPercentage,
+		Measurements:      d.Measurements,
+		Notes:             d.Notes,
+		CreatedAt:         d.CreatedAt,
+		UpdatedAt:         d.UpdatedAt,
+	}
+}
+
+func (r *MongoBodyMetricRepository) Create(ctx context.Context, metric *model.BodyMetric) error {
+	if metric.ID == "" {
+		metric.ID = bson.NewObjectID().Hex()
+	}
+
+	oid, err := bson.ObjectIDFromHex(metric.ID)
+	if err != nil {
+		return fmt.Errorf("invalid body metric ID format: %w", err)
+	}
+
+	doc := bodyMetricDoc{
+		ID:                oid,
+		UserID:            metric.UserID,
+		RecordedAt:        metric.RecordedAt,
+		Weight:            metric.Weight,
+		BodyFatPercentage: metric.BodyFatPercentage,
+		Measurements:      metric.Measurements,
+		Notes:             metric.Notes,
+		CreatedAt:         metric.CreatedAt,
+		UpdatedAt:         metric.UpdatedAt,
+	}
+
+	if _, err := r.collection.InsertOne(ctx, doc); err != nil {
+		return fmt.Errorf("failed to insert body metric: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoBodyMetricRepository) GetByID(ctx context.Context, id string) (*model.BodyMetric, error) {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body metric ID format: %w", err)
+	}
+
+	var doc bodyMetricDoc
+	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("database error finding body metric by ID: %w", err)
+	}
+
+	return doc.toModel(), nil
+}
+
+func (r *MongoBodyMetricRepository) ListByUser(ctx context.Context, userID string, from, to *time.Time, limit, offset int) ([]*model.BodyMetric, error) {
+	filter := bson.M{"userId": userID}
+
+	recordedAt := bson.M{}
+	if from != nil {
+		recordedAt["$gte"] = *from
+	}
+	if to != nil {
+		recordedAt["$lte"] = *to
+	}
+	if len(recordedAt) > 0 {
+		filter["recordedAt"] = recordedAt
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "recordedAt", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	if offset > 0 {
+		opts.SetSkip(int64(offset))
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list body metrics: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	var metrics []*model.BodyMetric
+	for cursor.Next(ctx) {
+		var doc bodyMetricDoc
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode body metric: %w", err)
+		}
+		metrics = append(metrics, doc.toModel())
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return metrics, nil
+}
+
+func (r *MongoBodyMetricRepository) Update(ctx context.Context, metric *model.BodyMetric) error {
+	oid, err := bson.ObjectIDFromHex(metric.ID)
+	if err != nil {
+		return fmt.Errorf("invalid body metric ID format: %w", err)
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"recordedAt":        metric.RecordedAt,
+			"weight":            metric.Weight,
+			"bodyFatPercentage": metric.BodyFatPercentage,
+			"measurements":      metric.Measurements,
+			"notes":             metric.Notes,
+			"updatedAt":         metric.UpdatedAt,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": oid, "userId": metric.UserID}, update)
+	if err != nil {
+		return fmt.Errorf("failed to update body metric: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("body metric not found or unauthorized")
+	}
+	return nil
+}
+
+func (r *MongoBodyMetricRepository) Delete(ctx context.Context, userID, id string) error {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid body metric ID format: %w", err)
+	}
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid, "userId": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete body metric: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("body metric not found or unauthorized")
+	}
+	return nil
+}
+
+func (r *MongoBodyMetricRepository) FindLatestWeightBefore(ctx context.Context, userID string, at time.Time) (*model.BodyMetric, error) {
+	filter := bson.M{
+		"userId":     userID,
+		"recordedAt": bson.M{"$lte": at},
+		"weight":     bson.M{"$ne": nil},
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "recordedAt", Value: -1}})
+
+	var doc bodyMetricDoc
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("database error finding latest bodyweight: %w", err)
+	}
+
+	return doc.toModel(), nil
+}
+
+func (r *MongoBodyMetricRepository) FindLatestWeightsBefore(ctx context.Context, keys []model.BodyweightKey) ([]*model.BodyMetric, error) {
+	metrics := make([]*model.BodyMetric, len(keys))
+	if len(keys) == 0 {
+		return metrics, nil
+	}
+
+	// One indexed sub-pipeline per key, combined with $unionWith and tagged with the key's position
+	var pipeline mongo.Pipeline
+	for i, key := range keys {
+		sub := mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{
+				"userId":     key.UserID,
+				"recordedAt": bson.M{"$lte": key.At},
+				"weight":     bson.M{"$ne": nil},
+			}}},
+			{{Key: "$sort", Value: bson.M{"recordedAt": -1}}},
+			{{Key: "$limit", Value: 1}},
+			{{Key: "$addFields", Value: bson.M{"keyIndex": i}}},
+		}
+		if i == 0 {
+			pipeline = sub
+			continue
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$unionWith", Value: bson.M{"coll": r.collection.Name(), "pipeline": sub}}})
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find latest bodyweights: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	for cursor.Next(ctx) {
+		var doc bodyMetricDoc
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode body metric: %w", err)
+		}
+		i, ok := cursor.Current.Lookup("keyIndex").AsInt64OK()
+		if !ok || i < 0 || int(i) >= len(metrics) {
+			return nil, fmt.Errorf("latest bodyweight has no valid key index")
+		}
+		metrics[i] = doc.toModel()
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return metrics, nil
+}
+
+func (r *MongoBodyMetricRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete user's body metrics: %w", err)
+	}
+	return result.DeletedCount, nil
+}
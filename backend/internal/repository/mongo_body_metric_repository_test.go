@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestMongoBodyMetricRepository_CreateAndGetByID(t *testing.T) {
+	cleanupCollection(t, "body_metrics")
+	repo := NewMongoBodyMetricRepository(testDB)
+	ctx := context.Background()
+
+	weight := 82.5
+	metric := &model.BodyMetric{
+		UserID:     bson.NewObjectID().Hex(),
+		RecordedAt: time.Now().UTC().Truncate(time.Millisecond),
+		Weight:     &weight,
+		Measurements: []*model.BodyMeasurement{
+			{Site: model.MeasurementSiteWaist, Value: 84},
+		},
+	}
+
+	require.NoError(t, repo.Create(ctx, metric))
+	require.NotEmpty(t, metric.ID)
+
+	found, err := repo.GetByID(ctx, metric.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, metric.UserID, found.UserID)
+	assert.Equal(t, weight, *found.Weight)
+	assert.Nil(t, found.BodyFatPercentage)
+	require.Len(t, found.Measurements, 1)
+	assert.Equal(t, model.MeasurementSiteWaist, found.Measurements[0].Site)
+
+	notFound, err := repo.GetByID(ctx, bson.NewObjectID().Hex())
+	assert.NoError(t, err)
+	assert.Nil(t, notFound)
+}
+
+func TestMongoBodyMetricRepository_ListByUser(t *testing.T) {
+	cleanupCollection(t, "body_metrics")
+	repo := NewMongoBodyMetricRepository(testDB)
+	ctx := context.Background()
+
+	userID := bson.NewObjectID().Hex()
+	base := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		w := 80.0 + float64(i)
+		require.NoError(t, repo.Create(ctx, &model.BodyMetric{UserID: userID, RecordedAt: base.AddDate(0, 0, i), Weight: &w}))
+	}
+	other := 70.0
+	require.NoError(t, repo.Create(ctx, &model.BodyMetric{UserID: bson.NewObjectID().Hex(), RecordedAt: base, Weight: &other}))
+
+	from := base.AddDate(0, 0, 1)
+	to := base.AddDate(0, 0, 3)
+	results, err := repo.ListByUser(ctx, userID, &from, &to, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, 83.0, *results[0].Weight) // Newest first
+	assert.Equal(t, 81.0, *results[2].Weight)
+
+	all, err := repo.ListByUser(ctx, userID, nil, nil, 2, 0)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func TestMongoBodyMetricRepository_FindLatestWeightBefore(t *testing.T) {
+	cleanupCollection(t, "body_metrics")
+	repo := NewMongoBodyMetricRepository(testDB)
+	ctx := context.Background()
+
+	userID := bson.NewObjectID().Hex()
+	base := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	weight := 81.0
+	bodyFat := 17.0
+	require.NoError(t, repo.Create(ctx, &model.BodyMetric{UserID: userID, RecordedAt: base, Weight: &weight}))
+	// A later entry without a weight must be skipped.
+	require.NoError(t, repo.Create(ctx, &model.BodyMetric{UserID: userID, RecordedAt: base.AddDate(0, 0, 2), BodyFatPercentage: &bodyFat}))
+
+	found, err := repo.FindLatestWeightBefore(ctx, userID, base.AddDate(0, 0, 3))
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, weight, *found.Weight)
+
+	none, err := repo.FindLatestWeightBefore(ctx, userID, base.AddDate(0, 0, -1))
+	require.NoError(t, err)
+	assert.Nil(t, none)
+}
+
+func TestMongoBodyMetricRepository_FindLatestWeightsBefore(t *testing.T) {
+	cleanupCollection(t, "body_metrics")
+	repo := NewMongoBodyMetricRepository(testDB)
+	ctx := context.Background()
+
+	userID := bson.NewObjectID().Hex()
+	otherID := bson.NewObjectID().Hex()
+	base := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	first, second, other := 81.0, 80.0, 70.0
+	require.NoError(t, repo.Create(ctx, &model.BodyMetric{UserID: userID, RecordedAt: base, Weight: &first}))
+	require.NoError(t, repo.Create(ctx, &model.BodyMetric{UserID: userID, RecordedAt: base.AddDate(0, 0, 7), Weight: &second}))
+	require.NoError(t, repo.Create(ctx, &model.BodyMetric{UserID: otherID, RecordedAt: base, Weight: &other}))
+
+	found, err := repo.FindLatestWeightsBefore(ctx, []model.BodyweightKey{
+		{UserID: userID, At: base.AddDate(0, 0, 8)},
+		{UserID: userID, At: base.AddDate(0, 0, -1)},
+		{UserID: userID, At: base.AddDate(0, 0, 3)},
+		{UserID: otherID, At: base},
+	})
+	require.NoError(t, err)
+	require.Len(t, found, 4)
+	require.NotNil(t, found[0])
+	assert.Equal(t, second, *found[0].Weight)
+	assert.Nil(t, found[1])
+	require.NotNil(t, found[2])
+	assert.Equal(t, first, *found[2].Weight)
+	require.NotNil(t, found[3])
+	assert.Equal(t, other, *found[3].Weight)
+
+	empty, err := repo.FindLatestWeightsBefore(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestMongoBodyMetricRepository_UpdateAndDelete(t *testing.T) {
+	cleanupCollection(t, "body_metrics")
+	repo := NewMongoBodyMetricRepository(testDB)
+	ctx := context.Background()
+
+	userID := bson.NewObjectID().Hex()
+	weight := 80.0
+	metric := &model.BodyMetric{UserID: userID, RecordedAt: time.Now(), Weight: &weight}
+	require.NoError(t, repo.Create(ctx, metric))
+
+	newWeight := 79.0
+	metric.Weight = &newWeight
+	require.NoError(t, repo.Update(ctx, metric))
+
+	found, err := repo.GetByID(ctx, metric.ID)
+	require.NoError(t, err)
+	assert.Equal(t, newWeight, *found.Weight)
+
+	// Another user cannot delete the entry.
+	assert.Error(t, repo.Delete(ctx, bson.NewObjectID().Hex(), metric.ID))
+	require.NoError(t, repo.Delete(ctx, userID, metric.ID))
+
+	found, err = repo.GetByID(ctx, metric.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
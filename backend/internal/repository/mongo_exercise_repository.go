@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -13,14 +15,71 @@ import (
 
 type MongoExerciseRepository struct {
 	collection *mongo.Collection
+	metadata   *mongo.Collection
 }
 
 func NewMongoExerciseRepository(database *mongo.Database) *MongoExerciseRepository {
 	return &MongoExerciseRepository{
 		collection: database.Collection("unique_exercises"),
+		metadata:   database.Collection("system_metadata"),
 	}
 }
 
+// uniqueExerciseDoc matches the DB (ObjectID _id) and is mapped to model.UniqueExercise (string ID).
+type uniqueExerciseDoc struct {
+	ID                bson.ObjectID           `bson:"_id"`
+	Name              string                  `bson:"name"`
+	NormalizedName    string                  `bson:"normalizedName"`
+	UserID            *string                 `bson:"userId,omitempty"`
+	Description       *string                 `bson:"description,omitempty"`
+	Aliases           []string                `bson:"aliases,omitempty"`
+	NormalizedAliases []string                `bson:"normalizedAliases,omitempty"`
+	Category          *model.ExerciseCategory `bson:"category,omitempty"`
+	PrimaryMuscles    []model.MuscleGroup     `bson:"primaryMuscles,omitempty"`
+	SecondaryMuscles  []model.MuscleGroup     `bson:"secondaryMuscles,omitempty"`
+	Equipment         *model.Equipment        `bson:"equipment,omitempty"`
+	MovementPattern   *model.MovementPattern  `bson:"movementPattern,omitempty"`
+	Unilateral        bool                    `bson:"unilateral"`
+	ArchivedAt        *time.Time              `bson:"archivedAt,omitempty"`
+	Slug              *string                 `bson:"slug,omitempty"`
+	ReplacedBy        *bson.ObjectID          `bson:"replacedBy,omitempty"`
+	// Keyed by locale
+	Translations map[string]model.ExerciseTranslation `bson:"translations,omitempty"`
+}
+
+func (d *uniqueExerciseDoc) toModel() *model.UniqueExercise {
+	exercise := &model.UniqueExercise{
+		ID:               d.ID.Hex(),
+		Name:             d.Name,
+		UserID:           d.UserID,
+		Description:      d.Description,
+		Aliases:          d.Aliases,
+		Category:         d.Category,
+		PrimaryMuscles:   d.PrimaryMuscles,
+		SecondaryMuscles: d.SecondaryMuscles,
+		Equipment:        d.Equipment,
+		MovementPattern:  d.MovementPattern,
+		Unilateral:       d.Unilateral,
+		ArchivedAt:       d.ArchivedAt,
+		Slug:             d.Slug,
+		Translations:     d.Translations,
+	}
+	if d.ReplacedBy != nil {
+		replacedBy := d.ReplacedBy.Hex()
+		exercise.ReplacedByID = &replacedBy
+	}
+	if exercise.Aliases == nil {
+		exercise.Aliases = []string{}
+	}
+	if exercise.PrimaryMuscles == nil {
+		exercise.PrimaryMuscles = []model.MuscleGroup{}
+	}
+	if exercise.SecondaryMuscles == nil {
+		exercise.SecondaryMuscles = []model.MuscleGroup{}
+	}
+	return exercise
+}
+
 func (r *MongoExerciseRepository) Create(ctx context.Context, exercise *model.UniqueExercise) error {
 	// Ensure ID is generated if empty
 	if exercise.ID == "" {
@@ -32,40 +91,48 @@ func (r *MongoExerciseRepository) Create(ctx context.Context, exercise *model.Un
 		return fmt.Errorf("invalid exercise ID format: %w", err)
 	}
 
-	doc := bson.M{
-		"_id":  oid,
-		"name": exercise.Name,
-	}
-	if exercise.UserID != nil {
-		doc["userId"] = *exercise.UserID
-	}
-	if exercise.Description != nil {
-		doc["description"] = *exercise.Description
+	doc := uniqueExerciseDoc{
+		ID:                oid,
+		Name:              exercise.Name,
+		NormalizedName:    model.NormalizeExerciseName(exercise.Name),
+		UserID:            exercise.UserID,
+		Description:       exercise.Description,
+		Aliases:           exercise.Aliases,
+		NormalizedAliases: model.NormalizeExerciseAliases(exercise.Aliases),
+		Category:          exercise.Category,
+		PrimaryMuscles:    exercise.PrimaryMuscles,
+		SecondaryMuscles:  exercise.SecondaryMuscles,
+		Equipment:         exercise.Equipment,
+		MovementPattern:   exercise.MovementPattern,
+		Unilateral:        exercise.Unilateral,
+		ArchivedAt:        exercise.ArchivedAt,
 	}
 
 	_, err = r.collection.InsertOne(ctx, doc)
-	if err != nil {
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateExercise
+	} else if err != nil {
 		return fmt.Errorf("failed to insert exercise: %w", err)
 	}
 
 	return nil
 }
 
-func (r *MongoExerciseRepository) Search(ctx context.Context, userID *string, query string, limit int, offset int) ([]*model.UniqueExercise, error) {
-	// Filter: (userId == nil OR userId == currentUserId) AND name matches query (if provided)
-	filter := bson.M{}
+func (r *MongoExerciseRepository) Search(ctx context.Context, userID *string, query string, filter model.ExerciseFilter, limit int, offset int) ([]*model.UniqueExercise, error) {
+	// Filter: (userId == nil OR userId == currentUserId) AND name/alias starts with query (if provided) AND taxonomy filters
+	mongoFilter := exerciseFilterToBSON(filter)
+	conditions := bson.A{visibleToUser(userID)}
 
-	if query != "" {
-		filter["name"] = bson.M{"$regex": query, "$options": "i"}
-	}
-
-	userFilter := bson.A{bson.M{"userId": nil}} // System exercises
-	if userID != nil {
-		userFilter = append(userFilter, bson.M{"userId": *userID})
+	if query = model.NormalizeExerciseName(query); query != "" {
+		// An anchored, case-sensitive pattern on the normalized fields is answered from their
+		// indexes. The input is escaped so it is matched literally.
+		pattern := bson.M{"$regex": "^" + regexp.QuoteMeta(query)}
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{"normalizedName": pattern},
+			bson.M{"normalizedAliases": pattern},
+		}})
 	}
-	// Combine user-specific exercises and system exercises using $or.
-	// MongoDB's implicit AND allows us to add this to the top-level filter map alongside any name filter.
-	filter["$or"] = userFilter
+	mongoFilter["$and"] = conditions
 
 	// Limit results to prevent overload
 	if limit <= 0 {
@@ -74,9 +141,9 @@ func (r *MongoExerciseRepository) Search(ctx context.Context, userID *string, qu
 	if offset < 0 {
 		offset = 0
 	}
-	opts := options.Find().SetLimit(int64(limit)).SetSkip(int64(offset))
+	opts := options.Find().SetLimit(int64(limit)).SetSkip(int64(offset)).SetSort(bson.D{{Key: "name", Value: 1}})
 
-	cursor, err := r.collection.Find(ctx, filter, opts)
+	cursor, err := r.collection.Find(ctx, mongoFilter, opts)
 	if err != nil {
 		return nil, fmt.Errorf("database error searching exercises: %w", err)
 	}
@@ -84,42 +151,244 @@ func (r *MongoExerciseRepository) Search(ctx context.Context, userID *string, qu
 		_ = cursor.Close(ctx)
 	}()
 
+	return decodeUniqueExercises(ctx, cursor)
+}
+
+// ListVisible returns every system exercise plus the user's own custom exercises that match the filter.
+func (r *MongoExerciseRepository) ListVisible(ctx context.Context, userID *string, filter model.ExerciseFilter) ([]*model.UniqueExercise, error) {
+	mongoFilter := exerciseFilterToBSON(filter)
+	mongoFilter["$and"] = bson.A{visibleToUser(userID)}
+
+	cursor, err := r.collection.Find(ctx, mongoFilter)
+	if err != nil {
+		return nil, fmt.Errorf("database error listing exercises: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	return decodeUniqueExercises(ctx, cursor)
+}
+
+// ListSystem returns every system exercise, including archived ones, sorted by name.
+func (r *MongoExerciseRepository) ListSystem(ctx context.Context) ([]*model.UniqueExercise, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"userId": nil}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("database error listing system exercises: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	return decodeUniqueExercises(ctx, cursor)
+}
+
+// ListCustom returns the user's own custom exercises that match the filter, excluding archived ones.
+func (r *MongoExerciseRepository) ListCustom(ctx context.Context, userID string, filter model.ExerciseFilter) ([]*model.UniqueExercise, error) {
+	mongoFilter := exerciseFilterToBSON(filter)
+	mongoFilter["userId"] = userID
+	mongoFilter["archivedAt"] = nil
+
+	cursor, err := r.collection.Find(ctx, mongoFilter)
+	if err != nil {
+		return nil, fmt.Errorf("database error listing custom exercises: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	return decodeUniqueExercises(ctx, cursor)
+}
+
+// SystemExercisesVersion returns the version of the system exercise catalogue recorded by the seeder,
+// or 0 if it has never been seeded.
+func (r *MongoExerciseRepository) SystemExercisesVersion(ctx context.Context) (int, error) {
+	var doc struct {
+		Version int `bson:"version"`
+	}
+	err := r.metadata.FindOne(ctx, bson.M{"_id": "system_exercises_version"}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to fetch system exercises version: %w", err)
+	}
+	return doc.Version, nil
+}
+
+// EnsureIndexes creates the indexes backing exercise lookups and searches, including the
+// per-user uniqueness of custom exercise names. Names stored before they were normalized are
+// backfilled, and duplicates renamed, by the normalize_exercise_names migration.
+func (r *MongoExerciseRepository) EnsureIndexes(ctx context.Context) error {
+	return exerciseIndexes.ensure(ctx, r.collection)
+}
+
+// MissingIndexes lists the declared indexes that EnsureIndexes would create.
+func (r *MongoExerciseRepository) MissingIndexes(ctx context.Context) ([]string, error) {
+	return exerciseIndexes.missing(ctx, r.collection)
+}
+
+// FindByName returns the user's custom exercise (or, for a nil user, the system exercise) whose
+// normalized name matches, including archived ones. It returns nil if there is none.
+func (r *MongoExerciseRepository) FindByName(ctx context.Context, userID *string, name string) (*model.UniqueExercise, error) {
+	filter := bson.M{"normalizedName": model.NormalizeExerciseName(name), "userId": nil}
+	if userID != nil {
+		filter["userId"] = *userID
+	}
+
+	var doc uniqueExerciseDoc
+	err := r.collection.FindOne(ctx, filter).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("database error finding exercise by name: %w", err)
+	}
+	return doc.toModel(), nil
+}
+
+// visibleToUser matches system exercises and, when logged in, the user's custom exercises.
+// Archived exercises are excluded.
+func visibleToUser(userID *string) bson.M {
+	userFilter := bson.A{bson.M{"userId": nil}} // System exercises
+	if userID != nil {
+		userFilter = append(userFilter, bson.M{"userId": *userID})
+	}
+	return bson.M{"$or": userFilter, "archivedAt": nil}
+}
+
+// Update saves the editable fields of one of the user's custom exercises.
+func (r *MongoExerciseRepository) Update(ctx context.Context, exercise *model.UniqueExercise) error {
+	if exercise.UserID == nil {
+		return fmt.Errorf("system exercises cannot be modified")
+	}
+	oid, err := bson.ObjectIDFromHex(exercise.ID)
+	if err != nil {
+		return fmt.Errorf("invalid exercise ID format: %w", err)
+	}
+
+	set := bson.M{
+		"name":              exercise.Name,
+		"normalizedName":    model.NormalizeExerciseName(exercise.Name),
+		"aliases":           exercise.Aliases,
+		"normalizedAliases": model.NormalizeExerciseAliases(exercise.Aliases),
+		"primaryMuscles":    exercise.PrimaryMuscles,
+		"secondaryMuscles":  exercise.SecondaryMuscles,
+		"unilateral":        exercise.Unilateral,
+	}
+	// Cleared optional fields are removed rather than stored as null
+	unset := bson.M{}
+	setOrUnset := func(field string, isNil bool, value any) {
+		if isNil {
+			unset[field] = ""
+		} else {
+			set[field] = value
+		}
+	}
+	setOrUnset("description", exercise.Description == nil, exercise.Description)
+	setOrUnset("category", exercise.Category == nil, exercise.Category)
+	setOrUnset("equipment", exercise.Equipment == nil, exercise.Equipment)
+	setOrUnset("movementPattern", exercise.MovementPattern == nil, exercise.MovementPattern)
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": oid, "userId": *exercise.UserID}, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateExercise
+	} else if err != nil {
+		return fmt.Errorf("failed to update exercise: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("exercise not found or unauthorized")
+	}
+	return nil
+}
+
+// SetArchived archives (archivedAt set) or restores (nil) one of the user's custom exercises.
+func (r *MongoExerciseRepository) SetArchived(ctx context.Context, userID, id string, archivedAt *time.Time) error {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid exercise ID format: %w", err)
+	}
+
+	update := bson.M{"$unset": bson.M{"archivedAt": ""}}
+	if archivedAt != nil {
+		update = bson.M{"$set": bson.M{"archivedAt": *archivedAt}}
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": oid, "userId": userID}, update)
+	if err != nil {
+		return fmt.Errorf("failed to archive exercise: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("exercise not found or unauthorized")
+	}
+	return nil
+}
+
+func (r *MongoExerciseRepository) Delete(ctx context.Context, userID, id string) error {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid exercise ID format: %w", err)
+	}
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid, "userId": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete exercise: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("exercise not found or unauthorized")
+	}
+	return nil
+}
+
+func decodeUniqueExercises(ctx context.Context, cursor *mongo.Cursor) ([]*model.UniqueExercise, error) {
 	var exercises []*model.UniqueExercise
 	for cursor.Next(ctx) {
-		var doc struct {
-			ID          bson.ObjectID `bson:"_id"`
-			Name        string        `bson:"name"`
-			UserID      *string       `bson:"userId"`
-			Description *string       `bson:"description"`
-		}
+		var doc uniqueExerciseDoc
 		if err := cursor.Decode(&doc); err != nil {
 			return nil, fmt.Errorf("failed to decode exercise: %w", err)
 		}
 
-		exercises = append(exercises, &model.UniqueExercise{
-			ID:          doc.ID.Hex(),
-			Name:        doc.Name,
-			UserID:      doc.UserID,
-			Description: doc.Description,
-		})
+		exercises = append(exercises, doc.toModel())
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
 	}
 
 	return exercises, nil
 }
 
+// exerciseFilterToBSON translates the taxonomy filter into query conditions.
+func exerciseFilterToBSON(filter model.ExerciseFilter) bson.M {
+	conditions := bson.M{}
+	if filter.Category != nil {
+		conditions["category"] = *filter.Category
+	}
+	if len(filter.MuscleGroups) > 0 {
+		conditions["primaryMuscles"] = bson.M{"$in": filter.MuscleGroups}
+	}
+	if len(filter.Equipment) > 0 {
+		conditions["equipment"] = bson.M{"$in": filter.Equipment}
+	}
+	if filter.MovementPattern != nil {
+		conditions["movementPattern"] = *filter.MovementPattern
+	}
+	if filter.Unilateral != nil {
+		conditions["unilateral"] = *filter.Unilateral
+	}
+	return conditions
+}
+
 func (r *MongoExerciseRepository) FindByID(ctx context.Context, id string) (*model.UniqueExercise, error) {
 	oid, err := bson.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, fmt.Errorf("invalid exercise ID format: %w", err)
 	}
 
-	var doc struct {
-		ID          bson.ObjectID `bson:"_id"`
-		Name        string        `bson:"name"`
-		UserID      *string       `bson:"userId"`
-		Description *string       `bson:"description"`
-	}
-
+	var doc uniqueExerciseDoc
 	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&doc)
 	if err == mongo.ErrNoDocuments {
 		return nil, nil
@@ -127,10 +396,41 @@ func (r *MongoExerciseRepository) FindByID(ctx context.Context, id string) (*mod
 		return nil, fmt.Errorf("database error finding exercise by ID: %w", err)
 	}
 
-	return &model.UniqueExercise{
-		ID:          doc.ID.Hex(),
-		Name:        doc.Name,
-		UserID:      doc.UserID,
-		Description: doc.Description,
-	}, nil
+	return doc.toModel(), nil
+}
+
+// FindByIDs returns the exercises with the given IDs in a single query, in no particular order.
+// IDs that do not exist (or are not valid IDs) are skipped.
+func (r *MongoExerciseRepository) FindByIDs(ctx context.Context, ids []string) ([]*model.UniqueExercise, error) {
+	oids := make([]bson.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if oid, err := bson.ObjectIDFromHex(id); err == nil {
+			oids = append(oids, oid)
+		}
+	}
+	if len(oids) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": oids}})
+	if err != nil {
+		return nil, fmt.Errorf("database error finding exercises by ID: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	return decodeUniqueExercises(ctx, cursor)
+}
+
+// DeleteAllByUser deletes the user's custom exercises; system exercises have no user.
+func (r *MongoExerciseRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	if userID == "" {
+		return 0, fmt.Errorf("user ID is required")
+	}
+	result, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete user's exercises: %w", err)
+	}
+	return result.DeletedCount, nil
 }
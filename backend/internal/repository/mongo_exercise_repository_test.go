@@ -3,9 +3,11 @@ package repository
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/riverajo/fitness-app/backend/internal/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
@@ -56,6 +58,29 @@ func TestMongoExerciseRepository_FindByID(t *testing.T) {
 	assert.Nil(t, notFoundExercise)
 }
 
+func TestMongoExerciseRepository_FindByIDs(t *testing.T) {
+	cleanupCollection(t, "unique_exercises")
+	repo := NewMongoExerciseRepository(testDB)
+	ctx := context.Background()
+
+	squat := &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Squat"}
+	bench := &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Bench Press"}
+	require.NoError(t, repo.Create(ctx, squat))
+	require.NoError(t, repo.Create(ctx, bench))
+
+	found, err := repo.FindByIDs(ctx, []string{squat.ID, bson.NewObjectID().Hex(), "not-an-id", bench.ID})
+	require.NoError(t, err)
+	names := []string{}
+	for _, e := range found {
+		names = append(names, e.Name)
+	}
+	assert.ElementsMatch(t, []string{"Squat", "Bench Press"}, names)
+
+	found, err = repo.FindByIDs(ctx, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, found)
+}
+
 func TestMongoExerciseRepository_Search(t *testing.T) {
 	cleanupCollection(t, "unique_exercises")
 	cleanupCollection(t, "unique_exercises")
@@ -90,7 +115,7 @@ func TestMongoExerciseRepository_Search(t *testing.T) {
 	// Search for "Push" as user123
 	// Should find "Push Up" (system) and "Push Press" (user123)
 	// Should NOT find "Push Jerk" (user456)
-	results, err := repo.Search(ctx, &userID, "Push", 50, 0)
+	results, err := repo.Search(ctx, &userID, "Push", model.ExerciseFilter{}, 50, 0)
 	assert.NoError(t, err)
 	assert.Len(t, results, 2)
 
@@ -104,8 +129,318 @@ func TestMongoExerciseRepository_Search(t *testing.T) {
 
 	// Search for "Push" as anonymous (nil userID)
 	// Should only find "Push Up"
-	results, err = repo.Search(ctx, nil, "Push", 50, 0)
+	results, err = repo.Search(ctx, nil, "Push", model.ExerciseFilter{}, 50, 0)
 	assert.NoError(t, err)
 	assert.Len(t, results, 1)
 	assert.Equal(t, "Push Up", results[0].Name)
 }
+
+func TestMongoExerciseRepository_SearchTaxonomy(t *testing.T) {
+	cleanupCollection(t, "unique_exercises")
+	repo := NewMongoExerciseRepository(testDB)
+	ctx := context.Background()
+
+	strength := model.ExerciseCategoryStrength
+	barbell := model.EquipmentBarbell
+	dumbbell := model.EquipmentDumbbell
+	hinge := model.MovementPatternHinge
+	pull := model.MovementPatternHorizontalPull
+
+	deadlift := &model.UniqueExercise{
+		ID:               bson.NewObjectID().Hex(),
+		Name:             "Deadlift",
+		Category:         &strength,
+		PrimaryMuscles:   []model.MuscleGroup{model.MuscleGroupHamstrings, model.MuscleGroupGlutes},
+		SecondaryMuscles: []model.MuscleGroup{model.MuscleGroupLowerBack},
+		Equipment:        &barbell,
+		MovementPattern:  &hinge,
+	}
+	row := &model.UniqueExercise{
+		ID:              bson.NewObjectID().Hex(),
+		Name:            "Dumbbell Row",
+		Category:        &strength,
+		PrimaryMuscles:  []model.MuscleGroup{model.MuscleGroupLats},
+		Equipment:       &dumbbell,
+		MovementPattern: &pull,
+		Unilateral:      true,
+	}
+	assert.NoError(t, repo.Create(ctx, deadlift))
+	assert.NoError(t, repo.Create(ctx, row))
+
+	found, err := repo.FindByID(ctx, deadlift.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, strength, *found.Category)
+	assert.Equal(t, []model.MuscleGroup{model.MuscleGroupLowerBack}, found.SecondaryMuscles)
+	assert.Equal(t, hinge, *found.MovementPattern)
+
+	results, err := repo.Search(ctx, nil, "", model.ExerciseFilter{MuscleGroups: []model.MuscleGroup{model.MuscleGroupGlutes}}, 50, 0)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Deadlift", results[0].Name)
+
+	unilateral := true
+	results, err = repo.Search(ctx, nil, "", model.ExerciseFilter{Unilateral: &unilateral}, 50, 0)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Dumbbell Row", results[0].Name)
+
+	results, err = repo.Search(ctx, nil, "", model.ExerciseFilter{
+		Category:  &strength,
+		Equipment: []model.Equipment{barbell, dumbbell},
+	}, 50, 0)
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+}
+
+func TestMongoExerciseRepository_SearchEscapesAndAliases(t *testing.T) {
+	cleanupCollection(t, "unique_exercises")
+	repo := NewMongoExerciseRepository(testDB)
+	ctx := context.Background()
+	require.NoError(t, repo.EnsureIndexes(ctx))
+
+	userID := "user123"
+	ohp := &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Overhead Press", Aliases: []string{"OHP"}}
+	custom := &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Row (DB)", UserID: &userID}
+	assert.NoError(t, repo.Create(ctx, ohp))
+	assert.NoError(t, repo.Create(ctx, custom))
+
+	// Regex metacharacters are matched literally instead of erroring
+	results, err := repo.Search(ctx, &userID, "Row (DB)", model.ExerciseFilter{}, 50, 0)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Row (DB)", results[0].Name)
+
+	results, err = repo.Search(ctx, &userID, ".*", model.ExerciseFilter{}, 50, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+
+	// Aliases are searched
+	results, err = repo.Search(ctx, nil, "ohp", model.ExerciseFilter{}, 50, 0)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, []string{"OHP"}, results[0].Aliases)
+
+	// Names match from the start, ignoring case and spacing
+	results, err = repo.Search(ctx, nil, "overhead  PR", model.ExerciseFilter{}, 50, 0)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	results, err = repo.Search(ctx, nil, "press", model.ExerciseFilter{}, 50, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestMongoExerciseRepository_ListVisible(t *testing.T) {
+	cleanupCollection(t, "unique_exercises")
+	repo := NewMongoExerciseRepository(testDB)
+	ctx := context.Background()
+
+	userID := "user123"
+	otherUserID := "user456"
+	assert.NoError(t, repo.Create(ctx, &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Squat"}))
+	assert.NoError(t, repo.Create(ctx, &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Box Squat", UserID: &userID}))
+	assert.NoError(t, repo.Create(ctx, &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Zercher Squat", UserID: &otherUserID}))
+
+	results, err := repo.ListVisible(ctx, &userID, model.ExerciseFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	results, err = repo.ListVisible(ctx, nil, model.ExerciseFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Squat", results[0].Name)
+}
+
+func TestMongoExerciseRepository_SystemCatalogue(t *testing.T) {
+	cleanupCollection(t, "unique_exercises")
+	cleanupCollection(t, "system_metadata")
+	repo := NewMongoExerciseRepository(testDB)
+	ctx := context.Background()
+
+	version, err := repo.SystemExercisesVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	_, err = testDB.Collection("system_metadata").InsertOne(ctx, bson.M{"_id": "system_exercises_version", "version": 7})
+	require.NoError(t, err)
+	version, err = repo.SystemExercisesVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 7, version)
+
+	userID := "user123"
+	otherUserID := "user456"
+	archivedAt := time.Now()
+	require.NoError(t, repo.Create(ctx, &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Squat"}))
+	require.NoError(t, repo.Create(ctx, &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Good Morning", ArchivedAt: &archivedAt}))
+	require.NoError(t, repo.Create(ctx, &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Box Squat", UserID: &userID}))
+	require.NoError(t, repo.Create(ctx, &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Old Squat", UserID: &userID, ArchivedAt: &archivedAt}))
+	require.NoError(t, repo.Create(ctx, &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Zercher Squat", UserID: &otherUserID}))
+
+	system, err := repo.ListSystem(ctx)
+	require.NoError(t, err)
+	require.Len(t, system, 2)
+	assert.Equal(t, "Good Morning", system[0].Name, "archived system exercises are included")
+	assert.Equal(t, "Squat", system[1].Name)
+
+	custom, err := repo.ListCustom(ctx, userID, model.ExerciseFilter{})
+	require.NoError(t, err)
+	require.Len(t, custom, 1)
+	assert.Equal(t, "Box Squat", custom[0].Name)
+}
+
+func TestMongoExerciseRepository_CatalogueFields(t *testing.T) {
+	cleanupCollection(t, "unique_exercises")
+	repo := NewMongoExerciseRepository(testDB)
+	ctx := context.Background()
+
+	replacementID := bson.NewObjectID()
+	deprecatedID := bson.NewObjectID()
+	_, err := testDB.Collection("unique_exercises").InsertMany(ctx, []any{
+		bson.M{"_id": replacementID, "name": "Tricep Dips", "slug": "tricep-dips", "userId": nil},
+		bson.M{"_id": deprecatedID, "name": "Dips", "slug": "dips", "userId": nil, "archivedAt": time.Now(), "replacedBy": replacementID,
+			"translations": bson.M{"es": bson.M{"name": "Fondos", "aliases": bson.A{"Fondos en paralelas"}}}},
+	})
+	require.NoError(t, err)
+
+	deprecated, err := repo.FindByID(ctx, deprecatedID.Hex())
+	require.NoError(t, err)
+	require.NotNil(t, deprecated.Slug)
+	assert.Equal(t, "dips", *deprecated.Slug)
+	require.NotNil(t, deprecated.ReplacedByID)
+	assert.Equal(t, replacementID.Hex(), *deprecated.ReplacedByID)
+	assert.Equal(t, "Fondos", deprecated.LocalizedName([]string{"es-ES"}))
+	assert.Equal(t, []string{"Fondos en paralelas"}, deprecated.Translations["es"].Aliases)
+
+	// Deprecated exercises are hidden from search
+	visible, err := repo.ListVisible(ctx, nil, model.ExerciseFilter{})
+	require.NoError(t, err)
+	require.Len(t, visible, 1)
+	assert.Equal(t, "Tricep Dips", visible[0].Name)
+	assert.Nil(t, visible[0].ReplacedByID)
+}
+
+func TestMongoExerciseRepository_UpdateArchiveDelete(t *testing.T) {
+	cleanupCollection(t, "unique_exercises")
+	repo := NewMongoExerciseRepository(testDB)
+	ctx := context.Background()
+
+	userID := "user123"
+	desc := "typo"
+	ex := &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Bulgarain Split Squat", UserID: &userID, Description: &desc}
+	require.NoError(t, repo.Create(ctx, ex))
+
+	// Update, clearing the description
+	ex.Name = "Bulgarian Split Squat"
+	ex.Description = nil
+	ex.Unilateral = true
+	require.NoError(t, repo.Update(ctx, ex))
+
+	found, err := repo.FindByID(ctx, ex.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Bulgarian Split Squat", found.Name)
+	assert.Nil(t, found.Description)
+	assert.True(t, found.Unilateral)
+
+	// Other users cannot update it
+	other := "user456"
+	ex.UserID = &other
+	assert.Error(t, repo.Update(ctx, ex))
+	ex.UserID = &userID
+
+	// Archived exercises are hidden from search but still resolvable
+	now := time.Now()
+	require.NoError(t, repo.SetArchived(ctx, userID, ex.ID, &now))
+	results, err := repo.Search(ctx, &userID, "Bulgarian", model.ExerciseFilter{}, 50, 0)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	found, err = repo.FindByID(ctx, ex.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, found.ArchivedAt)
+
+	require.NoError(t, repo.SetArchived(ctx, userID, ex.ID, nil))
+	results, err = repo.ListVisible(ctx, &userID, model.ExerciseFilter{})
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	assert.Error(t, repo.Delete(ctx, "user456", ex.ID))
+	require.NoError(t, repo.Delete(ctx, userID, ex.ID))
+	found, err = repo.FindByID(ctx, ex.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestMongoExerciseRepository_DuplicateNames(t *testing.T) {
+	cleanupCollection(t, "unique_exercises")
+	repo := NewMongoExerciseRepository(testDB)
+	ctx := context.Background()
+
+	require.NoError(t, repo.EnsureIndexes(ctx))
+
+	userID := "user-1"
+	legacy := &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Legacy  Curl", UserID: &userID, Aliases: []string{"Old Curl"}}
+	require.NoError(t, repo.Create(ctx, legacy))
+	found, err := repo.FindByName(ctx, &userID, "legacy curl")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, legacy.ID, found.ID)
+	// Its aliases are searchable too
+	results, err := repo.Search(ctx, &userID, "old curl", model.ExerciseFilter{}, 50, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, legacy.ID, results[0].ID)
+
+	require.NoError(t, repo.Create(ctx, &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Pause Squat", UserID: &userID}))
+
+	err = repo.Create(ctx, &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "  pause   SQUAT ", UserID: &userID})
+	assert.ErrorIs(t, err, ErrDuplicateExercise)
+
+	// Other users and system exercises may share the name
+	otherUser := "user-2"
+	assert.NoError(t, repo.Create(ctx, &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Pause Squat", UserID: &otherUser}))
+	assert.NoError(t, repo.Create(ctx, &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Pause Squat"}))
+
+	// Renaming onto an existing name is rejected as well
+	curl := &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Curl", UserID: &userID}
+	require.NoError(t, repo.Create(ctx, curl))
+	curl.Name = "Pause Squat"
+	assert.ErrorIs(t, repo.Update(ctx, curl), ErrDuplicateExercise)
+
+	system, err := repo.FindByName(ctx, nil, "pause squat")
+	require.NoError(t, err)
+	require.NotNil(t, system)
+	assert.Nil(t, system.UserID)
+
+	missing, err := repo.FindByName(ctx, &userID, "Front Squat")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestMongoExerciseRepository_DeleteAllByUser(t *testing.T) {
+	cleanupCollection(t, "unique_exercises")
+	repo := NewMongoExerciseRepository(testDB)
+	ctx := context.Background()
+
+	userID := bson.NewObjectID().Hex()
+	otherUserID := bson.NewObjectID().Hex()
+	custom := &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "My Curl", UserID: &userID}
+	other := &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Their Curl", UserID: &otherUserID}
+	system := &model.UniqueExercise{ID: bson.NewObjectID().Hex(), Name: "Bench Press"}
+	for _, exercise := range []*model.UniqueExercise{custom, other, system} {
+		require.NoError(t, repo.Create(ctx, exercise))
+	}
+
+	deleted, err := repo.DeleteAllByUser(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	found, err := repo.FindByID(ctx, custom.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+	for _, kept := range []*model.UniqueExercise{other, system} {
+		found, err := repo.FindByID(ctx, kept.ID)
+		require.NoError(t, err)
+		assert.NotNil(t, found)
+	}
+
+	// An empty ID must never match system exercises
+	_, err = repo.DeleteAllByUser(ctx, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+type MongoGoalRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoGoalRepository(database *mongo.Database) *MongoGoalRepository {
+	return &MongoGoalRepository{
+		collection: database.Collection("goals"),
+	}
+}
+
+// EnsureIndexes creates the index used to list a user's goals.
+func (r *MongoGoalRepository) EnsureIndexes(ctx context.Context) error {
+	return goalIndexes.ensure(ctx, r.collection)
+}
+
+// MissingIndexes lists the declared indexes that EnsureIndexes would create.
+func (r *MongoGoalRepository) MissingIndexes(ctx context.Context) ([]string, error) {
+	return goalIndexes.missing(ctx, r.collection)
+}
+
+// goalDoc mirrors the stored document so _id can be decoded as an ObjectID.
+type goalDoc struct {
+	ID               bson.ObjectID  `bson:"_id"`
+	UserID           string         `bson:"userId"`
+	Type             model.GoalType `bson:"type"`
+	Name             string         `bson:"name"`
+	UniqueExerciseID *string        `bson:"uniqueExerciseId,omitempty"`
+	TargetValue      float64        `bson:"targetValue"`
+	TargetReps       *int32         `bson:"targetReps,omitempty"`
+	StartDate        time.Time      `bson:"startDate"`
+	Deadline         *time.Time     `bson:"deadline,omitempty"`
+	StartingValue    *float64       `bson:"startingValue,omitempty"`
+	AchievedAt       *time.Time     `bson:"achievedAt,omitempty"`
+	CreatedAt        time.Time      `bson:"createdAt"`
+	UpdatedAt        time.Time      `bson:"updatedAt"`
+}
+
+func (d *goalDoc) toModel() *model.Goal {
+	return &model.Goal{
+		ID:               d.ID.Hex(),
+		UserID:           d.UserID,
+		Type:             d.Type,
+		Name:             d.Name,
+		UniqueExerciseID: d.UniqueExerciseID,
+		TargetValue:      d.TargetValue,
+		TargetReps:       d.TargetReps,
+		StartDate:        d.StartDate,
+		Deadline:         d.Deadline,
+		StartingValue:    d.StartingValue,
+		AchievedAt:       d.AchievedAt,
+		CreatedAt:        d.CreatedAt,
+		UpdatedAt:        d.UpdatedAt,
+	}
+}
+
+func (r *MongoGoalRepository) Create(ctx context.Context, goal *model.Goal) error {
+	if goal.ID == "" {
+		goal.ID = bson.NewObjectID().Hex()
+	}
+
+	oid, err := bson.ObjectIDFromHex(goal.ID)
+	if err != nil {
+		return fmt.Errorf("invalid goal ID format: %w", err)
+	}
+
+	doc := goalDoc{
+		ID:               oid,
+		UserID:           goal.UserID,
+		Type:             goal.Type,
+		Name:             goal.Name,
+		UniqueExerciseID: goal.UniqueExerciseID,
+		TargetValue:      goal.TargetValue,
+		TargetReps:       goal.TargetReps,
+		StartDate:        goal.StartDate,
+		Deadline:         goal.Deadline,
+		StartingValue:    goal.StartingValue,
+		AchievedAt:       goal.AchievedAt,
+		CreatedAt:        goal.CreatedAt,
+		UpdatedAt:        goal.UpdatedAt,
+	}
+
+	if _, err := r.collection.InsertOne(ctx, doc); err != nil {
+		return fmt.Errorf("failed to insert goal: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoGoalRepository) GetByID(ctx context.Context, id string) (*model.Goal, error) {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid goal ID format: %w", err)
+	}
+
+	var doc goalDoc
+	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("database error finding goal by ID: %w", err)
+	}
+
+	return doc.toModel(), nil
+}
+
+func (r *MongoGoalRepository) ListByUser(ctx context.Context, userID string) ([]*model.Goal, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list goals: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	var goals []*model.Goal
+	for cursor.Next(ctx) {
+		var doc goalDoc
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode goal: %w", err)
+		}
+		goals = append(goals, doc.toModel())
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return goals, nil
+}
+
+func (r *MongoGoalRepository) Update(ctx context.Context, goal *model.Goal) error {
+	oid, err := bson.ObjectIDFromHex(goal.ID)
+	if err != nil {
+		return fmt.Errorf("invalid goal ID format: %w", err)
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"name":             goal.Name,
+			"uniqueExerciseId": goal.UniqueExerciseID,
+			"targetValue":      goal.TargetValue,
+			"targetReps":       goal.TargetReps,
+			"deadline":         goal.Deadline,
+			"startingValue":    goal.StartingValue,
+			"achievedAt":       goal.AchievedAt,
+			"updatedAt":        goal.UpdatedAt,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": oid, "userId": goal.UserID}, update)
+	if err != nil {
+		return fmt.Errorf("failed to update goal: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("goal not found or unauthorized")
+	}
+	return nil
+}
+
+func (r *MongoGoalRepository) Delete(ctx context.Context, userID, id string) error {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid goal ID format: %w", err)
+	}
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": oid, "userId": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete goal: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("goal not found or unauthorized")
+	}
+	return nil
+}
+
+// CountByExercise returns how many of the user's goals track the exercise.
+func (r *MongoGoalRepository) CountByExercise(ctx context.Context, userID, exerciseID string) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"userId": userID, "uniqueExerciseId": exerciseID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count goals for exercise: %w", err)
+	}
+	return count, nil
+}
+
+// ReplaceExercise points the user's goals tracking sourceID at targetID.
+func (r *MongoGoalRepository) ReplaceExercise(ctx context.Context, userID, sourceID, targetID string) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx,
+		bson.M{"userId": userID, "uniqueExerciseId": sourceID},
+		bson.M{"$set": bson.M{"uniqueExerciseId": targetID}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to replace exercise in goals: %w", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// DeleteByExercise deletes the user's goals tracking the exercise.
+func (r *MongoGoalRepository) DeleteByExercise(ctx context.Context, userID, exerciseID string) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID, "uniqueExerciseId": exerciseID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete goals for exercise: %w", err)
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *MongoGoalRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete user's goals: %w", err)
+	}
+	return result.DeletedCount, nil
+}
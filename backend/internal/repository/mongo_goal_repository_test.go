@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestMongoGoalRepository_CRUD(t *testing.T) {
+	cleanupCollection(t, "goals")
+	repo := NewMongoGoalRepository(testDB)
+	ctx := context.Background()
+
+	userID := bson.NewObjectID().Hex()
+	exerciseID := bson.NewObjectID().Hex()
+	deadline := time.Now().AddDate(0, 3, 0).UTC().Truncate(time.Millisecond)
+	goal := &model.Goal{
+		UserID:           userID,
+		Type:             model.GoalTypeLift,
+		Name:             "Squat 140",
+		UniqueExerciseID: &exerciseID,
+		TargetValue:      140,
+		StartDate:        time.Now().UTC().Truncate(time.Millisecond),
+		Deadline:         &deadline,
+		CreatedAt:        time.Now(),
+	}
+	require.NoError(t, repo.Create(ctx, goal))
+	require.NotEmpty(t, goal.ID)
+
+	found, err := repo.GetByID(ctx, goal.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, model.GoalTypeLift, found.Type)
+	assert.Equal(t, exerciseID, *found.UniqueExerciseID)
+	assert.True(t, deadline.Equal(*found.Deadline))
+	assert.Nil(t, found.AchievedAt)
+
+	achievedAt := time.Now()
+	found.AchievedAt = &achievedAt
+	found.Name = "Squat 140 kg"
+	require.NoError(t, repo.Update(ctx, found))
+
+	goals, err := repo.ListByUser(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, goals, 1)
+	assert.Equal(t, "Squat 140 kg", goals[0].Name)
+	assert.NotNil(t, goals[0].AchievedAt)
+
+	others, err := repo.ListByUser(ctx, bson.NewObjectID().Hex())
+	require.NoError(t, err)
+	assert.Empty(t, others)
+
+	assert.Error(t, repo.Delete(ctx, bson.NewObjectID().Hex(), goal.ID))
+	require.NoError(t, repo.Delete(ctx, userID, goal.ID))
+
+	found, err = repo.GetByID(ctx, goal.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestMongoGoalRepository_ByExercise(t *testing.T) {
+	cleanupCollection(t, "goals")
+	repo := NewMongoGoalRepository(testDB)
+	ctx := context.Background()
+
+	userID := bson.NewObjectID().Hex()
+	source := "ex-source"
+	target := "ex-target"
+	goal := &model.Goal{UserID: userID, Type: model.GoalTypeLift, Name: "Squat 100", UniqueExerciseID: &source, TargetValue: 100, StartDate: time.Now()}
+	require.NoError(t, repo.Create(ctx, goal))
+
+	count, err := repo.CountByExercise(ctx, userID, source)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	modified, err := repo.ReplaceExercise(ctx, userID, source, target)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), modified)
+	found, err := repo.GetByID(ctx, goal.ID)
+	require.NoError(t, err)
+	assert.Equal(t, target, *found.UniqueExerciseID)
+
+	deleted, err := repo.DeleteByExercise(ctx, userID, target)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoLeaseRepository keeps leases in system_locks, alongside the migration and seeder locks.
+type MongoLeaseRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoLeaseRepository(database *mongo.Database) *MongoLeaseRepository {
+	return &MongoLeaseRepository{
+		collection: database.Collection("system_locks"),
+	}
+}
+
+// EnsureIndexes creates the TTL index that deletes the leases of crashed replicas.
+func (r *MongoLeaseRepository) EnsureIndexes(ctx context.Context) error {
+	return leaseIndexes.ensure(ctx, r.collection)
+}
+
+// MissingIndexes lists the declared indexes that EnsureIndexes would create.
+func (r *MongoLeaseRepository) MissingIndexes(ctx context.Context) ([]string, error) {
+	return leaseIndexes.missing(ctx, r.collection)
+}
+
+// Acquire upserts the lease if owner holds it or it has expired. The TTL index only deletes
+// expired leases about once a minute, so expiry is also checked here. When another owner holds
+// the lease, the upsert fails on the duplicate _id.
+func (r *MongoLeaseRepository) Acquire(ctx context.Context, name, owner string) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id": name,
+		"$or": bson.A{
+			bson.M{"owner": owner},
+			bson.M{"createdAt": bson.M{"$lte": now.Add(-LeaseTTL)}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"owner": owner, "createdAt": now}}
+	_, err := r.collection.UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to acquire lease %s: %w", name, err)
+	}
+	return true, nil
+}
+
+func (r *MongoLeaseRepository) Release(ctx context.Context, name, owner string) error {
+	if _, err := r.collection.DeleteOne(ctx, bson.M{"_id": name, "owner": owner}); err != nil {
+		return fmt.Errorf("failed to release lease %s: %w", name, err)
+	}
+	return nil
+}
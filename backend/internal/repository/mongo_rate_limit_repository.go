@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+// MongoRateLimitRepository shares rate limits between replicas.
+type MongoRateLimitRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoRateLimitRepository(database *mongo.Database) *MongoRateLimitRepository {
+	return &MongoRateLimitRepository{
+		collection: database.Collection("rate_limits"),
+	}
+}
+
+// EnsureIndexes creates the TTL index that deletes expired rate limit states.
+func (r *MongoRateLimitRepository) EnsureIndexes(ctx context.Context) error {
+	return rateLimitIndexes.ensure(ctx, r.collection)
+}
+
+// MissingIndexes lists the declared indexes that EnsureIndexes would create.
+func (r *MongoRateLimitRepository) MissingIndexes(ctx context.Context) ([]string, error) {
+	return rateLimitIndexes.missing(ctx, r.collection)
+}
+
+func (r *MongoRateLimitRepository) Get(ctx context.Context, key string) (*model.RateLimitState, error) {
+	var state model.RateLimitState
+	err := r.collection.FindOne(ctx, bson.M{"_id": key}).Decode(&state)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch rate limit state: %w", err)
+	}
+	return &state, nil
+}
+
+// RecordFailure updates the count in a single atomic operation, so concurrent attempts
+// against different replicas are all counted.
+func (r *MongoRateLimitRepository) RecordFailure(ctx context.Context, key string, at time.Time, resetAfter time.Duration, expiresAt time.Time) (*model.RateLimitState, error) {
+	stale := bson.M{"$or": bson.A{
+		bson.M{"$eq": bson.A{bson.M{"$type": "$lastFailureAt"}, "missing"}},
+		bson.M{"$lt": bson.A{"$lastFailureAt", at.Add(-resetAfter)}},
+	}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"failures":      bson.M{"$cond": bson.A{stale, 1, bson.M{"$add": bson.A{"$failures", 1}}}},
+			"lastFailureAt": at,
+			"expiresAt":     expiresAt,
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var state model.RateLimitState
+	if err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&state); err != nil {
+		return nil, fmt.Errorf("failed to record failed attempt: %w", err)
+	}
+	return &state, nil
+}
+
+// RecordAttempt inserts the state if none was seen, and otherwise only updates it while it still
+// matches what was seen, so concurrent attempts against different replicas cannot both be
+// admitted against the same count.
+func (r *MongoRateLimitRepository) RecordAttempt(ctx context.Context, key string, seen *model.RateLimitState, failures int, at time.Time, expiresAt time.Time) (bool, error) {
+	state := model.RateLimitState{Key: key, Failures: failures, LastFailureAt: at, ExpiresAt: expiresAt}
+	if seen == nil {
+		_, err := r.collection.InsertOne(ctx, state)
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		} else if err != nil {
+			return false, fmt.Errorf("failed to record attempt: %w", err)
+		}
+		return true, nil
+	}
+
+	filter := bson.M{"_id": key, "failures": seen.Failures, "lastFailureAt": seen.LastFailureAt}
+	update := bson.M{"$set": bson.M{"failures": failures, "lastFailureAt": at, "expiresAt": expiresAt}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to record attempt: %w", err)
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (r *MongoRateLimitRepository) Forgive(ctx context.Context, key string) error {
+	filter := bson.M{"_id": key, "failures": bson.M{"$gt": 0}}
+	if _, err := r.collection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"failures": -1}}); err != nil {
+		return fmt.Errorf("failed to forgive attempt: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoRateLimitRepository) Reset(ctx context.Context, key string) error {
+	if _, err := r.collection.DeleteOne(ctx, bson.M{"_id": key}); err != nil {
+		return fmt.Errorf("failed to reset rate limit: %w", err)
+	}
+	return nil
+}
@@ -3,7 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
-	"log/slog"
+	"time"
 
 	"github.com/riverajo/fitness-app/backend/internal/model"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -12,10 +12,23 @@ import (
 )
 
 type RefreshTokenRepository interface {
+	// Create saves the token, starting a new family named after its ID if it has no FamilyID.
 	Create(ctx context.Context, token *model.RefreshToken) error
 	FindByID(ctx context.Context, id string) (*model.RefreshToken, error)
 	Revoke(ctx context.Context, id string) error
 	RevokeAllForUser(ctx context.Context, userID string) error
+	// MarkRotated records that the token was exchanged for a new one. It returns false if the
+	// token was already rotated (or no longer exists), so only one exchange can succeed.
+	MarkRotated(ctx context.Context, id string, at time.Time) (bool, error)
+	// RevokeFamily deletes every token of the family and returns how many were deleted.
+	RevokeFamily(ctx context.Context, familyID string) (int64, error)
+	// ListActiveByUser returns the latest, unexpired token of each of the user's families, most
+	// recently used first.
+	ListActiveByUser(ctx context.Context, userID string, now time.Time) ([]*model.RefreshToken, error)
+	// RevokeFamilyForUser is RevokeFamily for a family that must belong to the user.
+	RevokeFamilyForUser(ctx context.Context, userID, familyID string) (int64, error)
+	// RevokeAllForUserExcept revokes every family of the user but keepFamilyID, which may be empty.
+	RevokeAllForUserExcept(ctx context.Context, userID, keepFamilyID string) (int64, error)
 }
 
 type MongoRefreshTokenRepository struct {
@@ -23,28 +36,29 @@ type MongoRefreshTokenRepository struct {
 }
 
 func NewMongoRefreshTokenRepository(db *mongo.Database) *MongoRefreshTokenRepository {
-	collection := db.Collection("refreshtokens")
-
-	// Create TTL Index on ExpiresAt
-	indexModel := mongo.IndexModel{
-		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
-		Options: options.Index().SetExpireAfterSeconds(0),
+	return &MongoRefreshTokenRepository{
+		collection: db.Collection("refreshtokens"),
 	}
+}
 
-	// Use a background context for index creation as it happens on startup
-	if _, err := collection.Indexes().CreateOne(context.Background(), indexModel); err != nil {
-		slog.Error("Failed to create TTL index for refresh tokens", "error", err)
-	}
+// EnsureIndexes creates the TTL index that deletes expired tokens and the per-user and per-family
+// indexes used to revoke them.
+func (r *MongoRefreshTokenRepository) EnsureIndexes(ctx context.Context) error {
+	return refreshTokenIndexes.ensure(ctx, r.collection)
+}
 
-	return &MongoRefreshTokenRepository{
-		collection: collection,
-	}
+// MissingIndexes lists the declared indexes that EnsureIndexes would create.
+func (r *MongoRefreshTokenRepository) MissingIndexes(ctx context.Context) ([]string, error) {
+	return refreshTokenIndexes.missing(ctx, r.collection)
 }
 
 func (r *MongoRefreshTokenRepository) Create(ctx context.Context, token *model.RefreshToken) error {
 	if token.ID == "" {
 		token.ID = bson.NewObjectID().Hex()
 	}
+	if token.FamilyID == "" {
+		token.FamilyID = token.ID
+	}
 	_, err := r.collection.InsertOne(ctx, token)
 	if err != nil {
 		return fmt.Errorf("failed to create refresh token: %w", err)
@@ -82,3 +96,55 @@ func (r *MongoRefreshTokenRepository) RevokeAllForUser(ctx context.Context, user
 	}
 	return nil
 }
+
+func (r *MongoRefreshTokenRepository) MarkRotated(ctx context.Context, id string, at time.Time) (bool, error) {
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "rotatedAt", Value: nil}}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"rotatedAt": at}})
+	if err != nil {
+		return false, fmt.Errorf("failed to mark refresh token as rotated: %w", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *MongoRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.D{{Key: "familyId", Value: familyID}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *MongoRefreshTokenRepository) ListActiveByUser(ctx context.Context, userID string, now time.Time) ([]*model.RefreshToken, error) {
+	filter := bson.D{
+		{Key: "userId", Value: userID},
+		{Key: "rotatedAt", Value: nil},
+		{Key: "expiresAt", Value: bson.M{"$gt": now}},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "lastUsedAt", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refresh tokens: %w", err)
+	}
+	var tokens []*model.RefreshToken
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to decode refresh tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (r *MongoRefreshTokenRepository) RevokeFamilyForUser(ctx context.Context, userID, familyID string) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.D{{Key: "userId", Value: userID}, {Key: "familyId", Value: familyID}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *MongoRefreshTokenRepository) RevokeAllForUserExcept(ctx context.Context, userID, keepFamilyID string) (int64, error) {
+	filter := bson.D{{Key: "userId", Value: userID}, {Key: "familyId", Value: bson.M{"$ne": keepFamilyID}}}
+	result, err := r.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke other tokens for user: %w", err)
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+func TestMongoRefreshTokenRepository_Families(t *testing.T) {
+	cleanupCollection(t, "refreshtokens")
+	repo := NewMongoRefreshTokenRepository(testDB)
+	ctx := context.Background()
+	require.NoError(t, repo.EnsureIndexes(ctx))
+
+	userID := bson.NewObjectID().Hex()
+	newToken := func(familyID string) *model.RefreshToken {
+		token := &model.RefreshToken{UserID: userID, TokenHash: "hash", FamilyID: familyID, ExpiresAt: time.Now().Add(time.Hour), CreatedAt: time.Now()}
+		require.NoError(t, repo.Create(ctx, token))
+		return token
+	}
+
+	// The first token names its family
+	root := newToken("")
+	assert.Equal(t, root.ID, root.FamilyID)
+	child := newToken(root.FamilyID)
+	other := newToken("")
+
+	// Only the first rotation succeeds
+	rotated, err := repo.MarkRotated(ctx, root.ID, time.Now())
+	require.NoError(t, err)
+	assert.True(t, rotated)
+	rotated, err = repo.MarkRotated(ctx, root.ID, time.Now())
+	require.NoError(t, err)
+	assert.False(t, rotated)
+	found, err := repo.FindByID(ctx, root.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.NotNil(t, found.RotatedAt)
+
+	revoked, err := repo.RevokeFamily(ctx, root.FamilyID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), revoked)
+	found, err = repo.FindByID(ctx, child.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+	found, err = repo.FindByID(ctx, other.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, found)
+}
+
+func TestMongoRefreshTokenRepository_Sessions(t *testing.T) {
+	cleanupCollection(t, "refreshtokens")
+	repo := NewMongoRefreshTokenRepository(testDB)
+	ctx := context.Background()
+
+	userID := bson.NewObjectID().Hex()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	newToken := func(familyID string, lastUsedAt time.Time) *model.RefreshToken {
+		token := &model.RefreshToken{UserID: userID, TokenHash: "hash", FamilyID: familyID, ExpiresAt: now.Add(time.Hour), CreatedAt: lastUsedAt, SignedInAt: now.Add(-time.Hour), LastUsedAt: lastUsedAt, UserAgent: "Firefox/120.0", IP: "203.0.113.7"}
+		require.NoError(t, repo.Create(ctx, token))
+		return token
+	}
+
+	// A session rotated once, and one used more recently
+	older := newToken("", now.Add(-30*time.Minute))
+	_, err := repo.MarkRotated(ctx, older.ID, now)
+	require.NoError(t, err)
+	current := newToken(older.FamilyID, now.Add(-10*time.Minute))
+	recent := newToken("", now)
+	expired := newToken("", now)
+	_, err = testDB.Collection("refreshtokens").UpdateOne(ctx, bson.M{"_id": expired.ID}, bson.M{"$set": bson.M{"expiresAt": now.Add(-time.Minute)}})
+	require.NoError(t, err)
+	// Another user's session
+	require.NoError(t, repo.Create(ctx, &model.RefreshToken{UserID: bson.NewObjectID().Hex(), TokenHash: "hash", ExpiresAt: now.Add(time.Hour), LastUsedAt: now}))
+
+	sessions, err := repo.ListActiveByUser(ctx, userID, now)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, recent.ID, sessions[0].ID)
+	assert.Equal(t, current.ID, sessions[1].ID)
+	assert.Equal(t, "Firefox/120.0", sessions[1].UserAgent)
+	assert.True(t, current.SignedInAt.Equal(sessions[1].SignedInAt))
+
+	// A session can only be revoked by its user
+	revoked, err := repo.RevokeFamilyForUser(ctx, bson.NewObjectID().Hex(), recent.FamilyID)
+	require.NoError(t, err)
+	assert.Zero(t, revoked)
+
+	revoked, err = repo.RevokeAllForUserExcept(ctx, userID, recent.FamilyID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), revoked)
+	sessions, err = repo.ListActiveByUser(ctx, userID, now)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, recent.ID, sessions[0].ID)
+}
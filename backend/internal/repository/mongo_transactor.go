@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ErrTransactionsUnsupported is returned when MongoDB runs as a standalone server, which cannot
+// run transactions.
+var ErrTransactionsUnsupported = errors.New("this change needs the database to run as a replica set; ask the administrator to enable one")
+
+// MongoTransactor runs transactions on the database's client. Transactions require
+// MongoDB to run as a replica set (a single-node one is enough).
+type MongoTransactor struct {
+	client *mongo.Client
+	// supported is set once the server is known to support transactions
+	supported atomic.Bool
+}
+
+func NewMongoTransactor(database *mongo.Database) *MongoTransactor {
+	return &MongoTransactor{
+		client: database.Client(),
+	}
+}
+
+func (t *MongoTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := t.CheckSupported(ctx); err != nil {
+		return err
+	}
+	session, err := t.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	// The driver retries fn on transient transaction errors, so fn must be safe to re-run.
+	_, err = session.WithTransaction(ctx, func(txCtx context.Context) (any, error) {
+		return nil, fn(txCtx)
+	})
+	return err
+}
+
+// CheckSupported returns ErrTransactionsUnsupported if the server is standalone rather than a
+// replica set member or a sharded cluster's router.
+func (t *MongoTransactor) CheckSupported(ctx context.Context) error {
+	if t.supported.Load() {
+		return nil
+	}
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := t.client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return fmt.Errorf("failed to check database topology: %w", err)
+	}
+	if hello.SetName == "" && hello.Msg != "isdbgrid" {
+		return ErrTransactionsUnsupported
+	}
+	t.supported.Store(true)
+	return nil
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestMongoTransactor_WithTransaction(t *testing.T) {
+	cleanupCollection(t, "tx_test")
+	collection := testDB.Collection("tx_test")
+	ctx := context.Background()
+	// Collections can't be created implicitly inside a transaction on older servers
+	require.NoError(t, testDB.CreateCollection(ctx, "tx_test"))
+	transactor := NewMongoTransactor(testDB)
+	// The test container runs a single-node replica set
+	require.NoError(t, transactor.CheckSupported(ctx))
+
+	err := transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := collection.InsertOne(ctx, bson.M{"name": "committed"})
+		return err
+	})
+	require.NoError(t, err)
+
+	err = transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := collection.InsertOne(ctx, bson.M{"name": "rolled back"}); err != nil {
+			return err
+		}
+		return errors.New("boom")
+	})
+	assert.EqualError(t, err, "boom")
+
+	count, err := collection.CountDocuments(ctx, bson.M{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
@@ -7,6 +7,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 
 	"github.com/riverajo/fitness-app/backend/internal/model"
 )
@@ -21,42 +22,101 @@ func NewMongoUserRepository(database *mongo.Database) *MongoUserRepository {
 	}
 }
 
-func (r *MongoUserRepository) Create(ctx context.Context, user model.User) error {
-	// Check if user already exists
-	filter := bson.M{"email": user.Email}
-	count, err := r.collection.CountDocuments(ctx, filter)
-	if err != nil {
-		return fmt.Errorf("database error during user check: %w", err)
+// userDoc matches the DB (ObjectID _id) and is mapped to model.User (string ID).
+type userDoc struct {
+	ID            bson.ObjectID   `bson:"_id,omitempty"`
+	Email         string          `bson:"email"`
+	EmailVerified bool            `bson:"emailVerified"`
+	PendingEmail  *string         `bson:"pendingEmail,omitempty"`
+	PasswordHash  string          `bson:"passwordHash"`
+	CreatedAt     time.Time       `bson:"createdAt"`
+	UpdatedAt     time.Time       `bson:"updatedAt"`
+	PreferredUnit string          `bson:"preferredUnit"`
+	Timezone      string          `bson:"timezone"`
+	TrainingDays  []model.Weekday `bson:"trainingDays"`
+	Locale        *string         `bson:"locale,omitempty"`
+
+	DeletionRequestedAt *time.Time `bson:"deletionRequestedAt,omitempty"`
+	DeletionScheduledAt *time.Time `bson:"deletionScheduledAt,omitempty"`
+	DeletionStartedAt   *time.Time `bson:"deletionStartedAt,omitempty"`
+
+	TOTPEnabled        bool     `bson:"totpEnabled,omitempty"`
+	TOTPSecret         string   `bson:"totpSecret,omitempty"`
+	TOTPLastStep       int64    `bson:"totpLastStep,omitempty"`
+	RecoveryCodeHashes []string `bson:"recoveryCodeHashes,omitempty"`
+}
+
+func (d *userDoc) toModel() *model.User {
+	user := &model.User{
+		ID:            d.ID.Hex(),
+		Email:         d.Email,
+		EmailVerified: d.EmailVerified,
+		PendingEmail:  d.PendingEmail,
+		PasswordHash:  d.PasswordHash,
+		CreatedAt:     d.CreatedAt,
+		UpdatedAt:     d.UpdatedAt,
+		PreferredUnit: model.WeightUnit(d.PreferredUnit),
+		Timezone:      d.Timezone,
+		TrainingDays:  d.TrainingDays,
+		Locale:        d.Locale,
+
+		DeletionRequestedAt: d.DeletionRequestedAt,
+		DeletionScheduledAt: d.DeletionScheduledAt,
+		DeletionStartedAt:   d.DeletionStartedAt,
+
+		TOTPEnabled:        d.TOTPEnabled,
+		TOTPSecret:         d.TOTPSecret,
+		TOTPLastStep:       d.TOTPLastStep,
+		RecoveryCodeHashes: d.RecoveryCodeHashes,
 	}
-	if count > 0 {
-		return fmt.Errorf("user with email %s already exists", user.Email)
+	// Accounts created before these settings existed.
+	if user.Timezone == "" {
+		user.Timezone = model.DefaultTimezone
 	}
+	if user.TrainingDays == nil {
+		user.TrainingDays = []model.Weekday{}
+	}
+	return user
+}
 
-	// Convert hex string ID to ObjectID for storage.
+// EnsureIndexes creates the unique index on email, which Create relies on to reject duplicates.
+// It fails if some email is already registered more than once.
+func (r *MongoUserRepository) EnsureIndexes(ctx context.Context) error {
+	return userIndexes.ensure(ctx, r.collection)
+}
 
+// MissingIndexes lists the declared indexes that EnsureIndexes would create.
+func (r *MongoUserRepository) MissingIndexes(ctx context.Context) ([]string, error) {
+	return userIndexes.missing(ctx, r.collection)
+}
+
+// Create inserts the user, returning ErrDuplicateEmail if the email is already registered.
+func (r *MongoUserRepository) Create(ctx context.Context, user model.User) error {
+	// Convert hex string ID to ObjectID for storage.
 	oid, err := bson.ObjectIDFromHex(user.ID)
 	if err != nil {
 		return fmt.Errorf("invalid user ID format: %w", err)
 	}
 
-	// Create a temporary struct or map for insertion to ensure _id is ObjectID
-	userDoc := struct {
-		ID            bson.ObjectID `bson:"_id,omitempty"`
-		Email         string        `bson:"email"`
-		PasswordHash  string        `bson:"passwordHash"`
-		CreatedAt     time.Time     `bson:"createdAt"`
-		UpdatedAt     time.Time     `bson:"updatedAt"`
-		PreferredUnit string        `bson:"preferredUnit"`
-	}{
+	// Use the document struct for insertion to ensure _id is ObjectID
+	doc := userDoc{
 		ID:            oid,
 		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		PendingEmail:  user.PendingEmail,
 		PasswordHash:  user.PasswordHash,
 		CreatedAt:     user.CreatedAt,
 		UpdatedAt:     user.UpdatedAt,
 		PreferredUnit: string(user.PreferredUnit),
+		Timezone:      user.Timezone,
+		TrainingDays:  user.TrainingDays,
+		Locale:        user.Locale,
 	}
 
-	_, err = r.collection.InsertOne(ctx, userDoc)
+	_, err = r.collection.InsertOne(ctx, doc)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateEmail
+	}
 	if err != nil {
 		return fmt.Errorf("failed to insert user into database: %w", err)
 	}
@@ -64,33 +124,19 @@ func (r *MongoUserRepository) Create(ctx context.Context, user model.User) error
 	return nil
 }
 
+// FindByEmail returns the user with the email, compared in its normalized form.
 func (r *MongoUserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
-	// We need to decode into a struct that matches the DB (ObjectID _id) and then map to model.User (string ID)
-	var userDoc struct {
-		ID            bson.ObjectID `bson:"_id"`
-		Email         string        `bson:"email"`
-		PasswordHash  string        `bson:"passwordHash"`
-		CreatedAt     time.Time     `bson:"createdAt"`
-		UpdatedAt     time.Time     `bson:"updatedAt"`
-		PreferredUnit string        `bson:"preferredUnit"`
-	}
-
-	filter := bson.M{"email": email}
-	err := r.collection.FindOne(ctx, filter).Decode(&userDoc)
+	var doc userDoc
+
+	filter := bson.M{"email": model.NormalizeEmail(email)}
+	err := r.collection.FindOne(ctx, filter).Decode(&doc)
 	if err == mongo.ErrNoDocuments {
 		return nil, nil
 	} else if err != nil {
 		return nil, fmt.Errorf("database error finding user by email: %w", err)
 	}
 
-	return &model.User{
-		ID:            userDoc.ID.Hex(),
-		Email:         userDoc.Email,
-		PasswordHash:  userDoc.PasswordHash,
-		CreatedAt:     userDoc.CreatedAt,
-		UpdatedAt:     userDoc.UpdatedAt,
-		PreferredUnit: model.WeightUnit(userDoc.PreferredUnit),
-	}, nil
+	return doc.toModel(), nil
 }
 
 func (r *MongoUserRepository) FindByID(ctx context.Context, id string) (*model.User, error) {
@@ -99,33 +145,21 @@ func (r *MongoUserRepository) FindByID(ctx context.Context, id string) (*model.U
 		return nil, fmt.Errorf("invalid user ID format: %w", err)
 	}
 
-	var userDoc struct {
-		ID            bson.ObjectID `bson:"_id"`
-		Email         string        `bson:"email"`
-		PasswordHash  string        `bson:"passwordHash"`
-		CreatedAt     time.Time     `bson:"createdAt"`
-		UpdatedAt     time.Time     `bson:"updatedAt"`
-		PreferredUnit string        `bson:"preferredUnit"`
-	}
+	var doc userDoc
 
 	filter := bson.M{"_id": objectID}
-	err = r.collection.FindOne(ctx, filter).Decode(&userDoc)
+	err = r.collection.FindOne(ctx, filter).Decode(&doc)
 	if err == mongo.ErrNoDocuments {
 		return nil, nil
 	} else if err != nil {
 		return nil, fmt.Errorf("database error finding user by ID: %w", err)
 	}
 
-	return &model.User{
-		ID:            userDoc.ID.Hex(),
-		Email:         userDoc.Email,
-		PasswordHash:  userDoc.PasswordHash,
-		CreatedAt:     userDoc.CreatedAt,
-		UpdatedAt:     userDoc.UpdatedAt,
-		PreferredUnit: model.WeightUnit(userDoc.PreferredUnit),
-	}, nil
+	return doc.toModel(), nil
 }
 
+// Update saves the user's mutable fields except the two-factor settings, returning
+// ErrDuplicateEmail if the email was changed to one already registered.
 func (r *MongoUserRepository) Update(ctx context.Context, user *model.User) error {
 	// Update mutable fields.
 
@@ -135,14 +169,133 @@ func (r *MongoUserRepository) Update(ctx context.Context, user *model.User) erro
 	}
 
 	updateFields := bson.M{
+		"email":         user.Email,
+		"pendingEmail":  user.PendingEmail,
 		"passwordHash":  user.PasswordHash,
+		"emailVerified": user.EmailVerified,
 		"preferredUnit": user.PreferredUnit,
+		"timezone":      user.Timezone,
+		"trainingDays":  user.TrainingDays,
+		"locale":        user.Locale,
 		"updatedAt":     time.Now(),
+
+		"deletionRequestedAt": user.DeletionRequestedAt,
+		"deletionScheduledAt": user.DeletionScheduledAt,
 	}
 
 	_, err = r.collection.UpdateByID(ctx, oid, bson.M{"$set": updateFields})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateEmail
+	}
 	if err != nil {
 		return fmt.Errorf("database error during update: %w", err)
 	}
 	return nil
 }
+
+func (r *MongoUserRepository) Delete(ctx context.Context, id string) error {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid user ID format: %w", err)
+	}
+	filter := bson.M{"_id": oid, "deletionStartedAt": bson.M{"$type": "date"}}
+	if _, err := r.collection.DeleteOne(ctx, filter); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueForDeletion sets deletionStartedAt in the same operation that finds the user, so a user
+// is only claimed once and CancelDeletion cannot race with the deletion.
+func (r *MongoUserRepository) ClaimDueForDeletion(ctx context.Context, now, staleBefore time.Time) (*model.User, error) {
+	filter := bson.M{
+		"deletionScheduledAt": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"deletionStartedAt": nil},
+			bson.M{"deletionStartedAt": bson.M{"$lte": staleBefore}},
+		},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "deletionScheduledAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var doc userDoc
+	err := r.collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"deletionStartedAt": now}}, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to claim user due for deletion: %w", err)
+	}
+	return doc.toModel(), nil
+}
+
+func (r *MongoUserRepository) CancelDeletion(ctx context.Context, id string) (bool, error) {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return false, fmt.Errorf("invalid user ID format: %w", err)
+	}
+	filter := bson.M{"_id": oid, "deletionScheduledAt": bson.M{"$type": "date"}, "deletionStartedAt": nil}
+	update := bson.M{
+		"$set":   bson.M{"updatedAt": time.Now()},
+		"$unset": bson.M{"deletionRequestedAt": "", "deletionScheduledAt": ""},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel deletion: %w", err)
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (r *MongoUserRepository) SetTOTPSecret(ctx context.Context, id, secret string) (bool, error) {
+	return r.updateTwoFactor(ctx, id, bson.M{"totpEnabled": bson.M{"$ne": true}}, bson.M{
+		"$set":   bson.M{"totpSecret": secret, "updatedAt": time.Now()},
+		"$unset": bson.M{"totpLastStep": ""},
+	})
+}
+
+func (r *MongoUserRepository) EnableTwoFactor(ctx context.Context, id, secret string, recoveryCodeHashes []string) (bool, error) {
+	return r.updateTwoFactor(ctx, id, bson.M{"totpEnabled": bson.M{"$ne": true}, "totpSecret": secret}, bson.M{
+		"$set": bson.M{"totpEnabled": true, "recoveryCodeHashes": recoveryCodeHashes, "updatedAt": time.Now()},
+	})
+}
+
+func (r *MongoUserRepository) SetRecoveryCodes(ctx context.Context, id string, recoveryCodeHashes []string) (bool, error) {
+	return r.updateTwoFactor(ctx, id, bson.M{"totpEnabled": true}, bson.M{
+		"$set": bson.M{"recoveryCodeHashes": recoveryCodeHashes, "updatedAt": time.Now()},
+	})
+}
+
+func (r *MongoUserRepository) DisableTwoFactor(ctx context.Context, id string) error {
+	_, err := r.updateTwoFactor(ctx, id, bson.M{}, bson.M{
+		"$set":   bson.M{"updatedAt": time.Now()},
+		"$unset": bson.M{"totpEnabled": "", "totpSecret": "", "totpLastStep": "", "recoveryCodeHashes": ""},
+	})
+	return err
+}
+
+func (r *MongoUserRepository) UseTOTPStep(ctx context.Context, id, secret string, step int64) (bool, error) {
+	// $not also matches users without a step yet, whose field is omitted
+	filter := bson.M{"totpSecret": secret, "totpLastStep": bson.M{"$not": bson.M{"$gte": step}}}
+	return r.updateTwoFactor(ctx, id, filter, bson.M{"$set": bson.M{"totpLastStep": step, "updatedAt": time.Now()}})
+}
+
+func (r *MongoUserRepository) UseRecoveryCode(ctx context.Context, id, hash string) (bool, error) {
+	return r.updateTwoFactor(ctx, id, bson.M{"recoveryCodeHashes": hash}, bson.M{
+		"$pull": bson.M{"recoveryCodeHashes": hash},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	})
+}
+
+// updateTwoFactor applies update to the user if they also match filter, reporting whether they did.
+func (r *MongoUserRepository) updateTwoFactor(ctx context.Context, id string, filter, update bson.M) (bool, error) {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return false, fmt.Errorf("invalid user ID format: %w", err)
+	}
+	filter["_id"] = oid
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to update two-factor settings: %w", err)
+	}
+	return result.MatchedCount == 1, nil
+}
@@ -7,7 +7,9 @@ import (
 
 	"github.com/riverajo/fitness-app/backend/internal/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
 func TestMongoUserRepository_Create(t *testing.T) {
@@ -34,6 +36,58 @@ func TestMongoUserRepository_Create(t *testing.T) {
 	assert.NotNil(t, foundUser)
 	assert.Equal(t, user.Email, foundUser.Email)
 	assert.Equal(t, user.ID, foundUser.ID)
+	// Users stored without schedule settings fall back to defaults
+	assert.Equal(t, model.DefaultTimezone, foundUser.Timezone)
+	assert.Empty(t, foundUser.TrainingDays)
+}
+
+func TestMongoUserRepository_DuplicateEmail(t *testing.T) {
+	cleanupCollection(t, "users")
+	repo := NewMongoUserRepository(testDB)
+	ctx := context.Background()
+
+	newUser := func(email string) model.User {
+		return model.User{ID: bson.NewObjectID().Hex(), Email: email, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	}
+
+	// Emails registered twice before the index existed block it
+	first, second := newUser("dup@example.com"), newUser("dup@example.com")
+	require.NoError(t, repo.Create(ctx, first))
+	require.NoError(t, repo.Create(ctx, second))
+	err := repo.EnsureIndexes(ctx)
+	require.Error(t, err)
+	assert.True(t, mongo.IsDuplicateKeyError(err))
+
+	secondID, err := bson.ObjectIDFromHex(second.ID)
+	require.NoError(t, err)
+	_, err = testDB.Collection("users").DeleteOne(ctx, bson.M{"_id": secondID})
+	require.NoError(t, err)
+	require.NoError(t, repo.EnsureIndexes(ctx))
+
+	missing, err := repo.MissingIndexes(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+
+	err = repo.Create(ctx, newUser("dup@example.com"))
+	assert.ErrorIs(t, err, ErrDuplicateEmail)
+
+	// Concurrent registrations of the same email: exactly one wins
+	const attempts = 5
+	errs := make(chan error, attempts)
+	for range attempts {
+		go func() {
+			errs <- repo.Create(ctx, newUser("race@example.com"))
+		}()
+	}
+	var created int
+	for range attempts {
+		if err := <-errs; err == nil {
+			created++
+		} else {
+			assert.ErrorIs(t, err, ErrDuplicateEmail)
+		}
+	}
+	assert.Equal(t, 1, created)
 }
 
 func TestMongoUserRepository_FindByEmail(t *testing.T) {
@@ -59,6 +113,12 @@ func TestMongoUserRepository_FindByEmail(t *testing.T) {
 	assert.NotNil(t, foundUser)
 	assert.Equal(t, user.Email, foundUser.Email)
 
+	// Emails are looked up in any case
+	foundUser, err = repo.FindByEmail(ctx, " FindMe@Example.com")
+	assert.NoError(t, err)
+	require.NotNil(t, foundUser)
+	assert.Equal(t, user.ID, foundUser.ID)
+
 	notFoundUser, err := repo.FindByEmail(ctx, "nonexistent@example.com")
 	assert.NoError(t, err)
 	assert.Nil(t, notFoundUser)
@@ -86,6 +146,9 @@ func TestMongoUserRepository_FindByID(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, foundUser)
 	assert.Equal(t, user.ID, foundUser.ID)
+	// Users stored without schedule settings fall back to defaults
+	assert.Equal(t, model.DefaultTimezone, foundUser.Timezone)
+	assert.Empty(t, foundUser.TrainingDays)
 
 	notFoundUser, err := repo.FindByID(ctx, bson.NewObjectID().Hex())
 	assert.NoError(t, err)
@@ -112,6 +175,10 @@ func TestMongoUserRepository_Update(t *testing.T) {
 
 	user.PasswordHash = "newhash"
 	user.PreferredUnit = model.WeightUnitPounds
+	user.Timezone = "Asia/Tokyo"
+	user.TrainingDays = []model.Weekday{model.WeekdayTuesday, model.WeekdaySaturday}
+	locale := "es"
+	user.Locale = &locale
 
 	err = repo.Update(ctx, &user)
 	assert.NoError(t, err)
@@ -120,4 +187,184 @@ func TestMongoUserRepository_Update(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "newhash", updatedUser.PasswordHash)
 	assert.Equal(t, model.WeightUnit("POUNDS"), updatedUser.PreferredUnit)
+	assert.Equal(t, "Asia/Tokyo", updatedUser.Timezone)
+	assert.Equal(t, []model.Weekday{model.WeekdayTuesday, model.WeekdaySaturday}, updatedUser.TrainingDays)
+	require.NotNil(t, updatedUser.Locale)
+	assert.Equal(t, "es", *updatedUser.Locale)
+
+	// Clearing the locale follows the browser again
+	user.Locale = nil
+	require.NoError(t, repo.Update(ctx, &user))
+	updatedUser, err = repo.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, updatedUser.Locale)
+}
+
+func TestMongoUserRepository_UpdateEmail(t *testing.T) {
+	cleanupCollection(t, "users")
+	repo := NewMongoUserRepository(testDB)
+	ctx := context.Background()
+	require.NoError(t, repo.EnsureIndexes(ctx))
+
+	user := model.User{ID: bson.NewObjectID().Hex(), Email: "old@example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, repo.Create(ctx, user))
+	other := model.User{ID: bson.NewObjectID().Hex(), Email: "taken@example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, repo.Create(ctx, other))
+
+	pending := "new@example.com"
+	user.PendingEmail = &pending
+	require.NoError(t, repo.Update(ctx, &user))
+	found, err := repo.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "old@example.com", found.Email)
+	require.NotNil(t, found.PendingEmail)
+	assert.Equal(t, "new@example.com", *found.PendingEmail)
+
+	user.Email = "new@example.com"
+	user.PendingEmail = nil
+	user.EmailVerified = true
+	require.NoError(t, repo.Update(ctx, &user))
+	found, err = repo.FindByEmail(ctx, "new@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, user.ID, found.ID)
+	assert.Nil(t, found.PendingEmail)
+	assert.True(t, found.EmailVerified)
+
+	user.Email = "taken@example.com"
+	assert.ErrorIs(t, repo.Update(ctx, &user), ErrDuplicateEmail)
+}
+
+func TestMongoUserRepository_Deletion(t *testing.T) {
+	cleanupCollection(t, "users")
+	repo := NewMongoUserRepository(testDB)
+	ctx := context.Background()
+	require.NoError(t, repo.EnsureIndexes(ctx))
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	due := now.Add(-time.Hour)
+	later := now.Add(time.Hour)
+	newUser := func(email string, scheduledAt *time.Time) model.User {
+		user := model.User{ID: bson.NewObjectID().Hex(), Email: email, CreatedAt: now, UpdatedAt: now}
+		require.NoError(t, repo.Create(ctx, user))
+		if scheduledAt != nil {
+			user.DeletionRequestedAt = &now
+			user.DeletionScheduledAt = scheduledAt
+			require.NoError(t, repo.Update(ctx, &user))
+		}
+		return user
+	}
+	dueUser := newUser("due@example.com", &due)
+	laterUser := newUser("later@example.com", &later)
+	newUser("active@example.com", nil)
+
+	// A user that is not being deleted cannot be removed
+	require.NoError(t, repo.Delete(ctx, dueUser.ID))
+	found, err := repo.FindByID(ctx, dueUser.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+
+	claimed, err := repo.ClaimDueForDeletion(ctx, now, now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, dueUser.ID, claimed.ID)
+	require.NotNil(t, claimed.DeletionScheduledAt)
+	assert.True(t, due.Equal(*claimed.DeletionScheduledAt))
+	require.NotNil(t, claimed.DeletionStartedAt)
+	assert.True(t, now.Equal(*claimed.DeletionStartedAt))
+
+	// A claimed user is not claimed again until its claim goes stale, and cannot be cancelled
+	claimed, err = repo.ClaimDueForDeletion(ctx, now, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, claimed)
+	cancelled, err := repo.CancelDeletion(ctx, dueUser.ID)
+	require.NoError(t, err)
+	assert.False(t, cancelled)
+
+	claimed, err = repo.ClaimDueForDeletion(ctx, now.Add(time.Minute), now)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, dueUser.ID, claimed.ID)
+
+	require.NoError(t, repo.Delete(ctx, dueUser.ID))
+	found, err = repo.FindByID(ctx, dueUser.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+
+	// Deletions that have not been claimed can be cancelled, once
+	cancelled, err = repo.CancelDeletion(ctx, laterUser.ID)
+	require.NoError(t, err)
+	assert.True(t, cancelled)
+	found, err = repo.FindByID(ctx, laterUser.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found.DeletionScheduledAt)
+	assert.Nil(t, found.DeletionRequestedAt)
+	cancelled, err = repo.CancelDeletion(ctx, laterUser.ID)
+	require.NoError(t, err)
+	assert.False(t, cancelled)
+}
+
+func TestMongoUserRepository_TwoFactor(t *testing.T) {
+	cleanupCollection(t, "users")
+	repo := NewMongoUserRepository(testDB)
+	ctx := context.Background()
+
+	user := model.User{ID: bson.NewObjectID().Hex(), Email: "totp@example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, repo.Create(ctx, user))
+
+	ok, err := repo.SetTOTPSecret(ctx, user.ID, "JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+	require.True(t, ok)
+	// Only the secret enrolled can be turned on
+	ok, err = repo.EnableTwoFactor(ctx, user.ID, "ANOTHERSECRET", []string{"hash-1"})
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = repo.EnableTwoFactor(ctx, user.ID, "JBSWY3DPEHPK3PXP", []string{"hash-1", "hash-2"})
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = repo.SetTOTPSecret(ctx, user.ID, "ANOTHERSECRET")
+	require.NoError(t, err)
+	assert.False(t, ok, "cannot enroll again while enabled")
+
+	// A time step and a recovery code can each be used once
+	ok, err = repo.UseTOTPStep(ctx, user.ID, "JBSWY3DPEHPK3PXP", 56666666)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	for _, step := range []int64{56666666, 56666665} {
+		ok, err = repo.UseTOTPStep(ctx, user.ID, "JBSWY3DPEHPK3PXP", step)
+		require.NoError(t, err)
+		assert.False(t, ok, "step %d", step)
+	}
+	ok, err = repo.UseRecoveryCode(ctx, user.ID, "hash-1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = repo.UseRecoveryCode(ctx, user.ID, "hash-1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Saving the profile does not undo any of it
+	user.Timezone = "Europe/Madrid"
+	require.NoError(t, repo.Update(ctx, &user))
+	found, err := repo.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, found.TOTPEnabled)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", found.TOTPSecret)
+	assert.Equal(t, int64(56666666), found.TOTPLastStep)
+	assert.Equal(t, []string{"hash-2"}, found.RecoveryCodeHashes)
+
+	ok, err = repo.SetRecoveryCodes(ctx, user.ID, []string{"hash-3"})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Turning it off clears everything
+	require.NoError(t, repo.DisableTwoFactor(ctx, user.ID))
+	found, err = repo.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.False(t, found.TOTPEnabled)
+	assert.Empty(t, found.TOTPSecret)
+	assert.Zero(t, found.TOTPLastStep)
+	assert.Empty(t, found.RecoveryCodeHashes)
+	ok, err = repo.SetRecoveryCodes(ctx, user.ID, []string{"hash-4"})
+	require.NoError(t, err)
+	assert.False(t, ok)
 }
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -22,6 +23,33 @@ func NewMongoWorkoutRepository(database *mongo.Database) *MongoWorkoutRepository
 	}
 }
 
+// workoutLogDoc mirrors the stored document so _id can be decoded as an ObjectID.
+type workoutLogDoc struct {
+	ID           bson.ObjectID        `bson:"_id"`
+	UserID       string               `bson:"userId"`
+	Name         string               `bson:"name"`
+	StartTime    time.Time            `bson:"startTime"`
+	EndTime      time.Time            `bson:"endTime"`
+	ExerciseLogs []*model.ExerciseLog `bson:"exerciseLogs"`
+	LocationName *string              `bson:"locationName"`
+	GeneralNotes *string              `bson:"generalNotes"`
+}
+
+func (d *workoutLogDoc) toModel() *model.WorkoutLog {
+	log := &model.WorkoutLog{
+		ID:           d.ID.Hex(),
+		UserID:       d.UserID,
+		Name:         d.Name,
+		StartTime:    d.StartTime,
+		EndTime:      d.EndTime,
+		ExerciseLogs: d.ExerciseLogs,
+		LocationName: d.LocationName,
+		GeneralNotes: d.GeneralNotes,
+	}
+	log.LinkExerciseLogs()
+	return log
+}
+
 func (r *MongoWorkoutRepository) Create(ctx context.Context, logData model.WorkoutLog) (*model.WorkoutLog, error) {
 	if logData.ID == "" {
 		logData.ID = bson.NewObjectID().Hex()
@@ -43,6 +71,9 @@ func (r *MongoWorkoutRepository) Create(ctx context.Context, logData model.Worko
 		"exerciseLogs": logData.ExerciseLogs,
 		"locationName": logData.LocationName,
 		"generalNotes": logData.GeneralNotes,
+		// Bumped on every update; deletedAt stays null until the workout is deleted
+		"version":   1,
+		"deletedAt": nil,
 	}
 
 	_, err = r.collection.InsertOne(ctx, doc)
@@ -50,6 +81,7 @@ func (r *MongoWorkoutRepository) Create(ctx context.Context, logData model.Worko
 		return nil, fmt.Errorf("failed to insert workout log: %w", err)
 	}
 
+	logData.LinkExerciseLogs()
 	return &logData, nil
 }
 
@@ -60,16 +92,7 @@ func (r *MongoWorkoutRepository) GetByID(ctx context.Context, id string) (*model
 		return nil, fmt.Errorf("invalid id format: %w", err)
 	}
 
-	var doc struct {
-		ID           bson.ObjectID        `bson:"_id"`
-		UserID       string               `bson:"userId"`
-		Name         string               `bson:"name"`
-		StartTime    time.Time            `bson:"startTime"`
-		EndTime      time.Time            `bson:"endTime"`
-		ExerciseLogs []*model.ExerciseLog `bson:"exerciseLogs"`
-		LocationName *string              `bson:"locationName"`
-		GeneralNotes *string              `bson:"generalNotes"`
-	}
+	var doc workoutLogDoc
 
 	err = r.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(&doc)
 	if err != nil {
@@ -78,16 +101,7 @@ func (r *MongoWorkoutRepository) GetByID(ctx context.Context, id string) (*model
 		}
 		return nil, fmt.Errorf("failed to fetch workout log: %w", err)
 	}
-	return &model.WorkoutLog{
-		ID:           doc.ID.Hex(),
-		UserID:       doc.UserID,
-		Name:         doc.Name,
-		StartTime:    doc.StartTime,
-		EndTime:      doc.EndTime,
-		ExerciseLogs: doc.ExerciseLogs,
-		LocationName: doc.LocationName,
-		GeneralNotes: doc.GeneralNotes,
-	}, nil
+	return doc.toModel(), nil
 }
 
 func (r *MongoWorkoutRepository) ListByUser(ctx context.Context, userID string, limit, offset int) ([]*model.WorkoutLog, error) {
@@ -100,31 +114,474 @@ func (r *MongoWorkoutRepository) ListByUser(ctx context.Context, userID string,
 		_ = cursor.Close(ctx)
 	}()
 
-	var logs []*model.WorkoutLog
+	return decodeWorkoutLogs(ctx, cursor)
+}
+
+// ListByUserInRange returns the user's workouts that started within [from, to], oldest first.
+func (r *MongoWorkoutRepository) ListByUserInRange(ctx context.Context, userID string, from, to time.Time) ([]*model.WorkoutLog, error) {
+	filter := bson.M{
+		"userId":    userID,
+		"startTime": bson.M{"$gte": from, "$lte": to},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "startTime", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workout logs in range: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	return decodeWorkoutLogs(ctx, cursor)
+}
+
+// CountByUserInRange returns how many of the user's workouts started within [from, to], counted
+// from the userId + startTime index without loading the workouts.
+func (r *MongoWorkoutRepository) CountByUserInRange(ctx context.Context, userID string, from, to time.Time) (int64, error) {
+	filter := bson.M{
+		"userId":    userID,
+		"startTime": bson.M{"$gte": from, "$lte": to},
+	}
+	count, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count workout logs in range: %w", err)
+	}
+	return count, nil
+}
+
+// SummarizeDays totals the user's workouts started within [from, to) per calendar day in the
+// time zone (an IANA name), oldest first. Durations are whole minutes per workout, as
+// model.WorkoutLog would give, and volume is the sum of reps × weight over every set.
+func (r *MongoWorkoutRepository) SummarizeDays(ctx context.Context, userID string, from, to time.Time, timezone string) ([]*model.TrainingCalendarDay, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"userId": userID, "startTime": bson.M{"$gte": from, "$lt": to}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":          bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$startTime", "timezone": timezone}},
+			"workoutCount": bson.M{"$sum": 1},
+			"durationMinutes": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{"$endTime", "$startTime"}},
+				bson.M{"$trunc": bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{"$endTime", "$startTime"}}, 60000}}},
+				0,
+			}}},
+			"volume": bson.M{"$sum": bson.M{"$sum": bson.M{"$map": bson.M{
+				"input": bson.M{"$ifNull": bson.A{"$exerciseLogs", bson.A{}}},
+				"as":    "log",
+				"in": bson.M{"$sum": bson.M{"$map": bson.M{
+					"input": bson.M{"$ifNull": bson.A{"$$log.sets", bson.A{}}},
+					"as":    "set",
+					"in":    bson.M{"$multiply": bson.A{"$$set.reps", "$$set.weight"}},
+				}}},
+			}}}},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize workout days: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	days := []*model.TrainingCalendarDay{}
+	for cursor.Next(ctx) {
+		var row struct {
+			Date            string  `bson:"_id"`
+			WorkoutCount    int32   `bson:"workoutCount"`
+			DurationMinutes int32   `bson:"durationMinutes"`
+			Volume          float64 `bson:"volume"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, fmt.Errorf("failed to decode workout day: %w", err)
+		}
+		days = append(days, &model.TrainingCalendarDay{
+			Date:            row.Date,
+			WorkoutCount:    row.WorkoutCount,
+			DurationMinutes: row.DurationMinutes,
+			Volume:          row.Volume,
+		})
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return days, nil
+}
+
+// ListStartTimes returns the start times of the user's workouts started by to, oldest first. Only
+// startTime is read, so the userId + startTime index covers the query.
+func (r *MongoWorkoutRepository) ListStartTimes(ctx context.Context, userID string, to time.Time) ([]time.Time, error) {
+	opts := options.Find().
+		SetProjection(bson.M{"_id": 0, "startTime": 1}).
+		SetSort(bson.D{{Key: "startTime", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"userId": userID, "startTime": bson.M{"$lte": to}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workout start times: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	var times []time.Time
+	for cursor.Next(ctx) {
+		var row struct {
+			StartTime time.Time `bson:"startTime"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, fmt.Errorf("failed to decode workout start time: %w", err)
+		}
+		times = append(times, row.StartTime)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return times, nil
+}
+
+// CountExerciseUsage returns, per unique exercise ID, how many of the user's workouts started
+// since the given time include it.
+func (r *MongoWorkoutRepository) CountExerciseUsage(ctx context.Context, userID string, since time.Time) (map[string]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"userId": userID, "startTime": bson.M{"$gte": since}}}},
+		{{Key: "$unwind", Value: "$exerciseLogs"}},
+		// Count each workout once per exercise, even if it was logged in several blocks
+		{{Key: "$group", Value: bson.M{"_id": bson.M{"workout": "$_id", "exercise": "$exerciseLogs.uniqueExerciseId"}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$_id.exercise", "count": bson.M{"$sum": 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count exercise usage: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	usage := make(map[string]int)
+	for cursor.Next(ctx) {
+		var row struct {
+			ExerciseID string `bson:"_id"`
+			Count      int    `bson:"count"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, fmt.Errorf("failed to decode exercise usage: %w", err)
+		}
+		usage[row.ExerciseID] = row.Count
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return usage, nil
+}
+
+// CountByExercise returns how many of the user's workouts reference the exercise.
+func (r *MongoWorkoutRepository) CountByExercise(ctx context.Context, userID, exerciseID string) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"userId": userID, "exerciseLogs.uniqueExerciseId": exerciseID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count workouts for exercise: %w", err)
+	}
+	return count, nil
+}
+
+// ReplaceExercise points every exercise log of sourceID in the user's workouts at targetID,
+// bumping their versions as Update does. It returns the number of workouts modified.
+func (r *MongoWorkoutRepository) ReplaceExercise(ctx context.Context, userID, sourceID, targetID string) (int64, error) {
+	filter := bson.M{"userId": userID, "exerciseLogs.uniqueExerciseId": sourceID}
+	update := bson.M{
+		"$set": bson.M{"exerciseLogs.$[log].uniqueExerciseId": targetID},
+		"$inc": bson.M{"version": 1},
+	}
+	opts := options.UpdateMany().SetArrayFilters([]any{bson.M{"log.uniqueExerciseId": sourceID}})
+
+	result, err := r.collection.UpdateMany(ctx, filter, update, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to replace exercise in workouts: %w", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// RemoveExercise deletes every exercise log of the exercise from the user's workouts, bumping
+// their versions as Update does. It returns the number of workouts modified.
+func (r *MongoWorkoutRepository) RemoveExercise(ctx context.Context, userID, exerciseID string) (int64, error) {
+	filter := bson.M{"userId": userID, "exerciseLogs.uniqueExerciseId": exerciseID}
+	update := bson.M{
+		"$pull": bson.M{"exerciseLogs": bson.M{"uniqueExerciseId": exerciseID}},
+		"$inc":  bson.M{"version": 1},
+	}
+
+	result, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove exercise from workouts: %w", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// ListExerciseSessions returns the user's workouts containing the exercise, newest first,
+// starting after the cursor (nil for the first page). Only the exercise's own logs are loaded.
+func (r *MongoWorkoutRepository) ListExerciseSessions(ctx context.Context, userID, exerciseID string, after *model.SessionCursor, limit int) ([]*model.ExerciseSession, error) {
+	pipeline, err := exerciseSessionsPipeline(userID, exerciseID, after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list exercise sessions: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	var sessions []*model.ExerciseSession
 	for cursor.Next(ctx) {
-		var doc struct {
-			ID           bson.ObjectID        `bson:"_id"`
-			UserID       string               `bson:"userId"`
-			Name         string               `bson:"name"`
-			StartTime    time.Time            `bson:"startTime"`
-			EndTime      time.Time            `bson:"endTime"`
-			ExerciseLogs []*model.ExerciseLog `bson:"exerciseLogs"`
-			LocationName *string              `bson:"locationName"`
-			GeneralNotes *string              `bson:"generalNotes"`
+		var doc workoutLogDoc
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode workout log: %w", err)
+		}
+		sessions = append(sessions, toExerciseSession(&doc))
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// FindPreviousSessions returns, for each key, the user's latest session of the exercise before the
+// given workout (nil if there is none), in a single round trip. Results are aligned with keys.
+func (r *MongoWorkoutRepository) FindPreviousSessions(ctx context.Context, keys []model.PreviousSessionKey) ([]*model.ExerciseSession, error) {
+	sessions := make([]*model.ExerciseSession, len(keys))
+	if len(keys) == 0 {
+		return sessions, nil
+	}
+
+	// One indexed sub-pipeline per key, combined with $unionWith and tagged with the key's position
+	var pipeline mongo.Pipeline
+	for i, key := range keys {
+		before := key.Before
+		sub, err := exerciseSessionsPipeline(key.UserID, key.ExerciseID, &before, 1)
+		if err != nil {
+			return nil, err
+		}
+		sub = append(sub, bson.D{{Key: "$addFields", Value: bson.M{"keyIndex": i}}})
+		if i == 0 {
+			pipeline = sub
+			continue
 		}
+		pipeline = append(pipeline, bson.D{{Key: "$unionWith", Value: bson.M{"coll": r.collection.Name(), "pipeline": sub}}})
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find previous sessions: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	for cursor.Next(ctx) {
+		var doc workoutLogDoc
 		if err := cursor.Decode(&doc); err != nil {
 			return nil, fmt.Errorf("failed to decode workout log: %w", err)
 		}
-		logs = append(logs, &model.WorkoutLog{
-			ID:           doc.ID.Hex(),
-			UserID:       doc.UserID,
-			Name:         doc.Name,
-			StartTime:    doc.StartTime,
-			EndTime:      doc.EndTime,
-			ExerciseLogs: doc.ExerciseLogs,
-			LocationName: doc.LocationName,
-			GeneralNotes: doc.GeneralNotes,
-		})
+		i, ok := cursor.Current.Lookup("keyIndex").AsInt64OK()
+		if !ok || i < 0 || int(i) >= len(sessions) {
+			return nil, fmt.Errorf("previous session has no valid key index")
+		}
+		sessions[i] = toExerciseSession(&doc)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// exerciseSessionsPipeline pages through the user's workouts containing the exercise, newest first,
+// keeping only the exercise's logs. It is served by the userId + exerciseLogs.uniqueExerciseId + startTime index.
+func exerciseSessionsPipeline(userID, exerciseID string, after *model.SessionCursor, limit int) (mongo.Pipeline, error) {
+	match := bson.M{"userId": userID, "exerciseLogs.uniqueExerciseId": exerciseID}
+	if after != nil {
+		oid, err := bson.ObjectIDFromHex(after.WorkoutID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id format: %w", err)
+		}
+		match["$or"] = bson.A{
+			bson.M{"startTime": bson.M{"$lt": after.StartTime}},
+			bson.M{"startTime": after.StartTime, "_id": bson.M{"$lt": oid}},
+		}
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "startTime", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: bson.M{
+			"name":      1,
+			"startTime": 1,
+			"exerciseLogs": bson.M{"$filter": bson.M{
+				"input": "$exerciseLogs",
+				"cond":  bson.M{"$eq": bson.A{"$$this.uniqueExerciseId", exerciseID}},
+			}},
+		}}},
+	}, nil
+}
+
+// toExerciseSession combines the sets and notes of the workout's (already filtered) exercise logs.
+func toExerciseSession(doc *workoutLogDoc) *model.ExerciseSession {
+	session := &model.ExerciseSession{
+		WorkoutID:   doc.ID.Hex(),
+		WorkoutName: doc.Name,
+		Date:        doc.StartTime,
+		Sets:        []*model.Set{},
+	}
+
+	var notes []string
+	for _, log := range doc.ExerciseLogs {
+		session.Sets = append(session.Sets, log.Sets...)
+		if log.Notes != nil && *log.Notes != "" {
+			notes = append(notes, *log.Notes)
+		}
+	}
+	if len(notes) > 0 {
+		joined := strings.Join(notes, "\n")
+		session.Notes = &joined
+	}
+	return session
+}
+
+// SummarizeExercise returns the last performed date, session count and best set over all of the
+// user's sessions of the exercise. The best set is the heaviest with at least one rep; more reps,
+// then the earlier workout, break ties.
+func (r *MongoWorkoutRepository) SummarizeExercise(ctx context.Context, userID, exerciseID string) (*model.ExerciseHistorySummary, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"userId": userID, "exerciseLogs.uniqueExerciseId": exerciseID}}},
+		{{Key: "$facet", Value: bson.M{
+			"sessions": bson.A{
+				bson.M{"$group": bson.M{"_id": nil, "count": bson.M{"$sum": 1}, "last": bson.M{"$max": "$startTime"}}},
+			},
+			"best": bson.A{
+				bson.M{"$unwind": "$exerciseLogs"},
+				bson.M{"$match": bson.M{"exerciseLogs.uniqueExerciseId": exerciseID}},
+				bson.M{"$unwind": "$exerciseLogs.sets"},
+				bson.M{"$match": bson.M{"exerciseLogs.sets.reps": bson.M{"$gte": 1}}},
+				bson.M{"$sort": bson.D{
+					{Key: "exerciseLogs.sets.weight", Value: -1},
+					{Key: "exerciseLogs.sets.reps", Value: -1},
+					{Key: "startTime", Value: 1},
+				}},
+				bson.M{"$limit": 1},
+				bson.M{"$project": bson.M{"startTime": 1, "set": "$exerciseLogs.sets"}},
+			},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize exercise history: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	var result struct {
+		Sessions []struct {
+			Count int32     `bson:"count"`
+			Last  time.Time `bson:"last"`
+		} `bson:"sessions"`
+		Best []struct {
+			ID        bson.ObjectID `bson:"_id"`
+			StartTime time.Time     `bson:"startTime"`
+			Set       *model.Set    `bson:"set"`
+		} `bson:"best"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return nil, fmt.Errorf("failed to decode exercise history summary: %w", err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	summary := &model.ExerciseHistorySummary{}
+	if len(result.Sessions) > 0 {
+		last := result.Sessions[0].Last
+		summary.LastPerformed = &last
+		summary.TotalSessions = result.Sessions[0].Count
+	}
+	if len(result.Best) > 0 {
+		best := result.Best[0]
+		summary.BestSet = &model.ExerciseBestSet{Set: best.Set, WorkoutID: best.ID.Hex(), Date: best.StartTime}
+	}
+	return summary, nil
+}
+
+// BestLift returns the heaviest weight of the user's sets of the exercise with at least minReps
+// reps, in workouts started by the given time, or nil if there are none. Only the exercise's
+// workouts are read, through the userId + exerciseLogs.uniqueExerciseId + startTime index.
+func (r *MongoWorkoutRepository) BestLift(ctx context.Context, userID, exerciseID string, minReps int32, to time.Time) (*float64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"userId":                        userID,
+			"exerciseLogs.uniqueExerciseId": exerciseID,
+			"startTime":                     bson.M{"$lte": to},
+		}}},
+		{{Key: "$unwind", Value: "$exerciseLogs"}},
+		{{Key: "$match", Value: bson.M{"exerciseLogs.uniqueExerciseId": exerciseID}}},
+		{{Key: "$unwind", Value: "$exerciseLogs.sets"}},
+		{{Key: "$match", Value: bson.M{"exerciseLogs.sets.reps": bson.M{"$gte": minReps}}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "best": bson.M{"$max": "$exerciseLogs.sets.weight"}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find best lift: %w", err)
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	var result struct {
+		Best *float64 `bson:"best"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return nil, fmt.Errorf("failed to decode best lift: %w", err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return result.Best, nil
+}
+
+// EnsureIndexes creates the indexes used by workout queries.
+func (r *MongoWorkoutRepository) EnsureIndexes(ctx context.Context) error {
+	return workoutIndexes.ensure(ctx, r.collection)
+}
+
+// MissingIndexes lists the declared indexes that EnsureIndexes would create.
+func (r *MongoWorkoutRepository) MissingIndexes(ctx context.Context) ([]string, error) {
+	return workoutIndexes.missing(ctx, r.collection)
+}
+
+func decodeWorkoutLogs(ctx context.Context, cursor *mongo.Cursor) ([]*model.WorkoutLog, error) {
+	var logs []*model.WorkoutLog
+	for cursor.Next(ctx) {
+		var doc workoutLogDoc
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode workout log: %w", err)
+		}
+		logs = append(logs, doc.toModel())
 	}
 
 	if err := cursor.Err(); err != nil {
@@ -149,6 +606,7 @@ func (r *MongoWorkoutRepository) Update(ctx context.Context, logData model.Worko
 			"locationName": logData.LocationName,
 			"generalNotes": logData.GeneralNotes,
 		},
+		"$inc": bson.M{"version": 1},
 	}
 
 	// Filter by _id and optionally userId to ensure ownership.
@@ -166,5 +624,14 @@ func (r *MongoWorkoutRepository) Update(ctx context.Context, logData model.Worko
 		return nil, fmt.Errorf("workout log not found or unauthorized")
 	}
 
+	logData.LinkExerciseLogs()
 	return &logData, nil
 }
+
+func (r *MongoWorkoutRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete user's workouts: %w", err)
+	}
+	return result.DeletedCount, nil
+}
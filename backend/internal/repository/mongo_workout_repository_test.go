@@ -37,6 +37,39 @@ func TestMongoWorkoutRepository_Create(t *testing.T) {
 	assert.Equal(t, workout.UserID, foundWorkout.UserID)
 }
 
+func TestMongoWorkoutRepository_UpdateBumpsVersion(t *testing.T) {
+	cleanupCollection(t, "workout_logs")
+	repo := NewMongoWorkoutRepository(testDB)
+	ctx := context.Background()
+
+	workout := model.WorkoutLog{
+		ID:        bson.NewObjectID().Hex(),
+		UserID:    bson.NewObjectID().Hex(),
+		StartTime: time.Now(),
+		EndTime:   time.Now().Add(1 * time.Hour),
+		Name:      "Morning Workout",
+	}
+	_, err := repo.Create(ctx, workout)
+	require.NoError(t, err)
+
+	stored := func() bson.Raw {
+		oid, err := bson.ObjectIDFromHex(workout.ID)
+		require.NoError(t, err)
+		raw, err := testDB.Collection("workout_logs").FindOne(ctx, bson.M{"_id": oid}).Raw()
+		require.NoError(t, err)
+		return raw
+	}
+
+	raw := stored()
+	assert.Equal(t, int32(1), raw.Lookup("version").Int32())
+	assert.Equal(t, bson.TypeNull, raw.Lookup("deletedAt").Type)
+
+	workout.Name = "Evening Workout"
+	_, err = repo.Update(ctx, workout)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), stored().Lookup("version").Int32())
+}
+
 func TestMongoWorkoutRepository_GetByID(t *testing.T) {
 	cleanupCollection(t, "workout_logs")
 	cleanupCollection(t, "workout_logs")
@@ -110,3 +143,363 @@ func TestMongoWorkoutRepository_ListByUser(t *testing.T) {
 	assert.True(t, ids[workout2.ID])
 	assert.False(t, ids[workout3.ID])
 }
+
+func TestMongoWorkoutRepository_ListByUserInRange(t *testing.T) {
+	cleanupCollection(t, "workout_logs")
+	repo := NewMongoWorkoutRepository(testDB)
+	ctx := context.Background()
+
+	userID := bson.NewObjectID().Hex()
+	base := time.Date(2025, 3, 1, 18, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		_, err := repo.Create(ctx, model.WorkoutLog{
+			UserID:    userID,
+			Name:      "Session",
+			StartTime: base.AddDate(0, 0, i),
+			EndTime:   base.AddDate(0, 0, i).Add(time.Hour),
+		})
+		require.NoError(t, err)
+	}
+	_, err := repo.Create(ctx, model.WorkoutLog{UserID: bson.NewObjectID().Hex(), StartTime: base.AddDate(0, 0, 1)})
+	require.NoError(t, err)
+
+	logs, err := repo.ListByUserInRange(ctx, userID, base.AddDate(0, 0, 1), base.AddDate(0, 0, 3))
+	require.NoError(t, err)
+	require.Len(t, logs, 3)
+	// Oldest first
+	assert.True(t, logs[0].StartTime.Equal(base.AddDate(0, 0, 1)))
+	assert.True(t, logs[2].StartTime.Equal(base.AddDate(0, 0, 3)))
+
+	count, err := repo.CountByUserInRange(ctx, userID, base.AddDate(0, 0, 1), base.AddDate(0, 0, 3))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestMongoWorkoutRepository_SummarizeDays(t *testing.T) {
+	cleanupCollection(t, "workout_logs")
+	repo := NewMongoWorkoutRepository(testDB)
+	ctx := context.Background()
+	userID := bson.NewObjectID().Hex()
+
+	// 2024-03-10 02:30 UTC is still 2024-03-09 in New York.
+	late := time.Date(2024, 3, 10, 2, 30, 0, 0, time.UTC)
+	morning := time.Date(2024, 3, 10, 14, 0, 0, 0, time.UTC)
+	lastYear := time.Date(2023, 12, 31, 15, 0, 0, 0, time.UTC)
+	for _, w := range []model.WorkoutLog{
+		{UserID: userID, StartTime: lastYear, EndTime: lastYear.Add(time.Hour)},
+		{
+			UserID:    userID,
+			StartTime: late,
+			EndTime:   late.Add(45*time.Minute + 30*time.Second),
+			ExerciseLogs: []*model.ExerciseLog{
+				{Sets: []*model.Set{{Reps: 5, Weight: 100}}},
+				{Sets: []*model.Set{{Reps: 10, Weight: 20}, {Reps: 10, Weight: 30}}},
+			},
+		},
+		{UserID: userID, StartTime: morning, EndTime: morning.Add(30 * time.Minute)},
+		// Still in progress, so no duration
+		{UserID: userID, StartTime: morning.Add(8 * time.Hour)},
+		{UserID: bson.NewObjectID().Hex(), StartTime: morning, EndTime: morning.Add(time.Hour)},
+	} {
+		_, err := repo.Create(ctx, w)
+		require.NoError(t, err)
+	}
+
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, ny)
+	days, err := repo.SummarizeDays(ctx, userID, from, from.AddDate(1, 0, 0), "America/New_York")
+	require.NoError(t, err)
+	assert.Equal(t, []*model.TrainingCalendarDay{
+		{Date: "2024-03-09", WorkoutCount: 1, DurationMinutes: 45, Volume: 1000},
+		{Date: "2024-03-10", WorkoutCount: 2, DurationMinutes: 30, Volume: 0},
+	}, days)
+
+	starts, err := repo.ListStartTimes(ctx, userID, morning)
+	require.NoError(t, err)
+	require.Len(t, starts, 3)
+	assert.True(t, starts[0].Equal(lastYear))
+	assert.True(t, starts[2].Equal(morning))
+}
+
+func TestMongoWorkoutRepository_CountExerciseUsage(t *testing.T) {
+	cleanupCollection(t, "workout_logs")
+	repo := NewMongoWorkoutRepository(testDB)
+	ctx := context.Background()
+	userID := bson.NewObjectID().Hex()
+
+	logs := []model.WorkoutLog{
+		{
+			UserID:    userID,
+			StartTime: time.Now().Add(-48 * time.Hour),
+			// Squat logged twice in one workout counts once
+			ExerciseLogs: []*model.ExerciseLog{{UniqueExerciseID: "squat"}, {UniqueExerciseID: "bench"}, {UniqueExerciseID: "squat"}},
+		},
+		{
+			UserID:       userID,
+			StartTime:    time.Now().Add(-24 * time.Hour),
+			ExerciseLogs: []*model.ExerciseLog{{UniqueExerciseID: "squat"}},
+		},
+		{
+			UserID:       bson.NewObjectID().Hex(),
+			StartTime:    time.Now(),
+			ExerciseLogs: []*model.ExerciseLog{{UniqueExerciseID: "bench"}},
+		},
+		// Before the window
+		{
+			UserID:       userID,
+			StartTime:    time.Now().Add(-30 * 24 * time.Hour),
+			ExerciseLogs: []*model.ExerciseLog{{UniqueExerciseID: "deadlift"}},
+		},
+	}
+	for _, l := range logs {
+		l.ID = bson.NewObjectID().Hex()
+		_, err := repo.Create(ctx, l)
+		require.NoError(t, err)
+	}
+
+	usage, err := repo.CountExerciseUsage(ctx, userID, time.Now().Add(-7*24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"squat": 2, "bench": 1}, usage)
+}
+
+func TestMongoWorkoutRepository_ReplaceAndRemoveExercise(t *testing.T) {
+	cleanupCollection(t, "workout_logs")
+	repo := NewMongoWorkoutRepository(testDB)
+	ctx := context.Background()
+	userID := bson.NewObjectID().Hex()
+	otherUserID := bson.NewObjectID().Hex()
+
+	mine, err := repo.Create(ctx, model.WorkoutLog{
+		ID:           bson.NewObjectID().Hex(),
+		UserID:       userID,
+		StartTime:    time.Now(),
+		ExerciseLogs: []*model.ExerciseLog{{UniqueExerciseID: "typo"}, {UniqueExerciseID: "bench"}, {UniqueExerciseID: "typo"}},
+	})
+	require.NoError(t, err)
+	theirs, err := repo.Create(ctx, model.WorkoutLog{
+		ID:           bson.NewObjectID().Hex(),
+		UserID:       otherUserID,
+		StartTime:    time.Now(),
+		ExerciseLogs: []*model.ExerciseLog{{UniqueExerciseID: "typo"}},
+	})
+	require.NoError(t, err)
+
+	count, err := repo.CountByExercise(ctx, userID, "typo")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Merges change workouts outside Update, so they must bump the version too
+	version := func(id string) int32 {
+		oid, err := bson.ObjectIDFromHex(id)
+		require.NoError(t, err)
+		raw, err := testDB.Collection("workout_logs").FindOne(ctx, bson.M{"_id": oid}).Raw()
+		require.NoError(t, err)
+		return raw.Lookup("version").Int32()
+	}
+
+	modified, err := repo.ReplaceExercise(ctx, userID, "typo", "squat")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), modified)
+
+	found, err := repo.GetByID(ctx, mine.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "squat", found.ExerciseLogs[0].UniqueExerciseID)
+	assert.Equal(t, "bench", found.ExerciseLogs[1].UniqueExerciseID)
+	assert.Equal(t, "squat", found.ExerciseLogs[2].UniqueExerciseID)
+	assert.Equal(t, int32(2), version(mine.ID))
+
+	// Other users' workouts are untouched
+	found, err = repo.GetByID(ctx, theirs.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "typo", found.ExerciseLogs[0].UniqueExerciseID)
+	assert.Equal(t, int32(1), version(theirs.ID))
+
+	modified, err = repo.RemoveExercise(ctx, userID, "squat")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), modified)
+	found, err = repo.GetByID(ctx, mine.ID)
+	require.NoError(t, err)
+	require.Len(t, found.ExerciseLogs, 1)
+	assert.Equal(t, "bench", found.ExerciseLogs[0].UniqueExerciseID)
+	assert.Equal(t, int32(3), version(mine.ID))
+}
+
+func TestMongoWorkoutRepository_ExerciseHistory(t *testing.T) {
+	cleanupCollection(t, "workout_logs")
+	repo := NewMongoWorkoutRepository(testDB)
+	ctx := context.Background()
+	require.NoError(t, repo.EnsureIndexes(ctx))
+	userID := bson.NewObjectID().Hex()
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	note := "felt heavy"
+
+	workouts := []model.WorkoutLog{
+		{
+			Name:      "Day 1",
+			StartTime: base,
+			ExerciseLogs: []*model.ExerciseLog{
+				{UniqueExerciseID: "bench", Sets: []*model.Set{{Reps: 5, Weight: 100, Order: 1}}},
+			},
+		},
+		{
+			Name:      "Day 2",
+			StartTime: base.Add(48 * time.Hour),
+			ExerciseLogs: []*model.ExerciseLog{
+				{UniqueExerciseID: "bench", Sets: []*model.Set{{Reps: 3, Weight: 100, Order: 1}}, Notes: &note},
+				{UniqueExerciseID: "squat", Sets: []*model.Set{{Reps: 5, Weight: 150, Order: 2}}},
+				{UniqueExerciseID: "bench", Sets: []*model.Set{{Reps: 8, Weight: 80, Order: 3}}},
+			},
+		},
+		{
+			Name:         "Legs",
+			StartTime:    base.Add(72 * time.Hour),
+			ExerciseLogs: []*model.ExerciseLog{{UniqueExerciseID: "squat", Sets: []*model.Set{{Reps: 5, Weight: 160}}}},
+		},
+		{
+			Name:      "Day 3",
+			StartTime: base.Add(96 * time.Hour),
+			ExerciseLogs: []*model.ExerciseLog{
+				// Heavier but zero reps (a failed attempt) is not a best set
+				{UniqueExerciseID: "bench", Sets: []*model.Set{{Reps: 0, Weight: 110, Order: 1}, {Reps: 2, Weight: 95, Order: 2}}},
+			},
+		},
+	}
+	ids := make([]string, len(workouts))
+	for i, w := range workouts {
+		w.ID = bson.NewObjectID().Hex()
+		w.UserID = userID
+		_, err := repo.Create(ctx, w)
+		require.NoError(t, err)
+		ids[i] = w.ID
+	}
+	// Another user's bench session is never included
+	_, err := repo.Create(ctx, model.WorkoutLog{
+		ID:           bson.NewObjectID().Hex(),
+		UserID:       bson.NewObjectID().Hex(),
+		StartTime:    base.Add(120 * time.Hour),
+		ExerciseLogs: []*model.ExerciseLog{{UniqueExerciseID: "bench", Sets: []*model.Set{{Reps: 1, Weight: 200}}}},
+	})
+	require.NoError(t, err)
+
+	page, err := repo.ListExerciseSessions(ctx, userID, "bench", nil, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, ids[3], page[0].WorkoutID)
+	assert.Equal(t, ids[1], page[1].WorkoutID)
+	assert.Equal(t, "Day 2", page[1].WorkoutName)
+	assert.True(t, base.Add(48*time.Hour).Equal(page[1].Date))
+	// Only bench sets, combined across both logs
+	require.Len(t, page[1].Sets, 2)
+	assert.Equal(t, int32(3), page[1].Sets[0].Reps)
+	assert.Equal(t, int32(8), page[1].Sets[1].Reps)
+	require.NotNil(t, page[1].Notes)
+	assert.Equal(t, "felt heavy", *page[1].Notes)
+
+	next, err := repo.ListExerciseSessions(ctx, userID, "bench", &model.SessionCursor{StartTime: page[1].Date, WorkoutID: page[1].WorkoutID}, 2)
+	require.NoError(t, err)
+	require.Len(t, next, 1)
+	assert.Equal(t, ids[0], next[0].WorkoutID)
+
+	summary, err := repo.SummarizeExercise(ctx, userID, "bench")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), summary.TotalSessions)
+	require.NotNil(t, summary.LastPerformed)
+	assert.True(t, base.Add(96*time.Hour).Equal(*summary.LastPerformed))
+	require.NotNil(t, summary.BestSet)
+	// 100kg × 5 beats 100kg × 3
+	assert.Equal(t, ids[0], summary.BestSet.WorkoutID)
+	assert.Equal(t, int32(5), summary.BestSet.Set.Reps)
+	assert.Equal(t, 100.0, summary.BestSet.Set.Weight)
+
+	empty, err := repo.SummarizeExercise(ctx, userID, "deadlift")
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), empty.TotalSessions)
+	assert.Nil(t, empty.LastPerformed)
+	assert.Nil(t, empty.BestSet)
+
+	// The failed 110 kg attempt and the other user's 200 kg single don't count
+	best, err := repo.BestLift(ctx, userID, "bench", 1, base.Add(120*time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, best)
+	assert.Equal(t, 100.0, *best)
+	best, err = repo.BestLift(ctx, userID, "bench", 6, base.Add(120*time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, best)
+	assert.Equal(t, 80.0, *best)
+	// Nor do workouts after the given time
+	best, err = repo.BestLift(ctx, userID, "bench", 6, base.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, best)
+}
+
+func TestMongoWorkoutRepository_FindPreviousSessions(t *testing.T) {
+	cleanupCollection(t, "workout_logs")
+	repo := NewMongoWorkoutRepository(testDB)
+	ctx := context.Background()
+	require.NoError(t, repo.EnsureIndexes(ctx))
+	userID := bson.NewObjectID().Hex()
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	create := func(start time.Time, exerciseIDs ...string) *model.WorkoutLog {
+		logs := make([]*model.ExerciseLog, 0, len(exerciseIDs))
+		for _, id := range exerciseIDs {
+			logs = append(logs, &model.ExerciseLog{UniqueExerciseID: id, Sets: []*model.Set{{Reps: 5, Weight: 100}}})
+		}
+		w, err := repo.Create(ctx, model.WorkoutLog{ID: bson.NewObjectID().Hex(), UserID: userID, StartTime: start, ExerciseLogs: logs})
+		require.NoError(t, err)
+		return w
+	}
+	first := create(base, "bench", "squat")
+	second := create(base.Add(48*time.Hour), "bench")
+	current := create(base.Add(96*time.Hour), "bench", "squat", "row")
+
+	keys := make([]model.PreviousSessionKey, 0, len(current.ExerciseLogs))
+	for _, l := range current.ExerciseLogs {
+		keys = append(keys, model.PreviousSessionKey{UserID: l.UserID, ExerciseID: l.UniqueExerciseID, Before: l.Workout})
+	}
+	// The current workout's logs were linked to it on creation
+	assert.Equal(t, current.ID, keys[0].Before.WorkoutID)
+
+	sessions, err := repo.FindPreviousSessions(ctx, keys)
+	require.NoError(t, err)
+	require.Len(t, sessions, 3)
+	require.NotNil(t, sessions[0])
+	assert.Equal(t, second.ID, sessions[0].WorkoutID)
+	require.NotNil(t, sessions[1])
+	assert.Equal(t, first.ID, sessions[1].WorkoutID)
+	assert.Len(t, sessions[1].Sets, 1)
+	assert.Nil(t, sessions[2], "row was never performed before")
+
+	// Nothing precedes the first workout
+	sessions, err = repo.FindPreviousSessions(ctx, []model.PreviousSessionKey{
+		{UserID: userID, ExerciseID: "bench", Before: model.SessionCursor{StartTime: first.StartTime, WorkoutID: first.ID}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*model.ExerciseSession{nil}, sessions)
+
+	sessions, err = repo.FindPreviousSessions(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestMongoWorkoutRepository_DeleteAllByUser(t *testing.T) {
+	cleanupCollection(t, "workout_logs")
+	repo := NewMongoWorkoutRepository(testDB)
+	ctx := context.Background()
+
+	userID := bson.NewObjectID().Hex()
+	otherUserID := bson.NewObjectID().Hex()
+	for _, owner := range []string{userID, userID, otherUserID} {
+		_, err := repo.Create(ctx, model.WorkoutLog{ID: bson.NewObjectID().Hex(), UserID: owner, StartTime: time.Now(), EndTime: time.Now(), Name: "Workout"})
+		require.NoError(t, err)
+	}
+
+	deleted, err := repo.DeleteAllByUser(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	count, err := testDB.Collection("workout_logs").CountDocuments(ctx, bson.M{"userId": otherUserID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
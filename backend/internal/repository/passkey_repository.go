@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+var ErrDuplicatePasskey = errors.New("this passkey is already registered")
+
+type PasskeyRepository interface {
+	// Create saves the passkey, failing with ErrDuplicatePasskey if its credential is already registered.
+	Create(ctx context.Context, passkey *model.Passkey) error
+	FindByCredentialID(ctx context.Context, credentialID []byte) (*model.Passkey, error)
+	// ListByUser returns the user's passkeys, oldest first.
+	ListByUser(ctx context.Context, userID string) ([]*model.Passkey, error)
+	// RecordUse saves the signature counter and time of a sign-in.
+	RecordUse(ctx context.Context, id string, signCount uint32, at time.Time) error
+	// DeleteForUser deletes a passkey of the user, reporting false if the user has no such passkey.
+	DeleteForUser(ctx context.Context, userID, id string) (bool, error)
+	// DeleteAllByUser deletes every passkey of the user, for account deletion.
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
+}
+
+type MongoPasskeyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoPasskeyRepository(db *mongo.Database) *MongoPasskeyRepository {
+	return &MongoPasskeyRepository{
+		collection: db.Collection("passkeys"),
+	}
+}
+
+// EnsureIndexes creates the unique index on credential IDs, used to sign in, and the per-user index.
+func (r *MongoPasskeyRepository) EnsureIndexes(ctx context.Context) error {
+	return passkeyIndexes.ensure(ctx, r.collection)
+}
+
+// MissingIndexes lists the declared indexes that EnsureIndexes would create.
+func (r *MongoPasskeyRepository) MissingIndexes(ctx context.Context) ([]string, error) {
+	return passkeyIndexes.missing(ctx, r.collection)
+}
+
+func (r *MongoPasskeyRepository) Create(ctx context.Context, passkey *model.Passkey) error {
+	if passkey.ID == "" {
+		passkey.ID = bson.NewObjectID().Hex()
+	}
+	_, err := r.collection.InsertOne(ctx, passkey)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicatePasskey
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create passkey: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoPasskeyRepository) FindByCredentialID(ctx context.Context, credentialID []byte) (*model.Passkey, error) {
+	var passkey model.Passkey
+	err := r.collection.FindOne(ctx, bson.M{"credentialId": credentialID}).Decode(&passkey)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find passkey: %w", err)
+	}
+	return &passkey, nil
+}
+
+func (r *MongoPasskeyRepository) ListByUser(ctx context.Context, userID string) ([]*model.Passkey, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"userId": userID}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list passkeys: %w", err)
+	}
+	passkeys := []*model.Passkey{}
+	if err := cursor.All(ctx, &passkeys); err != nil {
+		return nil, fmt.Errorf("failed to decode passkeys: %w", err)
+	}
+	return passkeys, nil
+}
+
+func (r *MongoPasskeyRepository) RecordUse(ctx context.Context, id string, signCount uint32, at time.Time) error {
+	_, err := r.collection.UpdateByID(ctx, id, bson.M{"$set": bson.M{"signCount": signCount, "lastUsedAt": at}})
+	if err != nil {
+		return fmt.Errorf("failed to record passkey use: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoPasskeyRepository) DeleteForUser(ctx context.Context, userID, id string) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "userId": userID})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete passkey: %w", err)
+	}
+	return result.DeletedCount == 1, nil
+}
+
+func (r *MongoPasskeyRepository) DeleteAllByUser(ctx context.Context, userID string) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete user's passkeys: %w", err)
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+func TestMongoPasskeyRepository(t *testing.T) {
+	cleanupCollection(t, "passkeys")
+	repo := NewMongoPasskeyRepository(testDB)
+	ctx := context.Background()
+	require.NoError(t, repo.EnsureIndexes(ctx))
+
+	userID := bson.NewObjectID().Hex()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	newPasskey := func(credentialID byte, createdAt time.Time) *model.Passkey {
+		return &model.Passkey{
+			UserID:       userID,
+			Name:         "Laptop",
+			CredentialID: []byte{credentialID, 0xff},
+			PublicKey:    []byte{0xa5, 0x01, 0x02},
+			SignCount:    4_000_000_000,
+			Transports:   []string{"internal"},
+			CreatedAt:    createdAt,
+		}
+	}
+
+	laptop := newPasskey(1, now)
+	require.NoError(t, repo.Create(ctx, laptop))
+	require.NotEmpty(t, laptop.ID)
+	phone := newPasskey(2, now.Add(-time.Hour))
+	require.NoError(t, repo.Create(ctx, phone))
+	assert.ErrorIs(t, repo.Create(ctx, newPasskey(1, now)), ErrDuplicatePasskey)
+
+	found, err := repo.FindByCredentialID(ctx, []byte{1, 0xff})
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, laptop.ID, found.ID)
+	assert.Equal(t, uint32(4_000_000_000), found.SignCount)
+	assert.Equal(t, []byte{0xa5, 0x01, 0x02}, found.PublicKey)
+	assert.Nil(t, found.LastUsedAt)
+
+	found, err = repo.FindByCredentialID(ctx, []byte{9})
+	require.NoError(t, err)
+	assert.Nil(t, found)
+
+	require.NoError(t, repo.RecordUse(ctx, laptop.ID, 4_000_000_001, now))
+	found, err = repo.FindByCredentialID(ctx, laptop.CredentialID)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(4_000_000_001), found.SignCount)
+	require.NotNil(t, found.LastUsedAt)
+	assert.True(t, now.Equal(*found.LastUsedAt))
+
+	passkeys, err := repo.ListByUser(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, passkeys, 2)
+	assert.Equal(t, phone.ID, passkeys[0].ID)
+
+	// Only the owner can delete a passkey
+	deleted, err := repo.DeleteForUser(ctx, bson.NewObjectID().Hex(), laptop.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	deleted, err = repo.DeleteForUser(ctx, userID, laptop.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	count, err := repo.DeleteAllByUser(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
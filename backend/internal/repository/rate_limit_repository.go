@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+// RateLimitRepository stores failed attempts per rate limit key.
+type RateLimitRepository interface {
+	// Get returns the key's failures, or nil if it has none.
+	Get(ctx context.Context, key string) (*model.RateLimitState, error)
+	// RecordFailure counts a failure at the given time and returns the updated state. The count
+	// restarts if the previous failure is older than resetAfter, and the state expires at expiresAt.
+	RecordFailure(ctx context.Context, key string, at time.Time, resetAfter time.Duration, expiresAt time.Time) (*model.RateLimitState, error)
+	// RecordAttempt stores the key's failures as of at, provided its state is still seen (nil if
+	// it had none). It reports false without storing anything if another attempt changed the
+	// state first, so each attempt is admitted against the count it was checked with.
+	RecordAttempt(ctx context.Context, key string, seen *model.RateLimitState, failures int, at time.Time, expiresAt time.Time) (bool, error)
+	// Forgive takes back one failure recorded against the key, e.g. for an attempt counted up
+	// front that turned out not to be a guess.
+	Forgive(ctx context.Context, key string) error
+	// Reset forgets the key's failures.
+	Reset(ctx context.Context, key string) error
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMongoRateLimitRepository(t *testing.T) {
+	cleanupCollection(t, "rate_limits")
+	repo := NewMongoRateLimitRepository(testDB)
+	require.NoError(t, repo.EnsureIndexes(context.Background()))
+	testRateLimitRepository(t, repo)
+}
+
+func TestMemoryRateLimitRepository(t *testing.T) {
+	testRateLimitRepository(t, NewMemoryRateLimitRepository())
+}
+
+// testRateLimitRepository checks the behaviour both implementations must share.
+func testRateLimitRepository(t *testing.T, repo RateLimitRepository) {
+	ctx := context.Background()
+	start := time.Now().UTC().Truncate(time.Millisecond)
+	expires := start.Add(time.Hour)
+
+	state, err := repo.Get(ctx, "login:account:a@example.com")
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	for i := 1; i <= 3; i++ {
+		state, err = repo.RecordFailure(ctx, "login:account:a@example.com", start.Add(time.Duration(i)*time.Second), time.Minute, expires)
+		require.NoError(t, err)
+		assert.Equal(t, i, state.Failures)
+	}
+	assert.True(t, start.Add(3*time.Second).Equal(state.LastFailureAt))
+	assert.True(t, expires.Equal(state.ExpiresAt))
+
+	// Keys are independent
+	other, err := repo.RecordFailure(ctx, "login:ip:203.0.113.7", start, time.Minute, expires)
+	require.NoError(t, err)
+	assert.Equal(t, 1, other.Failures)
+
+	found, err := repo.Get(ctx, "login:account:a@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, 3, found.Failures)
+
+	// A failure after the reset window starts counting again
+	state, err = repo.RecordFailure(ctx, "login:account:a@example.com", start.Add(5*time.Minute), time.Minute, expires)
+	require.NoError(t, err)
+	assert.Equal(t, 1, state.Failures)
+
+	require.NoError(t, repo.Reset(ctx, "login:account:a@example.com"))
+	found, err = repo.Get(ctx, "login:account:a@example.com")
+	require.NoError(t, err)
+	assert.Nil(t, found)
+
+	// Resetting an unknown key is not an error
+	require.NoError(t, repo.Reset(ctx, "login:account:unknown@example.com"))
+
+	// Attempts are only recorded against the state they were checked with
+	recorded, err := repo.RecordAttempt(ctx, "2fa:user:1", nil, 1, start, expires)
+	require.NoError(t, err)
+	assert.True(t, recorded)
+	recorded, err = repo.RecordAttempt(ctx, "2fa:user:1", nil, 1, start, expires)
+	require.NoError(t, err)
+	assert.False(t, recorded, "a second first attempt must not be recorded")
+
+	seen, err := repo.Get(ctx, "2fa:user:1")
+	require.NoError(t, err)
+	require.NotNil(t, seen)
+	recorded, err = repo.RecordAttempt(ctx, "2fa:user:1", seen, 2, start.Add(time.Second), expires)
+	require.NoError(t, err)
+	assert.True(t, recorded)
+	recorded, err = repo.RecordAttempt(ctx, "2fa:user:1", seen, 2, start.Add(time.Second), expires)
+	require.NoError(t, err)
+	assert.False(t, recorded, "an attempt checked against an outdated state must not be recorded")
+
+	// Forgiving takes back one failure, and never goes below zero
+	require.NoError(t, repo.Forgive(ctx, "2fa:user:1"))
+	found, err = repo.Get(ctx, "2fa:user:1")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, 1, found.Failures)
+	require.NoError(t, repo.Forgive(ctx, "2fa:user:1"))
+	require.NoError(t, repo.Forgive(ctx, "2fa:user:1"))
+	found, err = repo.Get(ctx, "2fa:user:1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, found.Failures)
+	require.NoError(t, repo.Forgive(ctx, "2fa:user:unknown"))
+}
@@ -0,0 +1,11 @@
+package repository
+
+import (
+	"context"
+)
+
+// Transactor runs a function inside a database transaction. Repository calls made with the
+// context passed to fn take part in the transaction; if fn returns an error nothing is committed.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
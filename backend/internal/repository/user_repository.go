@@ -2,14 +2,48 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/riverajo/fitness-app/backend/internal/model"
 )
 
+// ErrDuplicateEmail is returned when creating a user whose email is already registered.
+var ErrDuplicateEmail = errors.New("a user with this email already exists")
+
 // UserRepository defines the interface for user data access.
 type UserRepository interface {
 	Create(ctx context.Context, user model.User) error
 	FindByEmail(ctx context.Context, email string) (*model.User, error)
 	FindByID(ctx context.Context, id string) (*model.User, error)
+	// Update saves the user's profile and account fields; two-factor settings are only changed
+	// through the methods below, which cannot undo a concurrent change to the rest of the user.
 	Update(ctx context.Context, user *model.User) error
+	// Delete removes a user claimed by ClaimDueForDeletion; other users are left alone.
+	Delete(ctx context.Context, id string) error
+	// ClaimDueForDeletion marks the user with the earliest scheduled deletion at or before now as
+	// being deleted and returns it, or nil if none is due. Users claimed at or before staleBefore,
+	// whose deletion was interrupted, are claimed again.
+	ClaimDueForDeletion(ctx context.Context, now, staleBefore time.Time) (*model.User, error)
+	// CancelDeletion clears the user's scheduled deletion, reporting false if none is scheduled or
+	// it has already been claimed.
+	CancelDeletion(ctx context.Context, id string) (bool, error)
+
+	// SetTOTPSecret saves a new, not yet confirmed, TOTP secret, reporting false if two-factor
+	// authentication is already enabled.
+	SetTOTPSecret(ctx context.Context, id, secret string) (bool, error)
+	// EnableTwoFactor turns on two-factor authentication with the recovery code hashes, reporting
+	// false if it is already on or the secret is no longer the one enrolled.
+	EnableTwoFactor(ctx context.Context, id, secret string, recoveryCodeHashes []string) (bool, error)
+	// SetRecoveryCodes replaces the recovery code hashes, reporting false if two-factor
+	// authentication is off.
+	SetRecoveryCodes(ctx context.Context, id string, recoveryCodeHashes []string) (bool, error)
+	// DisableTwoFactor turns off two-factor authentication and clears its secret and codes.
+	DisableTwoFactor(ctx context.Context, id string) error
+	// UseTOTPStep records step as the time step of the last accepted code for the secret,
+	// reporting false if a code for it or a later step was already accepted, so each code is
+	// accepted once even by concurrent requests.
+	UseTOTPStep(ctx context.Context, id, secret string, step int64) (bool, error)
+	// UseRecoveryCode removes the recovery code hash, reporting false if it was already used.
+	UseRecoveryCode(ctx context.Context, id, hash string) (bool, error)
 }
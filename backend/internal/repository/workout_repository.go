@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/riverajo/fitness-app/backend/internal/model"
 )
@@ -9,7 +10,38 @@ import (
 // WorkoutRepository defines the interface for workout data access.
 type WorkoutRepository interface {
 	Create(ctx context.Context, log model.WorkoutLog) (*model.WorkoutLog, error)
+	// DeleteAllByUser deletes every workout of the user, for account deletion.
+	DeleteAllByUser(ctx context.Context, userID string) (int64, error)
 	GetByID(ctx context.Context, id string) (*model.WorkoutLog, error)
 	ListByUser(ctx context.Context, userID string, limit, offset int) ([]*model.WorkoutLog, error)
+	// ListByUserInRange returns the user's workouts that started within [from, to], oldest first.
+	ListByUserInRange(ctx context.Context, userID string, from, to time.Time) ([]*model.WorkoutLog, error)
+	// CountByUserInRange returns how many of the user's workouts started within [from, to].
+	CountByUserInRange(ctx context.Context, userID string, from, to time.Time) (int64, error)
+	// SummarizeDays totals the user's workouts started within [from, to) per calendar day in the
+	// time zone (an IANA name), oldest first.
+	SummarizeDays(ctx context.Context, userID string, from, to time.Time, timezone string) ([]*model.TrainingCalendarDay, error)
+	// ListStartTimes returns the start times of the user's workouts started by to, oldest first.
+	ListStartTimes(ctx context.Context, userID string, to time.Time) ([]time.Time, error)
 	Update(ctx context.Context, log model.WorkoutLog) (*model.WorkoutLog, error)
+	// CountExerciseUsage returns, per unique exercise ID, how many of the user's workouts started
+	// since the given time include it.
+	CountExerciseUsage(ctx context.Context, userID string, since time.Time) (map[string]int, error)
+	// CountByExercise returns how many of the user's workouts reference the exercise.
+	CountByExercise(ctx context.Context, userID, exerciseID string) (int64, error)
+	// ReplaceExercise points every exercise log of sourceID in the user's workouts at targetID.
+	ReplaceExercise(ctx context.Context, userID, sourceID, targetID string) (int64, error)
+	// ListExerciseSessions returns the user's workouts containing the exercise, newest first,
+	// starting after the cursor (nil for the first page).
+	ListExerciseSessions(ctx context.Context, userID, exerciseID string, after *model.SessionCursor, limit int) ([]*model.ExerciseSession, error)
+	// FindPreviousSessions returns, for each key, the user's latest session of the exercise before the
+	// given workout (nil if there is none), in a single query. Results are aligned with keys.
+	FindPreviousSessions(ctx context.Context, keys []model.PreviousSessionKey) ([]*model.ExerciseSession, error)
+	// BestLift returns the heaviest weight of the user's sets of the exercise with at least minReps
+	// reps, in workouts started by the given time, or nil if there are none.
+	BestLift(ctx context.Context, userID, exerciseID string, minReps int32, to time.Time) (*float64, error)
+	// SummarizeExercise returns the last performed date, session count and best set over all of the user's sessions of the exercise.
+	SummarizeExercise(ctx context.Context, userID, exerciseID string) (*model.ExerciseHistorySummary, error)
+	// RemoveExercise deletes every exercise log of the exercise from the user's workouts.
+	RemoveExercise(ctx context.Context, userID, exerciseID string) (int64, error)
 }
@@ -4,17 +4,120 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
 )
 
 type SystemExercise struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Category    string `json:"category"`
+	// Slug is the entry's stable identifier; exercises are matched by it, so names can change freely
+	Slug string `json:"slug"`
+	// PreviousNames lets an entry adopt an exercise seeded under an older name before slugs existed
+	PreviousNames []string `json:"previousNames,omitempty"`
+	// Deprecated exercises are hidden from search but still resolve from existing logs
+	Deprecated bool `json:"deprecated,omitempty"`
+	// ReplacedBy is the slug of the exercise that supersedes this (deprecated) one;
+	// workouts and goals referencing this exercise are moved to the replacement
+	ReplacedBy string `json:"replacedBy,omitempty"`
+
+	Name             string                 `json:"name"`
+	Description      string                 `json:"description"`
+	Aliases          []string               `json:"aliases"`
+	Category         model.ExerciseCategory `json:"category"`
+	PrimaryMuscles   []model.MuscleGroup    `json:"primaryMuscles"`
+	SecondaryMuscles []model.MuscleGroup    `json:"secondaryMuscles"`
+	Equipment        model.Equipment        `json:"equipment"`
+	MovementPattern  model.MovementPattern  `json:"movementPattern"`
+	Unilateral       bool                   `json:"unilateral"`
+
+	// Translations of the name, description and aliases, keyed by locale (e.g. "es", "pt-BR")
+	Translations map[string]model.ExerciseTranslation `json:"translations,omitempty"`
+}
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// normalize upper-cases the taxonomy values (older data files used "Strength") and validates them.
+func (ex *SystemExercise) normalize() error {
+	if !slugPattern.MatchString(ex.Slug) {
+		return fmt.Errorf("exercise %s has invalid slug %q", ex.Name, ex.Slug)
+	}
+	ex.Category = model.ExerciseCategory(strings.ToUpper(string(ex.Category)))
+	if ex.Category != "" && !ex.Category.IsValid() {
+		return fmt.Errorf("exercise %s has invalid category %q", ex.Name, ex.Category)
+	}
+	for _, m := range append(append([]model.MuscleGroup{}, ex.PrimaryMuscles...), ex.SecondaryMuscles...) {
+		if !m.IsValid() {
+			return fmt.Errorf("exercise %s has invalid muscle group %q", ex.Name, m)
+		}
+	}
+	if ex.Equipment != "" && !ex.Equipment.IsValid() {
+		return fmt.Errorf("exercise %s has invalid equipment %q", ex.Name, ex.Equipment)
+	}
+	if ex.MovementPattern != "" && !ex.MovementPattern.IsValid() {
+		return fmt.Errorf("exercise %s has invalid movement pattern %q", ex.Name, ex.MovementPattern)
+	}
+	for locale, t := range ex.Translations {
+		if normalized, ok := model.NormalizeLocale(locale); !ok || normalized != locale {
+			return fmt.Errorf("exercise %s has invalid translation locale %q", ex.Name, locale)
+		}
+		if strings.SplitN(locale, "-", 2)[0] == model.DefaultLocale {
+			return fmt.Errorf("exercise %s has a translation into %q, the default language", ex.Name, locale)
+		}
+		if strings.TrimSpace(t.Name) == "" {
+			return fmt.Errorf("exercise %s has no name in its %q translation", ex.Name, locale)
+		}
+		if t.Aliases == nil {
+			t.Aliases = []string{}
+			ex.Translations[locale] = t
+		}
+	}
+	return nil
+}
+
+// updateFields returns the $set document for upserting the exercise, omitting unset taxonomy values.
+func (ex *SystemExercise) updateFields() bson.M {
+	fields := bson.M{
+		"slug":              ex.Slug,
+		"name":              ex.Name,
+		"normalizedName":    model.NormalizeExerciseName(ex.Name),
+		"description":       ex.Description,
+		"userId":            nil,
+		"primaryMuscles":    ex.PrimaryMuscles,
+		"secondaryMuscles":  ex.SecondaryMuscles,
+		"unilateral":        ex.Unilateral,
+		"aliases":           ex.Aliases,
+		"normalizedAliases": model.NormalizeExerciseAliases(ex.Aliases),
+	}
+	if ex.Aliases == nil {
+		fields["aliases"] = []string{}
+	}
+	if ex.PrimaryMuscles == nil {
+		fields["primaryMuscles"] = []model.MuscleGroup{}
+	}
+	if ex.SecondaryMuscles == nil {
+		fields["secondaryMuscles"] = []model.MuscleGroup{}
+	}
+	// Always set, so translations removed from the catalogue are removed from the database
+	fields["translations"] = ex.Translations
+	if ex.Translations == nil {
+		fields["translations"] = map[string]model.ExerciseTranslation{}
+	}
+	if ex.Category != "" {
+		fields["category"] = ex.Category
+	}
+	if ex.Equipment != "" {
+		fields["equipment"] = ex.Equipment
+	}
+	if ex.MovementPattern != "" {
+		fields["movementPattern"] = ex.MovementPattern
+	}
+	return fields
 }
 
 type SystemExercisesData struct {
@@ -22,6 +125,35 @@ type SystemExercisesData struct {
 	Exercises []SystemExercise `json:"exercises"`
 }
 
+// validate normalizes every entry and checks that slugs are unique and replacements point
+// at active entries.
+func (d *SystemExercisesData) validate() error {
+	active := make(map[string]bool, len(d.Exercises))
+	for i := range d.Exercises {
+		ex := &d.Exercises[i]
+		if err := ex.normalize(); err != nil {
+			return err
+		}
+		if _, ok := active[ex.Slug]; ok {
+			return fmt.Errorf("duplicate slug %q", ex.Slug)
+		}
+		active[ex.Slug] = !ex.Deprecated
+	}
+
+	for _, ex := range d.Exercises {
+		if ex.ReplacedBy == "" {
+			continue
+		}
+		if !ex.Deprecated {
+			return fmt.Errorf("exercise %s has a replacement but is not deprecated", ex.Slug)
+		}
+		if !active[ex.ReplacedBy] {
+			return fmt.Errorf("exercise %s is replaced by %q, which is not an active exercise", ex.Slug, ex.ReplacedBy)
+		}
+	}
+	return nil
+}
+
 type SystemMetadata struct {
 	ID      string `bson:"_id,omitempty"`
 	Version int    `bson:"version"`
@@ -29,6 +161,8 @@ type SystemMetadata struct {
 
 const MetadataCollection = "system_metadata"
 const ExercisesCollection = "unique_exercises"
+const WorkoutsCollection = "workout_logs"
+const GoalsCollection = "goals"
 const LocksCollection = "system_locks"
 const LockID = "seeder_lock"
 
@@ -42,6 +176,15 @@ func ensureLockIndex(ctx context.Context, db *mongo.Database) error {
 	return err
 }
 
+// ensureSlugIndex makes slugs unique among system exercises.
+func ensureSlugIndex(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(ExercisesCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"slug": 1},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"slug": bson.M{"$type": "string"}}),
+	})
+	return err
+}
+
 // SeedSystemExercises loads system exercises from the provided JSON data if the version is newer than what's in the DB.
 func SeedSystemExercises(ctx context.Context, db *mongo.Database, jsonData []byte) error {
 	// 1. Parse JSON data
@@ -49,6 +192,9 @@ func SeedSystemExercises(ctx context.Context, db *mongo.Database, jsonData []byt
 	if err := json.Unmarshal(jsonData, &data); err != nil {
 		return fmt.Errorf("failed to parse system exercises json: %w", err)
 	}
+	if err := data.validate(); err != nil {
+		return fmt.Errorf("invalid system exercises json: %w", err)
+	}
 
 	// 2. Check current version in DB (Optimization: check before locking)
 	metadataColl := db.Collection(MetadataCollection)
@@ -103,26 +249,11 @@ func SeedSystemExercises(ctx context.Context, db *mongo.Database, jsonData []byt
 	fmt.Printf("Seeding system exercises (Version %d -> %d)...\n", metadata.Version, data.Version)
 
 	// 4. Seed exercises
-	exercisesColl := db.Collection(ExercisesCollection)
-	for _, ex := range data.Exercises {
-		filter := bson.M{
-			"name":   ex.Name,
-			"userId": nil,
-		}
-
-		update := bson.M{
-			"$set": bson.M{
-				"name":        ex.Name,
-				"description": ex.Description,
-				"userId":      nil,
-			},
-		}
-
-		opts := options.UpdateOne().SetUpsert(true)
-		_, err := exercisesColl.UpdateOne(ctx, filter, update, opts)
-		if err != nil {
-			return fmt.Errorf("failed to upsert exercise %s: %w", ex.Name, err)
-		}
+	if err := ensureSlugIndex(ctx, db); err != nil {
+		return fmt.Errorf("failed to ensure slug index: %w", err)
+	}
+	if err := applyCatalogue(ctx, db, data.Exercises, time.Now()); err != nil {
+		return err
 	}
 
 	// 5. Update version
@@ -139,3 +270,112 @@ func SeedSystemExercises(ctx context.Context, db *mongo.Database, jsonData []byt
 	fmt.Println("System exercises seeded successfully.")
 	return nil
 }
+
+// applyCatalogue upserts every entry by slug, deprecates entries marked as such (and any system
+// exercise no longer in the catalogue), and moves references from replaced exercises to their
+// replacements.
+func applyCatalogue(ctx context.Context, db *mongo.Database, exercises []SystemExercise, now time.Time) error {
+	exercisesColl := db.Collection(ExercisesCollection)
+	ids := make(map[string]bson.ObjectID, len(exercises))
+
+	for _, ex := range exercises {
+		id, err := upsertSystemExercise(ctx, exercisesColl, ex, now)
+		if err != nil {
+			return fmt.Errorf("failed to upsert exercise %s: %w", ex.Slug, err)
+		}
+		ids[ex.Slug] = id
+	}
+
+	for _, ex := range exercises {
+		if ex.ReplacedBy == "" {
+			continue
+		}
+		oldID, newID := ids[ex.Slug], ids[ex.ReplacedBy]
+		_, err := exercisesColl.UpdateByID(ctx, oldID, bson.M{"$set": bson.M{"replacedBy": newID}})
+		if err != nil {
+			return fmt.Errorf("failed to record replacement of %s: %w", ex.Slug, err)
+		}
+		if err := migrateReferences(ctx, db, oldID.Hex(), newID.Hex()); err != nil {
+			return fmt.Errorf("failed to migrate references from %s to %s: %w", ex.Slug, ex.ReplacedBy, err)
+		}
+	}
+
+	// Retire system exercises that were removed from the catalogue (including ones seeded by
+	// name before slugs existed that no entry adopted)
+	slugs := make([]string, 0, len(exercises))
+	for _, ex := range exercises {
+		slugs = append(slugs, ex.Slug)
+	}
+	_, err := exercisesColl.UpdateMany(ctx,
+		bson.M{"userId": nil, "slug": bson.M{"$nin": slugs}, "archivedAt": nil},
+		bson.M{"$set": bson.M{"archivedAt": now}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to retire removed exercises: %w", err)
+	}
+	return nil
+}
+
+// upsertSystemExercise writes the entry to the exercise with its slug. If there is none yet, an
+// exercise seeded under the entry's current or a previous name before slugs existed is adopted,
+// so renames keep the exercise ID (and every reference to it).
+func upsertSystemExercise(ctx context.Context, coll *mongo.Collection, ex SystemExercise, now time.Time) (bson.ObjectID, error) {
+	filter := bson.M{"slug": ex.Slug, "userId": nil}
+
+	var existing struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+	err := coll.FindOne(ctx, filter).Decode(&existing)
+	if err == mongo.ErrNoDocuments {
+		names := append([]string{ex.Name}, ex.PreviousNames...)
+		legacy := bson.M{"userId": nil, "slug": bson.M{"$exists": false}, "name": bson.M{"$in": names}}
+		err = coll.FindOne(ctx, legacy).Decode(&existing)
+		if err == nil {
+			filter = bson.M{"_id": existing.ID}
+		}
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return bson.ObjectID{}, err
+	}
+
+	update := bson.M{"$set": ex.updateFields()}
+	if ex.Deprecated {
+		// Keep the original deprecation date on later seeds
+		update["$min"] = bson.M{"archivedAt": now}
+		if ex.ReplacedBy == "" {
+			update["$unset"] = bson.M{"replacedBy": ""}
+		}
+	} else {
+		update["$unset"] = bson.M{"archivedAt": "", "replacedBy": ""}
+	}
+
+	var updated struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After).SetProjection(bson.M{"_id": 1})
+	if err := coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated); err != nil {
+		return bson.ObjectID{}, err
+	}
+	return updated.ID, nil
+}
+
+// migrateReferences points every user's workout exercise logs and goals at the replacement exercise.
+func migrateReferences(ctx context.Context, db *mongo.Database, oldID, newID string) error {
+	_, err := db.Collection(WorkoutsCollection).UpdateMany(ctx,
+		bson.M{"exerciseLogs.uniqueExerciseId": oldID},
+		bson.M{"$set": bson.M{"exerciseLogs.$[log].uniqueExerciseId": newID}},
+		options.UpdateMany().SetArrayFilters([]any{bson.M{"log.uniqueExerciseId": oldID}}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update workouts: %w", err)
+	}
+
+	_, err = db.Collection(GoalsCollection).UpdateMany(ctx,
+		bson.M{"uniqueExerciseId": oldID},
+		bson.M{"$set": bson.M{"uniqueExerciseId": newID}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update goals: %w", err)
+	}
+	return nil
+}
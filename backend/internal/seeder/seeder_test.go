@@ -18,6 +18,8 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
 )
 
 var testDB *mongo.Database
@@ -134,7 +136,7 @@ func TestSeedSystemExercises(t *testing.T) {
 		data := SystemExercisesData{
 			Version: 1,
 			Exercises: []SystemExercise{
-				{Name: "Push Up", Description: "Basic push up", Category: "Strength"},
+				{Slug: "push-up", Name: "Push Up", Description: "Basic push up", Category: "Strength"},
 			},
 		}
 		jsonData := createJSONData(t, data)
@@ -157,13 +159,58 @@ func TestSeedSystemExercises(t *testing.T) {
 		assert.Equal(t, 1, metadata.Version)
 	})
 
+	t.Run("Persists exercise taxonomy", func(t *testing.T) {
+		require.NoError(t, testDB.Drop(ctx))
+
+		data := SystemExercisesData{
+			Version: 1,
+			Exercises: []SystemExercise{
+				{
+					Slug:             "dumbbell-row",
+					Name:             "Dumbbell Row",
+					Description:      "Single-arm row",
+					Category:         "Strength",
+					PrimaryMuscles:   []model.MuscleGroup{model.MuscleGroupLats},
+					SecondaryMuscles: []model.MuscleGroup{model.MuscleGroupBiceps},
+					Equipment:        model.EquipmentDumbbell,
+					MovementPattern:  model.MovementPatternHorizontalPull,
+					Unilateral:       true,
+				},
+			},
+		}
+		require.NoError(t, SeedSystemExercises(ctx, testDB, createJSONData(t, data)))
+
+		var result bson.M
+		err := testDB.Collection(ExercisesCollection).FindOne(ctx, bson.M{"name": "Dumbbell Row"}).Decode(&result)
+		require.NoError(t, err)
+		assert.Equal(t, "STRENGTH", result["category"])
+		assert.Equal(t, bson.A{"LATS"}, result["primaryMuscles"])
+		assert.Equal(t, bson.A{"BICEPS"}, result["secondaryMuscles"])
+		assert.Equal(t, "DUMBBELL", result["equipment"])
+		assert.Equal(t, "HORIZONTAL_PULL", result["movementPattern"])
+		assert.Equal(t, true, result["unilateral"])
+	})
+
+	t.Run("Rejects unknown taxonomy values", func(t *testing.T) {
+		require.NoError(t, testDB.Drop(ctx))
+
+		data := SystemExercisesData{
+			Version: 1,
+			Exercises: []SystemExercise{
+				{Slug: "mystery", Name: "Mystery", Category: "Strength", PrimaryMuscles: []model.MuscleGroup{"SPLEEN"}},
+			},
+		}
+		err := SeedSystemExercises(ctx, testDB, createJSONData(t, data))
+		require.ErrorContains(t, err, "invalid muscle group")
+	})
+
 	t.Run("Does not update if version is same or lower", func(t *testing.T) {
 		// Setup initial state (Version 1)
 		require.NoError(t, testDB.Drop(ctx))
 		initialData := SystemExercisesData{
 			Version: 1,
 			Exercises: []SystemExercise{
-				{Name: "Push Up", Description: "Original description", Category: "Strength"},
+				{Slug: "push-up", Name: "Push Up", Description: "Original description", Category: "Strength"},
 			},
 		}
 		err := SeedSystemExercises(ctx, testDB, createJSONData(t, initialData))
@@ -173,7 +220,7 @@ func TestSeedSystemExercises(t *testing.T) {
 		newData := SystemExercisesData{
 			Version: 1,
 			Exercises: []SystemExercise{
-				{Name: "Push Up", Description: "Changed description", Category: "Strength"},
+				{Slug: "push-up", Name: "Push Up", Description: "Changed description", Category: "Strength"},
 			},
 		}
 		err = SeedSystemExercises(ctx, testDB, createJSONData(t, newData))
@@ -192,7 +239,7 @@ func TestSeedSystemExercises(t *testing.T) {
 		initialData := SystemExercisesData{
 			Version: 1,
 			Exercises: []SystemExercise{
-				{Name: "Push Up", Description: "Original description", Category: "Strength"},
+				{Slug: "push-up", Name: "Push Up", Description: "Original description", Category: "Strength"},
 			},
 		}
 		err := SeedSystemExercises(ctx, testDB, createJSONData(t, initialData))
@@ -202,8 +249,8 @@ func TestSeedSystemExercises(t *testing.T) {
 		newData := SystemExercisesData{
 			Version: 2,
 			Exercises: []SystemExercise{
-				{Name: "Push Up", Description: "Updated description", Category: "Strength"},
-				{Name: "Pull Up", Description: "New exercise", Category: "Strength"},
+				{Slug: "push-up", Name: "Push Up", Description: "Updated description", Category: "Strength"},
+				{Slug: "pull-up", Name: "Pull Up", Description: "New exercise", Category: "Strength"},
 			},
 		}
 		err = SeedSystemExercises(ctx, testDB, createJSONData(t, newData))
@@ -241,7 +288,7 @@ func TestSeedSystemExercises(t *testing.T) {
 		data := SystemExercisesData{
 			Version: 1,
 			Exercises: []SystemExercise{
-				{Name: "Push Up", Description: "Basic push up", Category: "Strength"},
+				{Slug: "push-up", Name: "Push Up", Description: "Basic push up", Category: "Strength"},
 			},
 		}
 		err = SeedSystemExercises(ctx, testDB, createJSONData(t, data))
@@ -265,4 +312,159 @@ func TestSeedSystemExercises(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, int64(1), count)
 	})
+
+	t.Run("Seeds translations", func(t *testing.T) {
+		require.NoError(t, testDB.Drop(ctx))
+
+		data := SystemExercisesData{Version: 1, Exercises: []SystemExercise{
+			{Slug: "push-up", Name: "Push Up", Translations: map[string]model.ExerciseTranslation{
+				"es":    {Name: "Flexiones", Aliases: []string{"Lagartijas"}},
+				"pt-BR": {Name: "Flexão de braço"},
+			}},
+		}}
+		require.NoError(t, SeedSystemExercises(ctx, testDB, createJSONData(t, data)))
+
+		var result struct {
+			Translations map[string]model.ExerciseTranslation `bson:"translations"`
+		}
+		require.NoError(t, testDB.Collection(ExercisesCollection).FindOne(ctx, bson.M{"slug": "push-up"}).Decode(&result))
+		assert.Equal(t, "Flexiones", result.Translations["es"].Name)
+		assert.Equal(t, []string{"Lagartijas"}, result.Translations["es"].Aliases)
+		assert.Equal(t, []string{}, result.Translations["pt-BR"].Aliases)
+
+		// Translations dropped from the catalogue are removed
+		data = SystemExercisesData{Version: 2, Exercises: []SystemExercise{
+			{Slug: "push-up", Name: "Push Up", Translations: map[string]model.ExerciseTranslation{
+				"es": {Name: "Flexiones"},
+			}},
+		}}
+		require.NoError(t, SeedSystemExercises(ctx, testDB, createJSONData(t, data)))
+		result.Translations = nil
+		require.NoError(t, testDB.Collection(ExercisesCollection).FindOne(ctx, bson.M{"slug": "push-up"}).Decode(&result))
+		assert.Len(t, result.Translations, 1)
+		assert.Contains(t, result.Translations, "es")
+	})
+
+	t.Run("Rejects invalid catalogues", func(t *testing.T) {
+		require.NoError(t, testDB.Drop(ctx))
+		description := "Push-up"
+
+		cases := map[string][]SystemExercise{
+			"invalid slug":           {{Slug: "Push Up", Name: "Push Up"}},
+			"duplicate slug":         {{Slug: "push-up", Name: "Push Up"}, {Slug: "push-up", Name: "Pushup"}},
+			"not deprecated":         {{Slug: "push-up", Name: "Push Up", ReplacedBy: "dip"}, {Slug: "dip", Name: "Dip"}},
+			"not an active exercise": {{Slug: "push-up", Name: "Push Up", Deprecated: true, ReplacedBy: "dip"}},
+			"invalid translation locale": {{Slug: "push-up", Name: "Push Up", Translations: map[string]model.ExerciseTranslation{
+				"es_ES": {Name: "Flexiones"},
+			}}},
+			"the default language": {{Slug: "push-up", Name: "Push Up", Translations: map[string]model.ExerciseTranslation{
+				"en-GB": {Name: "Press Up"},
+			}}},
+			"no name in its \"es\" translation": {{Slug: "push-up", Name: "Push Up", Translations: map[string]model.ExerciseTranslation{
+				"es": {Description: &description},
+			}}},
+		}
+		for message, exercises := range cases {
+			err := SeedSystemExercises(ctx, testDB, createJSONData(t, SystemExercisesData{Version: 1, Exercises: exercises}))
+			assert.ErrorContains(t, err, message)
+		}
+	})
+
+	t.Run("Renames keep the exercise ID", func(t *testing.T) {
+		require.NoError(t, testDB.Drop(ctx))
+
+		// Seeded by name before slugs existed
+		legacyID := bson.NewObjectID()
+		_, err := testDB.Collection(ExercisesCollection).InsertOne(ctx, bson.M{"_id": legacyID, "name": "Lunges", "userId": nil})
+		require.NoError(t, err)
+
+		data := SystemExercisesData{Version: 1, Exercises: []SystemExercise{
+			{Slug: "lunge", Name: "Walking Lunge", PreviousNames: []string{"Lunges"}},
+		}}
+		require.NoError(t, SeedSystemExercises(ctx, testDB, createJSONData(t, data)))
+
+		var result bson.M
+		require.NoError(t, testDB.Collection(ExercisesCollection).FindOne(ctx, bson.M{"_id": legacyID}).Decode(&result))
+		assert.Equal(t, "Walking Lunge", result["name"])
+		assert.Equal(t, "lunge", result["slug"])
+
+		// Later renames match by slug alone
+		data = SystemExercisesData{Version: 2, Exercises: []SystemExercise{{Slug: "lunge", Name: "Lunge"}}}
+		require.NoError(t, SeedSystemExercises(ctx, testDB, createJSONData(t, data)))
+
+		require.NoError(t, testDB.Collection(ExercisesCollection).FindOne(ctx, bson.M{"_id": legacyID}).Decode(&result))
+		assert.Equal(t, "Lunge", result["name"])
+		count, err := testDB.Collection(ExercisesCollection).CountDocuments(ctx, bson.M{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Deprecates, retires and replaces exercises", func(t *testing.T) {
+		require.NoError(t, testDB.Drop(ctx))
+
+		data := SystemExercisesData{Version: 1, Exercises: []SystemExercise{
+			{Slug: "dips", Name: "Dips"},
+			{Slug: "bench-dips", Name: "Bench Dips"},
+			{Slug: "good-morning", Name: "Good Morning"},
+			{Slug: "tricep-dips", Name: "Tricep Dips"},
+		}}
+		require.NoError(t, SeedSystemExercises(ctx, testDB, createJSONData(t, data)))
+
+		idOf := func(slug string) bson.ObjectID {
+			var doc struct {
+				ID bson.ObjectID `bson:"_id"`
+			}
+			require.NoError(t, testDB.Collection(ExercisesCollection).FindOne(ctx, bson.M{"slug": slug}).Decode(&doc))
+			return doc.ID
+		}
+		dipsID, tricepDipsID := idOf("dips").Hex(), idOf("tricep-dips").Hex()
+
+		_, err := testDB.Collection(WorkoutsCollection).InsertOne(ctx, bson.M{
+			"userId":       "user-1",
+			"exerciseLogs": bson.A{bson.M{"uniqueExerciseId": dipsID}, bson.M{"uniqueExerciseId": "other"}, bson.M{"uniqueExerciseId": dipsID}},
+		})
+		require.NoError(t, err)
+		_, err = testDB.Collection(GoalsCollection).InsertOne(ctx, bson.M{"userId": "user-2", "uniqueExerciseId": dipsID})
+		require.NoError(t, err)
+
+		// Dips is replaced by Tricep Dips, Bench Dips is deprecated, Good Morning is dropped
+		data = SystemExercisesData{Version: 2, Exercises: []SystemExercise{
+			{Slug: "dips", Name: "Dips", Deprecated: true, ReplacedBy: "tricep-dips"},
+			{Slug: "bench-dips", Name: "Bench Dips", Deprecated: true},
+			{Slug: "tricep-dips", Name: "Tricep Dips"},
+		}}
+		require.NoError(t, SeedSystemExercises(ctx, testDB, createJSONData(t, data)))
+
+		var dips, benchDips, goodMorning, tricepDips bson.M
+		require.NoError(t, testDB.Collection(ExercisesCollection).FindOne(ctx, bson.M{"slug": "dips"}).Decode(&dips))
+		require.NoError(t, testDB.Collection(ExercisesCollection).FindOne(ctx, bson.M{"slug": "bench-dips"}).Decode(&benchDips))
+		require.NoError(t, testDB.Collection(ExercisesCollection).FindOne(ctx, bson.M{"slug": "good-morning"}).Decode(&goodMorning))
+		require.NoError(t, testDB.Collection(ExercisesCollection).FindOne(ctx, bson.M{"slug": "tricep-dips"}).Decode(&tricepDips))
+		assert.NotNil(t, dips["archivedAt"])
+		assert.Equal(t, idOf("tricep-dips"), dips["replacedBy"])
+		assert.NotNil(t, benchDips["archivedAt"])
+		assert.NotNil(t, goodMorning["archivedAt"], "exercises removed from the catalogue are retired")
+		assert.Nil(t, tricepDips["archivedAt"])
+
+		var workout struct {
+			ExerciseLogs []struct {
+				UniqueExerciseID string `bson:"uniqueExerciseId"`
+			} `bson:"exerciseLogs"`
+		}
+		require.NoError(t, testDB.Collection(WorkoutsCollection).FindOne(ctx, bson.M{"userId": "user-1"}).Decode(&workout))
+		assert.Equal(t, tricepDipsID, workout.ExerciseLogs[0].UniqueExerciseID)
+		assert.Equal(t, "other", workout.ExerciseLogs[1].UniqueExerciseID)
+		assert.Equal(t, tricepDipsID, workout.ExerciseLogs[2].UniqueExerciseID)
+
+		var goal bson.M
+		require.NoError(t, testDB.Collection(GoalsCollection).FindOne(ctx, bson.M{"userId": "user-2"}).Decode(&goal))
+		assert.Equal(t, tricepDipsID, goal["uniqueExerciseId"])
+
+		// Restoring an entry un-deprecates it
+		data.Version = 3
+		data.Exercises[1].Deprecated = false
+		require.NoError(t, SeedSystemExercises(ctx, testDB, createJSONData(t, data)))
+		require.NoError(t, testDB.Collection(ExercisesCollection).FindOne(ctx, bson.M{"slug": "bench-dips"}).Decode(&benchDips))
+		assert.Nil(t, benchDips["archivedAt"])
+	})
 }
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/mailer"
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+const (
+	// purgeBatchSize caps how many accounts one PurgeDue call deletes.
+	purgeBatchSize = 50
+	// purgeClaimTimeout is how long an account claimed for deletion waits before a purge that
+	// was interrupted is retried.
+	purgeClaimTimeout = time.Hour
+	// purgeLease is the lease that Run holds while purging, so one replica purges at a time.
+	purgeLease = "account_deletion_purge"
+)
+
+// ErrAccountDeletionStarted is returned when cancelling a deletion that has already started.
+var ErrAccountDeletionStarted = errors.New("account deletion has already started")
+
+// AccountDeletionRepositories groups the repositories holding per-user data. A new per-user
+// collection must be added here and to AccountDeletionService.purge.
+type AccountDeletionRepositories struct {
+	Users         repository.UserRepository
+	Workouts      repository.WorkoutRepository
+	Exercises     repository.ExerciseRepository
+	BodyMetrics   repository.BodyMetricRepository
+	Goals         repository.GoalRepository
+	RefreshTokens repository.RefreshTokenRepository
+	ActionTokens  repository.ActionTokenRepository
+	Tombstones    repository.AccountTombstoneRepository
+	Passkeys      repository.PasskeyRepository
+	Identities    repository.ExternalIdentityRepository
+	Leases        repository.LeaseRepository
+}
+
+// AccountDeletionService deletes accounts in two steps: a request schedules the deletion after a
+// grace period, during which it can be cancelled, and PurgeDue later removes the account's data
+// leaving only a tombstone.
+type AccountDeletionService struct {
+	repos       AccountDeletionRepositories
+	mailer      mailer.Mailer
+	gracePeriod time.Duration
+	now         func() time.Time
+	// owner identifies this replica's hold on purgeLease
+	owner string
+}
+
+// NewAccountDeletionService creates an AccountDeletionService.
+func NewAccountDeletionService(repos AccountDeletionRepositories, m mailer.Mailer, gracePeriod time.Duration) *AccountDeletionService {
+	return &AccountDeletionService{
+		repos:       repos,
+		mailer:      m,
+		gracePeriod: gracePeriod,
+		now:         time.Now,
+		owner:       rand.Text(),
+	}
+}
+
+// RequestDeletion schedules the user's account for deletion once the grace period ends, and
+// signs out every session. Requesting again keeps the original schedule.
+func (s *AccountDeletionService) RequestDeletion(ctx context.Context, userID, currentPassword string) (*model.User, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCurrentPassword(user, currentPassword); err != nil {
+		return nil, err
+	}
+	if user.DeletionScheduledAt != nil {
+		return user, nil
+	}
+
+	now := s.now()
+	scheduledAt := now.Add(s.gracePeriod)
+	user.DeletionRequestedAt = &now
+	user.DeletionScheduledAt = &scheduledAt
+	user.UpdatedAt = now
+	if err := s.repos.Users.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if err := s.repos.RefreshTokens.RevokeAllForUser(ctx, user.ID); err != nil {
+		slog.Warn("Failed to sign out sessions of account scheduled for deletion", "user_id", user.ID, "error", err)
+	}
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your account will be deleted",
+		Body: fmt.Sprintf("Your account and all of its data will be permanently deleted on %s.\n\n"+
+			"Changed your mind? Sign in before then and cancel the deletion from your profile.\n",
+			scheduledAt.UTC().Format("January 2, 2006 at 15:04 UTC")),
+	}); err != nil {
+		// The deletion is scheduled either way
+		slog.Warn("Failed to send account deletion notice", "user_id", user.ID, "error", err)
+	}
+	return user, nil
+}
+
+// CancelDeletion keeps the user's account if its deletion has not started yet.
+func (s *AccountDeletionService) CancelDeletion(ctx context.Context, userID string) (*model.User, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletionScheduledAt == nil {
+		return nil, fmt.Errorf("account is not scheduled for deletion")
+	}
+	if user.DeletionStartedAt != nil {
+		return nil, ErrAccountDeletionStarted
+	}
+
+	// The repository only cancels if the purge has not claimed the account in the meantime
+	cancelled, err := s.repos.Users.CancelDeletion(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		return nil, ErrAccountDeletionStarted
+	}
+	user.DeletionRequestedAt = nil
+	user.DeletionScheduledAt = nil
+	user.UpdatedAt = s.now()
+	return user, nil
+}
+
+// PurgeDue deletes the accounts whose grace period has ended and returns how many it deleted.
+// Each account is claimed before any of its data is deleted, so it is purged once even when
+// called concurrently, and can no longer be cancelled. It stops at the first failure; the
+// failed account is retried once its claim goes stale.
+func (s *AccountDeletionService) PurgeDue(ctx context.Context) (int, error) {
+	for i := range purgeBatchSize {
+		now := s.now()
+		user, err := s.repos.Users.ClaimDueForDeletion(ctx, now, now.Add(-purgeClaimTimeout))
+		if err != nil {
+			return i, err
+		}
+		if user == nil {
+			return i, nil
+		}
+		if err := s.purge(ctx, user); err != nil {
+			return i, fmt.Errorf("failed to delete account %s: %w", user.ID, err)
+		}
+	}
+	return purgeBatchSize, nil
+}
+
+// purge removes everything stored for the user. The tombstone is recorded first and the user
+// last, so an interrupted purge is picked up again when the user is claimed again.
+func (s *AccountDeletionService) purge(ctx context.Context, user *model.User) error {
+	tombstone := &model.AccountTombstone{
+		UserID:           user.ID,
+		AccountCreatedAt: user.CreatedAt,
+		DeletedAt:        s.now(),
+		Deleted:          map[string]int64{},
+	}
+	if user.DeletionRequestedAt != nil {
+		tombstone.DeletionRequestedAt = *user.DeletionRequestedAt
+	}
+	if err := s.repos.Tombstones.Record(ctx, tombstone); err != nil {
+		return err
+	}
+
+	collections := []struct {
+		name      string
+		deleteAll func(context.Context, string) (int64, error)
+	}{
+		{"workouts", s.repos.Workouts.DeleteAllByUser},
+		{"customExercises", s.repos.Exercises.DeleteAllByUser},
+		{"bodyMetrics", s.repos.BodyMetrics.DeleteAllByUser},
+		{"goals", s.repos.Goals.DeleteAllByUser},
+		{"actionTokens", s.repos.ActionTokens.DeleteAllByUser},
+		{"passkeys", s.repos.Passkeys.DeleteAllByUser},
+		{"identities", s.repos.Identities.DeleteAllByUser},
+	}
+	for _, c := range collections {
+		deleted, err := c.deleteAll(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		tombstone.Deleted[c.name] = deleted
+	}
+	if err := s.repos.RefreshTokens.RevokeAllForUser(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := s.repos.Users.Delete(ctx, user.ID); err != nil {
+		return err
+	}
+
+	// Save the final counts; the deletion itself is complete even if this fails
+	if err := s.repos.Tombstones.Record(ctx, tombstone); err != nil {
+		slog.Warn("Failed to record deleted data counts", "user_id", user.ID, "error", err)
+	}
+	return nil
+}
+
+// Run purges due accounts every interval until ctx is cancelled. Each replica runs it, but
+// only the one holding the purge lease purges on a given tick.
+func (s *AccountDeletionService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.purgeWithLease(ctx)
+			if err != nil {
+				slog.Error("Failed to delete accounts", "error", err)
+			}
+			if deleted > 0 {
+				slog.Info("Deleted accounts", "count", deleted)
+			}
+		}
+	}
+}
+
+// purgeWithLease runs PurgeDue if this replica can take the purge lease, and otherwise leaves
+// the due accounts to the replica holding it.
+func (s *AccountDeletionService) purgeWithLease(ctx context.Context) (int, error) {
+	acquired, err := s.repos.Leases.Acquire(ctx, purgeLease, s.owner)
+	if err != nil || !acquired {
+		return 0, err
+	}
+	defer func() {
+		if err := s.repos.Leases.Release(context.WithoutCancel(ctx), purgeLease, s.owner); err != nil {
+			slog.Warn("Failed to release account deletion lease", "error", err)
+		}
+	}()
+	return s.PurgeDue(ctx)
+}
+
+func (s *AccountDeletionService) findUser(ctx context.Context, userID string) (*model.User, error) {
+	user, err := s.repos.Users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	return user, nil
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/riverajo/fitness-app/backend/internal/mailer"
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+type accountDeletionFixture struct {
+	service       *AccountDeletionService
+	users         *repository.MockUserRepository
+	workouts      *repository.MockWorkoutRepository
+	exercises     *repository.MockExerciseRepository
+	bodyMetrics   *repository.MockBodyMetricRepository
+	goals         *repository.MockGoalRepository
+	refreshTokens *repository.MockRefreshTokenRepository
+	actionTokens  *repository.MockActionTokenRepository
+	tombstones    *repository.MockAccountTombstoneRepository
+	passkeys      *repository.MockPasskeyRepository
+	identities    *repository.MockExternalIdentityRepository
+	leases        *repository.MemoryLeaseRepository
+	mailer        *mailer.FileMailer
+	now           time.Time
+}
+
+func newAccountDeletionFixture() *accountDeletionFixture {
+	f := &accountDeletionFixture{
+		users:         new(repository.MockUserRepository),
+		workouts:      new(repository.MockWorkoutRepository),
+		exercises:     new(repository.MockExerciseRepository),
+		bodyMetrics:   new(repository.MockBodyMetricRepository),
+		goals:         new(repository.MockGoalRepository),
+		refreshTokens: new(repository.MockRefreshTokenRepository),
+		actionTokens:  new(repository.MockActionTokenRepository),
+		tombstones:    new(repository.MockAccountTombstoneRepository),
+		passkeys:      new(repository.MockPasskeyRepository),
+		identities:    new(repository.MockExternalIdentityRepository),
+		leases:        repository.NewMemoryLeaseRepository(),
+		mailer:        &mailer.FileMailer{},
+		now:           time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	f.service = NewAccountDeletionService(AccountDeletionRepositories{
+		Users:         f.users,
+		Workouts:      f.workouts,
+		Exercises:     f.exercises,
+		BodyMetrics:   f.bodyMetrics,
+		Goals:         f.goals,
+		RefreshTokens: f.refreshTokens,
+		ActionTokens:  f.actionTokens,
+		Tombstones:    f.tombstones,
+		Passkeys:      f.passkeys,
+		Identities:    f.identities,
+		Leases:        f.leases,
+	}, f.mailer, 30*24*time.Hour)
+	f.service.now = func() time.Time { return f.now }
+	return f
+}
+
+func TestAccountDeletionRequest(t *testing.T) {
+	ctx := context.Background()
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+
+	t.Run("Schedules deletion after the grace period and signs out", func(t *testing.T) {
+		f := newAccountDeletionFixture()
+		f.users.On("FindByID", ctx, "user-1").Return(&model.User{ID: "user-1", Email: "jane@example.com", PasswordHash: string(hash)}, nil)
+		f.users.On("Update", ctx, mock.Anything).Return(nil)
+		f.refreshTokens.On("RevokeAllForUser", ctx, "user-1").Return(nil)
+
+		user, err := f.service.RequestDeletion(ctx, "user-1", "password123")
+		require.NoError(t, err)
+		require.NotNil(t, user.DeletionScheduledAt)
+		assert.Equal(t, f.now.Add(30*24*time.Hour), *user.DeletionScheduledAt)
+		assert.Equal(t, f.now, *user.DeletionRequestedAt)
+		f.refreshTokens.AssertExpectations(t)
+
+		sent := f.mailer.Sent()
+		require.Len(t, sent, 1)
+		assert.Equal(t, "jane@example.com", sent[0].To)
+		assert.Contains(t, sent[0].Body, "March 31, 2024")
+	})
+
+	t.Run("Keeps the original schedule when requested again", func(t *testing.T) {
+		f := newAccountDeletionFixture()
+		scheduled := f.now.Add(time.Hour)
+		f.users.On("FindByID", ctx, "user-1").Return(&model.User{ID: "user-1", PasswordHash: string(hash), DeletionScheduledAt: &scheduled}, nil)
+
+		user, err := f.service.RequestDeletion(ctx, "user-1", "password123")
+		require.NoError(t, err)
+		assert.Equal(t, scheduled, *user.DeletionScheduledAt)
+		f.users.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects a wrong password", func(t *testing.T) {
+		f := newAccountDeletionFixture()
+		f.users.On("FindByID", ctx, "user-1").Return(&model.User{ID: "user-1", PasswordHash: string(hash)}, nil)
+
+		_, err := f.service.RequestDeletion(ctx, "user-1", "wrong")
+		assert.EqualError(t, err, "invalid current password")
+		f.users.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Asks an account without a password to set one", func(t *testing.T) {
+		f := newAccountDeletionFixture()
+		f.users.On("FindByID", ctx, "user-1").Return(&model.User{ID: "user-1"}, nil)
+
+		_, err := f.service.RequestDeletion(ctx, "user-1", "")
+		assert.ErrorIs(t, err, ErrNoPassword)
+		f.users.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAccountDeletionCancel(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Clears the schedule", func(t *testing.T) {
+		f := newAccountDeletionFixture()
+		scheduled := f.now.Add(time.Hour)
+		f.users.On("FindByID", ctx, "user-1").Return(&model.User{ID: "user-1", DeletionRequestedAt: &f.now, DeletionScheduledAt: &scheduled}, nil)
+		f.users.On("CancelDeletion", ctx, "user-1").Return(true, nil)
+
+		user, err := f.service.CancelDeletion(ctx, "user-1")
+		require.NoError(t, err)
+		assert.Nil(t, user.DeletionScheduledAt)
+		assert.Nil(t, user.DeletionRequestedAt)
+		f.users.AssertExpectations(t)
+	})
+
+	t.Run("Fails once the deletion has started", func(t *testing.T) {
+		f := newAccountDeletionFixture()
+		f.users.On("FindByID", ctx, "user-1").Return(&model.User{ID: "user-1", DeletionRequestedAt: &f.now, DeletionScheduledAt: &f.now, DeletionStartedAt: &f.now}, nil)
+
+		_, err := f.service.CancelDeletion(ctx, "user-1")
+		assert.ErrorIs(t, err, ErrAccountDeletionStarted)
+		f.users.AssertNotCalled(t, "CancelDeletion", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Fails when the deletion starts while cancelling", func(t *testing.T) {
+		f := newAccountDeletionFixture()
+		f.users.On("FindByID", ctx, "user-1").Return(&model.User{ID: "user-1", DeletionRequestedAt: &f.now, DeletionScheduledAt: &f.now}, nil)
+		f.users.On("CancelDeletion", ctx, "user-1").Return(false, nil)
+
+		_, err := f.service.CancelDeletion(ctx, "user-1")
+		assert.ErrorIs(t, err, ErrAccountDeletionStarted)
+	})
+
+	t.Run("Fails when no deletion is scheduled", func(t *testing.T) {
+		f := newAccountDeletionFixture()
+		f.users.On("FindByID", ctx, "user-1").Return(&model.User{ID: "user-1"}, nil)
+
+		_, err := f.service.CancelDeletion(ctx, "user-1")
+		assert.EqualError(t, err, "account is not scheduled for deletion")
+	})
+}
+
+func TestAccountDeletionPurgeDue(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Deletes every collection and leaves a tombstone", func(t *testing.T) {
+		f := newAccountDeletionFixture()
+		created := f.now.Add(-365 * 24 * time.Hour)
+		requested := f.now.Add(-30 * 24 * time.Hour)
+		f.users.On("ClaimDueForDeletion", ctx, f.now, f.now.Add(-purgeClaimTimeout)).Return(
+			&model.User{ID: "user-1", CreatedAt: created, DeletionRequestedAt: &requested, DeletionScheduledAt: &f.now, DeletionStartedAt: &f.now}, nil,
+		).Once()
+		f.users.On("ClaimDueForDeletion", ctx, f.now, f.now.Add(-purgeClaimTimeout)).Return(nil, nil).Once()
+		var recorded []model.AccountTombstone
+		f.tombstones.On("Record", ctx, mock.Anything).Run(func(args mock.Arguments) {
+			tombstone := *args.Get(1).(*model.AccountTombstone)
+			tombstone.Deleted = maps.Clone(tombstone.Deleted)
+			recorded = append(recorded, tombstone)
+		}).Return(nil)
+		f.workouts.On("DeleteAllByUser", ctx, "user-1").Return(int64(12), nil)
+		f.exercises.On("DeleteAllByUser", ctx, "user-1").Return(int64(2), nil)
+		f.bodyMetrics.On("DeleteAllByUser", ctx, "user-1").Return(int64(5), nil)
+		f.goals.On("DeleteAllByUser", ctx, "user-1").Return(int64(1), nil)
+		f.actionTokens.On("DeleteAllByUser", ctx, "user-1").Return(int64(0), nil)
+		f.passkeys.On("DeleteAllByUser", ctx, "user-1").Return(int64(2), nil)
+		f.identities.On("DeleteAllByUser", ctx, "user-1").Return(int64(1), nil)
+		f.refreshTokens.On("RevokeAllForUser", ctx, "user-1").Return(nil)
+		f.users.On("Delete", ctx, "user-1").Return(nil)
+
+		deleted, err := f.service.PurgeDue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+		f.users.AssertExpectations(t)
+		f.refreshTokens.AssertExpectations(t)
+
+		// Recorded before anything is deleted, then updated with the counts
+		require.Len(t, recorded, 2)
+		assert.Empty(t, recorded[0].Deleted)
+		final := recorded[1]
+		assert.Equal(t, "user-1", final.UserID)
+		assert.Equal(t, created, final.AccountCreatedAt)
+		assert.Equal(t, requested, final.DeletionRequestedAt)
+		assert.Equal(t, f.now, final.DeletedAt)
+		assert.Equal(t, map[string]int64{
+			"workouts":        12,
+			"customExercises": 2,
+			"bodyMetrics":     5,
+			"goals":           1,
+			"actionTokens":    0,
+			"passkeys":        2,
+			"identities":      1,
+		}, final.Deleted)
+	})
+
+	t.Run("Keeps the user when a collection fails to delete", func(t *testing.T) {
+		f := newAccountDeletionFixture()
+		f.users.On("ClaimDueForDeletion", ctx, f.now, f.now.Add(-purgeClaimTimeout)).Return(&model.User{ID: "user-1"}, nil).Once()
+		f.tombstones.On("Record", ctx, mock.Anything).Return(nil)
+		f.workouts.On("DeleteAllByUser", ctx, "user-1").Return(int64(0), errors.New("connection reset"))
+
+		deleted, err := f.service.PurgeDue(ctx)
+		assert.ErrorContains(t, err, "connection reset")
+		assert.Equal(t, 0, deleted)
+		f.users.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Leaves the purge to the replica holding the lease", func(t *testing.T) {
+		f := newAccountDeletionFixture()
+		acquired, err := f.leases.Acquire(ctx, purgeLease, "other-replica")
+		require.NoError(t, err)
+		require.True(t, acquired)
+
+		deleted, err := f.service.purgeWithLease(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, deleted)
+		f.users.AssertNotCalled(t, "ClaimDueForDeletion", mock.Anything, mock.Anything, mock.Anything)
+
+		// Once released, this replica purges and gives the lease back
+		require.NoError(t, f.leases.Release(ctx, purgeLease, "other-replica"))
+		f.users.On("ClaimDueForDeletion", ctx, f.now, f.now.Add(-purgeClaimTimeout)).Return(nil, nil).Once()
+		deleted, err = f.service.purgeWithLease(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, deleted)
+		f.users.AssertExpectations(t)
+		acquired, err = f.leases.Acquire(ctx, purgeLease, "other-replica")
+		require.NoError(t, err)
+		assert.True(t, acquired)
+	})
+}
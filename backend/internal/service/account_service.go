@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/riverajo/fitness-app/backend/internal/mailer"
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+)
+
+// ErrInvalidActionToken is returned for emailed links that are unknown, expired, already used
+// or meant for something else. The cases are not distinguished.
+var ErrInvalidActionToken = errors.New("this link is invalid or has expired")
+
+// ErrEmailAlreadyVerified is returned when asking to verify an address that already is.
+var ErrEmailAlreadyVerified = errors.New("email is already verified")
+
+// AccountService handles the account flows that prove control of an email address: password
+// resets and email verification. Links carry single-use tokens stored hashed like refresh tokens.
+type AccountService struct {
+	users         *UserService
+	userRepo      repository.UserRepository
+	tokens        repository.ActionTokenRepository
+	refreshTokens repository.RefreshTokenRepository
+	mailer        mailer.Mailer
+	appURL        string
+}
+
+// NewAccountService creates an AccountService. Links in emails point to pages under appURL.
+func NewAccountService(users *UserService, userRepo repository.UserRepository, tokens repository.ActionTokenRepository, refreshTokens repository.RefreshTokenRepository, m mailer.Mailer, appURL string) *AccountService {
+	return &AccountService{
+		users:         users,
+		userRepo:      userRepo,
+		tokens:        tokens,
+		refreshTokens: refreshTokens,
+		mailer:        m,
+		appURL:        strings.TrimSuffix(appURL, "/"),
+	}
+}
+
+// RequestPasswordReset emails a password reset link if an account uses this email. It returns
+// nil for unknown emails, so callers cannot tell which emails are registered.
+func (s *AccountService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		slog.Info("Password reset requested for unknown email")
+		return nil
+	}
+
+	token, err := issueActionToken(ctx, s.tokens, user, model.ActionTokenPasswordReset, user.Email, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your account.\n\n"+
+			"To choose a new password, open this link within %d minutes:\n\n%s\n\n"+
+			"If it wasn't you, you can ignore this email; your password has not changed.\n",
+			int(passwordResetTTL.Minutes()), s.link("/reset-password", token)),
+	})
+}
+
+// ResetPassword sets a new password using a reset token, and signs out every session. The
+// token is only used up once the new password is accepted.
+func (s *AccountService) ResetPassword(ctx context.Context, token, newPassword string) (*model.User, error) {
+	actionToken, err := lookupActionToken(ctx, s.tokens, token, model.ActionTokenPasswordReset)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.FindByID(ctx, actionToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidActionToken
+	}
+	if err := s.users.ValidatePassword(newPassword, user.Email); err != nil {
+		return nil, err
+	}
+
+	if err := consumeActionToken(ctx, s.tokens, actionToken); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash new password: %w", err)
+	}
+	user.PasswordHash = string(hashedPassword)
+	// Receiving the link proves control of the address
+	if actionToken.Email == user.Email {
+		user.EmailVerified = true
+	}
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// Whoever knew the old password may still hold a session
+	if err := s.refreshTokens.RevokeAllForUser(ctx, user.ID); err != nil {
+		return nil, fmt.Errorf("password changed, but failed to sign out other sessions: %w", err)
+	}
+	if err := s.tokens.DeleteForUser(ctx, user.ID, model.ActionTokenPasswordReset); err != nil {
+		slog.Warn("Failed to delete outstanding password reset links", "user_id", user.ID, "error", err)
+	}
+	return user, nil
+}
+
+// SendVerificationEmail emails a link that verifies the user's address.
+func (s *AccountService) SendVerificationEmail(ctx context.Context, user *model.User) error {
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := issueActionToken(ctx, s.tokens, user, model.ActionTokenEmailVerification, user.Email, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Please confirm this is your email address by opening this link within %d hours:\n\n%s\n\n"+
+			"If you didn't create an account, you can ignore this email.\n",
+			int(emailVerificationTTL.Hours()), s.link("/verify-email", token)),
+	})
+}
+
+// VerifyEmail marks the user's address as verified using a verification token.
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	actionToken, err := lookupActionToken(ctx, s.tokens, token, model.ActionTokenEmailVerification)
+	if err != nil {
+		return nil, err
+	}
+	if err := consumeActionToken(ctx, s.tokens, actionToken); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, actionToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+	// The link only verifies the address it was sent to
+	if user == nil || user.Email != actionToken.Email {
+		return nil, ErrInvalidActionToken
+	}
+	if !user.EmailVerified {
+		user.EmailVerified = true
+		user.UpdatedAt = time.Now()
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// SendEmailChangeConfirmation emails a confirmation link to the user's pending email, and tells
+// the current address about the change so its owner can react if it wasn't them.
+func (s *AccountService) SendEmailChangeConfirmation(ctx context.Context, user *model.User) error {
+	if user.PendingEmail == nil {
+		return fmt.Errorf("no email change is pending")
+	}
+	newEmail := *user.PendingEmail
+
+	token, err := issueActionToken(ctx, s.tokens, user, model.ActionTokenEmailChange, newEmail, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("To start signing in with this address instead of %s, open this link within %d hours:\n\n%s\n\n"+
+			"If you didn't ask for this, you can ignore this email.\n",
+			user.Email, int(emailVerificationTTL.Hours()), s.link("/confirm-email", token)),
+	}); err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Someone asked to change the email address of your account to %s. "+
+			"It will change once the link sent to that address is opened.\n\n"+
+			"If it wasn't you, sign in and change your password, then set your email back to this address to cancel.\n",
+			newEmail),
+	}); err != nil {
+		// The change itself can go ahead
+		slog.Warn("Failed to notify previous email address of change", "user_id", user.ID, "error", err)
+	}
+	return nil
+}
+
+// ConfirmEmailChange replaces the user's email with the pending one the token was sent to. It
+// returns repository.ErrDuplicateEmail if another account took the address in the meantime.
+func (s *AccountService) ConfirmEmailChange(ctx context.Context, token string) (*model.User, error) {
+	actionToken, err := lookupActionToken(ctx, s.tokens, token, model.ActionTokenEmailChange)
+	if err != nil {
+		return nil, err
+	}
+	if err := consumeActionToken(ctx, s.tokens, actionToken); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, actionToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+	// The change may have been cancelled or replaced by another since the link was sent
+	if user == nil || user.PendingEmail == nil || *user.PendingEmail != actionToken.Email {
+		return nil, ErrInvalidActionToken
+	}
+
+	user.Email = actionToken.Email
+	user.PendingEmail = nil
+	user.EmailVerified = true
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// Links sent to the previous address no longer apply
+	for _, purpose := range []model.ActionTokenPurpose{model.ActionTokenPasswordReset, model.ActionTokenEmailVerification} {
+		if err := s.tokens.DeleteForUser(ctx, user.ID, purpose); err != nil {
+			slog.Warn("Failed to delete links sent to previous email", "user_id", user.ID, "error", err)
+		}
+	}
+	return user, nil
+}
+
+func (s *AccountService) link(path, token string) string {
+	return s.appURL + path + "?token=" + url.QueryEscape(token)
+}
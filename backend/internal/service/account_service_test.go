@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/riverajo/fitness-app/backend/internal/mailer"
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+var linkToken = regexp.MustCompile(`\?token=(\S+)`)
+
+// tokenFromEmail returns the token in the link of the latest email sent with one.
+func tokenFromEmail(t *testing.T, m *mailer.FileMailer) string {
+	t.Helper()
+	sent := m.Sent()
+	for i := len(sent) - 1; i >= 0; i-- {
+		if match := linkToken.FindStringSubmatch(sent[i].Body); match != nil {
+			token, err := url.QueryUnescape(match[1])
+			require.NoError(t, err)
+			return token
+		}
+	}
+	t.Fatal("no email with a link was sent")
+	return ""
+}
+
+type accountServiceFixture struct {
+	service       *AccountService
+	userRepo      *repository.MockUserRepository
+	tokenRepo     *repository.MockActionTokenRepository
+	refreshTokens *repository.MockRefreshTokenRepository
+	mailer        *mailer.FileMailer
+	stored        *model.ActionToken
+}
+
+func newAccountServiceFixture() *accountServiceFixture {
+	f := &accountServiceFixture{
+		userRepo:      new(repository.MockUserRepository),
+		tokenRepo:     new(repository.MockActionTokenRepository),
+		refreshTokens: new(repository.MockRefreshTokenRepository),
+		mailer:        &mailer.FileMailer{},
+		stored:        &model.ActionToken{},
+	}
+	f.service = NewAccountService(NewUserService(f.userRepo, f.refreshTokens), f.userRepo, f.tokenRepo, f.refreshTokens, f.mailer, "https://fitness.example.com/")
+
+	// Keep the issued token so lookups can find it
+	f.tokenRepo.On("DeleteForUser", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	f.tokenRepo.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		token := args.Get(1).(*model.ActionToken)
+		token.ID = "token-1"
+		*f.stored = *token
+	}).Return(nil)
+	f.tokenRepo.On("FindByID", mock.Anything, "token-1").Return(f.stored, nil)
+	return f
+}
+
+func TestAccountServicePasswordReset(t *testing.T) {
+	ctx := context.Background()
+	oldHash, _ := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	newUser := func() *model.User {
+		return &model.User{ID: "user-1", Email: "jane@example.com", PasswordHash: string(oldHash)}
+	}
+
+	t.Run("Resets the password and signs out every session", func(t *testing.T) {
+		f := newAccountServiceFixture()
+		f.userRepo.On("FindByEmail", ctx, "jane@example.com").Return(newUser(), nil)
+		require.NoError(t, f.service.RequestPasswordReset(ctx, "jane@example.com"))
+
+		sent := f.mailer.Sent()
+		require.Len(t, sent, 1)
+		assert.Equal(t, "jane@example.com", sent[0].To)
+		assert.Contains(t, sent[0].Body, "https://fitness.example.com/reset-password?token=")
+		assert.Equal(t, model.ActionTokenPasswordReset, f.stored.Purpose)
+		assert.WithinDuration(t, time.Now().Add(passwordResetTTL), f.stored.ExpiresAt, time.Minute)
+		token := tokenFromEmail(t, f.mailer)
+		assert.NotContains(t, f.stored.TokenHash, token, "only a hash of the token is stored")
+
+		f.userRepo.On("FindByID", ctx, "user-1").Return(newUser(), nil)
+		f.tokenRepo.On("Consume", ctx, "token-1").Return(true, nil).Once()
+		f.userRepo.On("Update", ctx, mock.MatchedBy(func(u *model.User) bool {
+			return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte("new-passphrase")) == nil && u.EmailVerified
+		})).Return(nil).Once()
+		f.refreshTokens.On("RevokeAllForUser", ctx, "user-1").Return(nil).Once()
+
+		user, err := f.service.ResetPassword(ctx, token, "new-passphrase")
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", user.ID)
+		f.refreshTokens.AssertExpectations(t)
+
+		// The token is single-use
+		f.tokenRepo.On("Consume", ctx, "token-1").Return(false, nil).Once()
+		_, err = f.service.ResetPassword(ctx, token, "another-passphrase")
+		assert.ErrorIs(t, err, ErrInvalidActionToken)
+	})
+
+	t.Run("Unknown email sends nothing", func(t *testing.T) {
+		f := newAccountServiceFixture()
+		f.userRepo.On("FindByEmail", ctx, "nobody@example.com").Return(nil, nil)
+		require.NoError(t, f.service.RequestPasswordReset(ctx, "nobody@example.com"))
+		assert.Empty(t, f.mailer.Sent())
+	})
+
+	t.Run("Rejected password keeps the token", func(t *testing.T) {
+		f := newAccountServiceFixture()
+		f.userRepo.On("FindByEmail", ctx, "jane@example.com").Return(newUser(), nil)
+		f.userRepo.On("FindByID", ctx, "user-1").Return(newUser(), nil)
+		require.NoError(t, f.service.RequestPasswordReset(ctx, "jane@example.com"))
+
+		_, err := f.service.ResetPassword(ctx, tokenFromEmail(t, f.mailer), "short")
+		var invalid *InvalidPasswordError
+		assert.ErrorAs(t, err, &invalid)
+		f.tokenRepo.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Rejects bad tokens", func(t *testing.T) {
+		f := newAccountServiceFixture()
+		f.userRepo.On("FindByEmail", ctx, "jane@example.com").Return(newUser(), nil)
+		require.NoError(t, f.service.RequestPasswordReset(ctx, "jane@example.com"))
+		token := tokenFromEmail(t, f.mailer)
+		f.tokenRepo.On("FindByID", mock.Anything, "unknown").Return(nil, nil)
+
+		for _, bad := range []string{"", "token-1", "token-1.wrong-secret", "unknown.secret"} {
+			_, err := f.service.ResetPassword(ctx, bad, "new-passphrase")
+			assert.ErrorIs(t, err, ErrInvalidActionToken, bad)
+		}
+
+		// A verification token cannot reset the password
+		f.stored.Purpose = model.ActionTokenEmailVerification
+		_, err := f.service.ResetPassword(ctx, token, "new-passphrase")
+		assert.ErrorIs(t, err, ErrInvalidActionToken)
+
+		f.stored.Purpose = model.ActionTokenPasswordReset
+		f.stored.ExpiresAt = time.Now().Add(-time.Second)
+		_, err = f.service.ResetPassword(ctx, token, "new-passphrase")
+		assert.ErrorIs(t, err, ErrInvalidActionToken)
+		f.tokenRepo.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAccountServiceVerifyEmail(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Verifies the address", func(t *testing.T) {
+		f := newAccountServiceFixture()
+		require.NoError(t, f.service.SendVerificationEmail(ctx, &model.User{ID: "user-1", Email: "jane@example.com"}))
+		assert.Contains(t, f.mailer.Sent()[0].Body, "https://fitness.example.com/verify-email?token=")
+
+		f.tokenRepo.On("Consume", ctx, "token-1").Return(true, nil).Once()
+		f.userRepo.On("FindByID", ctx, "user-1").Return(&model.User{ID: "user-1", Email: "jane@example.com"}, nil)
+		f.userRepo.On("Update", ctx, mock.MatchedBy(func(u *model.User) bool { return u.EmailVerified })).Return(nil).Once()
+
+		user, err := f.service.VerifyEmail(ctx, tokenFromEmail(t, f.mailer))
+		require.NoError(t, err)
+		assert.True(t, user.EmailVerified)
+		f.userRepo.AssertExpectations(t)
+	})
+
+	t.Run("Already verified", func(t *testing.T) {
+		f := newAccountServiceFixture()
+		err := f.service.SendVerificationEmail(ctx, &model.User{ID: "user-1", Email: "jane@example.com", EmailVerified: true})
+		assert.ErrorIs(t, err, ErrEmailAlreadyVerified)
+		assert.Empty(t, f.mailer.Sent())
+	})
+
+	t.Run("Link for a previous address", func(t *testing.T) {
+		f := newAccountServiceFixture()
+		require.NoError(t, f.service.SendVerificationEmail(ctx, &model.User{ID: "user-1", Email: "old@example.com"}))
+
+		f.tokenRepo.On("Consume", ctx, "token-1").Return(true, nil).Once()
+		f.userRepo.On("FindByID", ctx, "user-1").Return(&model.User{ID: "user-1", Email: "new@example.com"}, nil)
+
+		_, err := f.service.VerifyEmail(ctx, tokenFromEmail(t, f.mailer))
+		assert.ErrorIs(t, err, ErrInvalidActionToken)
+		f.userRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAccountServiceEmailChange(t *testing.T) {
+	ctx := context.Background()
+	pendingUser := func(pending string) *model.User {
+		return &model.User{ID: "user-1", Email: "old@example.com", PendingEmail: &pending}
+	}
+
+	t.Run("Confirms the new address and notifies the old one", func(t *testing.T) {
+		f := newAccountServiceFixture()
+		require.NoError(t, f.service.SendEmailChangeConfirmation(ctx, pendingUser("new@example.com")))
+
+		sent := f.mailer.Sent()
+		require.Len(t, sent, 2)
+		assert.Equal(t, "new@example.com", sent[0].To)
+		assert.Contains(t, sent[0].Body, "https://fitness.example.com/confirm-email?token=")
+		assert.Equal(t, "old@example.com", sent[1].To)
+		assert.Contains(t, sent[1].Body, "new@example.com")
+		assert.NotContains(t, sent[1].Body, "token=", "the old address must not receive the link")
+		assert.Equal(t, "new@example.com", f.stored.Email)
+		token := tokenFromEmail(t, f.mailer)
+
+		f.tokenRepo.On("Consume", ctx, "token-1").Return(true, nil).Once()
+		f.userRepo.On("FindByID", ctx, "user-1").Return(pendingUser("new@example.com"), nil)
+		f.userRepo.On("Update", ctx, mock.MatchedBy(func(u *model.User) bool {
+			return u.Email == "new@example.com" && u.PendingEmail == nil && u.EmailVerified
+		})).Return(nil).Once()
+
+		user, err := f.service.ConfirmEmailChange(ctx, token)
+		require.NoError(t, err)
+		assert.Equal(t, "new@example.com", user.Email)
+		f.tokenRepo.AssertCalled(t, "DeleteForUser", mock.Anything, "user-1", model.ActionTokenPasswordReset)
+		f.userRepo.AssertExpectations(t)
+	})
+
+	t.Run("Superseded change", func(t *testing.T) {
+		f := newAccountServiceFixture()
+		require.NoError(t, f.service.SendEmailChangeConfirmation(ctx, pendingUser("new@example.com")))
+
+		f.tokenRepo.On("Consume", ctx, "token-1").Return(true, nil).Once()
+		f.userRepo.On("FindByID", ctx, "user-1").Return(pendingUser("other@example.com"), nil)
+
+		_, err := f.service.ConfirmEmailChange(ctx, tokenFromEmail(t, f.mailer))
+		assert.ErrorIs(t, err, ErrInvalidActionToken)
+		f.userRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Address taken in the meantime", func(t *testing.T) {
+		f := newAccountServiceFixture()
+		require.NoError(t, f.service.SendEmailChangeConfirmation(ctx, pendingUser("new@example.com")))
+		token := tokenFromEmail(t, f.mailer)
+
+		f.tokenRepo.On("Consume", ctx, "token-1").Return(true, nil).Once()
+		f.userRepo.On("FindByID", ctx, "user-1").Return(pendingUser("new@example.com"), nil)
+		f.userRepo.On("Update", ctx, mock.Anything).Return(repository.ErrDuplicateEmail).Once()
+
+		_, err := f.service.ConfirmEmailChange(ctx, token)
+		assert.ErrorIs(t, err, repository.ErrDuplicateEmail)
+	})
+
+	t.Run("Nothing pending", func(t *testing.T) {
+		f := newAccountServiceFixture()
+		assert.Error(t, f.service.SendEmailChangeConfirmation(ctx, &model.User{ID: "user-1", Email: "old@example.com"}))
+		assert.Empty(t, f.mailer.Sent())
+	})
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+// issueActionToken replaces the user's outstanding tokens for the purpose with a new one for the
+// email it is sent to, returned as "ID.Secret".
+func issueActionToken(ctx context.Context, tokens repository.ActionTokenRepository, user *model.User, purpose model.ActionTokenPurpose, email string, ttl time.Duration) (string, error) {
+	if err := tokens.DeleteForUser(ctx, user.ID, purpose); err != nil {
+		return "", err
+	}
+	return createActionToken(ctx, tokens, user.ID, purpose, email, ttl)
+}
+
+// createActionToken creates a token alongside any outstanding ones, returned as "ID.Secret".
+func createActionToken(ctx context.Context, tokens repository.ActionTokenRepository, userID string, purpose model.ActionTokenPurpose, email string, ttl time.Duration) (string, error) {
+	secret, hash, err := newHashedSecret()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	token := &model.ActionToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		TokenHash: hash,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	if err := tokens.Create(ctx, token); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s.%s", token.ID, secret), nil
+}
+
+// lookupActionToken returns the stored token matching an "ID.Secret" token, without using it up.
+func lookupActionToken(ctx context.Context, tokens repository.ActionTokenRepository, compositeToken string, purpose model.ActionTokenPurpose) (*model.ActionToken, error) {
+	tokenID, secret, ok := strings.Cut(compositeToken, ".")
+	if !ok || tokenID == "" || secret == "" {
+		return nil, ErrInvalidActionToken
+	}
+
+	token, err := tokens.FindByID(ctx, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.Purpose != purpose || time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidActionToken
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(token.TokenHash), []byte(secret)); err != nil {
+		return nil, ErrInvalidActionToken
+	}
+	return token, nil
+}
+
+// consumeActionToken uses up a token; it fails if a concurrent request used it first.
+func consumeActionToken(ctx context.Context, tokens repository.ActionTokenRepository, token *model.ActionToken) error {
+	consumed, err := tokens.Consume(ctx, token.ID)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrInvalidActionToken
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+const defaultTrendWindowDays = 7
+
+// BodyMetricService handles bodyweight and body measurement tracking.
+type BodyMetricService struct {
+	repo repository.BodyMetricRepository
+}
+
+// NewBodyMetricService creates a new instance of the BodyMetricService.
+func NewBodyMetricService(repo repository.BodyMetricRepository) *BodyMetricService {
+	return &BodyMetricService{
+		repo: repo,
+	}
+}
+
+// LogMetric records a new body metric entry for the user.
+func (s *BodyMetricService) LogMetric(ctx context.Context, userID string, input model.BodyMetricInput) (*model.BodyMetric, error) {
+	if input.Weight == nil && input.BodyFatPercentage == nil && len(input.Measurements) == 0 {
+		return nil, fmt.Errorf("at least one of weight, body fat percentage or measurements is required")
+	}
+
+	now := time.Now()
+	metric := &model.BodyMetric{
+		UserID:     userID,
+		RecordedAt: now,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := applyBodyMetricInput(metric, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, metric); err != nil {
+		return nil, err
+	}
+	return metric, nil
+}
+
+// UpdateMetric applies the provided changes to an entry owned by the user.
+func (s *BodyMetricService) UpdateMetric(ctx context.Context, userID, id string, input model.BodyMetricInput) (*model.BodyMetric, error) {
+	metric, err := s.GetMetric(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyBodyMetricInput(metric, input); err != nil {
+		return nil, err
+	}
+	if metric.Weight == nil && metric.BodyFatPercentage == nil && len(metric.Measurements) == 0 {
+		return nil, fmt.Errorf("at least one of weight, body fat percentage or measurements is required")
+	}
+
+	metric.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, metric); err != nil {
+		return nil, err
+	}
+	return metric, nil
+}
+
+// DeleteMetric removes an entry owned by the user.
+func (s *BodyMetricService) DeleteMetric(ctx context.Context, userID, id string) error {
+	return s.repo.Delete(ctx, userID, id)
+}
+
+// GetMetric fetches a single entry, verifying that it belongs to the user.
+func (s *BodyMetricService) GetMetric(ctx context.Context, userID, id string) (*model.BodyMetric, error) {
+	metric, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if metric == nil || metric.UserID != userID {
+		return nil, fmt.Errorf("body metric not found")
+	}
+	return metric, nil
+}
+
+// ListMetrics returns the user's entries within the optional date range, newest first.
+func (s *BodyMetricService) ListMetrics(ctx context.Context, userID string, from, to *time.Time, limit, offset int) ([]*model.BodyMetric, error) {
+	return s.repo.ListByUser(ctx, userID, from, to, limit, offset)
+}
+
+// BodyweightAt returns the user's bodyweight in kilograms as of the given time
+// (the latest weigh-in at or before it), or nil if none has been recorded yet.
+func (s *BodyMetricService) BodyweightAt(ctx context.Context, userID string, at time.Time) (*float64, error) {
+	metric, err := s.repo.FindLatestWeightBefore(ctx, userID, at)
+	if err != nil {
+		return nil, err
+	}
+	if metric == nil {
+		return nil, nil
+	}
+	return metric.Weight, nil
+}
+
+// BodyweightsAt looks up BodyweightAt for each key at once. Results are aligned with keys.
+func (s *BodyMetricService) BodyweightsAt(ctx context.Context, keys []model.BodyweightKey) ([]*float64, error) {
+	metrics, err := s.repo.FindLatestWeightsBefore(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	weights := make([]*float64, len(metrics))
+	for i, metric := range metrics {
+		if metric != nil {
+			weights[i] = metric.Weight
+		}
+	}
+	return weights, nil
+}
+
+// BodyweightTrend returns every weigh-in between from and to (oldest first), each paired with
+// the average of all weigh-ins in the trailing windowDays-day window ending at that entry.
+func (s *BodyMetricService) BodyweightTrend(ctx context.Context, userID string, from, to time.Time, windowDays int) ([]*model.BodyweightTrendPoint, error) {
+	if windowDays <= 0 {
+		windowDays = defaultTrendWindowDays
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("trend range end must not be before its start")
+	}
+	window := time.Duration(windowDays) * 24 * time.Hour
+
+	// Load entries preceding the range too, so the first points have a full window.
+	windowStart := from.Add(-window)
+	metrics, err := s.repo.ListByUser(ctx, userID, &windowStart, &to, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var weighIns []*model.BodyMetric
+	for _, m := range metrics {
+		if m.Weight != nil {
+			weighIns = append(weighIns, m)
+		}
+	}
+	sort.Slice(weighIns, func(i, j int) bool {
+		return weighIns[i].RecordedAt.Before(weighIns[j].RecordedAt)
+	})
+
+	points := []*model.BodyweightTrendPoint{}
+	start := 0
+	sum := 0.0
+	for i, m := range weighIns {
+		sum += *m.Weight
+		for !weighIns[start].RecordedAt.After(m.RecordedAt.Add(-window)) {
+			sum -= *weighIns[start].Weight
+			start++
+		}
+		if m.RecordedAt.Before(from) {
+			continue
+		}
+		points = append(points, &model.BodyweightTrendPoint{
+			Date:          m.RecordedAt,
+			Weight:        *m.Weight,
+			MovingAverage: sum / float64(i-start+1),
+		})
+	}
+
+	return points, nil
+}
+
+// applyBodyMetricInput validates the input and copies it onto the metric in storage units.
+func applyBodyMetricInput(metric *model.BodyMetric, input model.BodyMetricInput) error {
+	unit := input.Unit
+	if unit == "" {
+		unit = model.WeightUnitKilograms
+	}
+
+	if input.RecordedAt != nil {
+		if input.RecordedAt.After(time.Now().Add(24 * time.Hour)) {
+			return fmt.Errorf("recorded date cannot be in the future")
+		}
+		metric.RecordedAt = *input.RecordedAt
+	}
+
+	if input.Weight != nil {
+		if *input.Weight <= 0 {
+			return fmt.Errorf("weight must be greater than zero")
+		}
+		kg := unit.ToKilograms(*input.Weight)
+		metric.Weight = &kg
+	}
+
+	if input.BodyFatPercentage != nil {
+		if *input.BodyFatPercentage <= 0 || *input.BodyFatPercentage >= 100 {
+			return fmt.Errorf("body fat percentage must be between 0 and 100")
+		}
+		metric.BodyFatPercentage = input.BodyFatPercentage
+	}
+
+	if input.Measurements != nil {
+		seen := make(map[model.MeasurementSite]bool)
+		measurements := make([]*model.BodyMeasurement, 0, len(input.Measurements))
+		for _, m := range input.Measurements {
+			if !m.Site.IsValid() {
+				return fmt.Errorf("invalid measurement site: %s", m.Site)
+			}
+			if seen[m.Site] {
+				return fmt.Errorf("duplicate measurement for site %s", m.Site)
+			}
+			if m.Value <= 0 {
+				return fmt.Errorf("measurement for %s must be greater than zero", m.Site)
+			}
+			seen[m.Site] = true
+			measurements = append(measurements, &model.BodyMeasurement{
+				Site:  m.Site,
+				Value: unit.ToCentimeters(m.Value),
+			})
+		}
+		metric.Measurements = measurements
+	}
+
+	if input.Notes != nil {
+		metric.Notes = input.Notes
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
+
+func TestLogMetric(t *testing.T) {
+	mockRepo := new(repository.MockBodyMetricRepository)
+	service := NewBodyMetricService(mockRepo)
+	ctx := context.Background()
+	userID := "user-123"
+
+	t.Run("converts pounds and inches to storage units", func(t *testing.T) {
+		input := model.BodyMetricInput{
+			Unit:   model.WeightUnitPounds,
+			Weight: float64Ptr(220),
+			Measurements: []*model.BodyMeasurement{
+				{Site: model.MeasurementSiteWaist, Value: 34},
+			},
+		}
+
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*model.BodyMetric")).Return(nil).Once()
+
+		result, err := service.LogMetric(ctx, userID, input)
+
+		assert.NoError(t, err)
+		assert.Equal(t, userID, result.UserID)
+		assert.InDelta(t, 99.79, *result.Weight, 0.01)
+		assert.InDelta(t, 86.36, result.Measurements[0].Value, 0.01)
+		assert.False(t, result.RecordedAt.IsZero())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("requires at least one value", func(t *testing.T) {
+		result, err := service.LogMetric(ctx, userID, model.BodyMetricInput{Unit: model.WeightUnitKilograms})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+	})
+
+	t.Run("rejects invalid body fat", func(t *testing.T) {
+		result, err := service.LogMetric(ctx, userID, model.BodyMetricInput{BodyFatPercentage: float64Ptr(120)})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+	})
+
+	t.Run("rejects duplicate measurement sites", func(t *testing.T) {
+		input := model.BodyMetricInput{
+			Measurements: []*model.BodyMeasurement{
+				{Site: model.MeasurementSiteArm, Value: 35},
+				{Site: model.MeasurementSiteArm, Value: 36},
+			},
+		}
+
+		result, err := service.LogMetric(ctx, userID, input)
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		mockRepo.AssertNumberOfCalls(t, "Create", 1)
+	})
+}
+
+func TestUpdateMetric(t *testing.T) {
+	mockRepo := new(repository.MockBodyMetricRepository)
+	service := NewBodyMetricService(mockRepo)
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		existing := &model.BodyMetric{ID: "bm-1", UserID: "user-123", Weight: float64Ptr(80)}
+		mockRepo.On("GetByID", ctx, "bm-1").Return(existing, nil).Once()
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(m *model.BodyMetric) bool {
+			return *m.Weight == 80 && *m.BodyFatPercentage == 15
+		})).Return(nil).Once()
+
+		result, err := service.UpdateMetric(ctx, "user-123", "bm-1", model.BodyMetricInput{BodyFatPercentage: float64Ptr(15)})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 15.0, *result.BodyFatPercentage)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("not owner", func(t *testing.T) {
+		existing := &model.BodyMetric{ID: "bm-2", UserID: "someone-else"}
+		mockRepo.On("GetByID", ctx, "bm-2").Return(existing, nil).Once()
+
+		result, err := service.UpdateMetric(ctx, "user-123", "bm-2", model.BodyMetricInput{Weight: float64Ptr(80)})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		mockRepo.AssertNotCalled(t, "Update", ctx, existing)
+	})
+}
+
+func TestBodyweightAt(t *testing.T) {
+	mockRepo := new(repository.MockBodyMetricRepository)
+	service := NewBodyMetricService(mockRepo)
+	ctx := context.Background()
+	at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	t.Run("found", func(t *testing.T) {
+		mockRepo.On("FindLatestWeightBefore", ctx, "user-123", at).Return(&model.BodyMetric{Weight: float64Ptr(82.5)}, nil).Once()
+
+		result, err := service.BodyweightAt(ctx, "user-123", at)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 82.5, *result)
+	})
+
+	t.Run("no weigh-ins yet", func(t *testing.T) {
+		mockRepo.On("FindLatestWeightBefore", ctx, "user-123", at).Return(nil, nil).Once()
+
+		result, err := service.BodyweightAt(ctx, "user-123", at)
+
+		assert.NoError(t, err)
+		assert.Nil(t, result)
+	})
+
+	t.Run("repo error", func(t *testing.T) {
+		mockRepo.On("FindLatestWeightBefore", ctx, "user-123", at).Return(nil, errors.New("db error")).Once()
+
+		result, err := service.BodyweightAt(ctx, "user-123", at)
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+	})
+}
+
+func TestBodyweightTrend(t *testing.T) {
+	mockRepo := new(repository.MockBodyMetricRepository)
+	service := NewBodyMetricService(mockRepo)
+	ctx := context.Background()
+
+	day := func(d int) time.Time {
+		return time.Date(2025, 1, d, 8, 0, 0, 0, time.UTC)
+	}
+	from := day(10)
+	to := day(20)
+
+	// Repository returns newest first; one entry has no weight and must be ignored.
+	metrics := []*model.BodyMetric{
+		{RecordedAt: day(14), Weight: float64Ptr(81)},
+		{RecordedAt: day(12), BodyFatPercentage: float64Ptr(18)},
+		{RecordedAt: day(11), Weight: float64Ptr(80)},
+		{RecordedAt: day(8), Weight: float64Ptr(82)},
+		{RecordedAt: day(1), Weight: float64Ptr(90)},
+	}
+	mockRepo.On("ListByUser", ctx, "user-123", mock.Anything, &to, 0, 0).Return(metrics, nil).Once()
+
+	points, err := service.BodyweightTrend(ctx, "user-123", from, to, 7)
+
+	assert.NoError(t, err)
+	assert.Len(t, points, 2)
+	// Day 11 window (4th..11th]: 82, 80
+	assert.Equal(t, day(11), points[0].Date)
+	assert.InDelta(t, 81.0, points[0].MovingAverage, 0.001)
+	// Day 14 window (7th..14th]: 82, 80, 81
+	assert.Equal(t, 81.0, points[1].Weight)
+	assert.InDelta(t, 81.0, points[1].MovingAverage, 0.001)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+const calendarDateLayout = "2006-01-02"
+
+// CalendarService builds training calendar (heatmap) data and consistency streaks.
+type CalendarService struct {
+	workoutRepo repository.WorkoutRepository
+	userRepo    repository.UserRepository
+}
+
+// NewCalendarService creates a new instance of the CalendarService.
+func NewCalendarService(workoutRepo repository.WorkoutRepository, userRepo repository.UserRepository) *CalendarService {
+	return &CalendarService{
+		workoutRepo: workoutRepo,
+		userRepo:    userRepo,
+	}
+}
+
+// TrainingCalendar returns per-day totals for the given year and the user's streaks,
+// bucketing workouts by their start time in the user's configured time zone.
+func (s *CalendarService) TrainingCalendar(ctx context.Context, userID string, year int) (*model.TrainingCalendar, error) {
+	if year < 1 || year > 9999 {
+		return nil, fmt.Errorf("invalid year: %d", year)
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	loc := user.Location()
+
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	days, err := s.workoutRepo.SummarizeDays(ctx, userID, from, from.AddDate(1, 0, 0), loc.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize workouts: %w", err)
+	}
+
+	// Streaks depend on the whole history, not just the requested year, but only on the dates trained
+	now := time.Now()
+	starts, err := s.workoutRepo.ListStartTimes(ctx, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workouts: %w", err)
+	}
+	trained := make(map[time.Time]bool)
+	for _, start := range starts {
+		trained[civilDate(start, loc)] = true
+	}
+
+	return &model.TrainingCalendar{
+		Year:     int32(year),
+		Timezone: loc.String(),
+		Days:     days,
+		Streaks:  computeTrainingStreaks(trained, user.TrainingDays, civilDate(now, loc)),
+	}, nil
+}
+
+// civilDate returns the local calendar date of t in loc, as midnight UTC.
+// Using UTC for the result keeps day arithmetic free of DST gaps.
+func civilDate(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// startOfWeek returns the Monday of the week containing date.
+func startOfWeek(date time.Time) time.Time {
+	offset := (int(date.Weekday()) + 6) % 7
+	return date.AddDate(0, 0, -offset)
+}
+
+// computeTrainingStreaks derives daily and weekly streaks from the set of trained dates.
+// An empty schedule means every day is a training day and one session a week meets the weekly goal.
+// Training on an unscheduled day extends the daily streak, missing one does not break it,
+// and the current day and week are never counted as missed while they are still in progress.
+func computeTrainingStreaks(trained map[time.Time]bool, schedule []model.Weekday, today time.Time) *model.TrainingStreaks {
+	streaks := &model.TrainingStreaks{}
+	if len(trained) == 0 {
+		return streaks
+	}
+
+	scheduled := make(map[time.Weekday]bool, len(schedule))
+	for _, day := range schedule {
+		scheduled[day.TimeWeekday()] = true
+	}
+	isScheduled := func(date time.Time) bool {
+		return len(scheduled) == 0 || scheduled[date.Weekday()]
+	}
+	required := len(scheduled)
+	if required == 0 {
+		required = 1
+	}
+
+	first := today
+	for date := range trained {
+		if date.Before(first) {
+			first = date
+		}
+	}
+
+	// Daily streaks
+	var run int32
+	for date := first; !date.After(today); date = date.AddDate(0, 0, 1) {
+		switch {
+		case trained[date]:
+			run++
+		case isScheduled(date) && !date.Equal(today):
+			run = 0
+		}
+		if run > streaks.LongestDaily {
+			streaks.LongestDaily = run
+		}
+	}
+	streaks.CurrentDaily = run
+
+	// Weekly streaks
+	currentWeek := startOfWeek(today)
+	run = 0
+	for week := startOfWeek(first); !week.After(currentWeek); week = week.AddDate(0, 0, 7) {
+		count := 0
+		for i := 0; i < 7; i++ {
+			if trained[week.AddDate(0, 0, i)] {
+				count++
+			}
+		}
+		switch {
+		case count >= required:
+			run++
+		case !week.Equal(currentWeek):
+			run = 0
+		}
+		if run > streaks.LongestWeekly {
+			streaks.LongestWeekly = run
+		}
+	}
+	streaks.CurrentWeekly = run
+
+	return streaks
+}
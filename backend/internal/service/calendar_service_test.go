@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func dates(values ...string) map[time.Time]bool {
+	result := make(map[time.Time]bool, len(values))
+	for _, v := range values {
+		d, _ := time.Parse(calendarDateLayout, v)
+		result[d] = true
+	}
+	return result
+}
+
+func TestTrainingCalendar(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+
+	t.Run("summarizes the year in the user's time zone", func(t *testing.T) {
+		workoutRepo := new(repository.MockWorkoutRepository)
+		userRepo := new(repository.MockUserRepository)
+		service := NewCalendarService(workoutRepo, userRepo)
+
+		userRepo.On("FindByID", ctx, userID).Return(&model.User{ID: userID, Timezone: "America/New_York"}, nil).Once()
+
+		ny, _ := time.LoadLocation("America/New_York")
+		from := time.Date(2024, 1, 1, 0, 0, 0, 0, ny)
+		days := []*model.TrainingCalendarDay{
+			{Date: "2024-03-09", WorkoutCount: 1, DurationMinutes: 45, Volume: 500},
+		}
+		workoutRepo.On("SummarizeDays", ctx, userID, from, from.AddDate(1, 0, 0), "America/New_York").Return(days, nil).Once()
+
+		// Yesterday at 23:30 in New York, which is already today in UTC, and the day before.
+		now := time.Now().In(ny)
+		yesterday := time.Date(now.Year(), now.Month(), now.Day()-1, 23, 30, 0, 0, ny)
+		starts := []time.Time{yesterday.AddDate(0, 0, -1).UTC(), yesterday.UTC()}
+		workoutRepo.On("ListStartTimes", ctx, userID, mock.Anything).Return(starts, nil).Once()
+
+		calendar, err := service.TrainingCalendar(ctx, userID, 2024)
+		require.NoError(t, err)
+		assert.Equal(t, int32(2024), calendar.Year)
+		assert.Equal(t, "America/New_York", calendar.Timezone)
+		assert.Equal(t, days, calendar.Days)
+		assert.Equal(t, int32(2), calendar.Streaks.CurrentDaily)
+		assert.Equal(t, int32(2), calendar.Streaks.LongestDaily)
+		userRepo.AssertExpectations(t)
+		workoutRepo.AssertExpectations(t)
+	})
+
+	t.Run("rejects invalid year", func(t *testing.T) {
+		service := NewCalendarService(new(repository.MockWorkoutRepository), new(repository.MockUserRepository))
+		_, err := service.TrainingCalendar(ctx, userID, 0)
+		assert.ErrorContains(t, err, "invalid year")
+	})
+}
+
+func TestComputeTrainingStreaks(t *testing.T) {
+	// 2024-06-05 is a Wednesday.
+	today, _ := time.Parse(calendarDateLayout, "2024-06-05")
+
+	t.Run("no workouts", func(t *testing.T) {
+		streaks := computeTrainingStreaks(map[time.Time]bool{}, nil, today)
+		assert.Equal(t, &model.TrainingStreaks{}, streaks)
+	})
+
+	t.Run("every day schedule", func(t *testing.T) {
+		trained := dates("2024-05-27", "2024-05-28", "2024-05-29", "2024-06-03", "2024-06-04")
+		streaks := computeTrainingStreaks(trained, nil, today)
+
+		// Today is untrained but still in progress.
+		assert.Equal(t, int32(2), streaks.CurrentDaily)
+		assert.Equal(t, int32(3), streaks.LongestDaily)
+		assert.Equal(t, int32(2), streaks.CurrentWeekly)
+		assert.Equal(t, int32(2), streaks.LongestWeekly)
+	})
+
+	t.Run("unscheduled days do not break streaks", func(t *testing.T) {
+		schedule := []model.Weekday{model.WeekdayMonday, model.WeekdayWednesday, model.WeekdayFriday}
+		// Mon/Wed/Fri for two weeks, plus a bonus Saturday, then Monday and today.
+		trained := dates(
+			"2024-05-20", "2024-05-22", "2024-05-24", "2024-05-25",
+			"2024-05-27", "2024-05-29", "2024-05-31",
+			"2024-06-03", "2024-06-05",
+		)
+		streaks := computeTrainingStreaks(trained, schedule, today)
+
+		assert.Equal(t, int32(9), streaks.CurrentDaily)
+		assert.Equal(t, int32(9), streaks.LongestDaily)
+		// The current week has only two of three sessions so far but is not broken yet.
+		assert.Equal(t, int32(2), streaks.CurrentWeekly)
+		assert.Equal(t, int32(2), streaks.LongestWeekly)
+	})
+
+	t.Run("missed scheduled day and week reset", func(t *testing.T) {
+		schedule := []model.Weekday{model.WeekdayMonday, model.WeekdayThursday}
+		trained := dates("2024-05-13", "2024-05-16", "2024-05-20", "2024-06-03")
+		streaks := computeTrainingStreaks(trained, schedule, today)
+
+		assert.Equal(t, int32(1), streaks.CurrentDaily)
+		assert.Equal(t, int32(3), streaks.LongestDaily)
+		assert.Equal(t, int32(0), streaks.CurrentWeekly)
+		assert.Equal(t, int32(1), streaks.LongestWeekly)
+	})
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+)
+
+// Match tiers, best first. A candidate's tier is the best one reached by its name or any alias.
+const (
+	matchNone = iota
+	matchFuzzy
+	matchWord
+	matchPrefix
+	matchExact
+)
+
+const (
+	// exerciseUsageWindow is how far back workouts count towards ranking the exercises a user
+	// does often, which also bounds the workouts read.
+	exerciseUsageWindow = 180 * 24 * time.Hour
+	// exerciseUsageTTL is how long a user's usage counts are reused, so searching as they type
+	// reads the workouts once.
+	exerciseUsageTTL = time.Minute
+)
+
+// searchAbbreviations expands common gym shorthand so "DB row" and "Dumbbell Row" compare equal.
+var searchAbbreviations = map[string]string{
+	"db": "dumbbell",
+	"bb": "barbell",
+	"kb": "kettlebell",
+	"bw": "bodyweight",
+}
+
+// normalizeSearchText lower-cases the text, treats punctuation as spaces and expands abbreviations.
+func normalizeSearchText(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		if expanded, ok := searchAbbreviations[w]; ok {
+			words[i] = expanded
+		}
+	}
+	return words
+}
+
+// typoAllowance is the number of edits tolerated for a query word of the given length.
+func typoAllowance(word string) int {
+	switch n := len([]rune(word)); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+type searchMatch struct {
+	tier     int
+	distance int // Total edits, only meaningful for fuzzy matches
+}
+
+func (m searchMatch) betterThan(other searchMatch) bool {
+	if m.tier != other.tier {
+		return m.tier > other.tier
+	}
+	return m.distance < other.distance
+}
+
+// matchText scores how well the normalized query matches a single name or alias.
+func matchText(query []string, text string) searchMatch {
+	words := normalizeSearchText(text)
+	if len(words) == 0 {
+		return searchMatch{}
+	}
+	joined := strings.Join(words, " ")
+	q := strings.Join(query, " ")
+
+	// Compare without spaces too, so "pullup" finds "Pull Up"
+	compactText := strings.Join(words, "")
+	compactQuery := strings.Join(query, "")
+
+	switch {
+	case joined == q || compactText == compactQuery:
+		return searchMatch{tier: matchExact}
+	case strings.HasPrefix(joined, q) || strings.HasPrefix(compactText, compactQuery):
+		return searchMatch{tier: matchPrefix}
+	}
+
+	// Word match: every query word starts some word of the text
+	allWords := true
+	for _, qw := range query {
+		found := false
+		for _, w := range words {
+			if strings.HasPrefix(w, qw) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			allWords = false
+			break
+		}
+	}
+	if allWords {
+		return searchMatch{tier: matchWord}
+	}
+
+	// Fuzzy: every query word is within its typo allowance of some word (or word prefix) of the text
+	total := 0
+	for _, qw := range query {
+		best := -1
+		for _, w := range words {
+			d := editDistance(qw, w)
+			// Allow the user to still be typing: compare against the word's prefix of the same length
+			if r := []rune(w); len(r) > len([]rune(qw)) {
+				if pd := editDistance(qw, string(r[:len([]rune(qw))])); pd < d {
+					d = pd
+				}
+			}
+			if best == -1 || d < best {
+				best = d
+			}
+		}
+		if best > typoAllowance(qw) {
+			return searchMatch{}
+		}
+		total += best
+	}
+	return searchMatch{tier: matchFuzzy, distance: total}
+}
+
+// rankExercises keeps the exercises matching the query and orders them by match quality,
+// then by how often the user has logged them, then by name. Names and aliases match in English
+// and in the exercise's translation for the locales, if it has one.
+func rankExercises(exercises []*model.UniqueExercise, query string, usage map[string]int, locales []string) []*model.UniqueExercise {
+	q := normalizeSearchText(query)
+	if len(q) == 0 {
+		return exercises
+	}
+
+	type ranked struct {
+		exercise *model.UniqueExercise
+		name     string // Localized
+		match    searchMatch
+	}
+	var results []ranked
+	for _, ex := range exercises {
+		texts := append([]string{ex.Name}, ex.Aliases...)
+		name := ex.Name
+		if t := ex.Translation(locales); t != nil {
+			texts = append(append(texts, t.Name), t.Aliases...)
+			name = t.Name
+		}
+
+		var best searchMatch
+		for _, text := range texts {
+			if m := matchText(q, text); m.betterThan(best) {
+				best = m
+			}
+		}
+		if best.tier != matchNone {
+			results = append(results, ranked{exercise: ex, name: name, match: best})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.match != b.match {
+			return a.match.betterThan(b.match)
+		}
+		if ua, ub := usage[a.exercise.ID], usage[b.exercise.ID]; ua != ub {
+			return ua > ub
+		}
+		return strings.ToLower(a.name) < strings.ToLower(b.name)
+	})
+
+	ordered := make([]*model.UniqueExercise, len(results))
+	for i, r := range results {
+		ordered[i] = r.exercise
+	}
+	return ordered
+}
+
+// editDistance returns the optimal string alignment distance between a and b: the number of
+// insertions, deletions, substitutions and adjacent transpositions ("bicpe" -> "bicep" is one edit).
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// closestExercise returns the exercise whose name or alias is nearly identical to name
+// (same words ignoring spacing and abbreviations, or within a small edit distance), if any.
+func closestExercise(name string, exercises []*model.UniqueExercise) *model.UniqueExercise {
+	words := normalizeSearchText(name)
+	if len(words) == 0 {
+		return nil
+	}
+	compact := strings.Join(words, "")
+	allowance := typoAllowance(compact)
+
+	var closest *model.UniqueExercise
+	bestDistance := allowance + 1
+	for _, ex := range exercises {
+		for _, text := range append([]string{ex.Name}, ex.Aliases...) {
+			d := editDistance(compact, strings.Join(normalizeSearchText(text), ""))
+			if d < bestDistance {
+				closest, bestDistance = ex, d
+			}
+		}
+	}
+	return closest
+}
+
+// exerciseUsageCache keeps each user's recent exercise usage counts for a short while.
+type exerciseUsageCache struct {
+	mu      sync.Mutex
+	entries map[string]exerciseUsageEntry
+}
+
+type exerciseUsageEntry struct {
+	usage     map[string]int
+	expiresAt time.Time
+}
+
+func newExerciseUsageCache() *exerciseUsageCache {
+	return &exerciseUsageCache{entries: make(map[string]exerciseUsageEntry)}
+}
+
+// get returns the user's usage counts if they were stored less than exerciseUsageTTL ago.
+func (c *exerciseUsageCache) get(userID string, now time.Time) (map[string]int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[userID]
+	if !ok || !now.Before(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.usage, true
+}
+
+func (c *exerciseUsageCache) put(userID string, usage map[string]int, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Drop expired entries so users who stopped searching don't accumulate
+	for id, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, id)
+		}
+	}
+	c.entries[userID] = exerciseUsageEntry{usage: usage, expiresAt: now.Add(exerciseUsageTTL)}
+}
@@ -2,49 +2,463 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/riverajo/fitness-app/backend/internal/model"
 	"github.com/riverajo/fitness-app/backend/internal/repository"
 )
 
 type ExerciseService struct {
-	repo repository.ExerciseRepository
+	repo        repository.ExerciseRepository
+	workoutRepo repository.WorkoutRepository
+	goalRepo    repository.GoalRepository
+	transactor  repository.Transactor
+	systemCache *SystemExerciseCache
+	usageCache  *exerciseUsageCache
+	now         func() time.Time
 }
 
-func NewExerciseService(repo repository.ExerciseRepository) *ExerciseService {
+func NewExerciseService(repo repository.ExerciseRepository, workoutRepo repository.WorkoutRepository, goalRepo repository.GoalRepository, transactor repository.Transactor) *ExerciseService {
 	return &ExerciseService{
-		repo: repo,
+		repo:        repo,
+		workoutRepo: workoutRepo,
+		goalRepo:    goalRepo,
+		transactor:  transactor,
+		systemCache: NewSystemExerciseCache(repo),
+		usageCache:  newExerciseUsageCache(),
+		now:         time.Now,
 	}
 }
 
-func (s *ExerciseService) CreateExercise(ctx context.Context, name string, description *string, userID *string) (*model.UniqueExercise, error) {
+// SystemCache returns the service's cache of system exercises, which must be loaded
+// (and watched for catalogue changes) before it serves requests.
+func (s *ExerciseService) SystemCache() *SystemExerciseCache {
+	return s.systemCache
+}
+
+// SimilarExerciseError is returned when a new custom exercise would duplicate one of the user's
+// exercises, or closely matches a system exercise the user probably meant to use instead.
+type SimilarExerciseError struct {
+	Duplicate    bool // true: same normalized name as the user's own exercise; false: close to a system exercise
+	ExistingID   string
+	ExistingName string
+}
+
+func (e *SimilarExerciseError) Error() string {
+	if e.Duplicate {
+		return fmt.Sprintf("you already have an exercise named %q", e.ExistingName)
+	}
+	return fmt.Sprintf("a similar exercise already exists: %q", e.ExistingName)
+}
+
+// CreateExercise creates a custom exercise. Names must be unique per user (ignoring case and
+// whitespace), and unless allowSimilar is set, names closely matching a system exercise are refused
+// with a SimilarExerciseError pointing at it.
+func (s *ExerciseService) CreateExercise(ctx context.Context, input model.ExerciseInput, userID *string, allowSimilar bool) (*model.UniqueExercise, error) {
 	// 1. Validate input
-	name = strings.TrimSpace(name)
-	if name == "" {
+	if input.Name == nil {
 		return nil, fmt.Errorf("exercise name cannot be empty")
 	}
 
-	// 2. Check for duplicates (optional but good practice)
+	exercise := &model.UniqueExercise{UserID: userID}
+	if err := applyExerciseInput(exercise, input); err != nil {
+		return nil, err
+	}
 
-	exercise := &model.UniqueExercise{
-		Name:        name,
-		UserID:      userID,
-		Description: description,
+	// 2. Check for duplicates
+	if err := s.checkDuplicate(ctx, exercise); err != nil {
+		return nil, err
+	}
+	if userID != nil && !allowSimilar {
+		systemExercises, err := s.visibleExercises(ctx, nil, model.ExerciseFilter{})
+		if err != nil {
+			return nil, err
+		}
+		if similar := closestExercise(exercise.Name, systemExercises); similar != nil {
+			return nil, &SimilarExerciseError{ExistingID: similar.ID, ExistingName: similar.Name}
+		}
 	}
 
 	if err := s.repo.Create(ctx, exercise); err != nil {
+		// Lost a race with a concurrent create; report it like any other duplicate
+		if errors.Is(err, repository.ErrDuplicateExercise) {
+			if dupErr := s.checkDuplicate(ctx, exercise); dupErr != nil {
+				return nil, dupErr
+			}
+		}
+		return nil, err
+	}
+
+	return exercise, nil
+}
+
+// checkDuplicate returns a SimilarExerciseError if another of the owner's exercises has the same normalized name.
+func (s *ExerciseService) checkDuplicate(ctx context.Context, exercise *model.UniqueExercise) error {
+	existing, err := s.repo.FindByName(ctx, exercise.UserID, exercise.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != exercise.ID {
+		return &SimilarExerciseError{Duplicate: true, ExistingID: existing.ID, ExistingName: existing.Name}
+	}
+	return nil
+}
+
+// UpdateExercise applies the provided fields to one of the user's custom exercises.
+func (s *ExerciseService) UpdateExercise(ctx context.Context, userID, id string, input model.ExerciseInput) (*model.UniqueExercise, error) {
+	exercise, err := s.getOwnedExercise(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyExerciseInput(exercise, input); err != nil {
+		return nil, err
+	}
+	if input.Name != nil {
+		if err := s.checkDuplicate(ctx, exercise); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Update(ctx, exercise); err != nil {
+		if errors.Is(err, repository.ErrDuplicateExercise) {
+			if dupErr := s.checkDuplicate(ctx, exercise); dupErr != nil {
+				return nil, dupErr
+			}
+		}
+		return nil, err
+	}
+	return exercise, nil
+}
+
+// ArchiveExercise hides (or, with archived=false, restores) one of the user's custom exercises.
+// Archived exercises no longer appear in search but still resolve from existing logs.
+func (s *ExerciseService) ArchiveExercise(ctx context.Context, userID, id string, archived bool) (*model.UniqueExercise, error) {
+	exercise, err := s.getOwnedExercise(ctx, userID, id)
+	if err != nil {
 		return nil, err
 	}
 
+	var archivedAt *time.Time
+	if archived {
+		now := time.Now()
+		archivedAt = &now
+	}
+	if err := s.repo.SetArchived(ctx, userID, id, archivedAt); err != nil {
+		return nil, err
+	}
+	exercise.ArchivedAt = archivedAt
+	return exercise, nil
+}
+
+// DeleteExercise deletes one of the user's custom exercises. While workouts or goals still reference it
+// the deletion is refused, unless force is set, in which case those logs and goals are deleted with it.
+func (s *ExerciseService) DeleteExercise(ctx context.Context, userID, id string, force bool) error {
+	if _, err := s.getOwnedExercise(ctx, userID, id); err != nil {
+		return err
+	}
+
+	workouts, err := s.workoutRepo.CountByExercise(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	goals, err := s.goalRepo.CountByExercise(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	if workouts+goals == 0 {
+		return s.repo.Delete(ctx, userID, id)
+	}
+	if !force {
+		return fmt.Errorf("exercise is used in %d workout(s) and %d goal(s); archive or merge it instead, or delete with force", workouts, goals)
+	}
+
+	return s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.workoutRepo.RemoveExercise(ctx, userID, id); err != nil {
+			return err
+		}
+		if _, err := s.goalRepo.DeleteByExercise(ctx, userID, id); err != nil {
+			return err
+		}
+		return s.repo.Delete(ctx, userID, id)
+	})
+}
+
+// MergeExercises moves every workout log and goal of the user's custom exercise sourceID to targetID
+// (a system exercise or another of the user's exercises) and deletes the source, in one transaction.
+func (s *ExerciseService) MergeExercises(ctx context.Context, userID, sourceID, targetID string) (*model.UniqueExercise, error) {
+	if sourceID == targetID {
+		return nil, fmt.Errorf("cannot merge an exercise into itself")
+	}
+	if _, err := s.getOwnedExercise(ctx, userID, sourceID); err != nil {
+		return nil, err
+	}
+
+	target, err := s.repo.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil || (target.UserID != nil && *target.UserID != userID) {
+		return nil, fmt.Errorf("target exercise not found")
+	}
+
+	err = s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.workoutRepo.ReplaceExercise(ctx, userID, sourceID, targetID); err != nil {
+			return err
+		}
+		if _, err := s.goalRepo.ReplaceExercise(ctx, userID, sourceID, targetID); err != nil {
+			return err
+		}
+		return s.repo.Delete(ctx, userID, sourceID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge exercises: %w", err)
+	}
+
+	return target, nil
+}
+
+// getOwnedExercise loads a custom exercise, checking it belongs to the user.
+func (s *ExerciseService) getOwnedExercise(ctx context.Context, userID, id string) (*model.UniqueExercise, error) {
+	exercise, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if exercise == nil {
+		return nil, fmt.Errorf("exercise not found")
+	}
+	if exercise.UserID == nil {
+		return nil, fmt.Errorf("system exercises cannot be modified")
+	}
+	if *exercise.UserID != userID {
+		return nil, fmt.Errorf("exercise not found")
+	}
 	return exercise, nil
 }
 
-func (s *ExerciseService) SearchExercises(ctx context.Context, userID *string, query string, limit int, offset int) ([]*model.UniqueExercise, error) {
-	return s.repo.Search(ctx, userID, query, limit, offset)
+// SearchExercises returns the exercises visible to the user. Without a query results are
+// listed by name; with one they are ranked by match quality and the user's recent usage.
+// Names are matched and sorted in the first of the locales an exercise is translated into,
+// and the query also matches the English names.
+func (s *ExerciseService) SearchExercises(ctx context.Context, userID *string, query string, filter model.ExerciseFilter, locales []string, limit int, offset int) ([]*model.UniqueExercise, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		if !s.systemCache.Loaded() {
+			return s.repo.Search(ctx, userID, query, filter, limit, offset)
+		}
+		exercises, err := s.visibleExercises(ctx, userID, filter)
+		if err != nil {
+			return nil, err
+		}
+		slices.SortStableFunc(exercises, func(a, b *model.UniqueExercise) int {
+			return strings.Compare(a.LocalizedName(locales), b.LocalizedName(locales))
+		})
+		return paginate(exercises, limit, offset), nil
+	}
+
+	// Typo tolerance can't be expressed as a database query, so rank the (small) visible set here.
+	candidates, err := s.visibleExercises(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var usage map[string]int
+	if userID != nil {
+		usage, err = s.exerciseUsage(ctx, *userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return paginate(rankExercises(candidates, query, usage, locales), limit, offset), nil
+}
+
+// exerciseUsage returns how many of the user's recent workouts include each exercise, reusing
+// counts loaded moments ago by the previous search.
+func (s *ExerciseService) exerciseUsage(ctx context.Context, userID string) (map[string]int, error) {
+	now := s.now()
+	if usage, ok := s.usageCache.get(userID, now); ok {
+		return usage, nil
+	}
+	usage, err := s.workoutRepo.CountExerciseUsage(ctx, userID, now.Add(-exerciseUsageWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exercise usage: %w", err)
+	}
+	s.usageCache.put(userID, usage, now)
+	return usage, nil
 }
 
 func (s *ExerciseService) GetExercise(ctx context.Context, id string) (*model.UniqueExercise, error) {
+	if exercise, ok := s.systemCache.Get(ctx, id); ok {
+		return exercise, nil
+	}
 	return s.repo.FindByID(ctx, id)
 }
+
+// GetExercises looks up several exercises at once. The result is aligned with ids,
+// with nil for exercises that do not exist. System exercises are served from the cache.
+func (s *ExerciseService) GetExercises(ctx context.Context, ids []string) ([]*model.UniqueExercise, error) {
+	exercises := make([]*model.UniqueExercise, len(ids))
+	var missing []string
+	for i, id := range ids {
+		if exercise, ok := s.systemCache.Get(ctx, id); ok {
+			exercises[i] = exercise
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return exercises, nil
+	}
+
+	found, err := s.repo.FindByIDs(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*model.UniqueExercise, len(found))
+	for _, e := range found {
+		byID[e.ID] = e
+	}
+	for i, id := range ids {
+		if exercises[i] == nil {
+			exercises[i] = byID[id]
+		}
+	}
+	return exercises, nil
+}
+
+// visibleExercises returns the non-archived system exercises plus, when logged in, the user's
+// custom exercises that match the filter. System exercises come from the cache when it is loaded.
+func (s *ExerciseService) visibleExercises(ctx context.Context, userID *string, filter model.ExerciseFilter) ([]*model.UniqueExercise, error) {
+	system, ok := s.systemCache.List(ctx, filter)
+	if !ok {
+		return s.repo.ListVisible(ctx, userID, filter)
+	}
+	if userID == nil {
+		return system, nil
+	}
+
+	custom, err := s.repo.ListCustom(ctx, *userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	return append(system, custom...), nil
+}
+
+// applyExerciseInput validates the input and copies the provided fields onto the exercise.
+func applyExerciseInput(exercise *model.UniqueExercise, input model.ExerciseInput) error {
+	if err := validateExerciseTaxonomy(input); err != nil {
+		return err
+	}
+
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" {
+			return fmt.Errorf("exercise name cannot be empty")
+		}
+		exercise.Name = name
+	}
+	if input.Description != nil {
+		exercise.Description = input.Description
+	}
+	if input.Aliases != nil {
+		exercise.Aliases = cleanAliases(input.Aliases)
+	}
+	if input.Category != nil {
+		exercise.Category = input.Category
+	}
+	if input.PrimaryMuscles != nil {
+		exercise.PrimaryMuscles = dedupeMuscles(input.PrimaryMuscles)
+	}
+	if input.SecondaryMuscles != nil {
+		exercise.SecondaryMuscles = dedupeMuscles(input.SecondaryMuscles)
+	}
+	if input.Equipment != nil {
+		exercise.Equipment = input.Equipment
+	}
+	if input.MovementPattern != nil {
+		exercise.MovementPattern = input.MovementPattern
+	}
+	if input.Unilateral != nil {
+		exercise.Unilateral = *input.Unilateral
+	}
+
+	if exercise.Aliases == nil {
+		exercise.Aliases = []string{}
+	}
+	if exercise.PrimaryMuscles == nil {
+		exercise.PrimaryMuscles = []model.MuscleGroup{}
+	}
+	if exercise.SecondaryMuscles == nil {
+		exercise.SecondaryMuscles = []model.MuscleGroup{}
+	}
+	return nil
+}
+
+// validateExerciseTaxonomy checks that every provided taxonomy value is known.
+func validateExerciseTaxonomy(input model.ExerciseInput) error {
+	if input.Category != nil && !input.Category.IsValid() {
+		return fmt.Errorf("invalid exercise category: %s", *input.Category)
+	}
+	for _, m := range append(append([]model.MuscleGroup{}, input.PrimaryMuscles...), input.SecondaryMuscles...) {
+		if !m.IsValid() {
+			return fmt.Errorf("invalid muscle group: %s", m)
+		}
+	}
+	if input.Equipment != nil && !input.Equipment.IsValid() {
+		return fmt.Errorf("invalid equipment: %s", *input.Equipment)
+	}
+	if input.MovementPattern != nil && !input.MovementPattern.IsValid() {
+		return fmt.Errorf("invalid movement pattern: %s", *input.MovementPattern)
+	}
+	return nil
+}
+
+// dedupeMuscles drops repeated muscle groups, keeping the given order.
+func dedupeMuscles(muscles []model.MuscleGroup) []model.MuscleGroup {
+	seen := make(map[model.MuscleGroup]bool, len(muscles))
+	result := make([]model.MuscleGroup, 0, len(muscles))
+	for _, m := range muscles {
+		if !seen[m] {
+			seen[m] = true
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// cleanAliases trims aliases and drops empty or repeated (case-insensitive) ones.
+func cleanAliases(aliases []string) []string {
+	seen := make(map[string]bool, len(aliases))
+	result := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		alias = strings.TrimSpace(alias)
+		key := strings.ToLower(alias)
+		if alias == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, alias)
+	}
+	return result
+}
+
+// paginate applies limit/offset to an in-memory result set, using the repository defaults.
+func paginate[T any](items []T, limit, offset int) []T {
+	if limit <= 0 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(items) {
+		return []T{}
+	}
+	end := min(offset+limit, len(items))
+	return items[offset:end]
+}
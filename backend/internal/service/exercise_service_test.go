@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/riverajo/fitness-app/backend/internal/model"
 	"github.com/riverajo/fitness-app/backend/internal/repository"
@@ -13,9 +14,15 @@ import (
 
 func TestCreateExercise(t *testing.T) {
 	mockRepo := new(repository.MockExerciseRepository)
-	service := NewExerciseService(mockRepo)
+	service := NewExerciseService(mockRepo, new(repository.MockWorkoutRepository), new(repository.MockGoalRepository), &repository.MockTransactor{})
 	ctx := context.Background()
 
+	// No duplicates or similar system exercises unless a case sets them up
+	mockRepo.On("FindByName", ctx, mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+	mockRepo.On("ListVisible", ctx, (*string)(nil), model.ExerciseFilter{}).Return([]*model.UniqueExercise{
+		{ID: "sys-bench", Name: "Bench Press", Aliases: []string{"Bench"}},
+	}, nil).Maybe()
+
 	t.Run("success", func(t *testing.T) {
 		name := "Push Up"
 		desc := "Standard push up"
@@ -25,7 +32,7 @@ func TestCreateExercise(t *testing.T) {
 			return e.Name == name && *e.UserID == userID && *e.Description == desc
 		})).Return(nil).Once()
 
-		result, err := service.CreateExercise(ctx, name, &desc, &userID)
+		result, err := service.CreateExercise(ctx, model.ExerciseInput{Name: &name, Description: &desc}, &userID, false)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
@@ -37,7 +44,7 @@ func TestCreateExercise(t *testing.T) {
 		name := "   "
 		userID := "user-123"
 
-		result, err := service.CreateExercise(ctx, name, nil, &userID)
+		result, err := service.CreateExercise(ctx, model.ExerciseInput{Name: &name}, &userID, false)
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
@@ -45,46 +52,306 @@ func TestCreateExercise(t *testing.T) {
 		mockRepo.AssertNotCalled(t, "Create")
 	})
 
+	t.Run("taxonomy", func(t *testing.T) {
+		userID := "user-123"
+		category := model.ExerciseCategoryStrength
+		equipment := model.EquipmentDumbbell
+		pattern := model.MovementPatternLunge
+		unilateral := true
+
+		mockRepo.On("Create", ctx, mock.MatchedBy(func(e *model.UniqueExercise) bool {
+			return e.Name == "Bulgarian Split Squat" && e.Unilateral && *e.Equipment == equipment
+		})).Return(nil).Once()
+
+		result, err := service.CreateExercise(ctx, model.ExerciseInput{
+			Name:             stringPtr("Bulgarian Split Squat"),
+			Category:         &category,
+			PrimaryMuscles:   []model.MuscleGroup{model.MuscleGroupQuadriceps, model.MuscleGroupGlutes, model.MuscleGroupQuadriceps},
+			SecondaryMuscles: []model.MuscleGroup{model.MuscleGroupAdductors},
+			Equipment:        &equipment,
+			MovementPattern:  &pattern,
+			Unilateral:       &unilateral,
+		}, &userID, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []model.MuscleGroup{model.MuscleGroupQuadriceps, model.MuscleGroupGlutes}, result.PrimaryMuscles)
+		assert.Equal(t, category, *result.Category)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("invalid muscle group", func(t *testing.T) {
+		userID := "user-123"
+
+		result, err := service.CreateExercise(ctx, model.ExerciseInput{
+			Name:           stringPtr("Mystery Move"),
+			PrimaryMuscles: []model.MuscleGroup{"SPLEEN"},
+		}, &userID, false)
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "invalid muscle group")
+	})
+
 	t.Run("repo error", func(t *testing.T) {
 		name := "Pull Up"
 		userID := "user-123"
 
 		mockRepo.On("Create", ctx, mock.AnythingOfType("*model.UniqueExercise")).Return(errors.New("db error")).Once()
 
-		result, err := service.CreateExercise(ctx, name, nil, &userID)
+		result, err := service.CreateExercise(ctx, model.ExerciseInput{Name: &name}, &userID, false)
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
 		assert.Equal(t, "db error", err.Error())
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("close to system exercise", func(t *testing.T) {
+		userID := "user-123"
+
+		result, err := service.CreateExercise(ctx, model.ExerciseInput{Name: stringPtr("bench  presss")}, &userID, false)
+
+		assert.Nil(t, result)
+		var similar *SimilarExerciseError
+		assert.ErrorAs(t, err, &similar)
+		assert.False(t, similar.Duplicate)
+		assert.Equal(t, "sys-bench", similar.ExistingID)
+	})
+
+	t.Run("close to system exercise allowed", func(t *testing.T) {
+		userID := "user-123"
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*model.UniqueExercise")).Return(nil).Once()
+
+		result, err := service.CreateExercise(ctx, model.ExerciseInput{Name: stringPtr("Bench Presss")}, &userID, true)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Bench Presss", result.Name)
+	})
+}
+
+func TestCreateExerciseDuplicate(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+	existing := &model.UniqueExercise{ID: "ex-1", Name: "Pause Squat", UserID: &userID}
+
+	t.Run("existing custom exercise", func(t *testing.T) {
+		mockRepo := new(repository.MockExerciseRepository)
+		service := NewExerciseService(mockRepo, new(repository.MockWorkoutRepository), new(repository.MockGoalRepository), &repository.MockTransactor{})
+		mockRepo.On("FindByName", ctx, &userID, "pause SQUAT").Return(existing, nil).Once()
+
+		_, err := service.CreateExercise(ctx, model.ExerciseInput{Name: stringPtr(" pause SQUAT ")}, &userID, true)
+
+		var similar *SimilarExerciseError
+		assert.ErrorAs(t, err, &similar)
+		assert.True(t, similar.Duplicate)
+		assert.Equal(t, "ex-1", similar.ExistingID)
+		mockRepo.AssertNotCalled(t, "Create")
+	})
+
+	t.Run("concurrent create hits unique index", func(t *testing.T) {
+		mockRepo := new(repository.MockExerciseRepository)
+		service := NewExerciseService(mockRepo, new(repository.MockWorkoutRepository), new(repository.MockGoalRepository), &repository.MockTransactor{})
+		mockRepo.On("FindByName", ctx, &userID, "Pause Squat").Return(nil, nil).Once()
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*model.UniqueExercise")).Return(repository.ErrDuplicateExercise).Once()
+		mockRepo.On("FindByName", ctx, &userID, "Pause Squat").Return(existing, nil).Once()
+
+		_, err := service.CreateExercise(ctx, model.ExerciseInput{Name: stringPtr("Pause Squat")}, &userID, true)
+
+		var similar *SimilarExerciseError
+		assert.ErrorAs(t, err, &similar)
+		assert.Equal(t, "ex-1", similar.ExistingID)
+	})
 }
 
 func TestSearchExercises(t *testing.T) {
-	mockRepo := new(repository.MockExerciseRepository)
-	service := NewExerciseService(mockRepo)
 	ctx := context.Background()
+	userID := "user-123"
 
-	t.Run("success", func(t *testing.T) {
-		query := "press"
-		limit := 10
-		offset := 0
-		userID := "user-123"
+	t.Run("no query lists from repository", func(t *testing.T) {
+		mockRepo := new(repository.MockExerciseRepository)
+		service := NewExerciseService(mockRepo, new(repository.MockWorkoutRepository), new(repository.MockGoalRepository), &repository.MockTransactor{})
 		expected := []*model.UniqueExercise{{Name: "Bench Press"}}
 
-		mockRepo.On("Search", ctx, &userID, query, limit, offset).Return(expected, nil).Once()
+		mockRepo.On("Search", ctx, &userID, "", model.ExerciseFilter{}, 10, 0).Return(expected, nil).Once()
 
-		result, err := service.SearchExercises(ctx, &userID, query, limit, offset)
+		result, err := service.SearchExercises(ctx, &userID, "  ", model.ExerciseFilter{}, nil, 10, 0)
 
 		assert.NoError(t, err)
 		assert.Equal(t, expected, result)
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("query is ranked", func(t *testing.T) {
+		mockRepo := new(repository.MockExerciseRepository)
+		workoutRepo := new(repository.MockWorkoutRepository)
+		service := NewExerciseService(mockRepo, workoutRepo, new(repository.MockGoalRepository), &repository.MockTransactor{})
+
+		candidates := []*model.UniqueExercise{
+			{ID: "incline", Name: "Incline Bench Press"},
+			{ID: "squat", Name: "Squat"},
+			{ID: "bench", Name: "Bench Press"},
+			{ID: "decline", Name: "Decline Bench Press"},
+		}
+		mockRepo.On("ListVisible", ctx, &userID, model.ExerciseFilter{}).Return(candidates, nil).Once()
+		workoutRepo.On("CountExerciseUsage", ctx, userID, mock.Anything).Return(map[string]int{"decline": 3}, nil).Once()
+
+		result, err := service.SearchExercises(ctx, &userID, "bench", model.ExerciseFilter{}, nil, 10, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Bench Press", "Decline Bench Press", "Incline Bench Press"}, exerciseNames(result))
+		mockRepo.AssertExpectations(t)
+		workoutRepo.AssertExpectations(t)
+	})
+
+	t.Run("usage is counted over recent workouts and reused while typing", func(t *testing.T) {
+		mockRepo := new(repository.MockExerciseRepository)
+		workoutRepo := new(repository.MockWorkoutRepository)
+		service := NewExerciseService(mockRepo, workoutRepo, new(repository.MockGoalRepository), &repository.MockTransactor{})
+		now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+		service.now = func() time.Time { return now }
+
+		mockRepo.On("ListVisible", ctx, &userID, model.ExerciseFilter{}).Return([]*model.UniqueExercise{{ID: "squat", Name: "Squat"}}, nil)
+		workoutRepo.On("CountExerciseUsage", ctx, userID, now.Add(-exerciseUsageWindow)).Return(map[string]int{"squat": 1}, nil).Once()
+
+		for _, query := range []string{"s", "sq", "squ"} {
+			_, err := service.SearchExercises(ctx, &userID, query, model.ExerciseFilter{}, nil, 10, 0)
+			assert.NoError(t, err)
+		}
+		workoutRepo.AssertNumberOfCalls(t, "CountExerciseUsage", 1)
+
+		// Counts are reloaded once they expire
+		now = now.Add(exerciseUsageTTL)
+		workoutRepo.On("CountExerciseUsage", ctx, userID, now.Add(-exerciseUsageWindow)).Return(map[string]int{"squat": 2}, nil).Once()
+		_, err := service.SearchExercises(ctx, &userID, "squat", model.ExerciseFilter{}, nil, 10, 0)
+		assert.NoError(t, err)
+		workoutRepo.AssertExpectations(t)
+	})
+
+	t.Run("anonymous search skips usage and paginates", func(t *testing.T) {
+		mockRepo := new(repository.MockExerciseRepository)
+		workoutRepo := new(repository.MockWorkoutRepository)
+		service := NewExerciseService(mockRepo, workoutRepo, new(repository.MockGoalRepository), &repository.MockTransactor{})
+
+		candidates := []*model.UniqueExercise{{Name: "Leg Press"}, {Name: "Leg Curl"}, {Name: "Leg Extension"}}
+		mockRepo.On("ListVisible", ctx, (*string)(nil), model.ExerciseFilter{}).Return(candidates, nil).Once()
+
+		result, err := service.SearchExercises(ctx, nil, "leg", model.ExerciseFilter{}, nil, 2, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Leg Extension", "Leg Press"}, exerciseNames(result))
+		workoutRepo.AssertNotCalled(t, "CountExerciseUsage")
+	})
+}
+
+func exerciseNames(exercises []*model.UniqueExercise) []string {
+	names := make([]string, len(exercises))
+	for i, e := range exercises {
+		names[i] = e.Name
+	}
+	return names
+}
+
+func TestRankExercises(t *testing.T) {
+	exercises := []*model.UniqueExercise{
+		{ID: "ohp", Name: "Overhead Press", Aliases: []string{"OHP", "Military Press"}},
+		{ID: "rdl", Name: "Romanian Deadlift", Aliases: []string{"RDL"}},
+		{ID: "deadlift", Name: "Deadlift"},
+		{ID: "row", Name: "Dumbbell Row", Aliases: []string{"DB Row"}},
+		{ID: "pullup", Name: "Pull Up"},
+		{ID: "pulldown", Name: "Lat Pulldown"},
+		{ID: "curl", Name: "Bicep Curl"},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		usage    map[string]int
+		expected []string
+	}{
+		{"alias abbreviation", "ohp", nil, []string{"Overhead Press"}},
+		{"alias for romanian deadlift", "RDL", nil, []string{"Romanian Deadlift"}},
+		{"prefix before word match", "dead", nil, []string{"Deadlift", "Romanian Deadlift"}},
+		{"abbreviated equipment", "db row", nil, []string{"Dumbbell Row"}},
+		{"special characters are literal", "Row (DB)", nil, []string{"Dumbbell Row"}},
+		{"spacing ignored", "pullup", nil, []string{"Pull Up"}},
+		{"typo tolerated", "bicpe curl", nil, []string{"Bicep Curl"}},
+		{"usage breaks ties", "pull", map[string]int{"pulldown": 5}, []string{"Pull Up", "Lat Pulldown"}},
+		{"no match", "zercher", nil, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, exerciseNames(rankExercises(exercises, tt.query, tt.usage, nil)))
+		})
+	}
+}
+
+func TestRankExercisesLocalized(t *testing.T) {
+	exercises := []*model.UniqueExercise{
+		{ID: "squat", Name: "Squat", Translations: map[string]model.ExerciseTranslation{
+			"es": {Name: "Sentadilla", Aliases: []string{"Sentadilla con barra"}},
+			"de": {Name: "Kniebeuge"},
+		}},
+		{ID: "bench", Name: "Bench Press", Translations: map[string]model.ExerciseTranslation{
+			"es": {Name: "Press de banca"},
+		}},
+		{ID: "leg-press", Name: "Leg Press", Translations: map[string]model.ExerciseTranslation{
+			"es": {Name: "Prensa de piernas"},
+		}},
+		{ID: "custom", Name: "Press Sentado"},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		locales  []string
+		expected []string
+	}{
+		{"translated name", "sentadilla", []string{"es"}, []string{"Squat"}},
+		{"translated alias", "con barra", []string{"es"}, []string{"Squat"}},
+		{"regional locale falls back to language", "sentadila", []string{"es-MX"}, []string{"Squat"}},
+		{"english still matches", "squat", []string{"es"}, []string{"Squat"}},
+		{"other languages do not match", "kniebeuge", []string{"es"}, []string{}},
+		{"first translated locale wins", "kniebeuge", []string{"fr", "de", "es"}, []string{"Squat"}},
+		{"english preference skips translations", "sentadilla", []string{"en", "es"}, []string{}},
+		// Ties are broken by the localized name: "Prensa de piernas" < "Press de banca" < "Press Sentado"
+		{"sorted by localized name", "pre", []string{"es"}, []string{"Leg Press", "Bench Press", "Press Sentado"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, exerciseNames(rankExercises(exercises, tt.query, nil, tt.locales)))
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("squat", "squat"))
+	assert.Equal(t, 1, editDistance("sqat", "squat"))
+	assert.Equal(t, 1, editDistance("bicpe", "bicep"))
+	assert.Equal(t, 2, editDistance("bnehc", "bench"))
+	assert.Equal(t, 5, editDistance("", "press"))
+}
+
+func TestClosestExercise(t *testing.T) {
+	exercises := []*model.UniqueExercise{
+		{ID: "squat", Name: "Squat"},
+		{ID: "pullup", Name: "Pull Up"},
+		{ID: "dips", Name: "Tricep Dips", Aliases: []string{"Dip"}},
+		{ID: "row", Name: "Dumbbell Row"},
+	}
+
+	assert.Equal(t, "squat", closestExercise("Squats", exercises).ID)
+	assert.Equal(t, "pullup", closestExercise("pullup", exercises).ID)
+	assert.Equal(t, "dips", closestExercise("dip", exercises).ID)
+	assert.Equal(t, "row", closestExercise("DB Row", exercises).ID)
+	assert.Nil(t, closestExercise("Push Up", exercises))
+	assert.Nil(t, closestExercise("Box Squat", exercises))
 }
 
 func TestGetExercise(t *testing.T) {
 	mockRepo := new(repository.MockExerciseRepository)
-	service := NewExerciseService(mockRepo)
+	service := NewExerciseService(mockRepo, new(repository.MockWorkoutRepository), new(repository.MockGoalRepository), &repository.MockTransactor{})
 	ctx := context.Background()
 
 	t.Run("found", func(t *testing.T) {
@@ -112,3 +379,195 @@ func TestGetExercise(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestUpdateExercise(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+
+	t.Run("applies provided fields only", func(t *testing.T) {
+		mockRepo := new(repository.MockExerciseRepository)
+		service := NewExerciseService(mockRepo, new(repository.MockWorkoutRepository), new(repository.MockGoalRepository), &repository.MockTransactor{})
+		desc := "Old description"
+		existing := &model.UniqueExercise{ID: "ex-1", Name: "Bulgarain Split Squat", UserID: &userID, Description: &desc}
+
+		mockRepo.On("FindByID", ctx, "ex-1").Return(existing, nil).Once()
+		mockRepo.On("FindByName", ctx, &userID, "Bulgarian Split Squat").Return(existing, nil).Once() // itself
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(e *model.UniqueExercise) bool {
+			return e.Name == "Bulgarian Split Squat" && *e.Description == desc
+		})).Return(nil).Once()
+
+		result, err := service.UpdateExercise(ctx, userID, "ex-1", model.ExerciseInput{Name: stringPtr(" Bulgarian Split Squat ")})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Bulgarian Split Squat", result.Name)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("rename onto another exercise", func(t *testing.T) {
+		mockRepo := new(repository.MockExerciseRepository)
+		service := NewExerciseService(mockRepo, new(repository.MockWorkoutRepository), new(repository.MockGoalRepository), &repository.MockTransactor{})
+		mockRepo.On("FindByID", ctx, "ex-1").Return(&model.UniqueExercise{ID: "ex-1", Name: "Squat A", UserID: &userID}, nil).Once()
+		mockRepo.On("FindByName", ctx, &userID, "Squat B").Return(&model.UniqueExercise{ID: "ex-2", Name: "squat b", UserID: &userID}, nil).Once()
+
+		_, err := service.UpdateExercise(ctx, userID, "ex-1", model.ExerciseInput{Name: stringPtr("Squat B")})
+
+		var similar *SimilarExerciseError
+		assert.ErrorAs(t, err, &similar)
+		assert.Equal(t, "ex-2", similar.ExistingID)
+		mockRepo.AssertNotCalled(t, "Update")
+	})
+
+	t.Run("system exercise refused", func(t *testing.T) {
+		mockRepo := new(repository.MockExerciseRepository)
+		service := NewExerciseService(mockRepo, new(repository.MockWorkoutRepository), new(repository.MockGoalRepository), &repository.MockTransactor{})
+		mockRepo.On("FindByID", ctx, "sys-1").Return(&model.UniqueExercise{ID: "sys-1", Name: "Squat"}, nil).Once()
+
+		_, err := service.UpdateExercise(ctx, userID, "sys-1", model.ExerciseInput{Name: stringPtr("Squats")})
+
+		assert.EqualError(t, err, "system exercises cannot be modified")
+		mockRepo.AssertNotCalled(t, "Update")
+	})
+
+	t.Run("other user's exercise hidden", func(t *testing.T) {
+		mockRepo := new(repository.MockExerciseRepository)
+		service := NewExerciseService(mockRepo, new(repository.MockWorkoutRepository), new(repository.MockGoalRepository), &repository.MockTransactor{})
+		mockRepo.On("FindByID", ctx, "ex-2").Return(&model.UniqueExercise{ID: "ex-2", UserID: stringPtr("someone-else")}, nil).Once()
+
+		_, err := service.UpdateExercise(ctx, userID, "ex-2", model.ExerciseInput{})
+
+		assert.EqualError(t, err, "exercise not found")
+	})
+}
+
+func TestArchiveExercise(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+	mockRepo := new(repository.MockExerciseRepository)
+	service := NewExerciseService(mockRepo, new(repository.MockWorkoutRepository), new(repository.MockGoalRepository), &repository.MockTransactor{})
+
+	mockRepo.On("FindByID", ctx, "ex-1").Return(&model.UniqueExercise{ID: "ex-1", UserID: &userID}, nil)
+	mockRepo.On("SetArchived", ctx, userID, "ex-1", mock.AnythingOfType("*time.Time")).Return(nil).Once()
+
+	result, err := service.ArchiveExercise(ctx, userID, "ex-1", true)
+	assert.NoError(t, err)
+	assert.NotNil(t, result.ArchivedAt)
+
+	mockRepo.On("SetArchived", ctx, userID, "ex-1", (*time.Time)(nil)).Return(nil).Once()
+	result, err = service.ArchiveExercise(ctx, userID, "ex-1", false)
+	assert.NoError(t, err)
+	assert.Nil(t, result.ArchivedAt)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDeleteExercise(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+	owned := &model.UniqueExercise{ID: "ex-1", UserID: &userID}
+
+	setup := func(workouts, goals int64) (*ExerciseService, *repository.MockExerciseRepository, *repository.MockWorkoutRepository, *repository.MockGoalRepository) {
+		mockRepo := new(repository.MockExerciseRepository)
+		workoutRepo := new(repository.MockWorkoutRepository)
+		goalRepo := new(repository.MockGoalRepository)
+		mockRepo.On("FindByID", ctx, "ex-1").Return(owned, nil)
+		workoutRepo.On("CountByExercise", ctx, userID, "ex-1").Return(workouts, nil)
+		goalRepo.On("CountByExercise", ctx, userID, "ex-1").Return(goals, nil)
+		return NewExerciseService(mockRepo, workoutRepo, goalRepo, &repository.MockTransactor{}), mockRepo, workoutRepo, goalRepo
+	}
+
+	t.Run("unreferenced", func(t *testing.T) {
+		service, mockRepo, _, _ := setup(0, 0)
+		mockRepo.On("Delete", ctx, userID, "ex-1").Return(nil).Once()
+
+		assert.NoError(t, service.DeleteExercise(ctx, userID, "ex-1", false))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("referenced without force", func(t *testing.T) {
+		service, mockRepo, _, _ := setup(3, 1)
+
+		err := service.DeleteExercise(ctx, userID, "ex-1", false)
+		assert.ErrorContains(t, err, "used in 3 workout(s) and 1 goal(s)")
+		mockRepo.AssertNotCalled(t, "Delete")
+	})
+
+	t.Run("referenced with force", func(t *testing.T) {
+		service, mockRepo, workoutRepo, goalRepo := setup(3, 1)
+		workoutRepo.On("RemoveExercise", ctx, userID, "ex-1").Return(int64(3), nil).Once()
+		goalRepo.On("DeleteByExercise", ctx, userID, "ex-1").Return(int64(1), nil).Once()
+		mockRepo.On("Delete", ctx, userID, "ex-1").Return(nil).Once()
+
+		assert.NoError(t, service.DeleteExercise(ctx, userID, "ex-1", true))
+		mockRepo.AssertExpectations(t)
+		workoutRepo.AssertExpectations(t)
+		goalRepo.AssertExpectations(t)
+	})
+}
+
+func TestMergeExercises(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+
+	t.Run("moves references and deletes source", func(t *testing.T) {
+		mockRepo := new(repository.MockExerciseRepository)
+		workoutRepo := new(repository.MockWorkoutRepository)
+		goalRepo := new(repository.MockGoalRepository)
+		service := NewExerciseService(mockRepo, workoutRepo, goalRepo, &repository.MockTransactor{})
+
+		target := &model.UniqueExercise{ID: "sys-squat", Name: "Squat"}
+		mockRepo.On("FindByID", ctx, "ex-squatt").Return(&model.UniqueExercise{ID: "ex-squatt", UserID: &userID}, nil).Once()
+		mockRepo.On("FindByID", ctx, "sys-squat").Return(target, nil).Once()
+		workoutRepo.On("ReplaceExercise", ctx, userID, "ex-squatt", "sys-squat").Return(int64(4), nil).Once()
+		goalRepo.On("ReplaceExercise", ctx, userID, "ex-squatt", "sys-squat").Return(int64(0), nil).Once()
+		mockRepo.On("Delete", ctx, userID, "ex-squatt").Return(nil).Once()
+
+		result, err := service.MergeExercises(ctx, userID, "ex-squatt", "sys-squat")
+
+		assert.NoError(t, err)
+		assert.Equal(t, target, result)
+		mockRepo.AssertExpectations(t)
+		workoutRepo.AssertExpectations(t)
+		goalRepo.AssertExpectations(t)
+	})
+
+	t.Run("target must be visible to user", func(t *testing.T) {
+		mockRepo := new(repository.MockExerciseRepository)
+		workoutRepo := new(repository.MockWorkoutRepository)
+		service := NewExerciseService(mockRepo, workoutRepo, new(repository.MockGoalRepository), &repository.MockTransactor{})
+
+		mockRepo.On("FindByID", ctx, "ex-1").Return(&model.UniqueExercise{ID: "ex-1", UserID: &userID}, nil).Once()
+		mockRepo.On("FindByID", ctx, "ex-2").Return(&model.UniqueExercise{ID: "ex-2", UserID: stringPtr("someone-else")}, nil).Once()
+
+		_, err := service.MergeExercises(ctx, userID, "ex-1", "ex-2")
+
+		assert.EqualError(t, err, "target exercise not found")
+		workoutRepo.AssertNotCalled(t, "ReplaceExercise")
+	})
+
+	t.Run("same exercise", func(t *testing.T) {
+		service := NewExerciseService(new(repository.MockExerciseRepository), new(repository.MockWorkoutRepository), new(repository.MockGoalRepository), &repository.MockTransactor{})
+		_, err := service.MergeExercises(ctx, userID, "ex-1", "ex-1")
+		assert.EqualError(t, err, "cannot merge an exercise into itself")
+	})
+}
+
+func TestGetExercises(t *testing.T) {
+	mockRepo := new(repository.MockExerciseRepository)
+	service := NewExerciseService(mockRepo, nil, nil, &repository.MockTransactor{})
+	ctx := context.Background()
+
+	ids := []string{"squat", "missing", "bench"}
+	mockRepo.On("FindByIDs", ctx, ids).Return([]*model.UniqueExercise{{ID: "bench"}, {ID: "squat"}}, nil).Once()
+
+	exercises, err := service.GetExercises(ctx, ids)
+
+	assert.NoError(t, err)
+	assert.Len(t, exercises, 3)
+	assert.Equal(t, "squat", exercises[0].ID)
+	assert.Nil(t, exercises[1])
+	assert.Equal(t, "bench", exercises[2].ID)
+	mockRepo.AssertExpectations(t)
+}
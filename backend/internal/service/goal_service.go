@@ -0,0 +1,387 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+const weekDuration = 7 * 24 * time.Hour
+
+// GoalService manages user goals and evaluates their progress from workout logs and body metrics.
+type GoalService struct {
+	repo           repository.GoalRepository
+	workoutRepo    repository.WorkoutRepository
+	bodyMetricRepo repository.BodyMetricRepository
+	exerciseRepo   repository.ExerciseRepository
+}
+
+// NewGoalService creates a new instance of the GoalService.
+func NewGoalService(repo repository.GoalRepository, workoutRepo repository.WorkoutRepository, bodyMetricRepo repository.BodyMetricRepository, exerciseRepo repository.ExerciseRepository) *GoalService {
+	return &GoalService{
+		repo:           repo,
+		workoutRepo:    workoutRepo,
+		bodyMetricRepo: bodyMetricRepo,
+		exerciseRepo:   exerciseRepo,
+	}
+}
+
+// CreateGoal validates the input, captures the starting baseline and saves the goal.
+func (s *GoalService) CreateGoal(ctx context.Context, userID string, input model.GoalInput) (*model.Goal, error) {
+	if !input.Type.IsValid() {
+		return nil, fmt.Errorf("invalid goal type: %s", input.Type)
+	}
+	if input.Name == nil || strings.TrimSpace(*input.Name) == "" {
+		return nil, fmt.Errorf("goal name cannot be empty")
+	}
+	if input.TargetValue == nil {
+		return nil, fmt.Errorf("goal target value is required")
+	}
+
+	now := time.Now()
+	goal := &model.Goal{
+		UserID:           userID,
+		Type:             input.Type,
+		UniqueExerciseID: input.UniqueExerciseID,
+		StartDate:        now,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if input.StartDate != nil {
+		goal.StartDate = *input.StartDate
+	}
+	if input.Deadline != nil && !input.Deadline.After(goal.StartDate) {
+		return nil, fmt.Errorf("goal deadline must be after its start date")
+	}
+
+	if goal.Type == model.GoalTypeLift {
+		if goal.UniqueExerciseID == nil || *goal.UniqueExerciseID == "" {
+			return nil, fmt.Errorf("lift goals require an exercise")
+		}
+		if input.TargetReps == nil {
+			one := int32(1)
+			input.TargetReps = &one
+		}
+	}
+	// Frequency counts every workout, whatever exercises it includes
+	if goal.Type == model.GoalTypeFrequency && goal.UniqueExerciseID != nil {
+		return nil, fmt.Errorf("frequency goals cannot target an exercise")
+	}
+	if goal.UniqueExerciseID != nil {
+		if err := s.checkExercise(ctx, userID, *goal.UniqueExerciseID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyGoalInput(goal, input); err != nil {
+		return nil, err
+	}
+
+	baseline, err := s.startingValue(ctx, goal)
+	if err != nil {
+		return nil, err
+	}
+	goal.StartingValue = baseline
+
+	if err := s.repo.Create(ctx, goal); err != nil {
+		return nil, err
+	}
+	return goal, nil
+}
+
+// UpdateGoal changes the name, target or deadline of a goal owned by the user.
+// Changing the target clears a previously recorded achievement so it is re-evaluated.
+func (s *GoalService) UpdateGoal(ctx context.Context, userID, id string, input model.GoalInput) (*model.Goal, error) {
+	goal, err := s.GetGoal(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.TargetValue != nil || input.TargetReps != nil {
+		goal.AchievedAt = nil
+	}
+	if err := applyGoalInput(goal, input); err != nil {
+		return nil, err
+	}
+
+	goal.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, goal); err != nil {
+		return nil, err
+	}
+	return goal, nil
+}
+
+// DeleteGoal removes a goal owned by the user.
+func (s *GoalService) DeleteGoal(ctx context.Context, userID, id string) error {
+	return s.repo.Delete(ctx, userID, id)
+}
+
+// GetGoal fetches a single goal, verifying that it belongs to the user.
+func (s *GoalService) GetGoal(ctx context.Context, userID, id string) (*model.Goal, error) {
+	goal, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if goal == nil || goal.UserID != userID {
+		return nil, fmt.Errorf("goal not found")
+	}
+	return goal, nil
+}
+
+// ListGoals returns all of the user's goals, newest first.
+func (s *GoalService) ListGoals(ctx context.Context, userID string) ([]*model.Goal, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+// EvaluateGoal computes the goal's current progress and status.
+// The first time a goal is found to be achieved, the achievement date is persisted.
+func (s *GoalService) EvaluateGoal(ctx context.Context, goal *model.Goal) (*model.GoalProgress, error) {
+	now := time.Now()
+
+	current, err := s.currentValue(ctx, goal, now)
+	if err != nil {
+		return nil, err
+	}
+
+	dirty := false
+	// A bodyweight goal created before any weigh-in uses the first one as its baseline.
+	if goal.Type == model.GoalTypeBodyweight && goal.StartingValue == nil && current != nil {
+		goal.StartingValue = current
+		dirty = true
+	}
+
+	progress := computeGoalProgress(goal, current, now)
+	if progress.Status == model.GoalStatusAchieved && goal.AchievedAt == nil {
+		goal.AchievedAt = &now
+		dirty = true
+	}
+
+	if dirty {
+		goal.UpdatedAt = now
+		if err := s.repo.Update(ctx, goal); err != nil {
+			// Progress is still correct for this request; it will be persisted on the next evaluation.
+			slog.Warn("Failed to persist goal evaluation", "goal_id", goal.ID, "error", err)
+		}
+	}
+
+	return progress, nil
+}
+
+// checkExercise verifies the exercise exists and is a system exercise or one of the user's own.
+func (s *GoalService) checkExercise(ctx context.Context, userID, exerciseID string) error {
+	exercise, err := s.exerciseRepo.FindByID(ctx, exerciseID)
+	if err != nil {
+		return err
+	}
+	if exercise == nil || (exercise.UserID != nil && *exercise.UserID != userID) {
+		return fmt.Errorf("exercise not found")
+	}
+	return nil
+}
+
+// startingValue captures the baseline for goals whose progress is measured from a starting point.
+func (s *GoalService) startingValue(ctx context.Context, goal *model.Goal) (*float64, error) {
+	switch goal.Type {
+	case model.GoalTypeLift:
+		return s.workoutRepo.BestLift(ctx, goal.UserID, *goal.UniqueExerciseID, targetReps(goal), goal.StartDate)
+	case model.GoalTypeBodyweight:
+		metric, err := s.bodyMetricRepo.FindLatestWeightBefore(ctx, goal.UserID, goal.StartDate)
+		if err != nil || metric == nil {
+			return nil, err
+		}
+		return metric.Weight, nil
+	}
+	return nil, nil
+}
+
+// currentValue measures the quantity the goal targets, or nil if there is no data yet. Only data up
+// to the deadline counts, so a goal missed is not achieved by later workouts or weigh-ins.
+func (s *GoalService) currentValue(ctx context.Context, goal *model.Goal, now time.Time) (*float64, error) {
+	end := now
+	if goal.Deadline != nil && goal.Deadline.Before(now) {
+		end = *goal.Deadline
+	}
+
+	switch goal.Type {
+	case model.GoalTypeLift:
+		if goal.UniqueExerciseID == nil {
+			return nil, nil
+		}
+		return s.workoutRepo.BestLift(ctx, goal.UserID, *goal.UniqueExerciseID, targetReps(goal), end)
+
+	case model.GoalTypeBodyweight:
+		metric, err := s.bodyMetricRepo.FindLatestWeightBefore(ctx, goal.UserID, end)
+		if err != nil || metric == nil {
+			return nil, err
+		}
+		return metric.Weight, nil
+
+	case model.GoalTypeVolume:
+		logs, err := s.workoutRepo.ListByUserInRange(ctx, goal.UserID, goal.StartDate, end)
+		if err != nil {
+			return nil, err
+		}
+		total := 0.0
+		for _, log := range logs {
+			for _, el := range log.ExerciseLogs {
+				if goal.UniqueExerciseID == nil || el.UniqueExerciseID == *goal.UniqueExerciseID {
+					total += el.Volume()
+				}
+			}
+		}
+		return &total, nil
+
+	case model.GoalTypeFrequency:
+		sessions, err := s.workoutRepo.CountByUserInRange(ctx, goal.UserID, goal.StartDate, end)
+		if err != nil {
+			return nil, err
+		}
+		weeks := end.Sub(goal.StartDate).Hours() / weekDuration.Hours()
+		if weeks < 1 {
+			weeks = 1
+		}
+		perWeek := float64(sessions) / weeks
+		return &perWeek, nil
+	}
+
+	return nil, fmt.Errorf("invalid goal type: %s", goal.Type)
+}
+
+// computeGoalProgress derives the status and completion of a goal from its current value.
+func computeGoalProgress(goal *model.Goal, current *float64, now time.Time) *model.GoalProgress {
+	progress := &model.GoalProgress{
+		GoalType:      goal.Type,
+		CurrentValue:  current,
+		StartingValue: goal.StartingValue,
+		TargetValue:   goal.TargetValue,
+	}
+
+	achieved := false
+	if current != nil {
+		start := goal.StartingValue
+		switch goal.Type {
+		case model.GoalTypeLift:
+			achieved = *current >= goal.TargetValue
+			if start != nil && *start < goal.TargetValue {
+				progress.PercentComplete = (*current - *start) / (goal.TargetValue - *start)
+			} else {
+				progress.PercentComplete = *current / goal.TargetValue
+			}
+		case model.GoalTypeBodyweight:
+			if start != nil && *start > goal.TargetValue {
+				achieved = *current <= goal.TargetValue
+			} else {
+				achieved = *current >= goal.TargetValue
+			}
+			if start != nil && *start != goal.TargetValue {
+				progress.PercentComplete = (*start - *current) / (*start - goal.TargetValue)
+			}
+		case model.GoalTypeVolume:
+			achieved = *current >= goal.TargetValue
+			progress.PercentComplete = *current / goal.TargetValue
+		case model.GoalTypeFrequency:
+			// A frequency target can only be judged complete once its period has ended.
+			achieved = goal.Deadline != nil && !now.Before(*goal.Deadline) && *current >= goal.TargetValue
+			progress.PercentComplete = *current / goal.TargetValue
+		}
+		progress.PercentComplete = clamp01(progress.PercentComplete)
+	}
+
+	if goal.AchievedAt != nil || achieved {
+		progress.Status = model.GoalStatusAchieved
+		progress.PercentComplete = 1
+		return progress
+	}
+
+	if goal.Deadline != nil {
+		expected := 1.0
+		if total := goal.Deadline.Sub(goal.StartDate); total > 0 {
+			expected = clamp01(float64(now.Sub(goal.StartDate)) / float64(total))
+		}
+		progress.ExpectedPercent = &expected
+	}
+
+	switch {
+	case goal.Type == model.GoalTypeFrequency:
+		if current != nil && *current >= goal.TargetValue {
+			progress.Status = model.GoalStatusOnTrack
+		} else {
+			progress.Status = model.GoalStatusBehind
+		}
+	case goal.Deadline != nil && !now.Before(*goal.Deadline):
+		progress.Status = model.GoalStatusBehind
+	case progress.ExpectedPercent != nil && progress.PercentComplete < *progress.ExpectedPercent:
+		progress.Status = model.GoalStatusBehind
+	default:
+		progress.Status = model.GoalStatusOnTrack
+	}
+
+	return progress
+}
+
+// applyGoalInput validates the mutable goal fields and copies them onto the goal in storage units.
+func applyGoalInput(goal *model.Goal, input model.GoalInput) error {
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" {
+			return fmt.Errorf("goal name cannot be empty")
+		}
+		goal.Name = name
+	}
+
+	if input.TargetValue != nil {
+		if *input.TargetValue <= 0 {
+			return fmt.Errorf("goal target must be greater than zero")
+		}
+		target := *input.TargetValue
+		if goal.Type.IsWeightBased() {
+			unit := input.Unit
+			if unit == "" {
+				unit = model.WeightUnitKilograms
+			}
+			target = unit.ToKilograms(target)
+		}
+		goal.TargetValue = target
+	}
+
+	if input.TargetReps != nil {
+		if goal.Type != model.GoalTypeLift {
+			return fmt.Errorf("target reps only apply to lift goals")
+		}
+		if *input.TargetReps < 1 {
+			return fmt.Errorf("target reps must be at least 1")
+		}
+		goal.TargetReps = input.TargetReps
+	}
+
+	if input.Deadline != nil {
+		if !input.Deadline.After(goal.StartDate) {
+			return fmt.Errorf("goal deadline must be after its start date")
+		}
+		goal.Deadline = input.Deadline
+	}
+
+	return nil
+}
+
+func targetReps(goal *model.Goal) int32 {
+	if goal.TargetReps == nil {
+		return 1
+	}
+	return *goal.TargetReps
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func workoutWithSets(start time.Time, exerciseID string, sets ...*model.Set) *model.WorkoutLog {
+	return &model.WorkoutLog{
+		StartTime: start,
+		ExerciseLogs: []*model.ExerciseLog{
+			{UniqueExerciseID: exerciseID, Sets: sets},
+		},
+	}
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestCreateGoal(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+	squat := "ex-squat"
+	name := "Squat 140 kg by June"
+
+	t.Run("lift goal captures baseline and converts units", func(t *testing.T) {
+		goalRepo := new(repository.MockGoalRepository)
+		workoutRepo := new(repository.MockWorkoutRepository)
+		exerciseRepo := new(repository.MockExerciseRepository)
+		service := NewGoalService(goalRepo, workoutRepo, new(repository.MockBodyMetricRepository), exerciseRepo)
+
+		exerciseRepo.On("FindByID", ctx, squat).Return(&model.UniqueExercise{ID: squat}, nil).Once()
+		workoutRepo.On("BestLift", ctx, userID, squat, int32(1), mock.Anything).Return(float(120), nil).Once()
+		goalRepo.On("Create", ctx, mock.AnythingOfType("*model.Goal")).Return(nil).Once()
+
+		target := 308.647 // ~140 kg
+		deadline := time.Now().AddDate(0, 6, 0)
+		goal, err := service.CreateGoal(ctx, userID, model.GoalInput{
+			Type:             model.GoalTypeLift,
+			Name:             &name,
+			UniqueExerciseID: &squat,
+			TargetValue:      &target,
+			Unit:             model.WeightUnitPounds,
+			Deadline:         &deadline,
+		})
+
+		require.NoError(t, err)
+		assert.InDelta(t, 140.0, goal.TargetValue, 0.01)
+		assert.Equal(t, int32(1), *goal.TargetReps)
+		assert.Equal(t, 120.0, *goal.StartingValue)
+		goalRepo.AssertExpectations(t)
+		workoutRepo.AssertExpectations(t)
+	})
+
+	t.Run("lift goal requires exercise", func(t *testing.T) {
+		service := NewGoalService(new(repository.MockGoalRepository), nil, nil, nil)
+		target := 140.0
+
+		goal, err := service.CreateGoal(ctx, userID, model.GoalInput{Type: model.GoalTypeLift, Name: &name, TargetValue: &target})
+
+		assert.Error(t, err)
+		assert.Nil(t, goal)
+	})
+
+	t.Run("exercise must be visible to the user", func(t *testing.T) {
+		goalRepo := new(repository.MockGoalRepository)
+		exerciseRepo := new(repository.MockExerciseRepository)
+		service := NewGoalService(goalRepo, nil, nil, exerciseRepo)
+		other := "user-456"
+		exerciseRepo.On("FindByID", ctx, "ex-theirs").Return(&model.UniqueExercise{ID: "ex-theirs", UserID: &other}, nil)
+		exerciseRepo.On("FindByID", ctx, "ex-missing").Return(nil, nil)
+		target := 140.0
+
+		for _, id := range []string{"ex-theirs", "ex-missing"} {
+			goal, err := service.CreateGoal(ctx, userID, model.GoalInput{Type: model.GoalTypeVolume, Name: &name, UniqueExerciseID: &id, TargetValue: &target})
+			assert.EqualError(t, err, "exercise not found")
+			assert.Nil(t, goal)
+		}
+		goalRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("frequency goal keeps sessions unconverted", func(t *testing.T) {
+		goalRepo := new(repository.MockGoalRepository)
+		service := NewGoalService(goalRepo, nil, nil, nil)
+		goalRepo.On("Create", ctx, mock.AnythingOfType("*model.Goal")).Return(nil).Once()
+
+		target := 4.0
+		freqName := "Train 4x/week"
+		goal, err := service.CreateGoal(ctx, userID, model.GoalInput{Type: model.GoalTypeFrequency, Name: &freqName, TargetValue: &target, Unit: model.WeightUnitPounds})
+
+		require.NoError(t, err)
+		assert.Equal(t, 4.0, goal.TargetValue)
+		assert.Nil(t, goal.StartingValue)
+	})
+
+	t.Run("deadline before start", func(t *testing.T) {
+		service := NewGoalService(new(repository.MockGoalRepository), nil, nil, nil)
+		target := 4.0
+		past := time.Now().AddDate(0, 0, -1)
+
+		goal, err := service.CreateGoal(ctx, userID, model.GoalInput{Type: model.GoalTypeFrequency, Name: &name, TargetValue: &target, Deadline: &past})
+
+		assert.Error(t, err)
+		assert.Nil(t, goal)
+	})
+
+	t.Run("deadline on the given start date", func(t *testing.T) {
+		service := NewGoalService(new(repository.MockGoalRepository), nil, nil, nil)
+		target := 140.0
+		start := time.Now().AddDate(0, 1, 0)
+
+		// Rejected before the exercise or baseline are looked up
+		goal, err := service.CreateGoal(ctx, userID, model.GoalInput{Type: model.GoalTypeLift, Name: &name, UniqueExerciseID: &squat, TargetValue: &target, StartDate: &start, Deadline: &start})
+
+		assert.EqualError(t, err, "goal deadline must be after its start date")
+		assert.Nil(t, goal)
+	})
+
+	t.Run("frequency goal rejects an exercise", func(t *testing.T) {
+		service := NewGoalService(new(repository.MockGoalRepository), nil, nil, nil)
+		target := 4.0
+
+		goal, err := service.CreateGoal(ctx, userID, model.GoalInput{Type: model.GoalTypeFrequency, Name: &name, UniqueExerciseID: &squat, TargetValue: &target})
+
+		assert.EqualError(t, err, "frequency goals cannot target an exercise")
+		assert.Nil(t, goal)
+	})
+}
+
+func TestEvaluateGoal(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-123"
+	squat := "ex-squat"
+
+	t.Run("lift achieved is persisted", func(t *testing.T) {
+		goalRepo := new(repository.MockGoalRepository)
+		workoutRepo := new(repository.MockWorkoutRepository)
+		service := NewGoalService(goalRepo, workoutRepo, nil, nil)
+
+		goal := &model.Goal{ID: "g1", UserID: userID, Type: model.GoalTypeLift, UniqueExerciseID: &squat, TargetValue: 140, StartDate: time.Now().AddDate(0, -2, 0)}
+		workoutRepo.On("BestLift", ctx, userID, squat, int32(1), mock.Anything).Return(float(142.5), nil).Once()
+		goalRepo.On("Update", ctx, mock.MatchedBy(func(g *model.Goal) bool { return g.AchievedAt != nil })).Return(nil).Once()
+
+		progress, err := service.EvaluateGoal(ctx, goal)
+
+		require.NoError(t, err)
+		assert.Equal(t, model.GoalStatusAchieved, progress.Status)
+		assert.Equal(t, 1.0, progress.PercentComplete)
+		goalRepo.AssertExpectations(t)
+	})
+
+	t.Run("bodyweight loss behind schedule", func(t *testing.T) {
+		goalRepo := new(repository.MockGoalRepository)
+		bodyMetricRepo := new(repository.MockBodyMetricRepository)
+		service := NewGoalService(goalRepo, nil, bodyMetricRepo, nil)
+
+		start := 90.0
+		deadline := time.Now().AddDate(0, 0, 10)
+		goal := &model.Goal{ID: "g2", UserID: userID, Type: model.GoalTypeBodyweight, TargetValue: 80, StartingValue: &start, StartDate: time.Now().AddDate(0, 0, -90), Deadline: &deadline}
+		current := 88.0
+		bodyMetricRepo.On("FindLatestWeightBefore", ctx, userID, mock.Anything).Return(&model.BodyMetric{Weight: &current}, nil).Once()
+
+		progress, err := service.EvaluateGoal(ctx, goal)
+
+		require.NoError(t, err)
+		assert.Equal(t, model.GoalStatusBehind, progress.Status)
+		assert.InDelta(t, 0.2, progress.PercentComplete, 0.0001)
+		require.NotNil(t, progress.ExpectedPercent)
+		assert.Greater(t, *progress.ExpectedPercent, 0.8)
+		goalRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("past deadline only counts data up to it", func(t *testing.T) {
+		workoutRepo := new(repository.MockWorkoutRepository)
+		bodyMetricRepo := new(repository.MockBodyMetricRepository)
+		service := NewGoalService(new(repository.MockGoalRepository), workoutRepo, bodyMetricRepo, nil)
+
+		deadline := time.Now().AddDate(0, 0, -7)
+		lift := &model.Goal{ID: "g5", UserID: userID, Type: model.GoalTypeLift, UniqueExerciseID: &squat, TargetValue: 140, StartDate: deadline.AddDate(0, -2, 0), Deadline: &deadline}
+		workoutRepo.On("BestLift", ctx, userID, squat, int32(1), deadline).Return(float(130), nil).Once()
+		start := 90.0
+		bodyweight := &model.Goal{ID: "g6", UserID: userID, Type: model.GoalTypeBodyweight, TargetValue: 80, StartingValue: &start, StartDate: deadline.AddDate(0, -2, 0), Deadline: &deadline}
+		bodyMetricRepo.On("FindLatestWeightBefore", ctx, userID, deadline).Return(&model.BodyMetric{Weight: float(85)}, nil).Once()
+
+		for _, goal := range []*model.Goal{lift, bodyweight} {
+			progress, err := service.EvaluateGoal(ctx, goal)
+			require.NoError(t, err)
+			assert.Equal(t, model.GoalStatusBehind, progress.Status)
+		}
+		workoutRepo.AssertExpectations(t)
+		bodyMetricRepo.AssertExpectations(t)
+	})
+
+	t.Run("frequency on track", func(t *testing.T) {
+		workoutRepo := new(repository.MockWorkoutRepository)
+		service := NewGoalService(new(repository.MockGoalRepository), workoutRepo, nil, nil)
+
+		goal := &model.Goal{ID: "g3", UserID: userID, Type: model.GoalTypeFrequency, TargetValue: 3, StartDate: time.Now().AddDate(0, 0, -14)}
+		workoutRepo.On("CountByUserInRange", ctx, userID, goal.StartDate, mock.Anything).Return(int64(7), nil).Once()
+
+		progress, err := service.EvaluateGoal(ctx, goal)
+
+		require.NoError(t, err)
+		assert.Equal(t, model.GoalStatusOnTrack, progress.Status)
+		assert.InDelta(t, 3.5, *progress.CurrentValue, 0.01)
+	})
+
+	t.Run("volume scoped to exercise", func(t *testing.T) {
+		workoutRepo := new(repository.MockWorkoutRepository)
+		service := NewGoalService(new(repository.MockGoalRepository), workoutRepo, nil, nil)
+
+		deadline := time.Now().AddDate(0, 1, 0)
+		goal := &model.Goal{ID: "g4", UserID: userID, Type: model.GoalTypeVolume, UniqueExerciseID: &squat, TargetValue: 10000, StartDate: time.Now().AddDate(0, -1, 0), Deadline: &deadline}
+		log := workoutWithSets(time.Now(), squat, &model.Set{Reps: 10, Weight: 100}, &model.Set{Reps: 10, Weight: 100})
+		log.ExerciseLogs = append(log.ExerciseLogs, &model.ExerciseLog{UniqueExerciseID: "ex-bench", Sets: []*model.Set{{Reps: 10, Weight: 80}}})
+		workoutRepo.On("ListByUserInRange", ctx, userID, goal.StartDate, mock.Anything).Return([]*model.WorkoutLog{log}, nil).Once()
+
+		progress, err := service.EvaluateGoal(ctx, goal)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2000.0, *progress.CurrentValue)
+		assert.InDelta(t, 0.2, progress.PercentComplete, 0.0001)
+		assert.Equal(t, model.GoalStatusBehind, progress.Status)
+	})
+}
+
+func TestComputeGoalProgress(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("past deadline without achievement is behind", func(t *testing.T) {
+		deadline := now.AddDate(0, 0, -1)
+		current := 120.0
+		goal := &model.Goal{Type: model.GoalTypeLift, TargetValue: 140, StartDate: now.AddDate(0, -3, 0), Deadline: &deadline}
+
+		progress := computeGoalProgress(goal, &current, now)
+
+		assert.Equal(t, model.GoalStatusBehind, progress.Status)
+	})
+
+	t.Run("no data and no deadline is on track", func(t *testing.T) {
+		goal := &model.Goal{Type: model.GoalTypeLift, TargetValue: 140, StartDate: now}
+
+		progress := computeGoalProgress(goal, nil, now)
+
+		assert.Equal(t, model.GoalStatusOnTrack, progress.Status)
+		assert.Equal(t, 0.0, progress.PercentComplete)
+	})
+
+	t.Run("bodyweight gain", func(t *testing.T) {
+		start := 70.0
+		current := 75.0
+		goal := &model.Goal{Type: model.GoalTypeBodyweight, TargetValue: 75, StartingValue: &start, StartDate: now}
+
+		progress := computeGoalProgress(goal, &current, now)
+
+		assert.Equal(t, model.GoalStatusAchieved, progress.Status)
+	})
+
+	t.Run("frequency is not achieved before the period ends", func(t *testing.T) {
+		deadline := now.AddDate(0, 1, 0)
+		current := 5.0
+		goal := &model.Goal{Type: model.GoalTypeFrequency, TargetValue: 4, StartDate: now.AddDate(0, -1, 0), Deadline: &deadline}
+
+		progress := computeGoalProgress(goal, &current, now)
+
+		assert.Equal(t, model.GoalStatusOnTrack, progress.Status)
+		assert.Equal(t, 1.0, progress.PercentComplete)
+	})
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/oidc"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+// oidcFlowTTL is how long the user has to sign in at the provider.
+const oidcFlowTTL = 10 * time.Minute
+
+var (
+	// ErrUnknownOIDCProvider is returned for a provider that is not configured.
+	ErrUnknownOIDCProvider = errors.New("unknown sign-in provider")
+	// ErrOIDCSignInFailed is returned when the provider's response cannot be verified, or the
+	// sign-in was started in another browser or too long ago.
+	ErrOIDCSignInFailed = errors.New("signing in with the provider failed; please try again")
+	// ErrOIDCEmailNotVerified is returned when the provider has not verified the user's email,
+	// which is needed to create or link an account.
+	ErrOIDCEmailNotVerified = errors.New("your email address is not verified by the provider")
+	// ErrOIDCAccountNotLinked is returned when an account with the provider's email exists but
+	// has not verified it, so cannot be linked to the provider's account.
+	ErrOIDCAccountNotLinked = errors.New("an account with this email already exists; sign in with your password and verify your email address to link it")
+)
+
+// LoginProvider is an OpenID Connect provider users can sign in with.
+type LoginProvider struct {
+	// ID names the provider in URLs, e.g. "google"
+	ID string
+	// Name is shown to users, e.g. "Google"
+	Name     string
+	Provider *oidc.Provider
+}
+
+// OIDCFlow is a sign-in in progress, kept by the browser while the user is at the provider.
+type OIDCFlow struct {
+	Provider string `json:"provider"`
+	// State ties the callback to this browser, and Nonce the ID token to this sign-in
+	State string `json:"state"`
+	Nonce string `json:"nonce"`
+	// Verifier is the PKCE code verifier
+	Verifier  string `json:"verifier"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+// OIDCService signs users in with OpenID Connect providers. The first sign-in creates an account
+// or, for an account with the same verified email, links the provider's account to it.
+type OIDCService struct {
+	providers  []LoginProvider
+	identities repository.ExternalIdentityRepository
+	users      repository.UserRepository
+	now        func() time.Time
+}
+
+// NewOIDCService creates an OIDCService for the providers, listed in the order given.
+func NewOIDCService(providers []LoginProvider, identities repository.ExternalIdentityRepository, users repository.UserRepository) *OIDCService {
+	return &OIDCService{
+		providers:  providers,
+		identities: identities,
+		users:      users,
+		now:        time.Now,
+	}
+}
+
+// Providers returns the providers users can sign in with.
+func (s *OIDCService) Providers() []LoginProvider {
+	return s.providers
+}
+
+// Start begins signing in with the provider: it returns the flow to keep until the callback and
+// the provider's page to send the user to.
+func (s *OIDCService) Start(ctx context.Context, providerID string) (*OIDCFlow, string, error) {
+	provider, err := s.provider(providerID)
+	if err != nil {
+		return nil, "", err
+	}
+	flow := &OIDCFlow{Provider: providerID, ExpiresAt: s.now().Add(oidcFlowTTL).Unix()}
+	for _, v := range []*string{&flow.State, &flow.Nonce, &flow.Verifier} {
+		if *v, err = oidc.RandomString(); err != nil {
+			return nil, "", err
+		}
+	}
+	authURL, err := provider.Provider.AuthCodeURL(ctx, flow.State, flow.Nonce, oidc.CodeChallenge(flow.Verifier))
+	if err != nil {
+		return nil, "", err
+	}
+	return flow, authURL, nil
+}
+
+// Complete finishes the flow with the state and code the provider redirected back with, and
+// returns the user signed in.
+func (s *OIDCService) Complete(ctx context.Context, flow *OIDCFlow, state, code string) (*model.User, error) {
+	provider, err := s.provider(flow.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(flow.State)) != 1 || s.now().Unix() > flow.ExpiresAt {
+		return nil, ErrOIDCSignInFailed
+	}
+	identity, err := provider.Provider.Exchange(ctx, code, flow.Verifier, flow.Nonce)
+	if err != nil {
+		slog.Warn("Failed to verify OIDC sign-in", "provider", flow.Provider, "error", err)
+		return nil, ErrOIDCSignInFailed
+	}
+	return s.signIn(ctx, flow.Provider, identity)
+}
+
+// signIn returns the user linked to the provider's account, linking or creating one on the first
+// sign-in. Only an email the provider has verified is trusted, and only linked to an account that
+// has verified it too, so neither side can claim an address it does not own.
+func (s *OIDCService) signIn(ctx context.Context, providerID string, identity *oidc.Identity) (*model.User, error) {
+	linked, err := s.identities.FindByProviderSubject(ctx, providerID, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil {
+		if err := s.identities.RecordUse(ctx, linked.ID, s.now()); err != nil {
+			return nil, err
+		}
+		return s.findUser(ctx, linked.UserID)
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+	email := model.NormalizeEmail(identity.Email)
+	user, err := s.users.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil && !user.EmailVerified {
+		return nil, ErrOIDCAccountNotLinked
+	}
+	if user == nil {
+		// The account has no password; one can be set through a password reset
+		user = model.NewUser(email, "")
+		user.EmailVerified = true
+		if err := s.users.Create(ctx, *user); err != nil {
+			return nil, err
+		}
+		slog.Info("Created account for OIDC sign-in", "user_id", user.ID, "provider", providerID)
+	}
+
+	now := s.now()
+	err = s.identities.Create(ctx, &model.ExternalIdentity{
+		UserID:     user.ID,
+		Provider:   providerID,
+		Subject:    identity.Subject,
+		Email:      email,
+		CreatedAt:  now,
+		LastUsedAt: &now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Linked OIDC identity", "user_id", user.ID, "provider", providerID)
+	return user, nil
+}
+
+func (s *OIDCService) provider(id string) (*LoginProvider, error) {
+	for i := range s.providers {
+		if s.providers[i].ID == id {
+			return &s.providers[i], nil
+		}
+	}
+	return nil, ErrUnknownOIDCProvider
+}
+
+func (s *OIDCService) findUser(ctx context.Context, userID string) (*model.User, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	return user, nil
+}
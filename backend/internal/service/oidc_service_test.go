@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/oidc"
+	"github.com/riverajo/fitness-app/backend/internal/oidc/oidctest"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+type oidcFixture struct {
+	service    *OIDCService
+	provider   *oidctest.Provider
+	identities *repository.MockExternalIdentityRepository
+	users      *repository.MockUserRepository
+}
+
+func newOIDCFixture(t *testing.T) *oidcFixture {
+	f := &oidcFixture{
+		provider:   oidctest.NewProvider(t, "fitness-app", "secret"),
+		identities: new(repository.MockExternalIdentityRepository),
+		users:      new(repository.MockUserRepository),
+	}
+	f.service = NewOIDCService([]LoginProvider{{
+		ID:   "test",
+		Name: "Test",
+		Provider: &oidc.Provider{
+			Issuer:       f.provider.Issuer(),
+			ClientID:     "fitness-app",
+			ClientSecret: "secret",
+			RedirectURL:  "https://fitness.example.com/auth/oidc/test/callback",
+		},
+	}}, f.identities, f.users)
+	return f
+}
+
+// authorize starts a sign-in and follows the provider's redirect back, returning the flow and
+// the state and code of the callback.
+func (f *oidcFixture) authorize(t *testing.T) (*OIDCFlow, string, string) {
+	flow, authURL, err := f.service.Start(context.Background(), "test")
+	require.NoError(t, err)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return flow, callback.Query().Get("state"), callback.Query().Get("code")
+}
+
+func (f *oidcFixture) signIn(t *testing.T) (*model.User, error) {
+	flow, state, code := f.authorize(t)
+	return f.service.Complete(context.Background(), flow, state, code)
+}
+
+func TestOIDCService_SignIn(t *testing.T) {
+	t.Run("Creates an account on the first sign-in", func(t *testing.T) {
+		f := newOIDCFixture(t)
+		f.identities.On("FindByProviderSubject", mock.Anything, "test", "subject-1").Return(nil, nil)
+		f.users.On("FindByEmail", mock.Anything, "user@example.com").Return(nil, nil)
+		var created model.User
+		f.users.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			created = args.Get(1).(model.User)
+		}).Return(nil)
+		var linked *model.ExternalIdentity
+		f.identities.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			linked = args.Get(1).(*model.ExternalIdentity)
+		}).Return(nil)
+
+		user, err := f.signIn(t)
+		require.NoError(t, err)
+		assert.Equal(t, "user@example.com", user.Email)
+		assert.True(t, created.EmailVerified)
+		assert.Empty(t, created.PasswordHash)
+		assert.Equal(t, created.ID, user.ID)
+		require.NotNil(t, linked)
+		assert.Equal(t, user.ID, linked.UserID)
+		assert.Equal(t, "test", linked.Provider)
+		assert.Equal(t, "subject-1", linked.Subject)
+	})
+
+	t.Run("Signs in the linked user", func(t *testing.T) {
+		f := newOIDCFixture(t)
+		// The provider's email may have changed since; the subject is what identifies the user
+		f.provider.Email = "new@example.com"
+		f.identities.On("FindByProviderSubject", mock.Anything, "test", "subject-1").
+			Return(&model.ExternalIdentity{ID: "identity-1", UserID: "user-1"}, nil)
+		f.identities.On("RecordUse", mock.Anything, "identity-1", mock.Anything).Return(nil)
+		f.users.On("FindByID", mock.Anything, "user-1").Return(&model.User{ID: "user-1", Email: "user@example.com"}, nil)
+
+		user, err := f.signIn(t)
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", user.ID)
+		f.identities.AssertExpectations(t)
+		f.users.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Links an account with the same verified email", func(t *testing.T) {
+		f := newOIDCFixture(t)
+		f.identities.On("FindByProviderSubject", mock.Anything, "test", "subject-1").Return(nil, nil)
+		f.users.On("FindByEmail", mock.Anything, "user@example.com").
+			Return(&model.User{ID: "user-1", Email: "user@example.com", EmailVerified: true}, nil)
+		f.identities.On("Create", mock.Anything, mock.MatchedBy(func(identity *model.ExternalIdentity) bool {
+			return identity.UserID == "user-1" && identity.Subject == "subject-1"
+		})).Return(nil)
+
+		user, err := f.signIn(t)
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", user.ID)
+		f.identities.AssertExpectations(t)
+		f.users.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Matches the email in any case", func(t *testing.T) {
+		f := newOIDCFixture(t)
+		f.provider.Email = "User@Example.com"
+		f.identities.On("FindByProviderSubject", mock.Anything, "test", "subject-1").Return(nil, nil)
+		f.users.On("FindByEmail", mock.Anything, "user@example.com").
+			Return(&model.User{ID: "user-1", Email: "user@example.com", EmailVerified: true}, nil)
+		f.identities.On("Create", mock.Anything, mock.MatchedBy(func(identity *model.ExternalIdentity) bool {
+			return identity.UserID == "user-1" && identity.Email == "user@example.com"
+		})).Return(nil)
+
+		user, err := f.signIn(t)
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", user.ID)
+		f.identities.AssertExpectations(t)
+	})
+
+	t.Run("Does not link an account that has not verified its email", func(t *testing.T) {
+		f := newOIDCFixture(t)
+		f.identities.On("FindByProviderSubject", mock.Anything, "test", "subject-1").Return(nil, nil)
+		f.users.On("FindByEmail", mock.Anything, "user@example.com").
+			Return(&model.User{ID: "user-1", Email: "user@example.com"}, nil)
+
+		_, err := f.signIn(t)
+		assert.ErrorIs(t, err, ErrOIDCAccountNotLinked)
+		f.identities.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Does not trust an email the provider has not verified", func(t *testing.T) {
+		f := newOIDCFixture(t)
+		f.provider.EmailVerified = false
+		f.identities.On("FindByProviderSubject", mock.Anything, "test", "subject-1").Return(nil, nil)
+
+		_, err := f.signIn(t)
+		assert.ErrorIs(t, err, ErrOIDCEmailNotVerified)
+		f.users.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	})
+}
+
+func TestOIDCService_Complete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Rejects a callback for another sign-in", func(t *testing.T) {
+		f := newOIDCFixture(t)
+		flow, _, code := f.authorize(t)
+
+		_, err := f.service.Complete(ctx, flow, "another-state", code)
+		assert.ErrorIs(t, err, ErrOIDCSignInFailed)
+	})
+
+	t.Run("Rejects an expired sign-in", func(t *testing.T) {
+		f := newOIDCFixture(t)
+		flow, state, code := f.authorize(t)
+		f.service.now = func() time.Time { return time.Now().Add(oidcFlowTTL + time.Minute) }
+
+		_, err := f.service.Complete(ctx, flow, state, code)
+		assert.ErrorIs(t, err, ErrOIDCSignInFailed)
+	})
+
+	t.Run("Rejects a code that does not redeem", func(t *testing.T) {
+		f := newOIDCFixture(t)
+		flow, state, _ := f.authorize(t)
+
+		_, err := f.service.Complete(ctx, flow, state, "made-up-code")
+		assert.ErrorIs(t, err, ErrOIDCSignInFailed)
+	})
+
+	t.Run("Rejects an unknown provider", func(t *testing.T) {
+		f := newOIDCFixture(t)
+		_, _, err := f.service.Start(ctx, "other")
+		assert.ErrorIs(t, err, ErrUnknownOIDCProvider)
+	})
+}
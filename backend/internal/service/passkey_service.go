@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+	"github.com/riverajo/fitness-app/backend/internal/webauthn"
+)
+
+const (
+	// passkeyCeremonyTTL is how long the user has to answer their browser's passkey prompt.
+	passkeyCeremonyTTL = 5 * time.Minute
+	// maxPasskeyNameLength caps passkey names, in characters.
+	maxPasskeyNameLength = 64
+)
+
+var (
+	// ErrInvalidPasskey is returned for a passkey response that does not verify, or a passkey that is not registered.
+	ErrInvalidPasskey = errors.New("the passkey could not be verified")
+	// ErrInvalidPasskeyChallenge is returned for a passkey response to an unknown, expired or already used challenge.
+	ErrInvalidPasskeyChallenge = errors.New("this passkey request has expired; please try again")
+	// ErrPasskeyNotFound is returned when deleting a passkey the user does not have.
+	ErrPasskeyNotFound = errors.New("passkey not found")
+)
+
+// PasskeyService registers passkeys (WebAuthn credentials) and signs users in with them. Each
+// ceremony's WebAuthn challenge is a single-use action token, so no other state is kept between
+// its two steps.
+type PasskeyService struct {
+	passkeys repository.PasskeyRepository
+	users    repository.UserRepository
+	tokens   repository.ActionTokenRepository
+	rp       *webauthn.RelyingParty
+	now      func() time.Time
+}
+
+// NewPasskeyService creates a PasskeyService for passkeys registered with rp.
+func NewPasskeyService(passkeys repository.PasskeyRepository, users repository.UserRepository, tokens repository.ActionTokenRepository, rp *webauthn.RelyingParty) *PasskeyService {
+	return &PasskeyService{
+		passkeys: passkeys,
+		users:    users,
+		tokens:   tokens,
+		rp:       rp,
+		now:      time.Now,
+	}
+}
+
+// BeginRegistration returns the options for navigator.credentials.create(), as JSON, to add a
+// passkey to the user's account.
+func (s *PasskeyService) BeginRegistration(ctx context.Context, userID string) (string, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	existing, err := s.passkeys.ListByUser(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	challenge, err := issueActionToken(ctx, s.tokens, user, model.ActionTokenPasskeyRegistration, user.Email, passkeyCeremonyTTL)
+	if err != nil {
+		return "", err
+	}
+
+	// The authenticator the user already registered a passkey on is not offered again
+	exclude := make([]webauthn.Credential, 0, len(existing))
+	for _, p := range existing {
+		exclude = append(exclude, webauthn.Credential{ID: p.CredentialID, Transports: p.Transports})
+	}
+	options := s.rp.CreationOptions([]byte(challenge), webauthn.User{ID: []byte(user.ID), Name: user.Email, DisplayName: user.Email}, exclude)
+	return marshalOptions(options)
+}
+
+// FinishRegistration verifies the browser's response, as JSON, to the user's BeginRegistration,
+// and saves the new passkey under name.
+func (s *PasskeyService) FinishRegistration(ctx context.Context, userID, name, credentialJSON string) (*model.Passkey, error) {
+	resp, err := webauthn.ParseRegistrationResponse(credentialJSON)
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+	challenge, err := resp.Challenge()
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+	token, err := s.lookupChallenge(ctx, challenge, model.ActionTokenPasskeyRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if token.UserID != userID {
+		return nil, ErrInvalidPasskeyChallenge
+	}
+
+	credential, err := s.rp.VerifyRegistration(resp, challenge)
+	if err != nil {
+		slog.Info("Passkey registration failed verification", "user_id", userID, "error", err)
+		return nil, ErrInvalidPasskey
+	}
+	if err := consumeActionToken(ctx, s.tokens, token); err != nil {
+		return nil, ErrInvalidPasskeyChallenge
+	}
+
+	passkey := &model.Passkey{
+		UserID:       userID,
+		Name:         passkeyName(name),
+		CredentialID: credential.ID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    credential.SignCount,
+		Transports:   credential.Transports,
+		CreatedAt:    s.now(),
+	}
+	if err := s.passkeys.Create(ctx, passkey); err != nil {
+		return nil, err
+	}
+	return passkey, nil
+}
+
+// ListPasskeys returns the user's passkeys, oldest first.
+func (s *PasskeyService) ListPasskeys(ctx context.Context, userID string) ([]*model.Passkey, error) {
+	return s.passkeys.ListByUser(ctx, userID)
+}
+
+// DeletePasskey removes one of the user's passkeys; it can no longer be used to sign in.
+func (s *PasskeyService) DeletePasskey(ctx context.Context, userID, id string) error {
+	deleted, err := s.passkeys.DeleteForUser(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrPasskeyNotFound
+	}
+	return nil
+}
+
+// BeginLogin returns the options for navigator.credentials.get(), as JSON. The user is not asked
+// who they are: their browser offers the passkeys it has for the site.
+func (s *PasskeyService) BeginLogin(ctx context.Context) (string, error) {
+	challenge, err := createActionToken(ctx, s.tokens, "", model.ActionTokenPasskeyLogin, "", passkeyCeremonyTTL)
+	if err != nil {
+		return "", err
+	}
+	return marshalOptions(s.rp.RequestOptions([]byte(challenge)))
+}
+
+// FinishLogin verifies the browser's response, as JSON, to a BeginLogin, and returns the user
+// whose passkey signed it. A passkey verifies the user itself, so no second factor is asked for.
+func (s *PasskeyService) FinishLogin(ctx context.Context, credentialJSON string) (*model.User, error) {
+	resp, err := webauthn.ParseAssertionResponse(credentialJSON)
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+	challenge, err := resp.Challenge()
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+	token, err := s.lookupChallenge(ctx, challenge, model.ActionTokenPasskeyLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialID, err := resp.CredentialID()
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+	passkey, err := s.passkeys.FindByCredentialID(ctx, credentialID)
+	if err != nil {
+		return nil, err
+	}
+	if passkey == nil {
+		return nil, ErrInvalidPasskey
+	}
+	if handle, err := resp.UserHandle(); err != nil || (handle != nil && string(handle) != passkey.UserID) {
+		return nil, ErrInvalidPasskey
+	}
+
+	signCount, err := s.rp.VerifyAssertion(resp, challenge, &webauthn.Credential{
+		ID:        passkey.CredentialID,
+		PublicKey: passkey.PublicKey,
+		SignCount: passkey.SignCount,
+	})
+	if errors.Is(err, webauthn.ErrSignCountNotIncreased) {
+		slog.Warn("Security event: passkey signature counter did not increase, it may have been cloned",
+			"event", "passkey_sign_count", "user_id", passkey.UserID, "passkey_id", passkey.ID)
+		return nil, ErrInvalidPasskey
+	} else if err != nil {
+		slog.Info("Passkey sign-in failed verification", "user_id", passkey.UserID, "error", err)
+		return nil, ErrInvalidPasskey
+	}
+	if err := consumeActionToken(ctx, s.tokens, token); err != nil {
+		return nil, ErrInvalidPasskeyChallenge
+	}
+
+	if err := s.passkeys.RecordUse(ctx, passkey.ID, signCount, s.now()); err != nil {
+		return nil, err
+	}
+	return s.findUser(ctx, passkey.UserID)
+}
+
+// lookupChallenge returns the action token a WebAuthn challenge stands for.
+func (s *PasskeyService) lookupChallenge(ctx context.Context, challenge []byte, purpose model.ActionTokenPurpose) (*model.ActionToken, error) {
+	token, err := lookupActionToken(ctx, s.tokens, string(challenge), purpose)
+	if errors.Is(err, ErrInvalidActionToken) {
+		return nil, ErrInvalidPasskeyChallenge
+	}
+	return token, err
+}
+
+func (s *PasskeyService) findUser(ctx context.Context, userID string) (*model.User, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	return user, nil
+}
+
+// passkeyName returns the name to save a passkey under.
+func passkeyName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "Passkey"
+	}
+	if runes := []rune(name); len(runes) > maxPasskeyNameLength {
+		name = string(runes[:maxPasskeyNameLength])
+	}
+	return name
+}
+
+func marshalOptions(options any) (string, error) {
+	b, err := json.Marshal(options)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode passkey options: %w", err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+	"github.com/riverajo/fitness-app/backend/internal/webauthn"
+	"github.com/riverajo/fitness-app/backend/internal/webauthn/webauthntest"
+)
+
+type passkeyFixture struct {
+	service       *PasskeyService
+	passkeys      *repository.MockPasskeyRepository
+	users         *repository.MockUserRepository
+	tokens        *repository.MockActionTokenRepository
+	authenticator *webauthntest.Authenticator
+}
+
+func newPasskeyFixture() *passkeyFixture {
+	f := &passkeyFixture{
+		passkeys:      new(repository.MockPasskeyRepository),
+		users:         new(repository.MockUserRepository),
+		tokens:        new(repository.MockActionTokenRepository),
+		authenticator: webauthntest.NewAuthenticator("https://fitness.example.com"),
+	}
+	rp := &webauthn.RelyingParty{ID: "fitness.example.com", Name: "Fitness App", Origins: []string{"https://fitness.example.com"}}
+	f.service = NewPasskeyService(f.passkeys, f.users, f.tokens, rp)
+
+	// Issued challenges can be looked up and used once
+	f.tokens.On("DeleteForUser", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	issued := 0
+	f.tokens.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		token := args.Get(1).(*model.ActionToken)
+		issued++
+		token.ID = fmt.Sprintf("token-%d", issued)
+		f.tokens.On("FindByID", mock.Anything, token.ID).Return(token, nil)
+		f.tokens.On("Consume", mock.Anything, token.ID).Return(true, nil).Once()
+		f.tokens.On("Consume", mock.Anything, token.ID).Return(false, nil)
+	}).Return(nil)
+
+	f.users.On("FindByID", mock.Anything, "user-1").Return(&model.User{ID: "user-1", Email: "jane@example.com"}, nil)
+	f.passkeys.On("ListByUser", mock.Anything, "user-1").Return([]*model.Passkey{}, nil)
+	return f
+}
+
+// register adds a passkey on the fixture's authenticator to user-1's account.
+func (f *passkeyFixture) register(t *testing.T) *model.Passkey {
+	ctx := context.Background()
+	var stored *model.Passkey
+	f.passkeys.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*model.Passkey)
+		stored.ID = "passkey-1"
+	}).Return(nil).Once()
+
+	options, err := f.service.BeginRegistration(ctx, "user-1")
+	require.NoError(t, err)
+	credential, err := f.authenticator.Register(options)
+	require.NoError(t, err)
+	passkey, err := f.service.FinishRegistration(ctx, "user-1", "  Work laptop ", credential)
+	require.NoError(t, err)
+	require.Same(t, stored, passkey)
+	return passkey
+}
+
+// login signs in with the fixture's authenticator.
+func (f *passkeyFixture) login(t *testing.T) (*model.User, error) {
+	options, err := f.service.BeginLogin(context.Background())
+	require.NoError(t, err)
+	credential, err := f.authenticator.Login(options)
+	require.NoError(t, err)
+	return f.service.FinishLogin(context.Background(), credential)
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	f := newPasskeyFixture()
+	passkey := f.register(t)
+	assert.Equal(t, "user-1", passkey.UserID)
+	assert.Equal(t, "Work laptop", passkey.Name)
+	assert.Equal(t, f.authenticator.CredentialID(), passkey.CredentialID)
+
+	f.passkeys.On("FindByCredentialID", mock.Anything, passkey.CredentialID).Return(passkey, nil)
+	f.passkeys.On("RecordUse", mock.Anything, "passkey-1", uint32(1), mock.Anything).Return(nil).Once()
+	user, err := f.login(t)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", user.ID)
+	f.passkeys.AssertExpectations(t)
+
+	t.Run("Rejects a cloned passkey", func(t *testing.T) {
+		passkey.SignCount = 10
+		_, err := f.login(t)
+		assert.ErrorIs(t, err, ErrInvalidPasskey)
+	})
+
+	t.Run("Rejects an unknown passkey", func(t *testing.T) {
+		other := newPasskeyFixture()
+		other.register(t)
+		other.passkeys.On("FindByCredentialID", mock.Anything, mock.Anything).Return(nil, nil)
+		_, err := other.login(t)
+		assert.ErrorIs(t, err, ErrInvalidPasskey)
+	})
+}
+
+func TestPasskeyRegistrationChallenge(t *testing.T) {
+	ctx := context.Background()
+	f := newPasskeyFixture()
+	options, err := f.service.BeginRegistration(ctx, "user-1")
+	require.NoError(t, err)
+	credential, err := f.authenticator.Register(options)
+	require.NoError(t, err)
+
+	t.Run("Belongs to the user who started it", func(t *testing.T) {
+		_, err := f.service.FinishRegistration(ctx, "user-2", "", credential)
+		assert.ErrorIs(t, err, ErrInvalidPasskeyChallenge)
+	})
+
+	t.Run("Can only be used once", func(t *testing.T) {
+		f.passkeys.On("Create", mock.Anything, mock.Anything).Return(nil).Once()
+		passkey, err := f.service.FinishRegistration(ctx, "user-1", "", credential)
+		require.NoError(t, err)
+		assert.Equal(t, "Passkey", passkey.Name)
+
+		_, err = f.service.FinishRegistration(ctx, "user-1", "", credential)
+		assert.ErrorIs(t, err, ErrInvalidPasskeyChallenge)
+	})
+}
+
+func TestDeletePasskey(t *testing.T) {
+	f := newPasskeyFixture()
+	f.passkeys.On("DeleteForUser", mock.Anything, "user-1", "passkey-1").Return(true, nil)
+	f.passkeys.On("DeleteForUser", mock.Anything, "user-1", "passkey-2").Return(false, nil)
+
+	assert.NoError(t, f.service.DeletePasskey(context.Background(), "user-1", "passkey-1"))
+	assert.ErrorIs(t, f.service.DeletePasskey(context.Background(), "user-1", "passkey-2"), ErrPasskeyNotFound)
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bcryptMaxBytes is the longest password bcrypt accepts; it rejects longer ones rather than
+// silently ignoring the rest.
+const bcryptMaxBytes = 72
+
+// DefaultPasswordMinLength is the minimum password length, in characters, when none is configured.
+const DefaultPasswordMinLength = 8
+
+// PasswordViolation identifies a requirement a password does not meet.
+type PasswordViolation string
+
+const (
+	PasswordTooShort      PasswordViolation = "TOO_SHORT"
+	PasswordTooLong       PasswordViolation = "TOO_LONG"
+	PasswordContainsEmail PasswordViolation = "CONTAINS_EMAIL"
+	PasswordBreached      PasswordViolation = "BREACHED"
+)
+
+// InvalidPasswordError lists every requirement a new password does not meet, so clients can
+// explain them all at once.
+type InvalidPasswordError struct {
+	Violations []PasswordViolation
+	MinLength  int
+	MaxBytes   int
+}
+
+func (e *InvalidPasswordError) Error() string {
+	reasons := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		switch v {
+		case PasswordTooShort:
+			reasons[i] = fmt.Sprintf("must be at least %d characters", e.MinLength)
+		case PasswordTooLong:
+			reasons[i] = fmt.Sprintf("must be at most %d bytes", e.MaxBytes)
+		case PasswordContainsEmail:
+			reasons[i] = "must not contain your email address"
+		case PasswordBreached:
+			reasons[i] = "appears in a known data breach"
+		default:
+			reasons[i] = string(v)
+		}
+	}
+	return "invalid password: " + strings.Join(reasons, "; ")
+}
+
+// PasswordPolicy is the set of requirements for new passwords. Existing passwords are not
+// checked, so tightening the policy does not lock anyone out.
+type PasswordPolicy struct {
+	// MinLength is counted in characters; zero means DefaultPasswordMinLength.
+	MinLength int
+	// Breached optionally rejects passwords known from data breaches.
+	Breached *BreachedPasswords
+}
+
+// Validate returns an *InvalidPasswordError if the password for the account with this email
+// does not meet the policy.
+func (p PasswordPolicy) Validate(password, email string) error {
+	minLength := p.MinLength
+	if minLength <= 0 {
+		minLength = DefaultPasswordMinLength
+	}
+
+	var violations []PasswordViolation
+	if utf8.RuneCountInString(password) < minLength {
+		violations = append(violations, PasswordTooShort)
+	}
+	if len(password) > bcryptMaxBytes {
+		violations = append(violations, PasswordTooLong)
+	}
+	if derivedFromEmail(password, email) {
+		violations = append(violations, PasswordContainsEmail)
+	}
+	if p.Breached.Contains(password) {
+		violations = append(violations, PasswordBreached)
+	}
+
+	if len(violations) > 0 {
+		return &InvalidPasswordError{Violations: violations, MinLength: minLength, MaxBytes: bcryptMaxBytes}
+	}
+	return nil
+}
+
+// derivedFromEmail reports whether the password contains the email's local part (e.g. "jane.doe"
+// in "JaneDoe2024!"), ignoring case and punctuation. Very short local parts are ignored, as
+// they would match too many unrelated passwords.
+func derivedFromEmail(password, email string) bool {
+	local, _, _ := strings.Cut(email, "@")
+	local = lettersAndDigits(local)
+	if len(local) < 3 {
+		return false
+	}
+	return strings.Contains(lettersAndDigits(password), local)
+}
+
+func lettersAndDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// BreachedPasswords is a list of SHA-1 hashes of breached passwords, grouped by their first
+// five hex digits like the k-anonymity range API of Have I Been Pwned, so a lookup only
+// searches the hashes sharing the password's prefix.
+type BreachedPasswords struct {
+	// ranges maps a 5-digit prefix to the sorted 35-digit suffixes of the hashes starting with it.
+	ranges map[string][]string
+	count  int
+}
+
+// LoadBreachedPasswords reads a breached password list, e.g. one produced by the Pwned
+// Passwords downloader. Each line is an uppercase or lowercase SHA-1 hash, optionally
+// followed by ":count"; blank lines and lines starting with "#" are ignored. The whole list
+// is held in memory, so large lists should be trimmed (e.g. to hashes seen many times).
+func LoadBreachedPasswords(path string) (*BreachedPasswords, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	list := &BreachedPasswords{ranges: make(map[string][]string)}
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("invalid hash on line %d of breached password list", lineNo)
+		}
+		list.ranges[hash[:5]] = append(list.ranges[hash[:5]], hash[5:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	for prefix, suffixes := range list.ranges {
+		slices.Sort(suffixes)
+		list.ranges[prefix] = slices.Compact(suffixes)
+		list.count += len(list.ranges[prefix])
+	}
+	return list, nil
+}
+
+// Len returns the number of hashes in the list.
+func (b *BreachedPasswords) Len() int {
+	if b == nil {
+		return 0
+	}
+	return b.count
+}
+
+// Contains reports whether the password is in the list. A nil list contains nothing.
+func (b *BreachedPasswords) Contains(password string) bool {
+	if b == nil {
+		return false
+	}
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	_, found := slices.BinarySearch(b.ranges[hash[:5]], hash[5:])
+	return found
+}
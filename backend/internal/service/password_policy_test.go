@@ -0,0 +1,100 @@
+package service
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func writeBreachedList(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600))
+	return path
+}
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	breached, err := LoadBreachedPasswords(writeBreachedList(t,
+		strings.ToUpper(sha1Hex("correcthorse"))+":1234",
+		sha1Hex("letmein-please"),
+	))
+	require.NoError(t, err)
+	policy := PasswordPolicy{MinLength: 10, Breached: breached}
+
+	tests := []struct {
+		name     string
+		password string
+		email    string
+		want     []PasswordViolation
+	}{
+		{name: "Valid", password: "plum-orbit-canvas", email: "jane.doe@example.com"},
+		{name: "Too short", password: "short", email: "jane@example.com", want: []PasswordViolation{PasswordTooShort}},
+		{name: "Length counts characters", password: "ééééééééé", email: "jane@example.com", want: []PasswordViolation{PasswordTooShort}},
+		{name: "Too long for bcrypt", password: strings.Repeat("é", 37), email: "jane@example.com", want: []PasswordViolation{PasswordTooLong}},
+		{name: "Contains email local part", password: "JaneDoe-2024!", email: "jane.doe@example.com", want: []PasswordViolation{PasswordContainsEmail}},
+		{name: "Short local parts are ignored", password: "jo-plum-orbit", email: "jo@example.com"},
+		{name: "Breached", password: "correcthorse", email: "jane@example.com", want: []PasswordViolation{PasswordBreached}},
+		{name: "Breached lowercase hash", password: "letmein-please", email: "jane@example.com", want: []PasswordViolation{PasswordBreached}},
+		{name: "Several violations", password: "jane", email: "jane@example.com", want: []PasswordViolation{PasswordTooShort, PasswordContainsEmail}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password, tt.email)
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var invalid *InvalidPasswordError
+			require.True(t, errors.As(err, &invalid), "expected InvalidPasswordError, got %v", err)
+			assert.Equal(t, tt.want, invalid.Violations)
+			assert.Equal(t, 10, invalid.MinLength)
+			assert.Equal(t, 72, invalid.MaxBytes)
+		})
+	}
+
+	t.Run("Default minimum length", func(t *testing.T) {
+		err := PasswordPolicy{}.Validate("seven77", "jane@example.com")
+		assert.EqualError(t, err, "invalid password: must be at least 8 characters")
+		assert.NoError(t, PasswordPolicy{}.Validate("eight888", "jane@example.com"))
+	})
+}
+
+func TestLoadBreachedPasswords(t *testing.T) {
+	t.Run("Skips comments and duplicates", func(t *testing.T) {
+		hash := sha1Hex("password1")
+		list, err := LoadBreachedPasswords(writeBreachedList(t, "# top breached passwords", "", hash+":9", strings.ToUpper(hash)+":9"))
+		require.NoError(t, err)
+		assert.Equal(t, 1, list.Len())
+		assert.True(t, list.Contains("password1"))
+		assert.False(t, list.Contains("password2"))
+	})
+
+	t.Run("Rejects malformed hashes", func(t *testing.T) {
+		_, err := LoadBreachedPasswords(writeBreachedList(t, sha1Hex("password1"), "not-a-hash:3"))
+		assert.EqualError(t, err, "invalid hash on line 2 of breached password list")
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		_, err := LoadBreachedPasswords(filepath.Join(t.TempDir(), "missing.txt"))
+		assert.Error(t, err)
+	})
+
+	t.Run("Nil list contains nothing", func(t *testing.T) {
+		var list *BreachedPasswords
+		assert.False(t, list.Contains("password1"))
+		assert.Equal(t, 0, list.Len())
+	})
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+// RateLimitPolicy describes how failed attempts against one key slow down further attempts.
+// After FreeAttempts failures each further attempt must wait BaseDelay, doubling with every
+// failure up to MaxDelay; after LockoutAfter failures the key is locked for LockoutDuration.
+// Failures are forgotten once ResetAfter passes without a new one.
+type RateLimitPolicy struct {
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutAfter    int
+	LockoutDuration time.Duration
+	ResetAfter      time.Duration
+}
+
+var (
+	// LoginIPPolicy is generous, as many users may share an address (e.g. a gym's Wi-Fi).
+	LoginIPPolicy = RateLimitPolicy{
+		FreeAttempts:    10,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAfter:    50,
+		LockoutDuration: 30 * time.Minute,
+		ResetAfter:      time.Hour,
+	}
+	// LoginAccountPolicy protects one account from guessing spread across many addresses.
+	LoginAccountPolicy = RateLimitPolicy{
+		FreeAttempts:    5,
+		BaseDelay:       time.Second,
+		MaxDelay:        5 * time.Minute,
+		LockoutAfter:    15,
+		LockoutDuration: 15 * time.Minute,
+		ResetAfter:      time.Hour,
+	}
+	// RegisterIPPolicy counts every registration, successful or not, to slow down account
+	// creation and email enumeration.
+	RegisterIPPolicy = RateLimitPolicy{
+		FreeAttempts:    5,
+		BaseDelay:       10 * time.Second,
+		MaxDelay:        10 * time.Minute,
+		LockoutAfter:    20,
+		LockoutDuration: time.Hour,
+		ResetAfter:      time.Hour,
+	}
+	// PasskeyChallengeIPPolicy counts every passkey sign-in started, since each stores a challenge;
+	// it allows far more than a person signing in needs.
+	PasskeyChallengeIPPolicy = RateLimitPolicy{
+		FreeAttempts:    30,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAfter:    200,
+		LockoutDuration: 30 * time.Minute,
+		ResetAfter:      time.Hour,
+	}
+	// EmailPolicy counts every email sent to one address, so requests for links cannot be
+	// used to flood an inbox.
+	EmailPolicy = RateLimitPolicy{
+		FreeAttempts: 3,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Hour,
+		ResetAfter:   24 * time.Hour,
+	}
+)
+
+// delay returns how long after the latest failure the next attempt must wait.
+func (p RateLimitPolicy) delay(failures int) time.Duration {
+	switch {
+	case failures < p.FreeAttempts || failures == 0:
+		return 0
+	case p.LockoutAfter > 0 && failures >= p.LockoutAfter:
+		return p.LockoutDuration
+	}
+	delay := p.BaseDelay
+	for i := p.FreeAttempts; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// retention is how long a failure can affect later attempts.
+func (p RateLimitPolicy) retention() time.Duration {
+	return max(p.ResetAfter, p.LockoutDuration, p.MaxDelay)
+}
+
+// RateLimitKey is a rate-limited subject (an address or account) and the policy applied to it.
+type RateLimitKey struct {
+	Key    string
+	Policy RateLimitPolicy
+}
+
+// LoginIPKey limits login attempts from one client address.
+func LoginIPKey(ip string) RateLimitKey {
+	return RateLimitKey{Key: "login:ip:" + ip, Policy: LoginIPPolicy}
+}
+
+// LoginAccountKey limits login attempts against one account, whether or not it exists.
+func LoginAccountKey(email string) RateLimitKey {
+	return RateLimitKey{Key: "login:account:" + strings.ToLower(strings.TrimSpace(email)), Policy: LoginAccountPolicy}
+}
+
+// PasskeyChallengeIPKey limits passkey sign-ins started from one client address.
+func PasskeyChallengeIPKey(ip string) RateLimitKey {
+	return RateLimitKey{Key: "passkey-challenge:ip:" + ip, Policy: PasskeyChallengeIPPolicy}
+}
+
+// RegisterIPKey limits registrations from one client address.
+func RegisterIPKey(ip string) RateLimitKey {
+	return RateLimitKey{Key: "register:ip:" + ip, Policy: RegisterIPPolicy}
+}
+
+// PasswordResetIPKey limits password reset requests from one client address.
+func PasswordResetIPKey(ip string) RateLimitKey {
+	return RateLimitKey{Key: "password-reset:ip:" + ip, Policy: RegisterIPPolicy}
+}
+
+// PasswordResetAccountKey limits password reset emails to one address, whether or not it is registered.
+func PasswordResetAccountKey(email string) RateLimitKey {
+	return RateLimitKey{Key: "password-reset:account:" + strings.ToLower(strings.TrimSpace(email)), Policy: EmailPolicy}
+}
+
+// EmailVerificationKey limits verification emails to one user.
+func EmailVerificationKey(userID string) RateLimitKey {
+	return RateLimitKey{Key: "verify-email:user:" + userID, Policy: EmailPolicy}
+}
+
+// EmailChangeKey limits email change confirmations sent for one user.
+func EmailChangeKey(userID string) RateLimitKey {
+	return RateLimitKey{Key: "email-change:user:" + userID, Policy: EmailPolicy}
+}
+
+// TwoFactorKey limits second factor codes tried against one user, during login or when changing
+// two-factor settings.
+func TwoFactorKey(userID string) RateLimitKey {
+	return RateLimitKey{Key: "2fa:user:" + userID, Policy: LoginAccountPolicy}
+}
+
+// RateLimitedError is returned when an attempt is made before the rate limit allows it.
+type RateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("too many attempts, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// RateLimiter slows down repeated failed attempts. Its state is kept in the repository, so
+// limits apply across replicas when the repository is shared.
+type RateLimiter struct {
+	repo repository.RateLimitRepository
+	now  func() time.Time
+}
+
+func NewRateLimiter(repo repository.RateLimitRepository) *RateLimiter {
+	return &RateLimiter{repo: repo, now: time.Now}
+}
+
+// attemptRetries bounds how often Attempt retries a key whose state concurrent attempts keep
+// changing, after which the attempt is turned away as though limited.
+const attemptRetries = 5
+
+// Check returns a *RateLimitedError if any of the keys must wait before another attempt,
+// retrying after the longest wait.
+func (l *RateLimiter) Check(ctx context.Context, keys ...RateLimitKey) error {
+	now := l.now()
+	var retryAfter time.Duration
+	for _, key := range keys {
+		state, err := l.repo.Get(ctx, key.Key)
+		if err != nil {
+			return err
+		}
+		retryAfter = max(retryAfter, key.wait(state, now))
+	}
+	if retryAfter > 0 {
+		return &RateLimitedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// Attempt counts an attempt against each key before it is made, returning a *RateLimitedError
+// instead if any key must wait. Each attempt is recorded against the state it was checked
+// with, so concurrent attempts cannot all pass a check made before any of them was counted.
+// A successful attempt should Reset or Forgive the keys afterwards.
+func (l *RateLimiter) Attempt(ctx context.Context, keys ...RateLimitKey) error {
+	if err := l.Check(ctx, keys...); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := l.attempt(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *RateLimiter) attempt(ctx context.Context, key RateLimitKey) error {
+	for range attemptRetries {
+		now := l.now()
+		state, err := l.repo.Get(ctx, key.Key)
+		if err != nil {
+			return err
+		}
+		if wait := key.wait(state, now); wait > 0 {
+			return &RateLimitedError{RetryAfter: wait}
+		}
+
+		failures := 1
+		if state != nil && now.Sub(state.LastFailureAt) <= key.Policy.ResetAfter {
+			failures = state.Failures + 1
+		}
+		recorded, err := l.repo.RecordAttempt(ctx, key.Key, state, failures, now, now.Add(key.Policy.retention()))
+		if err != nil {
+			return err
+		}
+		if recorded {
+			return nil
+		}
+	}
+	return &RateLimitedError{RetryAfter: time.Second}
+}
+
+// wait returns how long the key must wait after now before another attempt.
+func (k RateLimitKey) wait(state *model.RateLimitState, now time.Time) time.Duration {
+	if state == nil || now.Sub(state.LastFailureAt) > k.Policy.ResetAfter {
+		return 0
+	}
+	return state.LastFailureAt.Add(k.Policy.delay(state.Failures)).Sub(now)
+}
+
+// Fail records a failed attempt against each key.
+func (l *RateLimiter) Fail(ctx context.Context, keys ...RateLimitKey) error {
+	now := l.now()
+	for _, key := range keys {
+		if _, err := l.repo.RecordFailure(ctx, key.Key, now, key.Policy.ResetAfter, now.Add(key.Policy.retention())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Forgive takes back an attempt counted by Attempt against each key, for attempts that turned
+// out not to be failures.
+func (l *RateLimiter) Forgive(ctx context.Context, keys ...RateLimitKey) error {
+	for _, key := range keys {
+		if err := l.repo.Forgive(ctx, key.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reset forgets the failed attempts against a key, e.g. after a successful login.
+func (l *RateLimiter) Reset(ctx context.Context, key RateLimitKey) error {
+	return l.repo.Reset(ctx, key.Key)
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+func TestRateLimitPolicyDelay(t *testing.T) {
+	policy := RateLimitPolicy{
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        10 * time.Second,
+		LockoutAfter:    8,
+		LockoutDuration: time.Hour,
+		ResetAfter:      time.Hour,
+	}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, 8 * time.Second},
+		{7, 10 * time.Second},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, policy.delay(tt.failures), "failures=%d", tt.failures)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	newLimiter := func() *RateLimiter {
+		limiter := NewRateLimiter(repository.NewMemoryRateLimitRepository())
+		limiter.now = func() time.Time { return now }
+		return limiter
+	}
+	policy := RateLimitPolicy{
+		FreeAttempts:    2,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAfter:    5,
+		LockoutDuration: 15 * time.Minute,
+		ResetAfter:      time.Hour,
+	}
+	key := RateLimitKey{Key: "login:account:a@example.com", Policy: policy}
+
+	t.Run("Backs off exponentially and locks out", func(t *testing.T) {
+		limiter := newLimiter()
+		require.NoError(t, limiter.Fail(ctx, key, key))
+		require.NoError(t, limiter.Fail(ctx, key))
+
+		var limited *RateLimitedError
+		require.True(t, errors.As(limiter.Check(ctx, key), &limited))
+		assert.Equal(t, 2*time.Second, limited.RetryAfter)
+		assert.EqualError(t, limited, "too many attempts, try again in 2s")
+
+		now = now.Add(2 * time.Second)
+		assert.NoError(t, limiter.Check(ctx, key))
+
+		require.NoError(t, limiter.Fail(ctx, key, key))
+		require.True(t, errors.As(limiter.Check(ctx, key), &limited))
+		assert.Equal(t, 15*time.Minute, limited.RetryAfter)
+
+		now = now.Add(15 * time.Minute)
+		assert.NoError(t, limiter.Check(ctx, key))
+	})
+
+	t.Run("Reports the longest wait", func(t *testing.T) {
+		limiter := newLimiter()
+		other := RateLimitKey{Key: "login:ip:203.0.113.7", Policy: RateLimitPolicy{FreeAttempts: 1, BaseDelay: time.Minute, MaxDelay: time.Minute, ResetAfter: time.Hour}}
+		require.NoError(t, limiter.Fail(ctx, key, other))
+
+		var limited *RateLimitedError
+		require.True(t, errors.As(limiter.Check(ctx, key, other), &limited))
+		assert.Equal(t, time.Minute, limited.RetryAfter)
+		assert.NoError(t, limiter.Check(ctx, key))
+	})
+
+	t.Run("Forgets failures after the reset window", func(t *testing.T) {
+		limiter := newLimiter()
+		require.NoError(t, limiter.Fail(ctx, key, key, key, key, key))
+		assert.Error(t, limiter.Check(ctx, key))
+
+		now = now.Add(policy.ResetAfter + time.Second)
+		assert.NoError(t, limiter.Check(ctx, key))
+
+		// Counting starts again from the next failure
+		require.NoError(t, limiter.Fail(ctx, key))
+		assert.NoError(t, limiter.Check(ctx, key))
+	})
+
+	t.Run("Reset clears failures", func(t *testing.T) {
+		limiter := newLimiter()
+		require.NoError(t, limiter.Fail(ctx, key, key, key))
+		assert.Error(t, limiter.Check(ctx, key))
+
+		require.NoError(t, limiter.Reset(ctx, key))
+		assert.NoError(t, limiter.Check(ctx, key))
+	})
+
+	t.Run("Attempt counts before the attempt is made", func(t *testing.T) {
+		limiter := newLimiter()
+		require.NoError(t, limiter.Attempt(ctx, key))
+		require.NoError(t, limiter.Attempt(ctx, key))
+
+		var limited *RateLimitedError
+		require.True(t, errors.As(limiter.Attempt(ctx, key), &limited))
+		assert.Equal(t, time.Second, limited.RetryAfter)
+
+		// A turned away attempt is not counted
+		now = now.Add(time.Second)
+		require.NoError(t, limiter.Attempt(ctx, key))
+		require.True(t, errors.As(limiter.Check(ctx, key), &limited))
+		assert.Equal(t, 2*time.Second, limited.RetryAfter)
+
+		// Forgiving an attempt that succeeded takes it back
+		require.NoError(t, limiter.Forgive(ctx, key))
+		require.True(t, errors.As(limiter.Check(ctx, key), &limited))
+		assert.Equal(t, time.Second, limited.RetryAfter)
+	})
+
+	t.Run("Concurrent attempts share the limit", func(t *testing.T) {
+		limiter := newLimiter()
+		var passed atomic.Int32
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Go(func() {
+				if limiter.Attempt(ctx, key) == nil {
+					passed.Add(1)
+				}
+			})
+		}
+		wg.Wait()
+		assert.Equal(t, int32(policy.FreeAttempts), passed.Load())
+	})
+}
+
+func TestLoginAccountKeyIgnoresCase(t *testing.T) {
+	assert.Equal(t, LoginAccountKey("a@example.com").Key, LoginAccountKey(" A@Example.com ").Key)
+}
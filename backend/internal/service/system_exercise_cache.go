@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+// Cache operations, recorded as the "operation" attribute of the hit/miss counters.
+const (
+	cacheOpLookup = "lookup"
+	cacheOpList   = "list"
+)
+
+// SystemExerciseCache keeps the system exercise catalogue in memory. The catalogue only changes
+// when the seeder bumps its version, so the cache is loaded once and reloaded when the version
+// recorded in system_metadata changes.
+//
+// Exercises returned from the cache are shared and must not be modified.
+type SystemExerciseCache struct {
+	repo repository.ExerciseRepository
+
+	mu        sync.RWMutex
+	loaded    bool
+	version   int
+	exercises []*model.UniqueExercise // Sorted by name, including archived ones
+	byID      map[string]*model.UniqueExercise
+
+	hits   metric.Int64Counter
+	misses metric.Int64Counter
+}
+
+// NewSystemExerciseCache creates an empty cache. Until Load succeeds every request is a miss,
+// and callers fall back to the database.
+func NewSystemExerciseCache(repo repository.ExerciseRepository) *SystemExerciseCache {
+	meter := otel.Meter("github.com/riverajo/fitness-app/backend/internal/service")
+	// Instrument creation only fails on invalid names; a no-op instrument is returned in that case
+	hits, _ := meter.Int64Counter("system_exercise_cache.hits",
+		metric.WithDescription("System exercise requests served from the in-memory cache"))
+	misses, _ := meter.Int64Counter("system_exercise_cache.misses",
+		metric.WithDescription("System exercise requests that fell through to the database"))
+
+	return &SystemExerciseCache{
+		repo:   repo,
+		hits:   hits,
+		misses: misses,
+	}
+}
+
+// Load reads the whole catalogue from the database, replacing the cached copy.
+func (c *SystemExerciseCache) Load(ctx context.Context) error {
+	// Read the version first: if the catalogue changes while it is being listed,
+	// the next refresh sees a newer version and reloads.
+	version, err := c.repo.SystemExercisesVersion(ctx)
+	if err != nil {
+		return err
+	}
+	exercises, err := c.repo.ListSystem(ctx)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]*model.UniqueExercise, len(exercises))
+	for _, e := range exercises {
+		byID[e.ID] = e
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loaded = true
+	c.version = version
+	c.exercises = exercises
+	c.byID = byID
+	return nil
+}
+
+// Refresh reloads the catalogue if it has not been loaded yet or its version changed.
+// It reports whether a reload happened.
+func (c *SystemExerciseCache) Refresh(ctx context.Context) (bool, error) {
+	version, err := c.repo.SystemExercisesVersion(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.RLock()
+	current := c.loaded && c.version == version
+	c.mu.RUnlock()
+	if current {
+		return false, nil
+	}
+
+	if err := c.Load(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Watch polls the catalogue version every interval until ctx is cancelled.
+func (c *SystemExerciseCache) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := c.Refresh(ctx)
+			if err != nil {
+				slog.Warn("Failed to refresh system exercise cache", "error", err)
+			} else if reloaded {
+				slog.Info("Reloaded system exercise cache", "version", c.Version())
+			}
+		}
+	}
+}
+
+// Loaded reports whether the cache holds a catalogue.
+func (c *SystemExerciseCache) Loaded() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.loaded
+}
+
+// Version returns the catalogue version currently cached.
+func (c *SystemExerciseCache) Version() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.version
+}
+
+// Get returns the system exercise with the given ID. It reports false, a miss, when the ID is
+// not a system exercise (e.g. a custom exercise) or the cache is not loaded.
+func (c *SystemExerciseCache) Get(ctx context.Context, id string) (*model.UniqueExercise, bool) {
+	c.mu.RLock()
+	exercise, ok := c.byID[id]
+	c.mu.RUnlock()
+
+	c.record(ctx, cacheOpLookup, ok)
+	return exercise, ok
+}
+
+// List returns the non-archived system exercises matching the filter, sorted by name.
+// It reports false if the cache is not loaded.
+func (c *SystemExerciseCache) List(ctx context.Context, filter model.ExerciseFilter) ([]*model.UniqueExercise, bool) {
+	c.mu.RLock()
+	loaded, all := c.loaded, c.exercises
+	c.mu.RUnlock()
+
+	c.record(ctx, cacheOpList, loaded)
+	if !loaded {
+		return nil, false
+	}
+
+	exercises := make([]*model.UniqueExercise, 0, len(all))
+	for _, e := range all {
+		if e.ArchivedAt == nil && filter.Matches(e) {
+			exercises = append(exercises, e)
+		}
+	}
+	return exercises, true
+}
+
+func (c *SystemExerciseCache) record(ctx context.Context, operation string, hit bool) {
+	attrs := metric.WithAttributes(attribute.String("operation", operation))
+	if hit {
+		c.hits.Add(ctx, 1, attrs)
+	} else {
+		c.misses.Add(ctx, 1, attrs)
+	}
+}
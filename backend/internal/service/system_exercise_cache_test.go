@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+func systemCatalogue() []*model.UniqueExercise {
+	barbell := model.EquipmentBarbell
+	dumbbell := model.EquipmentDumbbell
+	return []*model.UniqueExercise{
+		{ID: "sys-bench", Name: "Bench Press", Equipment: &barbell, PrimaryMuscles: []model.MuscleGroup{model.MuscleGroupChest}},
+		{ID: "sys-curl", Name: "Dumbbell Curl", Equipment: &dumbbell, PrimaryMuscles: []model.MuscleGroup{model.MuscleGroupBiceps}},
+		{ID: "sys-squat", Name: "Squat", Equipment: &barbell, PrimaryMuscles: []model.MuscleGroup{model.MuscleGroupQuadriceps}},
+	}
+}
+
+func TestSystemExerciseCache(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	previous := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	t.Cleanup(func() { otel.SetMeterProvider(previous) })
+
+	ctx := context.Background()
+	mockRepo := new(repository.MockExerciseRepository)
+	cache := NewSystemExerciseCache(mockRepo)
+
+	// Not loaded: every request misses
+	_, ok := cache.Get(ctx, "sys-bench")
+	assert.False(t, ok)
+	_, ok = cache.List(ctx, model.ExerciseFilter{})
+	assert.False(t, ok)
+
+	mockRepo.On("SystemExercisesVersion", ctx).Return(4, nil).Once()
+	mockRepo.On("ListSystem", ctx).Return(systemCatalogue(), nil).Once()
+	require.NoError(t, cache.Load(ctx))
+	assert.Equal(t, 4, cache.Version())
+
+	bench, ok := cache.Get(ctx, "sys-bench")
+	assert.True(t, ok)
+	assert.Equal(t, "Bench Press", bench.Name)
+	_, ok = cache.Get(ctx, "custom-1")
+	assert.False(t, ok, "custom exercises are never cached")
+
+	barbell := model.EquipmentBarbell
+	list, ok := cache.List(ctx, model.ExerciseFilter{Equipment: []model.Equipment{barbell}})
+	assert.True(t, ok)
+	assert.Len(t, list, 2)
+
+	// Unchanged version: no reload
+	mockRepo.On("SystemExercisesVersion", ctx).Return(4, nil).Once()
+	reloaded, err := cache.Refresh(ctx)
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+
+	// Bumped version: reload, archived exercises stay resolvable but are not listed
+	updated := systemCatalogue()
+	archivedAt := time.Now()
+	updated[1].ArchivedAt = &archivedAt
+	mockRepo.On("SystemExercisesVersion", ctx).Return(5, nil).Twice()
+	mockRepo.On("ListSystem", ctx).Return(updated, nil).Once()
+	reloaded, err = cache.Refresh(ctx)
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, 5, cache.Version())
+	_, ok = cache.Get(ctx, "sys-curl")
+	assert.True(t, ok)
+	list, _ = cache.List(ctx, model.ExerciseFilter{})
+	assert.Len(t, list, 2)
+	mockRepo.AssertExpectations(t)
+
+	var metrics metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &metrics))
+	counts := map[string]map[string]int64{}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				continue
+			}
+			counts[m.Name] = map[string]int64{}
+			for _, dp := range sum.DataPoints {
+				op, _ := dp.Attributes.Value("operation")
+				counts[m.Name][op.AsString()] = dp.Value
+			}
+		}
+	}
+	assert.Equal(t, map[string]int64{"lookup": 2, "list": 2}, counts["system_exercise_cache.hits"])
+	assert.Equal(t, map[string]int64{"lookup": 2, "list": 1}, counts["system_exercise_cache.misses"])
+}
+
+func TestExerciseServiceUsesSystemCache(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-1"
+	mockRepo := new(repository.MockExerciseRepository)
+	workoutRepo := new(repository.MockWorkoutRepository)
+	service := NewExerciseService(mockRepo, workoutRepo, nil, &repository.MockTransactor{})
+
+	mockRepo.On("SystemExercisesVersion", mock.Anything).Return(1, nil).Once()
+	mockRepo.On("ListSystem", mock.Anything).Return(systemCatalogue(), nil).Once()
+	require.NoError(t, service.SystemCache().Load(ctx))
+
+	t.Run("lookups", func(t *testing.T) {
+		exercise, err := service.GetExercise(ctx, "sys-squat")
+		require.NoError(t, err)
+		assert.Equal(t, "Squat", exercise.Name)
+
+		// Only custom exercises reach the database
+		mockRepo.On("FindByIDs", ctx, []string{"custom-1"}).Return([]*model.UniqueExercise{{ID: "custom-1", Name: "Pause Squat"}}, nil).Once()
+		exercises, err := service.GetExercises(ctx, []string{"sys-bench", "custom-1"})
+		require.NoError(t, err)
+		assert.Equal(t, "Bench Press", exercises[0].Name)
+		assert.Equal(t, "Pause Squat", exercises[1].Name)
+	})
+
+	t.Run("browse merges custom exercises by name", func(t *testing.T) {
+		mockRepo.On("ListCustom", ctx, userID, model.ExerciseFilter{}).Return([]*model.UniqueExercise{{ID: "custom-1", Name: "Pause Squat", UserID: &userID}}, nil).Once()
+
+		exercises, err := service.SearchExercises(ctx, &userID, "", model.ExerciseFilter{}, nil, 2, 1)
+
+		require.NoError(t, err)
+		require.Len(t, exercises, 2)
+		assert.Equal(t, "Dumbbell Curl", exercises[0].Name)
+		assert.Equal(t, "Pause Squat", exercises[1].Name)
+	})
+
+	t.Run("ranked search", func(t *testing.T) {
+		mockRepo.On("ListCustom", ctx, userID, model.ExerciseFilter{}).Return([]*model.UniqueExercise{}, nil).Once()
+		workoutRepo.On("CountExerciseUsage", ctx, userID, mock.Anything).Return(map[string]int{}, nil).Once()
+
+		exercises, err := service.SearchExercises(ctx, &userID, "squat", model.ExerciseFilter{}, nil, 10, 0)
+
+		require.NoError(t, err)
+		require.NotEmpty(t, exercises)
+		assert.Equal(t, "sys-squat", exercises[0].ID)
+	})
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "ListVisible", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -23,6 +25,11 @@ func NewTokenService(repo repository.RefreshTokenRepository) *TokenService {
 
 // GenerateRefreshToken creates a random token and its hash.
 func (s *TokenService) GenerateRefreshToken() (secret string, hash string, err error) {
+	return newHashedSecret()
+}
+
+// newHashedSecret creates a random secret and its hash, for tokens stored hashed like refresh tokens.
+func newHashedSecret() (secret string, hash string, err error) {
 	// 32 bytes of random data
 	b := make([]byte, 32)
 	if _, err := rand.Read(b); err != nil {
@@ -39,79 +46,199 @@ func (s *TokenService) GenerateRefreshToken() (secret string, hash string, err e
 	return secret, string(hashBytes), nil
 }
 
-// CreateCompositeRefreshToken generates a new token, saves it, and returns "ID.Secret".
-func (s *TokenService) CreateCompositeRefreshToken(ctx context.Context, userID string) (string, error) {
+// ErrRefreshTokenReused is returned when a token that was already exchanged for a new one is
+// presented again. Its whole family has been revoked, so every session from that login must sign in again.
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// maxUserAgentLength caps the user agent stored with a session.
+const maxUserAgentLength = 512
+
+// CreateCompositeRefreshToken generates a new token starting a new session (token family) for
+// the client, saves it, and returns "ID.Secret" along with the stored token.
+func (s *TokenService) CreateCompositeRefreshToken(ctx context.Context, userID string, client model.SessionClient) (string, *model.RefreshToken, error) {
+	now := time.Now()
+	return s.createRefreshToken(ctx, &model.RefreshToken{
+		UserID:     userID,
+		UserAgent:  truncateUserAgent(client.UserAgent),
+		IP:         client.IP,
+		SignedInAt: now,
+		LastUsedAt: now,
+	})
+}
+
+// createRefreshToken generates the secret of a token describing its session, and saves it.
+func (s *TokenService) createRefreshToken(ctx context.Context, refreshToken *model.RefreshToken) (string, *model.RefreshToken, error) {
 	secret, hash, err := s.GenerateRefreshToken()
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	refreshToken := &model.RefreshToken{
-		UserID:    userID,
-		TokenHash: hash,
-		ExpiresAt: time.Now().Add(7 * 24 * time.Hour), // 7 days
-		CreatedAt: time.Now(),
-	}
+	refreshToken.TokenHash = hash
+	refreshToken.ExpiresAt = time.Now().Add(7 * 24 * time.Hour) // 7 days
+	refreshToken.CreatedAt = time.Now()
 
 	if err := s.repo.Create(ctx, refreshToken); err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	return fmt.Sprintf("%s.%s", refreshToken.ID, secret), nil
+	return fmt.Sprintf("%s.%s", refreshToken.ID, secret), refreshToken, nil
 }
 
-// ValidateRotate validates the old refresh token, revokes it (rotation), and issues a new one.
-// Returns: newCompositeToken, userID, error
-func (s *TokenService) ValidateRotate(ctx context.Context, compositeToken string) (string, string, error) {
+// ValidateRotate validates the old refresh token, marks it as rotated, and issues a new one in
+// the same family. Presenting a token that was already rotated means it was copied, so the whole
+// family is revoked and ErrRefreshTokenReused returned.
+// The new token records the client refreshing the session.
+// Returns: newCompositeToken, newToken, error
+func (s *TokenService) ValidateRotate(ctx context.Context, compositeToken string, client model.SessionClient) (string, *model.RefreshToken, error) {
 	// 1. Split "ID.Secret"
 	parts := strings.Split(compositeToken, ".")
 	if len(parts) != 2 {
-		return "", "", fmt.Errorf("invalid token format")
+		return "", nil, fmt.Errorf("invalid token format")
 	}
 	tokenID, secret := parts[0], parts[1]
 
 	// 2. Find in DB
 	storedToken, err := s.repo.FindByID(ctx, tokenID)
 	if err != nil {
-		return "", "", fmt.Errorf("database error: %w", err)
+		return "", nil, fmt.Errorf("database error: %w", err)
 	}
 	if storedToken == nil {
-		return "", "", fmt.Errorf("invalid refresh token (not found)")
+		return "", nil, fmt.Errorf("invalid refresh token (not found)")
 	}
 
 	// 3. Check Expiration
 	if time.Now().After(storedToken.ExpiresAt) {
 		// Cleanup (fire and forget or do it now)
 		_ = s.repo.Revoke(ctx, tokenID)
-		return "", "", fmt.Errorf("refresh token expired")
+		return "", nil, fmt.Errorf("refresh token expired")
 	}
 
-	// 4. Validate Secret against Hash
+	// 4. Validate Secret against Hash; only the token's holder can trigger reuse detection
 	if err := bcrypt.CompareHashAndPassword([]byte(storedToken.TokenHash), []byte(secret)); err != nil {
-		// Potential reuse detection could go here (if we kept history)
-		return "", "", fmt.Errorf("invalid token signature")
+		return "", nil, fmt.Errorf("invalid token signature")
 	}
 
-	// 5. Revoke Old Token (Rotation)
-	if err := s.repo.Revoke(ctx, tokenID); err != nil {
-		return "", "", fmt.Errorf("failed to revoke used token: %w", err)
+	// 5. Mark Old Token as Rotated; a token rotated before, even concurrently, is being reused
+	if storedToken.RotatedAt != nil {
+		return "", nil, s.revokeReusedFamily(ctx, storedToken)
+	}
+	rotated, err := s.repo.MarkRotated(ctx, tokenID, time.Now())
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to rotate used token: %w", err)
+	}
+	if !rotated {
+		return "", nil, s.revokeReusedFamily(ctx, storedToken)
 	}
 
-	// 6. Issue New Token
-	newToken, err := s.CreateCompositeRefreshToken(ctx, storedToken.UserID)
+	// 6. Issue New Token, carrying the session over
+	next := &model.RefreshToken{
+		UserID:     storedToken.UserID,
+		FamilyID:   storedToken.FamilyID,
+		UserAgent:  storedToken.UserAgent,
+		IP:         storedToken.IP,
+		SignedInAt: storedToken.SignedInAt,
+		LastUsedAt: time.Now(),
+	}
+	if client.UserAgent != "" {
+		next.UserAgent = truncateUserAgent(client.UserAgent)
+	}
+	if client.IP != "" {
+		next.IP = client.IP
+	}
+	if next.SignedInAt.IsZero() {
+		// Issued before sessions were recorded
+		next.SignedInAt = storedToken.CreatedAt
+	}
+	newToken, newStoredToken, err := s.createRefreshToken(ctx, next)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to rotate token: %w", err)
+		return "", nil, fmt.Errorf("failed to rotate token: %w", err)
 	}
 
-	return newToken, storedToken.UserID, nil
+	return newToken, newStoredToken, nil
 }
 
-// Revoke allows manual revocation (e.g. logout).
+// revokeReusedFamily revokes the family of a reused token and logs the likely theft.
+func (s *TokenService) revokeReusedFamily(ctx context.Context, token *model.RefreshToken) error {
+	revoked, err := s.repo.RevokeFamily(ctx, token.FamilyID)
+	slog.Warn("Security event: rotated refresh token reused, revoking its family",
+		"event", "refresh_token_reuse",
+		"user_id", token.UserID,
+		"family_id", token.FamilyID,
+		"token_id", token.ID,
+		"revoked", revoked,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke reused token family: %w", err)
+	}
+	return ErrRefreshTokenReused
+}
+
+// Revoke allows manual revocation (e.g. logout). It revokes the token's whole family, including
+// the rotated tokens kept for reuse detection.
 func (s *TokenService) Revoke(ctx context.Context, compositeToken string) error {
 	parts := strings.Split(compositeToken, ".")
 	if len(parts) != 2 {
 		return nil // checking valid format not strictly necessary for simple logout, but good practice
 	}
 	tokenID := parts[0]
-	return s.repo.Revoke(ctx, tokenID)
+	storedToken, err := s.repo.FindByID(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	if storedToken == nil {
+		return nil
+	}
+	_, err = s.repo.RevokeFamily(ctx, storedToken.FamilyID)
+	return err
+}
+
+// ErrSessionNotFound is returned when revoking a session the user does not have.
+var ErrSessionNotFound = errors.New("session not found")
+
+// ListSessions returns the user's signed-in sessions, most recently used first, marking the
+// session with currentSessionID as current.
+func (s *TokenService) ListSessions(ctx context.Context, userID, currentSessionID string) ([]*model.Session, error) {
+	tokens, err := s.repo.ListActiveByUser(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]*model.Session, len(tokens))
+	for i, token := range tokens {
+		sessions[i] = &model.Session{
+			ID:         token.FamilyID,
+			Device:     describeUserAgent(token.UserAgent),
+			UserAgent:  token.UserAgent,
+			IP:         token.IP,
+			SignedInAt: token.SignedInAt,
+			LastUsedAt: token.LastUsedAt,
+			Current:    currentSessionID != "" && token.FamilyID == currentSessionID,
+		}
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs out one of the user's sessions.
+func (s *TokenService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	revoked, err := s.repo.RevokeFamilyForUser(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeOtherSessions signs out every session of the user but currentSessionID. With no current
+// session, every session is signed out.
+func (s *TokenService) RevokeOtherSessions(ctx context.Context, userID, currentSessionID string) error {
+	_, err := s.repo.RevokeAllForUserExcept(ctx, userID, currentSessionID)
+	return err
+}
+
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) <= maxUserAgentLength {
+		return userAgent
+	}
+	return strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
 }
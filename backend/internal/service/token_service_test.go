@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+// newTokenRepo returns a mock repository that keeps created tokens, assigning IDs and families
+// as the Mongo repository does.
+func newTokenRepo() (*repository.MockRefreshTokenRepository, map[string]*model.RefreshToken) {
+	repo := new(repository.MockRefreshTokenRepository)
+	stored := map[string]*model.RefreshToken{}
+	repo.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		token := args.Get(1).(*model.RefreshToken)
+		token.ID = fmt.Sprintf("token-%d", len(stored)+1)
+		if token.FamilyID == "" {
+			token.FamilyID = token.ID
+		}
+		stored[token.ID] = token
+	}).Return(nil)
+	return repo, stored
+}
+
+func tokenID(composite string) string {
+	id, _, _ := strings.Cut(composite, ".")
+	return id
+}
+
+func TestTokenServiceValidateRotate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Rotates within the family", func(t *testing.T) {
+		repo, stored := newTokenRepo()
+		s := NewTokenService(repo)
+
+		first, _, err := s.CreateCompositeRefreshToken(ctx, "user-1", model.SessionClient{UserAgent: "Firefox/120.0", IP: "203.0.113.7"})
+		require.NoError(t, err)
+		firstID := tokenID(first)
+		repo.On("FindByID", ctx, firstID).Return(stored[firstID], nil)
+		repo.On("MarkRotated", ctx, firstID, mock.Anything).Return(true, nil)
+
+		second, session, err := s.ValidateRotate(ctx, first, model.SessionClient{IP: "198.51.100.2"})
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", session.UserID)
+		assert.NotEqual(t, firstID, tokenID(second))
+		assert.Equal(t, firstID, session.FamilyID)
+		assert.Equal(t, stored[tokenID(second)], session)
+
+		// The session keeps its start and user agent, and records the new address
+		assert.Equal(t, stored[firstID].SignedInAt, session.SignedInAt)
+		assert.Equal(t, "Firefox/120.0", session.UserAgent)
+		assert.Equal(t, "198.51.100.2", session.IP)
+		assert.False(t, session.LastUsedAt.Before(stored[firstID].LastUsedAt))
+		repo.AssertNotCalled(t, "RevokeFamily", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Revokes the family when a rotated token is reused", func(t *testing.T) {
+		repo, stored := newTokenRepo()
+		s := NewTokenService(repo)
+
+		first, _, err := s.CreateCompositeRefreshToken(ctx, "user-1", model.SessionClient{UserAgent: "Firefox/120.0", IP: "203.0.113.7"})
+		require.NoError(t, err)
+		rotatedAt := time.Now()
+		reused := *stored[tokenID(first)]
+		reused.RotatedAt = &rotatedAt
+		repo.On("FindByID", ctx, reused.ID).Return(&reused, nil)
+		repo.On("RevokeFamily", ctx, reused.FamilyID).Return(int64(3), nil)
+
+		_, _, err = s.ValidateRotate(ctx, first, model.SessionClient{})
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "MarkRotated", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Treats losing a concurrent rotation as reuse", func(t *testing.T) {
+		repo, stored := newTokenRepo()
+		s := NewTokenService(repo)
+
+		first, _, err := s.CreateCompositeRefreshToken(ctx, "user-1", model.SessionClient{UserAgent: "Firefox/120.0", IP: "203.0.113.7"})
+		require.NoError(t, err)
+		firstID := tokenID(first)
+		repo.On("FindByID", ctx, firstID).Return(stored[firstID], nil)
+		repo.On("MarkRotated", ctx, firstID, mock.Anything).Return(false, nil)
+		repo.On("RevokeFamily", ctx, firstID).Return(int64(1), nil)
+
+		_, _, err = s.ValidateRotate(ctx, first, model.SessionClient{})
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+		assert.Len(t, stored, 1, "no new token should be issued")
+	})
+
+	t.Run("Does not revoke the family for a wrong secret", func(t *testing.T) {
+		repo, stored := newTokenRepo()
+		s := NewTokenService(repo)
+
+		first, _, err := s.CreateCompositeRefreshToken(ctx, "user-1", model.SessionClient{UserAgent: "Firefox/120.0", IP: "203.0.113.7"})
+		require.NoError(t, err)
+		rotatedAt := time.Now()
+		reused := *stored[tokenID(first)]
+		reused.RotatedAt = &rotatedAt
+		repo.On("FindByID", ctx, reused.ID).Return(&reused, nil)
+
+		_, _, err = s.ValidateRotate(ctx, reused.ID+".forged", model.SessionClient{})
+		assert.EqualError(t, err, "invalid token signature")
+		repo.AssertNotCalled(t, "RevokeFamily", mock.Anything, mock.Anything)
+	})
+}
+
+func TestTokenServiceRevoke(t *testing.T) {
+	ctx := context.Background()
+	repo := new(repository.MockRefreshTokenRepository)
+	repo.On("FindByID", ctx, "token-2").Return(&model.RefreshToken{ID: "token-2", FamilyID: "token-1"}, nil)
+	repo.On("RevokeFamily", ctx, "token-1").Return(int64(2), nil)
+
+	require.NoError(t, NewTokenService(repo).Revoke(ctx, "token-2.secret"))
+	repo.AssertExpectations(t)
+}
+
+func TestTokenServiceSessions(t *testing.T) {
+	ctx := context.Background()
+	repo := new(repository.MockRefreshTokenRepository)
+	s := NewTokenService(repo)
+	signedIn := time.Now().Add(-time.Hour)
+
+	repo.On("ListActiveByUser", ctx, "user-1", mock.Anything).Return([]*model.RefreshToken{
+		{ID: "token-3", FamilyID: "token-1", UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:120.0) Gecko/20100101 Firefox/120.0", IP: "203.0.113.7", SignedInAt: signedIn, LastUsedAt: time.Now()},
+		{ID: "token-2", FamilyID: "token-2", UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1", SignedInAt: signedIn, LastUsedAt: signedIn},
+	}, nil)
+
+	sessions, err := s.ListSessions(ctx, "user-1", "token-2")
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "token-1", sessions[0].ID)
+	assert.Equal(t, "Firefox on Windows", sessions[0].Device)
+	assert.False(t, sessions[0].Current)
+	assert.Equal(t, "Safari on iOS", sessions[1].Device)
+	assert.True(t, sessions[1].Current)
+
+	t.Run("Revokes a session of the user", func(t *testing.T) {
+		repo.On("RevokeFamilyForUser", ctx, "user-1", "token-1").Return(int64(3), nil).Once()
+		assert.NoError(t, s.RevokeSession(ctx, "user-1", "token-1"))
+	})
+
+	t.Run("Fails for a session the user does not have", func(t *testing.T) {
+		repo.On("RevokeFamilyForUser", ctx, "user-1", "someone-else").Return(int64(0), nil).Once()
+		assert.ErrorIs(t, s.RevokeSession(ctx, "user-1", "someone-else"), ErrSessionNotFound)
+	})
+
+	t.Run("Keeps the current session when revoking the others", func(t *testing.T) {
+		repo.On("RevokeAllForUserExcept", ctx, "user-1", "token-2").Return(int64(3), nil).Once()
+		assert.NoError(t, s.RevokeOtherSessions(ctx, "user-1", "token-2"))
+		repo.AssertExpectations(t)
+	})
+}
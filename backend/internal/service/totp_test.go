@@ -0,0 +1,78 @@
+package service
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 lists 8 digit codes; ours are their last 6 digits
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range vectors {
+		code, err := totpCode(rfcSecret, totpStep(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, want, code, "at %d", unix)
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step := totpStep(now)
+
+	t.Run("Accepts codes one step either side", func(t *testing.T) {
+		for _, s := range []int64{step - 1, step, step + 1} {
+			code, _ := totpCode(rfcSecret, s)
+			matched, ok := matchTOTP(rfcSecret, code, now, 0)
+			assert.True(t, ok)
+			assert.Equal(t, s, matched)
+		}
+	})
+
+	t.Run("Rejects older codes", func(t *testing.T) {
+		code, _ := totpCode(rfcSecret, step-2)
+		_, ok := matchTOTP(rfcSecret, code, now, 0)
+		assert.False(t, ok)
+	})
+
+	t.Run("Rejects a code already used", func(t *testing.T) {
+		code, _ := totpCode(rfcSecret, step)
+		_, ok := matchTOTP(rfcSecret, code, now, step)
+		assert.False(t, ok)
+	})
+
+	t.Run("Ignores spaces", func(t *testing.T) {
+		_, ok := matchTOTP(rfcSecret, " 081 804 ", now, 0)
+		assert.True(t, ok)
+	})
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := totpURI("Fitness App", "jane@example.com", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Fitness%20App:jane@example.com?"), uri)
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=Fitness+App")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := newRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, recoveryCodeCount)
+	assert.Regexp(t, `^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`, codes[0])
+
+	assert.Equal(t, 3, matchRecoveryCode(hashes, codes[3]))
+	assert.Equal(t, 3, matchRecoveryCode(hashes, strings.ToUpper(strings.ReplaceAll(codes[3], "-", ""))))
+	assert.Equal(t, -1, matchRecoveryCode(hashes, "aaaa-bbbb-cccc"))
+}
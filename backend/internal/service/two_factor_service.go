@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+// twoFactorChallengeTTL is how long after entering their password a user has to enter their code.
+const twoFactorChallengeTTL = 5 * time.Minute
+
+var (
+	// ErrInvalidTwoFactorCode is returned for a wrong, reused or expired authenticator or recovery code.
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrInvalidTwoFactorChallenge is returned for an unknown, expired or already used challenge token.
+	ErrInvalidTwoFactorChallenge = errors.New("this sign-in attempt has expired; please sign in again")
+	// ErrTwoFactorAlreadyEnabled is returned when enrolling while two-factor authentication is on.
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnabled is returned when changing two-factor settings that are not on.
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+)
+
+// TOTPEnrollment is what an authenticator app needs to generate codes for the account.
+type TOTPEnrollment struct {
+	Secret string
+	// URI is the otpauth:// URI, usually shown as a QR code
+	URI string
+}
+
+// TwoFactorService handles optional TOTP two-factor authentication: enrolment, one-time recovery
+// codes, and the second step of login. Codes tried are rate limited per user.
+type TwoFactorService struct {
+	users   repository.UserRepository
+	tokens  repository.ActionTokenRepository
+	limiter *RateLimiter
+	issuer  string
+	now     func() time.Time
+}
+
+// NewTwoFactorService creates a TwoFactorService. Authenticator apps list the account under issuer.
+func NewTwoFactorService(users repository.UserRepository, tokens repository.ActionTokenRepository, limiter *RateLimiter, issuer string) *TwoFactorService {
+	return &TwoFactorService{
+		users:   users,
+		tokens:  tokens,
+		limiter: limiter,
+		issuer:  issuer,
+		now:     time.Now,
+	}
+}
+
+// Enroll generates a new TOTP secret for the user. It is only required at login once confirmed
+// with a code from the authenticator app.
+func (s *TwoFactorService) Enroll(ctx context.Context, userID, currentPassword string) (*TOTPEnrollment, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCurrentPassword(user, currentPassword); err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	// Saved only while two-factor authentication is off, so a concurrent confirmation is not undone
+	ok, err := s.users.SetTOTPSecret(ctx, user.ID, secret)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	return &TOTPEnrollment{Secret: secret, URI: totpURI(s.issuer, user.Email, secret)}, nil
+}
+
+// Confirm turns on two-factor authentication once the user enters a code for the enrolled
+// secret, and returns the recovery codes. They are only stored hashed, so cannot be shown again.
+func (s *TwoFactorService) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, fmt.Errorf("start two-factor enrolment first")
+	}
+	// Recovery codes are not issued yet, so only the authenticator app's codes are accepted
+	if err := s.verifyCode(ctx, user, code, false); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	// Fails if the user has since enrolled again, so the secret confirmed is the one turned on
+	ok, err := s.users.EnableTwoFactor(ctx, user.ID, user.TOTPSecret, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	user.TOTPEnabled = true
+	user.RecoveryCodeHashes = hashes
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes with new ones, after checking a
+// code from the authenticator app or an unused recovery code.
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := s.findEnabledUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyCode(ctx, user, code, true); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.users.SetRecoveryCodes(ctx, user.ID, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	user.RecoveryCodeHashes = hashes
+	return codes, nil
+}
+
+// Disable turns off two-factor authentication, after checking the password and a code.
+func (s *TwoFactorService) Disable(ctx context.Context, userID, currentPassword, code string) (*model.User, error) {
+	user, err := s.findEnabledUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCurrentPassword(user, currentPassword); err != nil {
+		return nil, err
+	}
+	if err := s.verifyCode(ctx, user, code, true); err != nil {
+		return nil, err
+	}
+
+	if err := s.users.DisableTwoFactor(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	user.RecoveryCodeHashes = nil
+	return user, nil
+}
+
+// StartChallenge returns a short-lived, single-use token standing for the password the user has
+// just entered, to be exchanged for a session with VerifyChallenge.
+func (s *TwoFactorService) StartChallenge(ctx context.Context, user *model.User) (string, error) {
+	return issueActionToken(ctx, s.tokens, user, model.ActionTokenTwoFactorChallenge, user.Email, twoFactorChallengeTTL)
+}
+
+// VerifyChallenge completes a login: it returns the user of the challenge token if the code, from
+// the authenticator app or an unused recovery code, is valid. The token can only be used once.
+func (s *TwoFactorService) VerifyChallenge(ctx context.Context, challengeToken, code string) (*model.User, error) {
+	challenge, err := lookupActionToken(ctx, s.tokens, challengeToken, model.ActionTokenTwoFactorChallenge)
+	if errors.Is(err, ErrInvalidActionToken) {
+		return nil, ErrInvalidTwoFactorChallenge
+	} else if err != nil {
+		return nil, err
+	}
+	user, err := s.findEnabledUser(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyCode(ctx, user, code, true); err != nil {
+		return nil, err
+	}
+
+	if err := consumeActionToken(ctx, s.tokens, challenge); err != nil {
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+	return user, nil
+}
+
+// verifyCode checks a code from the authenticator app or, if allowed, a recovery code, which is
+// then used up. The code is only accepted if the repository records its use, so each is accepted
+// once even by concurrent requests.
+func (s *TwoFactorService) verifyCode(ctx context.Context, user *model.User, code string, allowRecovery bool) error {
+	key := TwoFactorKey(user.ID)
+	// The attempt is counted before the code is checked, so parallel guesses share the limit
+	if err := s.limiter.Attempt(ctx, key); err != nil {
+		return err
+	}
+
+	used, err := s.useCode(ctx, user, code, allowRecovery)
+	if err != nil {
+		if err := s.limiter.Forgive(ctx, key); err != nil {
+			slog.Error("Failed to forgive two-factor attempt", "user_id", user.ID, "error", err)
+		}
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+
+	if err := s.limiter.Reset(ctx, key); err != nil {
+		slog.Warn("Failed to reset two-factor rate limit", "user_id", user.ID, "error", err)
+	}
+	return nil
+}
+
+// useCode records the use of a matching code, reporting false for a code that does not match or
+// was already used.
+func (s *TwoFactorService) useCode(ctx context.Context, user *model.User, code string, allowRecovery bool) (bool, error) {
+	if step, ok := matchTOTP(user.TOTPSecret, code, s.now(), user.TOTPLastStep); ok {
+		used, err := s.users.UseTOTPStep(ctx, user.ID, user.TOTPSecret, step)
+		if err != nil || !used {
+			return false, err
+		}
+		user.TOTPLastStep = step
+		return true, nil
+	}
+
+	i := matchRecoveryCode(user.RecoveryCodeHashes, code)
+	if !allowRecovery || i < 0 {
+		return false, nil
+	}
+	used, err := s.users.UseRecoveryCode(ctx, user.ID, user.RecoveryCodeHashes[i])
+	if err != nil || !used {
+		return false, err
+	}
+	user.RecoveryCodeHashes = slices.Delete(slices.Clone(user.RecoveryCodeHashes), i, i+1)
+	slog.Info("Recovery code used", "user_id", user.ID, "remaining", len(user.RecoveryCodeHashes))
+	return true, nil
+}
+
+func (s *TwoFactorService) findUser(ctx context.Context, userID string) (*model.User, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	return user, nil
+}
+
+func (s *TwoFactorService) findEnabledUser(ctx context.Context, userID string) (*model.User, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	return user, nil
+}
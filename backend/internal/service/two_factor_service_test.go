@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/riverajo/fitness-app/backend/internal/model"
+	"github.com/riverajo/fitness-app/backend/internal/repository"
+)
+
+type twoFactorFixture struct {
+	service *TwoFactorService
+	users   *repository.MockUserRepository
+	tokens  *repository.MockActionTokenRepository
+	user    *model.User
+	now     time.Time
+}
+
+func newTwoFactorFixture(t *testing.T) *twoFactorFixture {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	f := &twoFactorFixture{
+		users:  new(repository.MockUserRepository),
+		tokens: new(repository.MockActionTokenRepository),
+		user:   &model.User{ID: "user-1", Email: "jane@example.com", PasswordHash: string(hash)},
+		now:    time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	f.service = NewTwoFactorService(f.users, f.tokens, NewRateLimiter(repository.NewMemoryRateLimitRepository()), "Fitness App")
+	f.service.now = func() time.Time { return f.now }
+	// A copy each time, like the database; f.user is the stored user
+	findByID := f.users.On("FindByID", mock.Anything, "user-1")
+	findByID.Run(func(mock.Arguments) {
+		user := *f.user
+		findByID.ReturnArguments = mock.Arguments{&user, nil}
+	})
+	f.mockTwoFactorUpdates()
+	return f
+}
+
+// mockTwoFactorUpdates makes the user repository mock apply the conditional two-factor updates to
+// the user, as the database would.
+func (f *twoFactorFixture) mockTwoFactorUpdates() {
+	update := func(method string, apply func(args mock.Arguments) bool, arguments ...any) {
+		call := f.users.On(method, arguments...)
+		call.Run(func(args mock.Arguments) {
+			call.ReturnArguments = mock.Arguments{apply(args), nil}
+		})
+	}
+	update("SetTOTPSecret", func(args mock.Arguments) bool {
+		if f.user.TOTPEnabled {
+			return false
+		}
+		f.user.TOTPSecret, f.user.TOTPLastStep = args.String(2), 0
+		return true
+	}, mock.Anything, "user-1", mock.Anything)
+	update("EnableTwoFactor", func(args mock.Arguments) bool {
+		if f.user.TOTPEnabled || f.user.TOTPSecret != args.String(2) {
+			return false
+		}
+		f.user.TOTPEnabled, f.user.RecoveryCodeHashes = true, args.Get(3).([]string)
+		return true
+	}, mock.Anything, "user-1", mock.Anything, mock.Anything)
+	update("SetRecoveryCodes", func(args mock.Arguments) bool {
+		if !f.user.TOTPEnabled {
+			return false
+		}
+		f.user.RecoveryCodeHashes = args.Get(2).([]string)
+		return true
+	}, mock.Anything, "user-1", mock.Anything)
+	update("UseTOTPStep", func(args mock.Arguments) bool {
+		step := args.Get(3).(int64)
+		if f.user.TOTPSecret != args.String(2) || f.user.TOTPLastStep >= step {
+			return false
+		}
+		f.user.TOTPLastStep = step
+		return true
+	}, mock.Anything, "user-1", mock.Anything, mock.Anything)
+	update("UseRecoveryCode", func(args mock.Arguments) bool {
+		i := slices.Index(f.user.RecoveryCodeHashes, args.String(2))
+		if i < 0 {
+			return false
+		}
+		f.user.RecoveryCodeHashes = slices.Delete(slices.Clone(f.user.RecoveryCodeHashes), i, i+1)
+		return true
+	}, mock.Anything, "user-1", mock.Anything)
+	f.users.On("DisableTwoFactor", mock.Anything, "user-1").Run(func(mock.Arguments) {
+		f.user.TOTPEnabled, f.user.TOTPSecret, f.user.TOTPLastStep, f.user.RecoveryCodeHashes = false, "", 0, nil
+	}).Return(nil)
+}
+
+// code returns the authenticator app's current code for the user.
+func (f *twoFactorFixture) code(t *testing.T) string {
+	code, err := totpCode(f.user.TOTPSecret, totpStep(f.now))
+	require.NoError(t, err)
+	return code
+}
+
+// enable turns on two-factor authentication for the user and returns the recovery codes.
+func (f *twoFactorFixture) enable(t *testing.T) []string {
+	ctx := context.Background()
+	_, err := f.service.Enroll(ctx, "user-1", "password123")
+	require.NoError(t, err)
+	codes, err := f.service.Confirm(ctx, "user-1", f.code(t))
+	require.NoError(t, err)
+	f.now = f.now.Add(totpPeriod)
+	return codes
+}
+
+func TestTwoFactorEnrollment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Turns on after confirming a code", func(t *testing.T) {
+		f := newTwoFactorFixture(t)
+		enrollment, err := f.service.Enroll(ctx, "user-1", "password123")
+		require.NoError(t, err)
+		assert.Equal(t, f.user.TOTPSecret, enrollment.Secret)
+		assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+		assert.False(t, f.user.TOTPEnabled, "enrolling alone must not turn it on")
+
+		codes, err := f.service.Confirm(ctx, "user-1", f.code(t))
+		require.NoError(t, err)
+		assert.True(t, f.user.TOTPEnabled)
+		assert.Len(t, codes, recoveryCodeCount)
+		assert.Len(t, f.user.RecoveryCodeHashes, recoveryCodeCount)
+		assert.NotContains(t, f.user.RecoveryCodeHashes, codes[0], "recovery codes are stored hashed")
+	})
+
+	t.Run("Rejects a wrong code", func(t *testing.T) {
+		f := newTwoFactorFixture(t)
+		_, err := f.service.Enroll(ctx, "user-1", "password123")
+		require.NoError(t, err)
+
+		_, err = f.service.Confirm(ctx, "user-1", "000000")
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+		assert.False(t, f.user.TOTPEnabled)
+	})
+
+	t.Run("Requires the password", func(t *testing.T) {
+		f := newTwoFactorFixture(t)
+		_, err := f.service.Enroll(ctx, "user-1", "wrong")
+		assert.EqualError(t, err, "invalid current password")
+	})
+
+	t.Run("Asks an account without a password to set one", func(t *testing.T) {
+		f := newTwoFactorFixture(t)
+		f.user.PasswordHash = ""
+		_, err := f.service.Enroll(ctx, "user-1", "")
+		assert.ErrorIs(t, err, ErrNoPassword)
+	})
+
+	t.Run("Cannot enroll again while enabled", func(t *testing.T) {
+		f := newTwoFactorFixture(t)
+		f.enable(t)
+		_, err := f.service.Enroll(ctx, "user-1", "password123")
+		assert.ErrorIs(t, err, ErrTwoFactorAlreadyEnabled)
+	})
+
+	t.Run("Disables with the password and a code", func(t *testing.T) {
+		f := newTwoFactorFixture(t)
+		f.enable(t)
+		user, err := f.service.Disable(ctx, "user-1", "password123", f.code(t))
+		require.NoError(t, err)
+		assert.False(t, user.TOTPEnabled)
+		assert.Empty(t, user.TOTPSecret)
+		assert.Empty(t, user.RecoveryCodeHashes)
+	})
+}
+
+func TestTwoFactorChallenge(t *testing.T) {
+	ctx := context.Background()
+
+	// startChallenge makes the action token mock keep the issued challenge
+	startChallenge := func(t *testing.T, f *twoFactorFixture) string {
+		stored := &model.ActionToken{}
+		f.tokens.On("DeleteForUser", mock.Anything, "user-1", model.ActionTokenTwoFactorChallenge).Return(nil)
+		f.tokens.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			token := args.Get(1).(*model.ActionToken)
+			token.ID = "challenge-1"
+			*stored = *token
+		}).Return(nil)
+		f.tokens.On("FindByID", mock.Anything, "challenge-1").Return(stored, nil)
+
+		challenge, err := f.service.StartChallenge(ctx, f.user)
+		require.NoError(t, err)
+		return challenge
+	}
+
+	t.Run("Completes with the authenticator code", func(t *testing.T) {
+		f := newTwoFactorFixture(t)
+		f.enable(t)
+		challenge := startChallenge(t, f)
+		f.tokens.On("Consume", mock.Anything, "challenge-1").Return(true, nil).Once()
+
+		code := f.code(t)
+		user, err := f.service.VerifyChallenge(ctx, challenge, code)
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", user.ID)
+
+		// The same code cannot be used for another sign-in
+		_, err = f.service.VerifyChallenge(ctx, challenge, code)
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	})
+
+	t.Run("Rejects a code another request has just used", func(t *testing.T) {
+		f := newTwoFactorFixture(t)
+		codes := f.enable(t)
+		challenge := startChallenge(t, f)
+		// Both requests loaded the user before either recorded the code's use
+		loaded := *f.user
+		f.users.ExpectedCalls = slices.DeleteFunc(f.users.ExpectedCalls, func(call *mock.Call) bool {
+			return call.Method == "FindByID"
+		})
+		call := f.users.On("FindByID", mock.Anything, "user-1")
+		call.Run(func(mock.Arguments) {
+			user := loaded
+			call.ReturnArguments = mock.Arguments{&user, nil}
+		})
+		f.tokens.On("Consume", mock.Anything, "challenge-1").Return(true, nil)
+
+		for _, code := range []string{f.code(t), codes[0]} {
+			_, err := f.service.VerifyChallenge(ctx, challenge, code)
+			require.NoError(t, err)
+			_, err = f.service.VerifyChallenge(ctx, challenge, code)
+			assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+		}
+	})
+
+	t.Run("Uses up a recovery code", func(t *testing.T) {
+		f := newTwoFactorFixture(t)
+		codes := f.enable(t)
+		challenge := startChallenge(t, f)
+		f.tokens.On("Consume", mock.Anything, "challenge-1").Return(true, nil)
+
+		_, err := f.service.VerifyChallenge(ctx, challenge, codes[2])
+		require.NoError(t, err)
+		assert.Len(t, f.user.RecoveryCodeHashes, recoveryCodeCount-1)
+
+		_, err = f.service.VerifyChallenge(ctx, challenge, codes[2])
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	})
+
+	t.Run("Fails once the challenge is used", func(t *testing.T) {
+		f := newTwoFactorFixture(t)
+		f.enable(t)
+		challenge := startChallenge(t, f)
+		f.tokens.On("Consume", mock.Anything, "challenge-1").Return(false, nil)
+
+		_, err := f.service.VerifyChallenge(ctx, challenge, f.code(t))
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorChallenge)
+	})
+
+	t.Run("Fails for an unknown challenge", func(t *testing.T) {
+		f := newTwoFactorFixture(t)
+		f.tokens.On("FindByID", mock.Anything, "unknown").Return(nil, nil)
+
+		_, err := f.service.VerifyChallenge(ctx, "unknown.secret", "123456")
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorChallenge)
+	})
+
+	t.Run("Rate limits wrong codes", func(t *testing.T) {
+		f := newTwoFactorFixture(t)
+		f.enable(t)
+		challenge := startChallenge(t, f)
+
+		for range LoginAccountPolicy.FreeAttempts {
+			_, err := f.service.VerifyChallenge(ctx, challenge, "000000")
+			require.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+		}
+		_, err := f.service.VerifyChallenge(ctx, challenge, f.code(t))
+		var limited *RateLimitedError
+		assert.True(t, errors.As(err, &limited), "got %v", err)
+	})
+}
@@ -0,0 +1,53 @@
+package service
+
+import "strings"
+
+// Markers are checked in order, as user agents also name the engines and browsers
+// they claim compatibility with (Chrome's mentions Safari; Edge's mentions Chrome).
+var (
+	browserMarkers = []struct{ marker, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"SamsungBrowser/", "Samsung Internet"},
+		{"Firefox/", "Firefox"},
+		{"FxiOS/", "Firefox"},
+		{"CriOS/", "Chrome"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	}
+	osMarkers = []struct{ marker, name string }{
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"CrOS", "ChromeOS"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// describeUserAgent returns a short description of the browser and OS in a user agent, such as
+// "Firefox on Windows", for listing sessions. Unrecognised parts are left out.
+func describeUserAgent(userAgent string) string {
+	browser := firstMarker(userAgent, browserMarkers)
+	platform := firstMarker(userAgent, osMarkers)
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	default:
+		return "Unknown device"
+	}
+}
+
+func firstMarker(userAgent string, markers []struct{ marker, name string }) string {
+	for _, m := range markers {
+		if strings.Contains(userAgent, m.marker) {
+			return m.name
+		}
+	}
+	return ""
+}
@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/mail"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -11,18 +14,37 @@ import (
 	"github.com/riverajo/fitness-app/backend/internal/repository"
 )
 
+// ErrNoPassword is returned when a change needs the current password, but the account was created
+// by signing in with a provider and has none yet.
+var ErrNoPassword = errors.New(`your account has no password yet; set one with "Forgot password" on the login page, then try again`)
+
 // UserService handles all user-related business logic and database interaction.
 type UserService struct {
-	repo repository.UserRepository
+	repo      repository.UserRepository
+	sessions  repository.RefreshTokenRepository
+	passwords PasswordPolicy
 }
 
-// NewUserService creates a new instance of the UserService.
-func NewUserService(repo repository.UserRepository) *UserService {
+// NewUserService creates a new instance of the UserService. Sessions are signed out through the
+// refresh token repository when the password changes.
+func NewUserService(repo repository.UserRepository, sessions repository.RefreshTokenRepository) *UserService {
 	return &UserService{
-		repo: repo,
+		repo:     repo,
+		sessions: sessions,
 	}
 }
 
+// SetPasswordPolicy replaces the default policy new passwords are checked against.
+func (s *UserService) SetPasswordPolicy(policy PasswordPolicy) {
+	s.passwords = policy
+}
+
+// ValidatePassword returns an *InvalidPasswordError if a new password for the account with this
+// email does not meet the password policy.
+func (s *UserService) ValidatePassword(password, email string) error {
+	return s.passwords.Validate(password, email)
+}
+
 // -------------------------------------------------------------------
 // CORE BUSINESS LOGIC
 // -------------------------------------------------------------------
@@ -73,34 +95,98 @@ func (s *UserService) UpdateUser(ctx context.Context, userID string, input model
 	}
 
 	// 2. Verify the current password (MUST BE PROVIDED)
+	if user.PasswordHash == "" {
+		return nil, ErrNoPassword
+	}
 	if input.CurrentPassword == nil || *input.CurrentPassword == "" {
 		return nil, fmt.Errorf("current password is required for update")
 	}
-
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(*input.CurrentPassword))
-	if err != nil {
-		return nil, fmt.Errorf("invalid current password")
+	if err := checkCurrentPassword(user, *input.CurrentPassword); err != nil {
+		return nil, err
 	}
 
 	// 3. Update fields
 	updated := false
 
 	// Handle New Password Update
+	passwordChanged := false
 	if input.NewPassword != nil && *input.NewPassword != "" {
+		if err := s.ValidatePassword(*input.NewPassword, user.Email); err != nil {
+			return nil, err
+		}
 		hashedPassword, hashErr := bcrypt.GenerateFromPassword([]byte(*input.NewPassword), bcrypt.DefaultCost)
 		if hashErr != nil {
 			return nil, fmt.Errorf("failed to hash new password: %w", hashErr)
 		}
 		user.PasswordHash = string(hashedPassword)
+		passwordChanged = true
 		updated = true
 	}
 
+	// Handle Email Update: the new address only replaces the current one once confirmed
+	if input.Email != nil && *input.Email != "" {
+		email, emailErr := normalizeEmail(*input.Email)
+		if emailErr != nil {
+			return nil, emailErr
+		}
+		if email == user.Email {
+			// Changing back to the current address cancels a pending change
+			if user.PendingEmail != nil {
+				user.PendingEmail = nil
+				updated = true
+			}
+		} else {
+			existing, findErr := s.repo.FindByEmail(ctx, email)
+			if findErr != nil {
+				return nil, findErr
+			}
+			if existing != nil {
+				return nil, repository.ErrDuplicateEmail
+			}
+			user.PendingEmail = &email
+			updated = true
+		}
+	}
+
 	// Handle Preferred Unit Update
 	if input.PreferredUnit != nil && *input.PreferredUnit != "" {
 		user.PreferredUnit = *input.PreferredUnit
 		updated = true
 	}
 
+	// Handle Timezone Update
+	if input.Timezone != nil && *input.Timezone != "" {
+		if _, tzErr := time.LoadLocation(*input.Timezone); tzErr != nil {
+			return nil, fmt.Errorf("invalid timezone: %s", *input.Timezone)
+		}
+		user.Timezone = *input.Timezone
+		updated = true
+	}
+
+	// Handle Locale Update
+	if input.Locale != nil {
+		if *input.Locale == "" {
+			user.Locale = nil
+		} else {
+			locale, ok := model.NormalizeLocale(*input.Locale)
+			if !ok {
+				return nil, fmt.Errorf("invalid locale: %s", *input.Locale)
+			}
+			user.Locale = &locale
+		}
+		updated = true
+	}
+
+	// Handle Training Days Update
+	if input.TrainingDays != nil {
+		days, daysErr := normalizeTrainingDays(input.TrainingDays)
+		if daysErr != nil {
+			return nil, daysErr
+		}
+		user.TrainingDays = days
+		updated = true
+	}
+
 	// If there are updates to apply
 	if updated {
 		user.UpdatedAt = time.Now()
@@ -110,6 +196,52 @@ func (s *UserService) UpdateUser(ctx context.Context, userID string, input model
 		}
 	}
 
+	// Whoever knew the old password may still hold a session; keep only the one making the change
+	if passwordChanged {
+		if _, err := s.sessions.RevokeAllForUserExcept(ctx, user.ID, input.CurrentSessionID); err != nil {
+			return nil, fmt.Errorf("password changed, but failed to sign out other sessions: %w", err)
+		}
+	}
+
 	// 4. Return the updated user entity
 	return user, nil
 }
+
+// checkCurrentPassword verifies the password a user entered to confirm a sensitive change.
+func checkCurrentPassword(user *model.User, password string) error {
+	if user.PasswordHash == "" {
+		return ErrNoPassword
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return fmt.Errorf("invalid current password")
+	}
+	return nil
+}
+
+// normalizeEmail checks that the input is a bare email address, e.g. "jane@example.com" but not
+// "Jane <jane@example.com>", and returns it in the form emails are stored in.
+func normalizeEmail(input string) (string, error) {
+	email := strings.TrimSpace(input)
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", fmt.Errorf("invalid email address: %s", input)
+	}
+	return model.NormalizeEmail(email), nil
+}
+
+// normalizeTrainingDays validates the weekdays and drops duplicates, keeping the given order.
+func normalizeTrainingDays(days []model.Weekday) ([]model.Weekday, error) {
+	seen := make(map[model.Weekday]bool, len(days))
+	result := make([]model.Weekday, 0, len(days))
+	for _, day := range days {
+		if !day.IsValid() {
+			return nil, fmt.Errorf("invalid training day: %s", day)
+		}
+		if seen[day] {
+			continue
+		}
+		seen[day] = true
+		result = append(result, day)
+	}
+	return result, nil
+}
@@ -15,7 +15,7 @@ import (
 
 func TestCreateUser(t *testing.T) {
 	mockRepo := new(repository.MockUserRepository)
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, nil)
 	ctx := context.Background()
 
 	t.Run("Success", func(t *testing.T) {
@@ -39,7 +39,7 @@ func TestCreateUser(t *testing.T) {
 
 func TestVerifyPassword(t *testing.T) {
 	mockRepo := new(repository.MockUserRepository)
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, nil)
 	ctx := context.Background()
 
 	password := "password123"
@@ -78,7 +78,7 @@ func TestVerifyPassword(t *testing.T) {
 
 func TestGetUserByID(t *testing.T) {
 	mockRepo := new(repository.MockUserRepository)
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, nil)
 	ctx := context.Background()
 	id := "user-1"
 
@@ -104,7 +104,8 @@ func TestGetUserByID(t *testing.T) {
 
 func TestUpdateUser(t *testing.T) {
 	mockRepo := new(repository.MockUserRepository)
-	service := NewUserService(mockRepo)
+	sessionRepo := new(repository.MockRefreshTokenRepository)
+	service := NewUserService(mockRepo, sessionRepo)
 	ctx := context.Background()
 	id := "user-1"
 	password := "password123"
@@ -112,13 +113,16 @@ func TestUpdateUser(t *testing.T) {
 
 	t.Run("Success New Password", func(t *testing.T) {
 		user := &model.User{ID: id, PasswordHash: string(hashedPassword), UpdatedAt: time.Now().Add(-1 * time.Hour)}
-		newPass := "newpass"
+		newPass := "new-passphrase"
 		input := model.UserUpdateInput{
-			CurrentPassword: &password,
-			NewPassword:     &newPass,
+			CurrentPassword:  &password,
+			NewPassword:      &newPass,
+			CurrentSessionID: "session-1",
 		}
 
 		mockRepo.On("FindByID", ctx, id).Return(user, nil).Once()
+		// Every other session is signed out
+		sessionRepo.On("RevokeAllForUserExcept", ctx, id, "session-1").Return(int64(2), nil).Once()
 
 		mockRepo.On("Update", ctx, mock.MatchedBy(func(u *model.User) bool {
 			// Verify key fields are present
@@ -134,6 +138,72 @@ func TestUpdateUser(t *testing.T) {
 		assert.NoError(t, err)
 
 		mockRepo.AssertExpectations(t)
+		sessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejects New Password Against Policy", func(t *testing.T) {
+		user := &model.User{ID: id, Email: "jane.doe@example.com", PasswordHash: string(hashedPassword)}
+		newPass := "janedoe1"
+		input := model.UserUpdateInput{
+			CurrentPassword: &password,
+			NewPassword:     &newPass,
+		}
+
+		mockRepo.On("FindByID", ctx, id).Return(user, nil).Once()
+
+		result, err := service.UpdateUser(ctx, id, input)
+		var invalid *InvalidPasswordError
+		if assert.ErrorAs(t, err, &invalid) {
+			assert.Equal(t, []PasswordViolation{PasswordContainsEmail}, invalid.Violations)
+		}
+		assert.Nil(t, result)
+	})
+
+	t.Run("Email Change Is Pending Until Confirmed", func(t *testing.T) {
+		user := &model.User{ID: id, Email: "old@example.com", PasswordHash: string(hashedPassword)}
+		newEmail := " New@Example.com "
+		mockRepo.On("FindByID", ctx, id).Return(user, nil).Once()
+		mockRepo.On("FindByEmail", ctx, "new@example.com").Return(nil, nil).Once()
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(u *model.User) bool {
+			return u.Email == "old@example.com" && u.PendingEmail != nil && *u.PendingEmail == "new@example.com"
+		})).Return(nil).Once()
+
+		result, err := service.UpdateUser(ctx, id, model.UserUpdateInput{CurrentPassword: &password, Email: &newEmail})
+		assert.NoError(t, err)
+		assert.Equal(t, "old@example.com", result.Email)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Email Change To Registered Email", func(t *testing.T) {
+		user := &model.User{ID: id, Email: "old@example.com", PasswordHash: string(hashedPassword)}
+		newEmail := "taken@example.com"
+		mockRepo.On("FindByID", ctx, id).Return(user, nil).Once()
+		mockRepo.On("FindByEmail", ctx, "taken@example.com").Return(&model.User{ID: "user-2", Email: "taken@example.com"}, nil).Once()
+
+		_, err := service.UpdateUser(ctx, id, model.UserUpdateInput{CurrentPassword: &password, Email: &newEmail})
+		assert.ErrorIs(t, err, repository.ErrDuplicateEmail)
+	})
+
+	t.Run("Invalid Email", func(t *testing.T) {
+		user := &model.User{ID: id, Email: "old@example.com", PasswordHash: string(hashedPassword)}
+		for _, newEmail := range []string{"not-an-email", "Jane <jane@example.com>"} {
+			mockRepo.On("FindByID", ctx, id).Return(user, nil).Once()
+			_, err := service.UpdateUser(ctx, id, model.UserUpdateInput{CurrentPassword: &password, Email: &newEmail})
+			assert.EqualError(t, err, "invalid email address: "+newEmail)
+		}
+	})
+
+	t.Run("Current Email Cancels Pending Change", func(t *testing.T) {
+		pending := "new@example.com"
+		user := &model.User{ID: id, Email: "old@example.com", PendingEmail: &pending, PasswordHash: string(hashedPassword)}
+		sameEmail := "old@example.com"
+		mockRepo.On("FindByID", ctx, id).Return(user, nil).Once()
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(u *model.User) bool { return u.PendingEmail == nil })).Return(nil).Once()
+
+		result, err := service.UpdateUser(ctx, id, model.UserUpdateInput{CurrentPassword: &password, Email: &sameEmail})
+		assert.NoError(t, err)
+		assert.Nil(t, result.PendingEmail)
+		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Success PreferredUnit Update", func(t *testing.T) {
@@ -161,8 +231,93 @@ func TestUpdateUser(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("Success Timezone And Training Days Update", func(t *testing.T) {
+		user := &model.User{ID: id, PasswordHash: string(hashedPassword), Timezone: model.DefaultTimezone}
+		tz := "Europe/Madrid"
+		input := model.UserUpdateInput{
+			CurrentPassword: &password,
+			Timezone:        &tz,
+			TrainingDays:    []model.Weekday{model.WeekdayMonday, model.WeekdayThursday, model.WeekdayMonday},
+		}
+
+		mockRepo.On("FindByID", ctx, id).Return(user, nil).Once()
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(u *model.User) bool {
+			return u.Timezone == tz && len(u.TrainingDays) == 2
+		})).Return(nil).Once()
+
+		result, err := service.UpdateUser(ctx, id, input)
+		assert.NoError(t, err)
+		assert.Equal(t, tz, result.Timezone)
+		assert.Equal(t, []model.Weekday{model.WeekdayMonday, model.WeekdayThursday}, result.TrainingDays)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Timezone", func(t *testing.T) {
+		user := &model.User{ID: id, PasswordHash: string(hashedPassword)}
+		tz := "Mars/Olympus_Mons"
+		input := model.UserUpdateInput{CurrentPassword: &password, Timezone: &tz}
+
+		mockRepo.On("FindByID", ctx, id).Return(user, nil).Once()
+
+		result, err := service.UpdateUser(ctx, id, input)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid timezone")
+		assert.Nil(t, result)
+	})
+
+	t.Run("Success Locale Update", func(t *testing.T) {
+		user := &model.User{ID: id, PasswordHash: string(hashedPassword)}
+		locale := "pt_br"
+		input := model.UserUpdateInput{CurrentPassword: &password, Locale: &locale}
+
+		mockRepo.On("FindByID", ctx, id).Return(user, nil).Once()
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(u *model.User) bool {
+			return u.Locale != nil && *u.Locale == "pt-BR"
+		})).Return(nil).Once()
+
+		result, err := service.UpdateUser(ctx, id, input)
+		assert.NoError(t, err)
+		assert.Equal(t, "pt-BR", *result.Locale)
+
+		// An empty locale clears the setting
+		cleared := ""
+		mockRepo.On("FindByID", ctx, id).Return(result, nil).Once()
+		mockRepo.On("Update", ctx, mock.MatchedBy(func(u *model.User) bool {
+			return u.Locale == nil
+		})).Return(nil).Once()
+
+		result, err = service.UpdateUser(ctx, id, model.UserUpdateInput{CurrentPassword: &password, Locale: &cleared})
+		assert.NoError(t, err)
+		assert.Nil(t, result.Locale)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Locale", func(t *testing.T) {
+		user := &model.User{ID: id, PasswordHash: string(hashedPassword)}
+		locale := "not a locale"
+		input := model.UserUpdateInput{CurrentPassword: &password, Locale: &locale}
+
+		mockRepo.On("FindByID", ctx, id).Return(user, nil).Once()
+
+		result, err := service.UpdateUser(ctx, id, input)
+		assert.EqualError(t, err, "invalid locale: not a locale")
+		assert.Nil(t, result)
+	})
+
+	t.Run("Invalid Training Day", func(t *testing.T) {
+		user := &model.User{ID: id, PasswordHash: string(hashedPassword)}
+		input := model.UserUpdateInput{CurrentPassword: &password, TrainingDays: []model.Weekday{"FUNDAY"}}
+
+		mockRepo.On("FindByID", ctx, id).Return(user, nil).Once()
+
+		result, err := service.UpdateUser(ctx, id, input)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid training day")
+		assert.Nil(t, result)
+	})
+
 	t.Run("Missing Current Password", func(t *testing.T) {
-		mockRepo.On("FindByID", ctx, id).Return(&model.User{ID: id}, nil).Once()
+		mockRepo.On("FindByID", ctx, id).Return(&model.User{ID: id, PasswordHash: string(hashedPassword)}, nil).Once()
 
 		input := model.UserUpdateInput{CurrentPassword: nil}
 		result, err := service.UpdateUser(ctx, id, input)
@@ -172,6 +327,16 @@ func TestUpdateUser(t *testing.T) {
 		mockRepo.AssertNotCalled(t, "Update")
 	})
 
+	t.Run("Account Without A Password", func(t *testing.T) {
+		// Created by signing in with a provider
+		mockRepo.On("FindByID", ctx, id).Return(&model.User{ID: id}, nil).Once()
+
+		result, err := service.UpdateUser(ctx, id, model.UserUpdateInput{CurrentPassword: &password})
+		assert.ErrorIs(t, err, ErrNoPassword)
+		assert.Nil(t, result)
+		mockRepo.AssertNotCalled(t, "Update")
+	})
+
 	t.Run("Wrong Current Password", func(t *testing.T) {
 		user := &model.User{ID: id, PasswordHash: string(hashedPassword)}
 		wrongPass := "wrong"
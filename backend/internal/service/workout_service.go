@@ -2,6 +2,11 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/riverajo/fitness-app/backend/internal/model"
 	"github.com/riverajo/fitness-app/backend/internal/repository"
@@ -38,3 +43,81 @@ func (s *WorkoutService) ListLogs(ctx context.Context, userID string, limit, off
 func (s *WorkoutService) UpdateLog(ctx context.Context, log model.WorkoutLog) (*model.WorkoutLog, error) {
 	return s.repo.Update(ctx, log)
 }
+
+// Page size bounds for exercise history.
+const (
+	DefaultHistoryPageSize = 20
+	MaxHistoryPageSize     = 100
+)
+
+// ExerciseHistory returns a page of the user's sessions of an exercise, newest first, plus a summary
+// over all sessions. after is the endCursor of the previous page, or nil for the first page.
+func (s *WorkoutService) ExerciseHistory(ctx context.Context, userID, exerciseID string, first int, after *string) (*model.ExerciseHistory, error) {
+	if first < 1 || first > MaxHistoryPageSize {
+		return nil, fmt.Errorf("first must be between 1 and %d", MaxHistoryPageSize)
+	}
+
+	var cursor *model.SessionCursor
+	if after != nil {
+		c, err := decodeSessionCursor(*after)
+		if err != nil {
+			return nil, err
+		}
+		cursor = c
+	}
+
+	// Fetch one extra session to know whether another page follows
+	sessions, err := s.repo.ListExerciseSessions(ctx, userID, exerciseID, cursor, first+1)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &model.ExerciseHistory{Sessions: sessions}
+	if len(sessions) > first {
+		history.Sessions = sessions[:first]
+		history.HasNextPage = true
+	}
+	if history.Sessions == nil {
+		history.Sessions = []*model.ExerciseSession{}
+	}
+	if n := len(history.Sessions); n > 0 {
+		end := encodeSessionCursor(history.Sessions[n-1])
+		history.EndCursor = &end
+	}
+
+	summary, err := s.repo.SummarizeExercise(ctx, userID, exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	history.Summary = summary
+
+	return history, nil
+}
+
+// PreviousSessions returns, for each key, the user's latest session of the exercise before the
+// key's workout, or nil if there is none. Results are aligned with keys.
+func (s *WorkoutService) PreviousSessions(ctx context.Context, keys []model.PreviousSessionKey) ([]*model.ExerciseSession, error) {
+	return s.repo.FindPreviousSessions(ctx, keys)
+}
+
+// encodeSessionCursor returns an opaque cursor pointing just after the session.
+func encodeSessionCursor(session *model.ExerciseSession) string {
+	raw := strconv.FormatInt(session.Date.UnixNano(), 10) + ":" + session.WorkoutID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeSessionCursor(cursor string) (*model.SessionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	nanos, workoutID, ok := strings.Cut(string(raw), ":")
+	if !ok || workoutID == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &model.SessionCursor{StartTime: time.Unix(0, n).UTC(), WorkoutID: workoutID}, nil
+}
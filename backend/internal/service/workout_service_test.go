@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/riverajo/fitness-app/backend/internal/model"
 	"github.com/riverajo/fitness-app/backend/internal/repository"
@@ -115,3 +116,79 @@ func TestUpdateLog(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestExerciseHistory(t *testing.T) {
+	ctx := context.Background()
+	userID := "user-1"
+	day := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	sessions := []*model.ExerciseSession{
+		{WorkoutID: "w3", Date: day.Add(48 * time.Hour)},
+		{WorkoutID: "w2", Date: day.Add(24 * time.Hour)},
+		{WorkoutID: "w1", Date: day},
+	}
+	summary := &model.ExerciseHistorySummary{TotalSessions: 3}
+
+	t.Run("first page has a next page", func(t *testing.T) {
+		mockRepo := new(repository.MockWorkoutRepository)
+		service := NewWorkoutService(mockRepo)
+		mockRepo.On("ListExerciseSessions", ctx, userID, "bench", (*model.SessionCursor)(nil), 3).Return(sessions, nil).Once()
+		mockRepo.On("SummarizeExercise", ctx, userID, "bench").Return(summary, nil).Once()
+
+		history, err := service.ExerciseHistory(ctx, userID, "bench", 2, nil)
+
+		assert.NoError(t, err)
+		assert.Len(t, history.Sessions, 2)
+		assert.True(t, history.HasNextPage)
+		assert.Equal(t, summary, history.Summary)
+
+		// The end cursor resumes after the last returned session
+		cursor, err := decodeSessionCursor(*history.EndCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, "w2", cursor.WorkoutID)
+		assert.True(t, day.Add(24*time.Hour).Equal(cursor.StartTime))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		mockRepo := new(repository.MockWorkoutRepository)
+		service := NewWorkoutService(mockRepo)
+		after := encodeSessionCursor(sessions[1])
+		expectedCursor := &model.SessionCursor{StartTime: sessions[1].Date, WorkoutID: "w2"}
+		mockRepo.On("ListExerciseSessions", ctx, userID, "bench", expectedCursor, 3).Return(sessions[2:], nil).Once()
+		mockRepo.On("SummarizeExercise", ctx, userID, "bench").Return(summary, nil).Once()
+
+		history, err := service.ExerciseHistory(ctx, userID, "bench", 2, &after)
+
+		assert.NoError(t, err)
+		assert.Len(t, history.Sessions, 1)
+		assert.False(t, history.HasNextPage)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("never performed", func(t *testing.T) {
+		mockRepo := new(repository.MockWorkoutRepository)
+		service := NewWorkoutService(mockRepo)
+		mockRepo.On("ListExerciseSessions", ctx, userID, "bench", (*model.SessionCursor)(nil), 21).Return(nil, nil).Once()
+		mockRepo.On("SummarizeExercise", ctx, userID, "bench").Return(&model.ExerciseHistorySummary{}, nil).Once()
+
+		history, err := service.ExerciseHistory(ctx, userID, "bench", DefaultHistoryPageSize, nil)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, history.Sessions)
+		assert.Empty(t, history.Sessions)
+		assert.Nil(t, history.EndCursor)
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		service := NewWorkoutService(new(repository.MockWorkoutRepository))
+
+		_, err := service.ExerciseHistory(ctx, userID, "bench", 0, nil)
+		assert.EqualError(t, err, "first must be between 1 and 100")
+		_, err = service.ExerciseHistory(ctx, userID, "bench", 101, nil)
+		assert.EqualError(t, err, "first must be between 1 and 100")
+
+		bad := "not-a-cursor"
+		_, err = service.ExerciseHistory(ctx, userID, "bench", 10, &bad)
+		assert.EqualError(t, err, "invalid cursor")
+	})
+}
@@ -0,0 +1,65 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Authenticator data flags.
+const (
+	flagUserPresent           = 0x01
+	flagUserVerified          = 0x04
+	flagAttestedCredential    = 0x40
+	flagExtensionDataIncluded = 0x80
+)
+
+// authenticatorData is the authenticator's signed statement about a ceremony.
+type authenticatorData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+	// Only set when registering
+	credentialID []byte
+	publicKey    []byte
+}
+
+func parseAuthenticatorData(b []byte) (*authenticatorData, error) {
+	if len(b) < 37 {
+		return nil, fmt.Errorf("authenticator data is too short")
+	}
+	data := &authenticatorData{
+		rpIDHash:  b[:32],
+		flags:     b[32],
+		signCount: binary.BigEndian.Uint32(b[33:37]),
+	}
+	rest := b[37:]
+
+	if data.flags&flagAttestedCredential != 0 {
+		// AAGUID, then the length of the credential ID
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("attested credential data is too short")
+		}
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLength {
+			return nil, fmt.Errorf("attested credential data is too short")
+		}
+		data.credentialID, rest = rest[:idLength], rest[idLength:]
+
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential public key: %w", err)
+		}
+		data.publicKey, rest = rest[:len(rest)-len(after)], after
+	}
+	if data.flags&flagExtensionDataIncluded != 0 {
+		var err error
+		if _, rest, err = decodeCBOR(rest); err != nil {
+			return nil, fmt.Errorf("invalid extension data: %w", err)
+		}
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("unexpected data after authenticator data")
+	}
+	return data, nil
+}
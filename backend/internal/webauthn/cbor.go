@@ -0,0 +1,142 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// maxCBORDepth limits the nesting of decoded items; WebAuthn structures nest two or three deep.
+const maxCBORDepth = 16
+
+var errCBORTruncated = errors.New("cbor: unexpected end of data")
+
+// decodeCBOR decodes the CBOR (RFC 8949) data item at the start of b and returns it with the bytes
+// that follow. Only what WebAuthn uses is supported: integers (as int64), byte strings ([]byte),
+// text strings, arrays ([]any), maps with integer or text keys (map[any]any), booleans and null.
+// Tags are skipped. Indefinite lengths and floats are rejected.
+func decodeCBOR(b []byte) (any, []byte, error) {
+	return decodeCBORItem(b, 0)
+}
+
+func decodeCBORItem(b []byte, depth int) (any, []byte, error) {
+	if depth > maxCBORDepth {
+		return nil, nil, errors.New("cbor: nested too deeply")
+	}
+	if len(b) == 0 {
+		return nil, nil, errCBORTruncated
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, b, nil
+		case 21:
+			return true, b, nil
+		case 22, 23: // null, undefined
+			return nil, b, nil
+		default:
+			return nil, nil, fmt.Errorf("cbor: unsupported simple value %d", info)
+		}
+	}
+
+	n, b, err := readCBORArgument(info, b)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch major {
+	case 0, 1:
+		if n > math.MaxInt64 {
+			return nil, nil, errors.New("cbor: integer out of range")
+		}
+		if major == 1 {
+			return -1 - int64(n), b, nil
+		}
+		return int64(n), b, nil
+	case 2, 3:
+		if n > uint64(len(b)) {
+			return nil, nil, errCBORTruncated
+		}
+		if major == 3 {
+			return string(b[:n]), b[n:], nil
+		}
+		return b[:n:n], b[n:], nil
+	case 4:
+		// Every item takes at least one byte, so a longer array cannot fit
+		if n > uint64(len(b)) {
+			return nil, nil, errCBORTruncated
+		}
+		items := make([]any, 0, n)
+		for range n {
+			var item any
+			if item, b, err = decodeCBORItem(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, b, nil
+	case 5:
+		if n > uint64(len(b))/2 {
+			return nil, nil, errCBORTruncated
+		}
+		m := make(map[any]any, n)
+		for range n {
+			var key, value any
+			if key, b, err = decodeCBORItem(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, fmt.Errorf("cbor: unsupported map key type %T", key)
+			}
+			if _, ok := m[key]; ok {
+				return nil, nil, fmt.Errorf("cbor: duplicate map key %v", key)
+			}
+			if value, b, err = decodeCBORItem(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+			m[key] = value
+		}
+		return m, b, nil
+	default: // 6, a tag: the tagged item is returned as is
+		return decodeCBORItem(b, depth+1)
+	}
+}
+
+// readCBORArgument reads the length or value that follows an item's initial byte.
+func readCBORArgument(info byte, b []byte) (uint64, []byte, error) {
+	var size int
+	switch {
+	case info < 24:
+		return uint64(info), b, nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, nil, errors.New("cbor: indefinite lengths are not supported")
+	}
+	if len(b) < size {
+		return 0, nil, errCBORTruncated
+	}
+	var n uint64
+	switch size {
+	case 1:
+		n = uint64(b[0])
+	case 2:
+		n = uint64(binary.BigEndian.Uint16(b))
+	case 4:
+		n = uint64(binary.BigEndian.Uint32(b))
+	default:
+		n = binary.BigEndian.Uint64(b)
+	}
+	return n, b[size:], nil
+}
@@ -0,0 +1,36 @@
+package webauthn
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	// {"fmt": "none", 1: [-7, h'0102'], "x": true} followed by one more byte
+	data := []byte{0xa3, 0x63, 'f', 'm', 't', 0x64, 'n', 'o', 'n', 'e', 0x01, 0x82, 0x26, 0x42, 0x01, 0x02, 0x61, 'x', 0xf5, 0xff}
+	item, rest, err := decodeCBOR(data)
+	require.NoError(t, err)
+	assert.Equal(t, map[any]any{
+		"fmt":    "none",
+		int64(1): []any{int64(-7), []byte{1, 2}},
+		"x":      true,
+	}, item)
+	assert.Equal(t, []byte{0xff}, rest)
+
+	for name, data := range map[string][]byte{
+		"truncated string":    {0x45, 1, 2},
+		"huge array":          {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"indefinite length":   {0x5f},
+		"duplicate key":       {0xa2, 0x01, 0x01, 0x01, 0x02},
+		"array key":           {0xa1, 0x80, 0x01},
+		"float":               {0xf9, 0x3c, 0x00},
+		"integer overflow":    {0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"deeply nested array": append(bytes.Repeat([]byte{0x81}, maxCBORDepth+1), 0x00),
+	} {
+		_, _, err := decodeCBOR(data)
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,111 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithms (RFC 9053) of the credential public keys that are accepted, in order of preference.
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+var supportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+// COSE_Key parameters (RFC 9052 and 9053).
+const (
+	coseKty = int64(1)
+	coseAlg = int64(3)
+	// Key type specific: the curve and x, y coordinates, or the RSA modulus n and exponent e
+	coseCrv = int64(-1)
+	coseX   = int64(-2)
+	coseY   = int64(-3)
+	coseN   = int64(-1)
+	coseE   = int64(-2)
+
+	coseKtyOKP = 1
+	coseKtyEC2 = 2
+	coseKtyRSA = 3
+
+	coseCrvP256    = 1
+	coseCrvEd25519 = 6
+)
+
+// publicKey is a credential public key parsed from its COSE_Key encoding.
+type publicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+func parsePublicKey(cose []byte) (*publicKey, error) {
+	item, rest, err := decodeCBOR(cose)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	m, ok := item.(map[any]any)
+	if !ok || len(rest) != 0 {
+		return nil, fmt.Errorf("invalid public key")
+	}
+	kty, _ := m[coseKty].(int64)
+	alg, _ := m[coseAlg].(int64)
+
+	switch alg {
+	case AlgES256:
+		crv, _ := m[coseCrv].(int64)
+		x, _ := m[coseX].([]byte)
+		y, _ := m[coseY].([]byte)
+		if kty != coseKtyEC2 || crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("invalid ES256 public key")
+		}
+		point := append(append([]byte{4}, x...), y...)
+		key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ES256 public key: %w", err)
+		}
+		return &publicKey{alg: alg, key: key}, nil
+	case AlgEdDSA:
+		crv, _ := m[coseCrv].(int64)
+		x, _ := m[coseX].([]byte)
+		if kty != coseKtyOKP || crv != coseCrvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid EdDSA public key")
+		}
+		return &publicKey{alg: alg, key: ed25519.PublicKey(x)}, nil
+	case AlgRS256:
+		n, _ := m[coseN].([]byte)
+		e, _ := m[coseE].([]byte)
+		if kty != coseKtyRSA || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid RS256 public key")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < 2048 || key.E < 3 {
+			return nil, fmt.Errorf("RS256 public key is too weak")
+		}
+		return &publicKey{alg: alg, key: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key algorithm %d", alg)
+	}
+}
+
+// verify reports whether sig is the key's signature of data.
+func (k *publicKey) verify(data, sig []byte) bool {
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(key, digest[:], sig)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, data, sig)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,393 @@
+// Package webauthn implements the relying party side of WebAuthn (passkey) registration and
+// sign-in, following the Web Authentication Level 2 specification.
+//
+// Options are produced, and responses accepted, in the JSON forms browsers use with
+// PublicKeyCredential.parseCreationOptionsFromJSON and PublicKeyCredential.toJSON. Attestation
+// is not requested, so authenticators are not checked against a list of trusted models, and
+// user verification (a PIN or biometric) is always required, since a passkey replaces both the
+// password and any second factor.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrVerificationFailed is wrapped by every error for a response that does not verify.
+	ErrVerificationFailed = errors.New("webauthn: verification failed")
+	// ErrSignCountNotIncreased is returned, wrapping ErrVerificationFailed, for a sign-in whose
+	// signature counter is not above the stored one, a sign that the credential was cloned.
+	ErrSignCountNotIncreased = fmt.Errorf("%w: signature counter did not increase", ErrVerificationFailed)
+)
+
+func verificationError(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrVerificationFailed}, args...)...)
+}
+
+// RelyingParty is the website credentials are registered with.
+type RelyingParty struct {
+	// ID is the domain credentials are scoped to, e.g. "fitness.example.com"
+	ID   string
+	Name string
+	// Origins are where the frontend is served from, e.g. "https://fitness.example.com"
+	Origins []string
+	// Timeout is how long the browser waits for the user; zero leaves it to the browser
+	Timeout time.Duration
+}
+
+// User is the account a credential is registered for.
+type User struct {
+	// ID is returned as the user handle when signing in; it must not contain personal information
+	ID          []byte
+	Name        string
+	DisplayName string
+}
+
+// Credential is a registered credential, as needed to verify sign-ins with it.
+type Credential struct {
+	ID []byte
+	// PublicKey is the COSE_Key encoding of the credential's public key
+	PublicKey []byte
+	SignCount uint32
+	// Transports are hints of how the browser can reach the authenticator, e.g. "internal" or "usb"
+	Transports []string
+}
+
+// CredentialDescriptor identifies a credential in options.
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type credentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type relyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type userEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type authenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions are the options for navigator.credentials.create().
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     relyingPartyEntity     `json:"rp"`
+	User                   userEntity             `json:"user"`
+	PubKeyCredParams       []credentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout,omitempty"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials,omitempty"`
+	AuthenticatorSelection authenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are the options for navigator.credentials.get().
+type RequestOptions struct {
+	Challenge string `json:"challenge"`
+	Timeout   int64  `json:"timeout,omitempty"`
+	RPID      string `json:"rpId"`
+	// Empty, so the user picks any of their passkeys for the site without entering who they are
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// CreationOptions returns the options to register a discoverable credential for the user. The
+// credentials in exclude are not registered again on the same authenticator.
+func (rp *RelyingParty) CreationOptions(challenge []byte, user User, exclude []Credential) *CreationOptions {
+	options := &CreationOptions{
+		Challenge: encode(challenge),
+		RP:        relyingPartyEntity{ID: rp.ID, Name: rp.Name},
+		User:      userEntity{ID: encode(user.ID), Name: user.Name, DisplayName: user.DisplayName},
+		Timeout:   rp.Timeout.Milliseconds(),
+		AuthenticatorSelection: authenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   "required",
+		},
+		Attestation: "none",
+	}
+	for _, alg := range supportedAlgorithms {
+		options.PubKeyCredParams = append(options.PubKeyCredParams, credentialParameter{Type: "public-key", Alg: alg})
+	}
+	for _, c := range exclude {
+		options.ExcludeCredentials = append(options.ExcludeCredentials, CredentialDescriptor{Type: "public-key", ID: encode(c.ID), Transports: c.Transports})
+	}
+	return options
+}
+
+// RequestOptions returns the options to sign in with any of the user's credentials.
+func (rp *RelyingParty) RequestOptions(challenge []byte) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        encode(challenge),
+		Timeout:          rp.Timeout.Milliseconds(),
+		RPID:             rp.ID,
+		AllowCredentials: []CredentialDescriptor{},
+		UserVerification: "required",
+	}
+}
+
+// RegistrationResponse is a new credential, as serialized by PublicKeyCredential.toJSON().
+type RegistrationResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports,omitempty"`
+	} `json:"response"`
+}
+
+// AssertionResponse is a sign-in, as serialized by PublicKeyCredential.toJSON().
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+// ParseRegistrationResponse parses a RegistrationResponse from its JSON.
+func ParseRegistrationResponse(data string) (*RegistrationResponse, error) {
+	var resp RegistrationResponse
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		return nil, verificationError("invalid registration response: %v", err)
+	}
+	return &resp, nil
+}
+
+// ParseAssertionResponse parses an AssertionResponse from its JSON.
+func ParseAssertionResponse(data string) (*AssertionResponse, error) {
+	var resp AssertionResponse
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		return nil, verificationError("invalid sign-in response: %v", err)
+	}
+	return &resp, nil
+}
+
+// Challenge returns the challenge the credential was created for, to find the registration it
+// completes. It is not verified until VerifyRegistration.
+func (r *RegistrationResponse) Challenge() ([]byte, error) {
+	return challengeOf(r.Response.ClientDataJSON)
+}
+
+// Challenge returns the challenge the response signs, to find the sign-in it completes. It is not
+// verified until VerifyAssertion.
+func (r *AssertionResponse) Challenge() ([]byte, error) {
+	return challengeOf(r.Response.ClientDataJSON)
+}
+
+// CredentialID returns the ID of the credential used, to look it up.
+func (r *AssertionResponse) CredentialID() ([]byte, error) {
+	id, err := decode(r.RawID)
+	if err != nil || len(id) == 0 {
+		return nil, verificationError("invalid credential ID")
+	}
+	return id, nil
+}
+
+// UserHandle returns the user ID the credential was registered with, or nil if the
+// authenticator did not return it.
+func (r *AssertionResponse) UserHandle() ([]byte, error) {
+	if r.Response.UserHandle == "" {
+		return nil, nil
+	}
+	handle, err := decode(r.Response.UserHandle)
+	if err != nil {
+		return nil, verificationError("invalid user handle")
+	}
+	return handle, nil
+}
+
+// VerifyRegistration verifies a new credential created for the challenge, and returns it to be stored.
+func (rp *RelyingParty) VerifyRegistration(resp *RegistrationResponse, challenge []byte) (*Credential, error) {
+	if resp.Type != "public-key" {
+		return nil, verificationError("unexpected credential type %q", resp.Type)
+	}
+	if _, err := rp.verifyClientData(resp.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	rawAttestation, err := decode(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, verificationError("invalid attestation object")
+	}
+	item, rest, err := decodeCBOR(rawAttestation)
+	attestation, ok := item.(map[any]any)
+	if err != nil || !ok || len(rest) != 0 {
+		return nil, verificationError("invalid attestation object")
+	}
+	// The attestation statement is not verified: attestation is not requested, and whatever
+	// an authenticator sends anyway is not trusted for anything.
+	rawAuthData, _ := attestation["authData"].([]byte)
+	authData, err := rp.verifyAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if authData.flags&flagAttestedCredential == 0 {
+		return nil, verificationError("no credential in authenticator data")
+	}
+
+	rawID, err := decode(resp.RawID)
+	if err != nil || !bytes.Equal(rawID, authData.credentialID) {
+		return nil, verificationError("credential ID does not match authenticator data")
+	}
+	if len(rawID) == 0 || len(rawID) > 1023 {
+		return nil, verificationError("invalid credential ID length %d", len(rawID))
+	}
+	if _, err := parsePublicKey(authData.publicKey); err != nil {
+		return nil, verificationError("%v", err)
+	}
+
+	return &Credential{
+		ID:         rawID,
+		PublicKey:  authData.publicKey,
+		SignCount:  authData.signCount,
+		Transports: resp.Response.Transports,
+	}, nil
+}
+
+// VerifyAssertion verifies a sign-in with the credential for the challenge, and returns the
+// credential's new signature counter to be stored.
+func (rp *RelyingParty) VerifyAssertion(resp *AssertionResponse, challenge []byte, credential *Credential) (uint32, error) {
+	if resp.Type != "public-key" {
+		return 0, verificationError("unexpected credential type %q", resp.Type)
+	}
+	if id, err := resp.CredentialID(); err != nil || !bytes.Equal(id, credential.ID) {
+		return 0, verificationError("response is for another credential")
+	}
+	clientDataJSON, err := rp.verifyClientData(resp.Response.ClientDataJSON, "webauthn.get", challenge)
+	if err != nil {
+		return 0, err
+	}
+
+	rawAuthData, err := decode(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, verificationError("invalid authenticator data")
+	}
+	authData, err := rp.verifyAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+
+	key, err := parsePublicKey(credential.PublicKey)
+	if err != nil {
+		return 0, fmt.Errorf("stored credential: %w", err)
+	}
+	signature, err := decode(resp.Response.Signature)
+	if err != nil {
+		return 0, verificationError("invalid signature")
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	if !key.verify(append(slices.Clone(rawAuthData), clientDataHash[:]...), signature) {
+		return 0, verificationError("invalid signature")
+	}
+
+	// Authenticators that do not count signatures always send 0
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return 0, ErrSignCountNotIncreased
+	}
+	return authData.signCount, nil
+}
+
+type collectedClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// verifyClientData checks what the browser says about the ceremony, and returns the raw client
+// data, whose hash the authenticator signs.
+func (rp *RelyingParty) verifyClientData(encoded, ceremony string, challenge []byte) ([]byte, error) {
+	raw, err := decode(encoded)
+	if err != nil {
+		return nil, verificationError("invalid client data")
+	}
+	var clientData collectedClientData
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return nil, verificationError("invalid client data")
+	}
+	if clientData.Type != ceremony {
+		return nil, verificationError("unexpected ceremony %q", clientData.Type)
+	}
+	got, err := decode(clientData.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return nil, verificationError("challenge does not match")
+	}
+	if !slices.Contains(rp.Origins, clientData.Origin) || clientData.CrossOrigin {
+		return nil, verificationError("unexpected origin %q", clientData.Origin)
+	}
+	return raw, nil
+}
+
+// verifyAuthenticatorData checks that the authenticator signed for this site, and that the user
+// was present and verified.
+func (rp *RelyingParty) verifyAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	authData, err := parseAuthenticatorData(raw)
+	if err != nil {
+		return nil, verificationError("%v", err)
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(authData.rpIDHash, rpIDHash[:]) {
+		return nil, verificationError("credential is for another site")
+	}
+	if authData.flags&flagUserPresent == 0 {
+		return nil, verificationError("user was not present")
+	}
+	if authData.flags&flagUserVerified == 0 {
+		return nil, verificationError("user was not verified")
+	}
+	return authData, nil
+}
+
+func challengeOf(encodedClientData string) ([]byte, error) {
+	raw, err := decode(encodedClientData)
+	if err != nil {
+		return nil, verificationError("invalid client data")
+	}
+	var clientData collectedClientData
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return nil, verificationError("invalid client data")
+	}
+	challenge, err := decode(clientData.Challenge)
+	if err != nil || len(challenge) == 0 {
+		return nil, verificationError("invalid challenge")
+	}
+	return challenge, nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decode decodes base64url, the encoding WebAuthn's JSON uses, with or without padding.
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
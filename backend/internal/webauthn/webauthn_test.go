@@ -0,0 +1,130 @@
+package webauthn_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverajo/fitness-app/backend/internal/webauthn"
+	"github.com/riverajo/fitness-app/backend/internal/webauthn/webauthntest"
+)
+
+var rp = &webauthn.RelyingParty{ID: "fitness.example.com", Name: "Fitness App", Origins: []string{"https://fitness.example.com"}}
+
+func optionsJSON(t *testing.T, options any) string {
+	b, err := json.Marshal(options)
+	require.NoError(t, err)
+	return string(b)
+}
+
+// register registers a passkey on authenticator with options for challenge, and verifies it as
+// the response to the issued challenge, "challenge-1".
+func register(t *testing.T, authenticator *webauthntest.Authenticator, challenge string) (*webauthn.Credential, error) {
+	options := rp.CreationOptions([]byte(challenge), webauthn.User{ID: []byte("user-1"), Name: "jane@example.com"}, nil)
+	response, err := authenticator.Register(optionsJSON(t, options))
+	require.NoError(t, err)
+	parsed, err := webauthn.ParseRegistrationResponse(response)
+	require.NoError(t, err)
+	return rp.VerifyRegistration(parsed, []byte("challenge-1"))
+}
+
+// login signs in with the passkey on authenticator with options for challenge, and verifies it
+// as the response to the issued challenge, "challenge-2".
+func login(t *testing.T, authenticator *webauthntest.Authenticator, challenge string, credential *webauthn.Credential) (uint32, error) {
+	response, err := authenticator.Login(optionsJSON(t, rp.RequestOptions([]byte(challenge))))
+	require.NoError(t, err)
+	parsed, err := webauthn.ParseAssertionResponse(response)
+	require.NoError(t, err)
+
+	got, err := parsed.Challenge()
+	require.NoError(t, err)
+	assert.Equal(t, challenge, string(got))
+	handle, err := parsed.UserHandle()
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", string(handle))
+	return rp.VerifyAssertion(parsed, []byte("challenge-2"), credential)
+}
+
+func TestRegistrationAndSignIn(t *testing.T) {
+	authenticator := webauthntest.NewAuthenticator("https://fitness.example.com")
+	credential, err := register(t, authenticator, "challenge-1")
+	require.NoError(t, err)
+	assert.Equal(t, authenticator.CredentialID(), credential.ID)
+	assert.Equal(t, []string{"internal"}, credential.Transports)
+
+	signCount, err := login(t, authenticator, "challenge-2", credential)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), signCount)
+
+	t.Run("Rejects a counter that did not increase", func(t *testing.T) {
+		credential := *credential
+		credential.SignCount = 5
+		authenticator.SignCount = 3
+		_, err := login(t, authenticator, "challenge-2", &credential)
+		assert.ErrorIs(t, err, webauthn.ErrSignCountNotIncreased)
+	})
+
+	t.Run("Rejects another challenge", func(t *testing.T) {
+		_, err := login(t, authenticator, "challenge-3", credential)
+		assert.ErrorIs(t, err, webauthn.ErrVerificationFailed)
+	})
+
+	t.Run("Rejects another credential's key", func(t *testing.T) {
+		other := webauthntest.NewAuthenticator("https://fitness.example.com")
+		otherCredential, err := register(t, other, "challenge-1")
+		require.NoError(t, err)
+		otherCredential.ID = credential.ID
+		_, err = login(t, authenticator, "challenge-2", otherCredential)
+		assert.ErrorContains(t, err, "invalid signature")
+	})
+}
+
+func TestRegistrationRejected(t *testing.T) {
+	t.Run("Another origin", func(t *testing.T) {
+		_, err := register(t, webauthntest.NewAuthenticator("https://evil.example.com"), "challenge-1")
+		assert.ErrorContains(t, err, "unexpected origin")
+	})
+
+	t.Run("Another challenge", func(t *testing.T) {
+		_, err := register(t, webauthntest.NewAuthenticator("https://fitness.example.com"), "challenge-0")
+		assert.ErrorContains(t, err, "challenge does not match")
+	})
+
+	t.Run("No user verification", func(t *testing.T) {
+		authenticator := webauthntest.NewAuthenticator("https://fitness.example.com")
+		authenticator.SkipUserVerification = true
+		_, err := register(t, authenticator, "challenge-1")
+		assert.ErrorContains(t, err, "user was not verified")
+	})
+
+	t.Run("Another site", func(t *testing.T) {
+		other := *rp
+		other.ID = "example.com"
+		options := other.CreationOptions([]byte("challenge-1"), webauthn.User{ID: []byte("user-1")}, nil)
+		response, err := webauthntest.NewAuthenticator("https://fitness.example.com").Register(optionsJSON(t, options))
+		require.NoError(t, err)
+		parsed, err := webauthn.ParseRegistrationResponse(response)
+		require.NoError(t, err)
+		_, err = rp.VerifyRegistration(parsed, []byte("challenge-1"))
+		assert.ErrorContains(t, err, "credential is for another site")
+	})
+}
+
+func TestCreationOptions(t *testing.T) {
+	options := rp.CreationOptions([]byte("challenge"), webauthn.User{ID: []byte("user-1"), Name: "jane@example.com", DisplayName: "Jane"},
+		[]webauthn.Credential{{ID: []byte{1, 2, 3}, Transports: []string{"usb"}}})
+	b := optionsJSON(t, options)
+	for _, want := range []string{
+		`"challenge":"Y2hhbGxlbmdl"`,
+		`"rp":{"id":"fitness.example.com","name":"Fitness App"}`,
+		`"user":{"id":"dXNlci0x","name":"jane@example.com","displayName":"Jane"}`,
+		`"excludeCredentials":[{"type":"public-key","id":"AQID","transports":["usb"]}]`,
+		`"residentKey":"required"`,
+		`"userVerification":"required"`,
+	} {
+		assert.True(t, strings.Contains(b, want), "%s in %s", want, b)
+	}
+}
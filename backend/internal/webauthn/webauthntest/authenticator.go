@@ -0,0 +1,219 @@
+// Package webauthntest provides a software passkey authenticator, standing in for a browser
+// and security key in tests of WebAuthn ceremonies.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+)
+
+// Authenticator holds one ES256 passkey, registered with Register and used with Login.
+type Authenticator struct {
+	// Origin is the origin the browser reports, e.g. "https://fitness.example.com"
+	Origin string
+	// SignCount is the signature counter, incremented before each sign-in. Tests can lower it to
+	// act as a cloned authenticator.
+	SignCount uint32
+	// SkipUserVerification clears the user verified flag, as an authenticator without a PIN or
+	// biometric would.
+	SkipUserVerification bool
+
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	rpID         string
+}
+
+// NewAuthenticator returns an authenticator for a frontend served from origin.
+func NewAuthenticator(origin string) *Authenticator {
+	return &Authenticator{Origin: origin}
+}
+
+// CredentialID returns the ID of the registered passkey.
+func (a *Authenticator) CredentialID() []byte {
+	return a.credentialID
+}
+
+// Register creates a passkey for options as passed to navigator.credentials.create(), in JSON,
+// and returns the new credential as PublicKeyCredential.toJSON() would.
+func (a *Authenticator) Register(optionsJSON string) (string, error) {
+	var options struct {
+		Challenge string `json:"challenge"`
+		RP        struct {
+			ID string `json:"id"`
+		} `json:"rp"`
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	}
+	if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+		return "", fmt.Errorf("invalid creation options: %w", err)
+	}
+	userHandle, err := base64.RawURLEncoding.DecodeString(options.User.ID)
+	if err != nil {
+		return "", fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		return "", err
+	}
+	a.key, a.credentialID, a.userHandle, a.rpID = key, credentialID, userHandle, options.RP.ID
+
+	publicKey, err := a.coseKey()
+	if err != nil {
+		return "", err
+	}
+	// AAGUID (zero for software), then the credential ID and public key
+	attested := make([]byte, 16, 18+len(credentialID)+len(publicKey))
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(credentialID)))
+	attested = append(append(attested, credentialID...), publicKey...)
+	authData := a.authenticatorData(0x40, attested)
+
+	var attestation []byte
+	attestation = appendHead(attestation, 5, 3)
+	attestation = appendText(attestation, "fmt")
+	attestation = appendText(attestation, "none")
+	attestation = appendText(attestation, "attStmt")
+	attestation = appendHead(attestation, 5, 0)
+	attestation = appendText(attestation, "authData")
+	attestation = appendBytes(attestation, authData)
+
+	response := map[string]any{
+		"id":    encode(credentialID),
+		"rawId": encode(credentialID),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    encode(a.clientData("webauthn.create", options.Challenge)),
+			"attestationObject": encode(attestation),
+			"transports":        []string{"internal"},
+		},
+	}
+	b, err := json.Marshal(response)
+	return string(b), err
+}
+
+// Login signs in with the passkey for options as passed to navigator.credentials.get(), in JSON,
+// and returns the assertion as PublicKeyCredential.toJSON() would.
+func (a *Authenticator) Login(optionsJSON string) (string, error) {
+	if a.key == nil {
+		return "", fmt.Errorf("no passkey registered")
+	}
+	var options struct {
+		Challenge string `json:"challenge"`
+	}
+	if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+		return "", fmt.Errorf("invalid request options: %w", err)
+	}
+
+	a.SignCount++
+	authData := a.authenticatorData(0, nil)
+	clientData := a.clientData("webauthn.get", options.Challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	response := map[string]any{
+		"id":    encode(a.credentialID),
+		"rawId": encode(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    encode(clientData),
+			"authenticatorData": encode(authData),
+			"signature":         encode(signature),
+			"userHandle":        encode(a.userHandle),
+		},
+	}
+	b, err := json.Marshal(response)
+	return string(b), err
+}
+
+// authenticatorData returns the authenticator data with the user present flag, the user
+// verified flag unless skipped, and the given flags and attested credential data.
+func (a *Authenticator) authenticatorData(flags byte, attested []byte) []byte {
+	flags |= 0x01
+	if !a.SkipUserVerification {
+		flags |= 0x04
+	}
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.SignCount)
+	return append(data, attested...)
+}
+
+func (a *Authenticator) clientData(ceremony, challenge string) []byte {
+	b, _ := json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   challenge,
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+	return b
+}
+
+// coseKey returns the public key as an ES256 COSE_Key.
+func (a *Authenticator) coseKey() ([]byte, error) {
+	point, err := a.key.PublicKey.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var b []byte
+	b = appendHead(b, 5, 5)
+	b = appendInt(b, 1) // kty: EC2
+	b = appendInt(b, 2)
+	b = appendInt(b, 3) // alg: ES256
+	b = appendInt(b, -7)
+	b = appendInt(b, -1) // crv: P-256
+	b = appendInt(b, 1)
+	b = appendInt(b, -2) // x
+	b = appendBytes(b, point[1:33])
+	b = appendInt(b, -3) // y
+	b = appendBytes(b, point[33:65])
+	return b, nil
+}
+
+// appendHead appends a CBOR item's initial bytes: its major type and length or value.
+func appendHead(b []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(b, major<<5|byte(n))
+	case n <= 0xff:
+		return append(b, major<<5|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, major<<5|25), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, major<<5|26), uint32(n))
+	}
+}
+
+func appendInt(b []byte, n int64) []byte {
+	if n < 0 {
+		return appendHead(b, 1, uint64(-1-n))
+	}
+	return appendHead(b, 0, uint64(n))
+}
+
+func appendBytes(b, data []byte) []byte {
+	return append(appendHead(b, 2, uint64(len(data))), data...)
+}
+
+func appendText(b []byte, s string) []byte {
+	return append(appendHead(b, 3, uint64(len(s))), s...)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
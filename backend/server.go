@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"embed"
+	"errors"
 
 	"io/fs"
 	"log/slog"
@@ -12,6 +13,7 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // Time zone database for user time zones; the runtime image ships none
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/extension"
@@ -28,9 +30,12 @@ import (
 	"github.com/riverajo/fitness-app/backend/internal/api"
 	"github.com/riverajo/fitness-app/backend/internal/config"
 	"github.com/riverajo/fitness-app/backend/internal/db"
+	"github.com/riverajo/fitness-app/backend/internal/loaders"
 	"github.com/riverajo/fitness-app/backend/internal/middleware"
+	"github.com/riverajo/fitness-app/backend/internal/migrations"
 	"github.com/riverajo/fitness-app/backend/internal/repository"
 	"github.com/riverajo/fitness-app/backend/internal/seeder"
+	"github.com/riverajo/fitness-app/backend/internal/service"
 	"github.com/riverajo/fitness-app/backend/internal/spa"
 	"github.com/riverajo/fitness-app/backend/telemetry"
 )
@@ -110,22 +115,98 @@ func main() {
 	workoutRepo := repository.NewMongoWorkoutRepository(database)
 	exerciseRepo := repository.NewMongoExerciseRepository(database)
 	refreshTokenRepo := repository.NewMongoRefreshTokenRepository(database)
+	bodyMetricRepo := repository.NewMongoBodyMetricRepository(database)
+	goalRepo := repository.NewMongoGoalRepository(database)
+	rateLimitRepo := repository.NewMongoRateLimitRepository(database)
+	actionTokenRepo := repository.NewMongoActionTokenRepository(database)
+	tombstoneRepo := repository.NewMongoAccountTombstoneRepository(database)
+	passkeyRepo := repository.NewMongoPasskeyRepository(database)
+	identityRepo := repository.NewMongoExternalIdentityRepository(database)
+	leaseRepo := repository.NewMongoLeaseRepository(database)
+
+	// Apply pending migrations and create indexes before serving; only one node does so at a time
+	migrationCtx, cancelMigrations := context.WithTimeout(context.Background(), cfg.MigrationTimeout)
+	applied, err := migrations.NewRunner(database, migrations.All,
+		userRepo, workoutRepo, exerciseRepo, refreshTokenRepo, bodyMetricRepo, goalRepo, rateLimitRepo, actionTokenRepo, passkeyRepo, identityRepo, leaseRepo,
+	).Run(migrationCtx)
+	cancelMigrations()
+	for _, m := range applied {
+		slog.Info("Applied migration", "version", m.Version, "name", m.Name, "duration", m.Duration)
+	}
+	if err != nil {
+		slog.Error("Failed to migrate database", "error", err)
+		if shutdown != nil {
+			_ = shutdown(context.Background())
+		}
+		os.Exit(1)
+	}
+
+	// Merging exercises and force-deleting used ones need transactions; everything else works without
+	transactor := repository.NewMongoTransactor(database)
+	if err := transactor.CheckSupported(context.Background()); errors.Is(err, repository.ErrTransactionsUnsupported) {
+		slog.Warn("MongoDB is not a replica set; merging exercises and deleting used ones will fail")
+	} else if err != nil {
+		slog.Warn("Failed to check MongoDB for transaction support", "error", err)
+	}
 
 	// The Resolver struct is where you inject services like the WorkoutService
-	resolver := graph.NewResolver(userRepo, workoutRepo, exerciseRepo, refreshTokenRepo, cfg.JWTSecret, cfg)
+	resolver := graph.NewResolver(graph.Repositories{
+		Users:         userRepo,
+		Workouts:      workoutRepo,
+		Exercises:     exerciseRepo,
+		RefreshTokens: refreshTokenRepo,
+		BodyMetrics:   bodyMetricRepo,
+		Goals:         goalRepo,
+		Transactor:    transactor,
+		RateLimits:    rateLimitRepo,
+		ActionTokens:  actionTokenRepo,
+		Tombstones:    tombstoneRepo,
+		Passkeys:      passkeyRepo,
+		Identities:    identityRepo,
+		Leases:        leaseRepo,
+	}, cfg.JWTSecret, cfg)
+
+	// Check new passwords against the policy and, if configured, the breached password list
+	passwordPolicy := service.PasswordPolicy{MinLength: cfg.PasswordMinLength}
+	if cfg.BreachedPasswordsFile != "" {
+		breached, err := service.LoadBreachedPasswords(cfg.BreachedPasswordsFile)
+		if err != nil {
+			slog.Error("Failed to load breached password list", "error", err)
+			if shutdown != nil {
+				_ = shutdown(context.Background())
+			}
+			os.Exit(1)
+		}
+		slog.Info("Loaded breached password list", "hashes", breached.Len())
+		passwordPolicy.Breached = breached
+	}
+	resolver.UserService.SetPasswordPolicy(passwordPolicy)
+
+	// Serve system exercises from memory, reloading when the seeded catalogue changes
+	systemExercises := resolver.ExerciseService.SystemCache()
+	if err := systemExercises.Load(context.Background()); err != nil {
+		slog.Warn("Failed to load system exercise cache", "error", err)
+	}
+	go systemExercises.Watch(context.Background(), cfg.SystemExerciseRefresh)
+
+	// Delete the data of accounts whose deletion grace period has ended
+	go resolver.AccountDeletion.Run(context.Background(), cfg.AccountDeletionInterval)
 
 	// 4. GRAPHQL SERVER SETUP
 	srv := handler.New(graph.NewExecutableSchema(graph.Config{Resolvers: resolver}))
 
 	// Wrap the GraphQL server with the necessary middleware chain
-	finalHandler := middleware.AuthMiddleware(srv, cfg.JWTSecret)    // 1. Run Auth to validate token and put user ID in context
-	finalHandler = middleware.ResponseWriterMiddleware(finalHandler) // 2. Run ResponseWriter injector (needed for setting the cookie)
+	finalHandler := loaders.Middleware(srv, resolver.WorkoutService, resolver.ExerciseService, resolver.BodyMetricService) // 0. Give each request its own batching loaders
+	finalHandler = middleware.AuthMiddleware(finalHandler, cfg.JWTSecret)                                                  // 1. Run Auth to validate token and put user ID in context
+	finalHandler = middleware.ResponseWriterMiddleware(finalHandler)                                                       // 2. Run ResponseWriter injector (needed for setting the cookie)
+	finalHandler = middleware.LocaleMiddleware(finalHandler)                                                               // 3. Read Accept-Language for localized exercise names
 
 	// 5. STANDARD GQLGEN CONFIGURATION
 	srv.AddTransport(transport.Options{})
 	srv.AddTransport(transport.GET{})
 	srv.AddTransport(transport.POST{})
 	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.SetErrorPresenter(graph.ErrorPresenter)
 	srv.Use(extension.Introspection{})
 	srv.Use(extension.AutomaticPersistedQuery{
 		Cache: lru.New[string](100),
@@ -142,8 +223,11 @@ func main() {
 
 	// 7. AUTH HANDLERS
 	secureCookie := cfg.AppEnv == "production" && !cfg.CI
-	authHandler := api.NewAuthHandler(resolver.TokenService, resolver.UserService, cfg.JWTSecret, secureCookie)
+	authHandler := api.NewAuthHandler(resolver.TokenService, resolver.UserService, cfg.JWTSecret, secureCookie, cfg.TrustedProxies)
 	http.HandleFunc("/auth/refresh", authHandler.Refresh)
+	oidcHandler := api.NewOIDCHandler(resolver.OIDCService, resolver.TwoFactorService, resolver.TokenService, cfg.JWTSecret, secureCookie, cfg.TrustedProxies, cfg.AppURL)
+	http.HandleFunc("GET /auth/oidc/{provider}", oidcHandler.Start)
+	http.HandleFunc("GET /auth/oidc/{provider}/callback", oidcHandler.Callback)
 
 	http.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
 		if err := client.Ping(r.Context(), nil); err != nil {